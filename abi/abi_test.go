@@ -0,0 +1,419 @@
+package abi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tinychain/tinychain/common"
+)
+
+const testJSON = `[
+	{"type":"constructor","inputs":[{"name":"owner","type":"address"}]},
+	{"type":"function","name":"transfer","stateMutability":"nonpayable","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"who","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"sam","inputs":[{"name":"name","type":"bytes"},{"name":"flag","type":"bool"},{"name":"ids","type":"uint256[]"}]},
+	{"type":"function","name":"f","inputs":[{"type":"uint256"},{"type":"uint32[]"},{"type":"bytes10"},{"type":"bytes"}]},
+	{"type":"function","name":"baz","inputs":[{"type":"uint32"},{"type":"bool"}],"outputs":[{"type":"bool"}]},
+	{"type":"function","name":"point","inputs":[{"name":"p","type":"tuple","components":[{"name":"x","type":"int64"},{"name":"label","type":"string"}]}],"outputs":[{"name":"","type":"tuple[]","components":[{"name":"x","type":"int64"},{"name":"label","type":"string"}]}]},
+	{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]},
+	{"type":"error","name":"InsufficientBalance","inputs":[{"name":"available","type":"uint256"},{"name":"required","type":"uint256"}]},
+	{"type":"fallback"}
+]`
+
+func mustJSON(t *testing.T) ABI {
+	abi, err := JSON(strings.NewReader(testJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return abi
+}
+
+// words joins hex encoded 32 byte words, right padding every word.
+func words(ws ...string) []byte {
+	var out []byte
+	for _, w := range ws {
+		b, _ := hex.DecodeString(w)
+		out = append(out, common.RightPadBytes(b, 32)...)
+	}
+	return out
+}
+
+// num encodes an integer as a single abi word.
+func num(n int64) string {
+	return hex.EncodeToString(common.LeftPadBytes(big.NewInt(n).Bytes(), 32))
+}
+
+func TestReader(t *testing.T) {
+	abi := mustJSON(t)
+
+	if len(abi.Methods) != 6 || len(abi.Events) != 1 || len(abi.Errors) != 1 {
+		t.Fatalf("unexpected abi contents: %d methods, %d events, %d errors", len(abi.Methods), len(abi.Events), len(abi.Errors))
+	}
+	if !abi.HasFallback() || abi.HasReceive() {
+		t.Errorf("fallback/receive mismatch")
+	}
+	if !abi.Methods["balanceOf"].IsConstant() || abi.Methods["transfer"].IsConstant() {
+		t.Errorf("constant mismatch")
+	}
+	if len(abi.Constructor.Inputs) != 1 {
+		t.Errorf("constructor inputs: have %d, want 1", len(abi.Constructor.Inputs))
+	}
+	if sig := abi.Methods["point"].Sig; sig != "point((int64,string))" {
+		t.Errorf("tuple signature: have %s", sig)
+	}
+}
+
+func TestSelectors(t *testing.T) {
+	abi := mustJSON(t)
+	for name, want := range map[string]string{
+		"transfer": "a9059cbb",
+		"baz":      "cdcd77c0",
+		"sam":      "a5643bf2",
+		"f":        "8be65246",
+	} {
+		if have := hex.EncodeToString(abi.Methods[name].ID); have != want {
+			t.Errorf("%s: selector mismatch: have %s, want %s", name, have, want)
+		}
+	}
+	want := "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+	if have := hex.EncodeToString(abi.Events["Transfer"].ID.Bytes()); have != want {
+		t.Errorf("event id mismatch: have %s, want %s", have, want)
+	}
+	if m, err := abi.MethodById(common.FromHex("a9059cbb00")); err != nil || m.Name != "transfer" {
+		t.Errorf("MethodById: have %v, %v", m.Name, err)
+	}
+}
+
+func TestPackSolidityExamples(t *testing.T) {
+	abi := mustJSON(t)
+
+	packed, err := abi.Pack("baz", uint32(69), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append(common.FromHex("cdcd77c0"), words(num(69), num(1))...); !bytes.Equal(packed, want) {
+		t.Errorf("baz: have %x, want %x", packed, want)
+	}
+
+	packed, err = abi.Pack("sam", []byte("dave"), true, []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append(common.FromHex("a5643bf2"), words(num(0x60), num(1), num(0xa0), num(4), "64617665", num(3), num(1), num(2), num(3))...)
+	if !bytes.Equal(packed, want) {
+		t.Errorf("sam: have %x, want %x", packed, want)
+	}
+
+	var b10 [10]byte
+	copy(b10[:], "1234567890")
+	packed, err = abi.Pack("f", big.NewInt(0x123), []uint32{0x456, 0x789}, b10, []byte("Hello, world!"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = append(common.FromHex("8be65246"), words(num(0x123), num(0x80), "31323334353637383930", num(0xe0), num(2), num(0x456), num(0x789), num(0xd), "48656c6c6f2c20776f726c6421")...)
+	if !bytes.Equal(packed, want) {
+		t.Errorf("f: have %x, want %x", packed, want)
+	}
+}
+
+func TestPackErrors(t *testing.T) {
+	abi := mustJSON(t)
+	for i, args := range [][]interface{}{
+		{uint32(1)},             // argument count
+		{int64(-1), true},       // negative unsigned
+		{uint64(1 << 40), true}, // overflowing uint32
+		{uint32(1), "true"},     // wrong type
+		{new(big.Int).Lsh(big.NewInt(1), 32), true},
+	} {
+		if _, err := abi.Pack("baz", args...); err == nil {
+			t.Errorf("test %d: expected error", i)
+		}
+	}
+	if _, err := abi.Pack("missing"); err == nil {
+		t.Errorf("expected error for unknown method")
+	}
+}
+
+func TestPackBySignature(t *testing.T) {
+	abi := mustJSON(t)
+	to := common.HexToAddress("0x00000000000000000000000000000000000000ff")
+
+	byName, err := abi.Pack("transfer", to, big.NewInt(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bySig, err := abi.Pack("transfer(address,uint256)", to, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(byName, bySig) {
+		t.Errorf("pack mismatch: %x != %x", byName, bySig)
+	}
+
+	// Methods don't have to be declared in an ABI to be called symbolically.
+	method, err := ParseMethod("transfer(address,uint256)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := method.Inputs.PackValues("0x00000000000000000000000000000000000000ff", "1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(append(method.ID, parsed...), byName) {
+		t.Errorf("symbolic pack mismatch: %x != %x", append(method.ID, parsed...), byName)
+	}
+}
+
+func TestTupleRoundTrip(t *testing.T) {
+	abi := mustJSON(t)
+	type point struct {
+		X     int64
+		Label string
+	}
+	packed, err := abi.Pack("point", point{X: -7, Label: "origin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A tuple given as a list packs identically.
+	listed, err := abi.Pack("point", []interface{}{int64(-7), "origin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, listed) {
+		t.Fatalf("struct and list packing differ: %x != %x", packed, listed)
+	}
+	values, err := abi.Methods["point"].Inputs.Unpack(packed[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := reflect.ValueOf(values[0])
+	if v.Field(0).Int() != -7 || v.Field(1).String() != "origin" {
+		t.Errorf("unexpected tuple: %+v", values[0])
+	}
+
+	outputs := abi.Methods["point"].Outputs
+	data, err := outputs.Pack([]point{{1, "a"}, {2, "bc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err = abi.Unpack("point", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := reflect.ValueOf(values[0]); s.Len() != 2 || s.Index(1).Field(1).String() != "bc" {
+		t.Errorf("unexpected tuple slice: %+v", values[0])
+	}
+}
+
+func TestArgumentsRoundTrip(t *testing.T) {
+	for i, test := range []struct {
+		sig    string
+		values []interface{}
+	}{
+		{"f(uint8,int16,int256)", []interface{}{uint8(255), int16(-300), big.NewInt(-1)}},
+		{"f(string,bytes)", []interface{}{"hello", []byte{1, 2, 3}}},
+		{"f(uint64[2][3])", []interface{}{[3][2]uint64{{1, 2}, {3, 4}, {5, 6}}}},
+		{"f(string[],bool)", []interface{}{[]string{"a", "", "long string that spans more than thirty-two bytes"}, true}},
+		{"f(bytes32,address)", []interface{}{[32]byte{1}, common.HexToAddress("0x0102")}},
+	} {
+		method, err := ParseMethod(test.sig)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		data, err := method.Inputs.Pack(test.values...)
+		if err != nil {
+			t.Fatalf("test %d: pack: %v", i, err)
+		}
+		values, err := method.Inputs.Unpack(data)
+		if err != nil {
+			t.Fatalf("test %d: unpack: %v", i, err)
+		}
+		if !reflect.DeepEqual(values, test.values) {
+			t.Errorf("test %d: have %v, want %v", i, values, test.values)
+		}
+	}
+}
+
+func TestUnpackMalformed(t *testing.T) {
+	method, _ := ParseMethod("f(bytes)")
+	for i, data := range [][]byte{
+		words(num(32)),            // missing length
+		words(num(32), num(1000)), // length beyond data
+		words(num(1<<40), num(1)), // offset beyond data
+	} {
+		if _, err := method.Inputs.Unpack(data); err == nil {
+			t.Errorf("test %d: expected error", i)
+		}
+	}
+	method, _ = ParseMethod("f(bool)")
+	if _, err := method.Inputs.Unpack(words(num(2))); err == nil {
+		t.Errorf("expected error for malformed bool")
+	}
+}
+
+func TestParseSignatures(t *testing.T) {
+	method, err := ParseMethod("balanceOf(address who) returns (uint256)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method.Sig != "balanceOf(address)" || len(method.Outputs) != 1 {
+		t.Errorf("unexpected method %v", method)
+	}
+	method, err = ParseMethod("function submit((uint256,bool)[] orders, bytes data)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method.Sig != "submit((uint256,bool)[],bytes)" {
+		t.Errorf("unexpected signature %s", method.Sig)
+	}
+
+	event, err := ParseEvent("event Transfer(address indexed from, address indexed to, uint256 value)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.ID != mustJSON(t).Events["Transfer"].ID || len(event.Inputs.Indexed()) != 2 {
+		t.Errorf("unexpected event %v", event)
+	}
+	for _, sig := range []string{"", "f(", "f(uint7)", "(uint256)", "f(uint256))"} {
+		if _, err := ParseMethod(sig); err == nil {
+			t.Errorf("%q: expected error", sig)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	for i, test := range []struct {
+		typ, value string
+		want       interface{}
+	}{
+		{"uint256", "0x10", big.NewInt(16)},
+		{"int8", "-128", big.NewInt(-128)},
+		{"bool", "true", true},
+		{"string", "hi", "hi"},
+		{"bytes", "0x0102", []byte{1, 2}},
+		{"bytes2", "0102", [2]byte{1, 2}},
+		{"address", "0x00000000000000000000000000000000000000ff", common.HexToAddress("0xff")},
+		{"uint8[]", `[1, "0x2"]`, []interface{}{big.NewInt(1), big.NewInt(2)}},
+	} {
+		typ, err := NewType(test.typ, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		have, err := ParseValue(typ, test.value)
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(have, test.want) {
+			t.Errorf("test %d: have %v, want %v", i, have, test.want)
+		}
+	}
+	for _, test := range [][2]string{{"uint8", "256"}, {"int8", "-129"}, {"address", "0x01"}, {"bytes2", "01"}, {"uint8[2]", "[1]"}} {
+		typ, _ := NewType(test[0], nil)
+		if _, err := ParseValue(typ, test[1]); err == nil {
+			t.Errorf("%s %s: expected error", test[0], test[1])
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	abi := mustJSON(t)
+
+	// Error(string) with reason "revert reason"
+	data := append(common.FromHex("08c379a0"), words(num(0x20), num(13), hex.EncodeToString([]byte("revert reason")))...)
+	reason, err := UnpackRevert(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason != "revert reason" {
+		t.Errorf("have %q, want %q", reason, "revert reason")
+	}
+	if _, err := UnpackRevert(data[:3]); err == nil {
+		t.Errorf("expected error for short data")
+	}
+
+	custom := abi.Errors["InsufficientBalance"]
+	packed, err := custom.Inputs.Pack(big.NewInt(1), big.NewInt(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := abi.ErrorByID(custom.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := e.Unpack(append(custom.ID, packed...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[1].(*big.Int).Int64() != 2 {
+		t.Errorf("unexpected values %v", values)
+	}
+}
+
+func TestDecodeLog(t *testing.T) {
+	abi := mustJSON(t)
+	from := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	to := common.HexToAddress("0x0000000000000000000000000000000000000002")
+	topics := []common.Hash{
+		abi.Events["Transfer"].ID,
+		common.BytesToHash(from.Bytes()),
+		common.BytesToHash(to.Bytes()),
+	}
+	event, err := abi.DecodeLog(topics, words(num(1000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Fields[0].Value != from || event.Fields[1].Value != to || event.Fields[2].Value.(*big.Int).Int64() != 1000 {
+		t.Errorf("unexpected fields %+v", event.Fields)
+	}
+	want := "Transfer(from: 0x0000000000000000000000000000000000000001, to: 0x0000000000000000000000000000000000000002, value: 1000)"
+	if have := event.String(); have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	blob, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{"event":"Transfer","signature":"Transfer(address,address,uint256)","args":{"from":"0x0000000000000000000000000000000000000001","to":"0x0000000000000000000000000000000000000002","value":"1000"}}`
+	if string(blob) != want {
+		t.Errorf("have %s, want %s", blob, want)
+	}
+
+	if _, err := abi.DecodeLog(topics[:2], words(num(1000))); err == nil {
+		t.Errorf("expected error for missing topic")
+	}
+	if _, err := abi.DecodeLog([]common.Hash{{1}}, nil); err == nil {
+		t.Errorf("expected error for unknown event")
+	}
+	if _, err := abi.DecodeLog(nil, nil); err == nil {
+		t.Errorf("expected error for log without topics")
+	}
+}
+
+func TestDecodeLogIndexedDynamic(t *testing.T) {
+	event, err := ParseEvent("event Named(string indexed name, bytes data, uint8[2] pair)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nameHash := common.HexToHash("0x1234")
+	data, err := event.Inputs.NonIndexed().Pack([]byte{0xca, 0xfe}, [2]uint8{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := event.DecodeLog([]common.Hash{event.ID, nameHash}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Fields[0].Value != nameHash {
+		t.Errorf("indexed string: have %v, want its hash", decoded.Fields[0].Value)
+	}
+	want := "Named(name: " + common.Hex(nameHash.Bytes()) + `, data: 0xcafe, pair: ["1","2"])`
+	if have := decoded.String(); have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+}
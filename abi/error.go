@@ -0,0 +1,97 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tinychain/tinychain/core/vm/evm/crypto"
+)
+
+// revertSelector is the selector of the builtin Error(string) revert reason.
+var revertSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+
+// Error is a custom error defined in the ABI, raised by reverting with its
+// selector followed by the encoded inputs.
+type Error struct {
+	Name   string
+	Inputs Arguments
+	str    string
+
+	// Sig contains the string signature according to the ABI spec.
+	// e.g.	 error foo(uint32 a, int b) = "foo(uint32,int256)"
+	// Please note that "int" is substitute for its canonical representation "int256"
+	Sig string
+
+	// ID returns the canonical representation of the error's signature used by the
+	// abi definition to identify error names and types.
+	ID []byte
+}
+
+// NewError creates a new Error.
+func NewError(name string, inputs Arguments) Error {
+	names := make([]string, len(inputs))
+	for i, input := range inputs {
+		if input.Name == "" {
+			inputs[i].Name = fmt.Sprintf("arg%d", i)
+		}
+		names[i] = fmt.Sprintf("%v %v", input.Type, inputs[i].Name)
+	}
+	sig := fmt.Sprintf("%v(%v)", name, inputs.Types())
+	return Error{
+		Name:   name,
+		Inputs: inputs,
+		str:    fmt.Sprintf("error %v(%v)", name, strings.Join(names, ", ")),
+		Sig:    sig,
+		ID:     crypto.Keccak256([]byte(sig))[:4],
+	}
+}
+
+func (e Error) String() string {
+	return e.str
+}
+
+// Unpack decodes the inputs of the error from revert data, which must start
+// with the error selector.
+func (e Error) Unpack(data []byte) ([]interface{}, error) {
+	if len(data) < 4 || !bytes.Equal(data[:4], e.ID) {
+		return nil, errors.New("abi: invalid data for unpacking")
+	}
+	return e.Inputs.Unpack(data[4:])
+}
+
+// UnpackRevert resolves the abi-encoded revert reason. According to the solidity
+// spec https://solidity.readthedocs.io/en/latest/control-structures.html#revert,
+// the provided revert reason is abi-encoded as if it were a call to a function
+// `Error(string)`. So it's a special tool for it.
+func UnpackRevert(data []byte) (string, error) {
+	if len(data) < 4 {
+		return "", errors.New("invalid data for unpacking")
+	}
+	if !bytes.Equal(data[:4], revertSelector) {
+		return "", errors.New("invalid data for unpacking")
+	}
+	typ, _ := NewType("string", nil)
+	unpacked, err := (Arguments{{Type: typ}}).Unpack(data[4:])
+	if err != nil {
+		return "", err
+	}
+	return unpacked[0].(string), nil
+}
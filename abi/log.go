@@ -0,0 +1,159 @@
+package abi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/tinychain/tinychain/common"
+)
+
+var (
+	errNoTopics          = errors.New("abi: log has no topics")
+	errTopicCountInvalid = errors.New("abi: topic count doesn't match indexed arguments")
+)
+
+// DecodedField is a single decoded event argument.
+type DecodedField struct {
+	Name    string
+	Type    Type
+	Indexed bool
+	// Value holds the decoded Go value. Indexed arguments of dynamic types
+	// (strings, bytes, arrays and tuples) are only logged as the keccak256
+	// hash of their encoding, their Value is that common.Hash.
+	Value interface{}
+}
+
+// DecodedEvent is a log matched against and decoded with an ABI event.
+type DecodedEvent struct {
+	Event  *Event
+	Fields []DecodedField
+}
+
+// DecodeLog looks the event emitting a log up by its first topic and decodes
+// both indexed arguments, held by the remaining topics, and the non-indexed
+// arguments packed in data.
+func (abi ABI) DecodeLog(topics []common.Hash, data []byte) (*DecodedEvent, error) {
+	if len(topics) == 0 {
+		return nil, errNoTopics
+	}
+	event, err := abi.EventByID(topics[0])
+	if err != nil {
+		return nil, err
+	}
+	return event.DecodeLog(topics, data)
+}
+
+// DecodeLog decodes the topics and data of a log emitted by the event.
+func (e Event) DecodeLog(topics []common.Hash, data []byte) (*DecodedEvent, error) {
+	if !e.Anonymous {
+		if len(topics) == 0 || topics[0] != e.ID {
+			return nil, fmt.Errorf("abi: log is not a %s event", e.RawName)
+		}
+		topics = topics[1:]
+	}
+	if len(topics) != len(e.Inputs.Indexed()) {
+		return nil, errTopicCountInvalid
+	}
+	values, err := e.Inputs.Unpack(data)
+	if err != nil {
+		return nil, err
+	}
+	decoded := &DecodedEvent{Event: &e, Fields: make([]DecodedField, len(e.Inputs))}
+	for i, input := range e.Inputs {
+		field := DecodedField{Name: input.Name, Type: input.Type, Indexed: input.Indexed}
+		if input.Indexed {
+			if field.Value, err = readTopic(input.Type, topics[0]); err != nil {
+				return nil, err
+			}
+			topics = topics[1:]
+		} else {
+			field.Value, values = values[0], values[1:]
+		}
+		decoded.Fields[i] = field
+	}
+	return decoded, nil
+}
+
+// readTopic decodes an indexed argument from its topic.
+func readTopic(t Type, topic common.Hash) (interface{}, error) {
+	switch t.T {
+	case StringTy, BytesTy, SliceTy, ArrayTy, TupleTy:
+		return topic, nil
+	default:
+		return toGoType(0, t, topic.Bytes())
+	}
+}
+
+// Map returns the decoded fields keyed by argument name, in the
+// representation used by MarshalJSON.
+func (d *DecodedEvent) Map() map[string]interface{} {
+	fields := make(map[string]interface{}, len(d.Fields))
+	for _, field := range d.Fields {
+		fields[field.Name] = jsonValue(field.Type, field.Value)
+	}
+	return fields
+}
+
+// MarshalJSON implements json.Marshaler. Integers are encoded as decimal
+// strings so no precision is lost, addresses and byte strings as hex.
+func (d *DecodedEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Event     string                 `json:"event"`
+		Signature string                 `json:"signature"`
+		Args      map[string]interface{} `json:"args"`
+	}{d.Event.RawName, d.Event.Sig, d.Map()})
+}
+
+// String returns a human readable representation of the event, e.g.
+// "Transfer(from: 0x01.., to: 0x02.., value: 1000)".
+func (d *DecodedEvent) String() string {
+	fields := make([]string, len(d.Fields))
+	for i, field := range d.Fields {
+		value, _ := json.Marshal(jsonValue(field.Type, field.Value))
+		fields[i] = fmt.Sprintf("%s: %s", field.Name, strings.Trim(string(value), `"`))
+	}
+	return fmt.Sprintf("%s(%s)", d.Event.RawName, strings.Join(fields, ", "))
+}
+
+// jsonValue converts a decoded value of type t into a value encoding to
+// readable JSON.
+func jsonValue(t Type, v interface{}) interface{} {
+	if hash, ok := v.(common.Hash); ok {
+		// indexed dynamic value
+		return common.Hex(hash.Bytes())
+	}
+	rv := reflect.ValueOf(v)
+	switch t.T {
+	case IntTy, UintTy:
+		n, err := toBig(indirect(rv))
+		if err != nil {
+			return v
+		}
+		return n.String()
+	case AddressTy:
+		return common.Hex(v.(common.Address).Bytes())
+	case BytesTy, FixedBytesTy, FunctionTy:
+		return common.Hex(bytesOf(rv))
+	case SliceTy, ArrayTy:
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = jsonValue(*t.Elem, rv.Index(i).Interface())
+		}
+		return out
+	case TupleTy:
+		out := make(map[string]interface{}, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			name := t.TupleRawNames[i]
+			if name == "" {
+				name = fmt.Sprintf("%d", i)
+			}
+			out[name] = jsonValue(*elem, rv.Field(i).Interface())
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+package abi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tinychain/tinychain/core/vm/evm/crypto"
+)
+
+// FunctionType represents different types of functions a contract might have.
+type FunctionType int
+
+const (
+	// Constructor represents the constructor of the contract.
+	// The constructor function is called while deploying a contract.
+	Constructor FunctionType = iota
+	// Fallback represents the fallback function.
+	// This function is executed if no other function matches the given function
+	// signature and no receive function is specified.
+	Fallback
+	// Receive represents the receive function.
+	// This function is executed on plain Ether transfers.
+	Receive
+	// Function represents a normal function.
+	Function
+)
+
+// Method represents a callable given a `Name` and whether the method is a constant.
+// If the method is `Const` no transaction needs to be created for this
+// particular Method call. It can easily be simulated using a local VM.
+// For example a `Balance()` method only needs to retrieve something
+// from the storage and therefore requires no Tx to be sent to the
+// network. A method such as `Transact` does require a Tx and thus will
+// be flagged `false`.
+// Input specifies the required input parameters for this gives method.
+type Method struct {
+	// Name is the method name used for internal representation. It's derived from
+	// the raw name and a suffix will be added in the case of a function overload.
+	//
+	// e.g.
+	// These are two functions that have the same name:
+	// * foo(int,int)
+	// * foo(uint,uint)
+	// The method name of the first one will be resolved as foo while the second one
+	// will be resolved as foo0.
+	Name    string
+	RawName string // RawName is the raw method name parsed from ABI
+
+	// Type indicates whether the method is a
+	// special fallback introduced in solidity v0.6.0
+	Type FunctionType
+
+	// StateMutability indicates the mutability state of method,
+	// the default value is nonpayable. It can be empty if the abi
+	// is generated by legacy compiler.
+	StateMutability string
+
+	Inputs  Arguments
+	Outputs Arguments
+
+	// Sig returns the methods string signature according to the ABI spec.
+	// e.g.		function foo(uint32 a, int b) = "foo(uint32,int256)"
+	// Please note that "int" is substitute for its canonical representation "int256"
+	Sig string
+	// ID returns the canonical representation of the method's signature used by the
+	// abi definition to identify method names and types.
+	ID []byte
+}
+
+// NewMethod creates a new Method.
+// A method should always be created using NewMethod.
+// It also precomputes the sig representation and the string representation
+// of the method.
+func NewMethod(name string, rawName string, funType FunctionType, mutability string, inputs Arguments, outputs Arguments) Method {
+	var sig string
+	var id []byte
+	// Only normal functions have a signature and a selector
+	if funType == Function {
+		sig = fmt.Sprintf("%v(%v)", rawName, inputs.Types())
+		id = crypto.Keccak256([]byte(sig))[:4]
+	}
+	return Method{
+		Name:            name,
+		RawName:         rawName,
+		Type:            funType,
+		StateMutability: mutability,
+		Inputs:          inputs,
+		Outputs:         outputs,
+		Sig:             sig,
+		ID:              id,
+	}
+}
+
+// Pack encodes the call of the method with the given arguments, prefixed by
+// the method selector. Constructor arguments are not prefixed.
+func (method Method) Pack(args ...interface{}) ([]byte, error) {
+	arguments, err := method.Inputs.Pack(args...)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, method.ID...), arguments...), nil
+}
+
+// IsConstant returns the indicator whether the method is read-only.
+func (method Method) IsConstant() bool {
+	return method.StateMutability == "view" || method.StateMutability == "pure"
+}
+
+// IsPayable returns the indicator whether the method can process
+// plain ether transfers.
+func (method Method) IsPayable() bool {
+	return method.StateMutability == "payable"
+}
+
+// String returns a human readable representation of the method, e.g.
+// "function transfer(address to, uint256 value) returns(bool)".
+func (method Method) String() string {
+	inputs := make([]string, len(method.Inputs))
+	for i, input := range method.Inputs {
+		inputs[i] = input.Type.String()
+		if input.Name != "" {
+			inputs[i] = fmt.Sprintf("%v %v", input.Type, input.Name)
+		}
+	}
+	outputs := make([]string, len(method.Outputs))
+	for i, output := range method.Outputs {
+		outputs[i] = output.Type.String()
+		if output.Name != "" {
+			outputs[i] = fmt.Sprintf("%v %v", output.Type, output.Name)
+		}
+	}
+	var identity string
+	switch method.Type {
+	case Fallback:
+		identity = "fallback"
+	case Receive:
+		identity = "receive"
+	case Constructor:
+		identity = "constructor"
+	default:
+		identity = "function " + method.RawName
+	}
+	state := method.StateMutability
+	if state != "" && state != "nonpayable" {
+		state = state + " "
+	} else {
+		state = ""
+	}
+	str := fmt.Sprintf("%v(%v) %sreturns(%v)", identity, strings.Join(inputs, ", "), state, strings.Join(outputs, ", "))
+	if len(method.Outputs) == 0 {
+		str = fmt.Sprintf("%v(%v) %s", identity, strings.Join(inputs, ", "), state)
+		str = strings.TrimSpace(str)
+	}
+	return str
+}
@@ -0,0 +1,287 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/tinychain/tinychain/common"
+)
+
+var (
+	bigT       = reflect.TypeOf(&big.Int{})
+	maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+)
+
+// pack packs the given value according to the abi type.
+func (t Type) pack(v reflect.Value) ([]byte, error) {
+	v = indirect(v)
+	if !v.IsValid() {
+		return nil, fmt.Errorf("abi: cannot use nil as type %v", t)
+	}
+
+	switch t.T {
+	case SliceTy, ArrayTy:
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return nil, typeErr(t, v)
+		}
+		if t.T == ArrayTy && v.Len() != t.Size {
+			return nil, fmt.Errorf("abi: cannot use array of length %d as type %v", v.Len(), t)
+		}
+		var ret []byte
+		if t.requiresLengthPrefix() {
+			// append length
+			ret = append(ret, packNum(big.NewInt(int64(v.Len())))...)
+		}
+
+		// calculate offset if any
+		offset := 0
+		offsetReq := isDynamicType(*t.Elem)
+		if offsetReq {
+			offset = getTypeSize(*t.Elem) * v.Len()
+		}
+		var tail []byte
+		for i := 0; i < v.Len(); i++ {
+			val, err := t.Elem.pack(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			if !offsetReq {
+				ret = append(ret, val...)
+				continue
+			}
+			ret = append(ret, packNum(big.NewInt(int64(offset)))...)
+			offset += len(val)
+			tail = append(tail, val...)
+		}
+		return append(ret, tail...), nil
+
+	case TupleTy:
+		fields, err := tupleFields(t, v)
+		if err != nil {
+			return nil, err
+		}
+		// calculate offset if any
+		offset := 0
+		for _, elem := range t.TupleElems {
+			offset += getTypeSize(*elem)
+		}
+		var ret, tail []byte
+		for i, elem := range t.TupleElems {
+			val, err := elem.pack(fields[i])
+			if err != nil {
+				return nil, err
+			}
+			if isDynamicType(*elem) {
+				ret = append(ret, packNum(big.NewInt(int64(offset)))...)
+				tail = append(tail, val...)
+				offset += len(val)
+			} else {
+				ret = append(ret, val...)
+			}
+		}
+		return append(ret, tail...), nil
+
+	default:
+		return packElement(t, v)
+	}
+}
+
+// packElement packs the given reflect value according to the abi specification in
+// t. Integers may be given as any Go integer type or as *big.Int.
+func packElement(t Type, v reflect.Value) ([]byte, error) {
+	switch t.T {
+	case IntTy, UintTy:
+		n, err := toBig(v)
+		if err != nil {
+			return nil, typeErr(t, v)
+		}
+		if err := checkIntRange(t, n); err != nil {
+			return nil, err
+		}
+		return packNum(n), nil
+
+	case BoolTy:
+		if v.Kind() != reflect.Bool {
+			return nil, typeErr(t, v)
+		}
+		if v.Bool() {
+			return math256(1), nil
+		}
+		return math256(0), nil
+
+	case StringTy:
+		if v.Kind() != reflect.String {
+			return nil, typeErr(t, v)
+		}
+		return packBytesSlice([]byte(v.String()), v.Len()), nil
+
+	case AddressTy:
+		if v.Kind() == reflect.Array && v.Len() == common.AddressLength && v.Type().Elem().Kind() == reflect.Uint8 {
+			return common.LeftPadBytes(bytesOf(v), 32), nil
+		}
+		return nil, typeErr(t, v)
+
+	case BytesTy:
+		if !isByteSeq(v) {
+			return nil, typeErr(t, v)
+		}
+		return packBytesSlice(bytesOf(v), v.Len()), nil
+
+	case FixedBytesTy, FunctionTy:
+		if !isByteSeq(v) || v.Len() != t.Size {
+			return nil, typeErr(t, v)
+		}
+		return common.RightPadBytes(bytesOf(v), 32), nil
+
+	default:
+		return nil, fmt.Errorf("abi: could not pack element, unknown type: %v", t)
+	}
+}
+
+// packNum packs the given number (using the reflect value) and will cast it to
+// the appropriate two's complement representation of 32 bytes.
+func packNum(n *big.Int) []byte {
+	if n.Sign() >= 0 {
+		return common.LeftPadBytes(n.Bytes(), 32)
+	}
+	u := new(big.Int).Add(maxUint256, n)
+	u.Add(u, big.NewInt(1))
+	return common.LeftPadBytes(u.Bytes(), 32)
+}
+
+// packBytesSlice packs the given bytes as [L, V] as the canonical representation
+// bytes slice.
+func packBytesSlice(bytes []byte, l int) []byte {
+	len := packNum(big.NewInt(int64(l)))
+	return append(len, common.RightPadBytes(bytes, (l+31)/32*32)...)
+}
+
+func math256(n int64) []byte {
+	return packNum(big.NewInt(n))
+}
+
+// checkIntRange ensures n fits into the integer type t.
+func checkIntRange(t Type, n *big.Int) error {
+	if t.T == UintTy {
+		if n.Sign() < 0 || n.BitLen() > t.Size {
+			return fmt.Errorf("abi: %v out of range for type %v", n, t)
+		}
+		return nil
+	}
+	// Signed values take t.Size-1 bits plus the sign
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+	if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+		return fmt.Errorf("abi: %v out of range for type %v", n, t)
+	}
+	return nil
+}
+
+// toBig converts any Go integer or *big.Int value into a *big.Int.
+func toBig(v reflect.Value) (*big.Int, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Int).SetUint64(v.Uint()), nil
+	}
+	if v.Type() == bigT.Elem() && v.CanAddr() {
+		return v.Addr().Interface().(*big.Int), nil
+	}
+	if v.Type() == bigT.Elem() {
+		n := v.Interface().(big.Int)
+		return &n, nil
+	}
+	return nil, errors.New("abi: not an integer")
+}
+
+// tupleFields returns the values to pack for every tuple element. Tuples may
+// be given as structs, whose fields are matched by name, or as slices holding
+// the elements in order.
+func tupleFields(t Type, v reflect.Value) ([]reflect.Value, error) {
+	fields := make([]reflect.Value, len(t.TupleElems))
+	switch v.Kind() {
+	case reflect.Struct:
+		for i, name := range t.TupleRawNames {
+			field, ok := structField(v, i, name)
+			if !ok {
+				return nil, fmt.Errorf("abi: field %s can't be found in the given value", name)
+			}
+			fields[i] = field
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Len() != len(t.TupleElems) {
+			return nil, fmt.Errorf("abi: cannot use %d values as tuple %v", v.Len(), t)
+		}
+		for i := range fields {
+			fields[i] = v.Index(i)
+		}
+	default:
+		return nil, typeErr(t, v)
+	}
+	return fields, nil
+}
+
+// structField looks the i'th tuple element up by its abi tag or its camel cased
+// name. Unnamed elements are matched by position.
+func structField(v reflect.Value, i int, name string) (reflect.Value, bool) {
+	if name == "" {
+		if i >= v.NumField() {
+			return reflect.Value{}, false
+		}
+		return v.Field(i), true
+	}
+	typ := v.Type()
+	for j := 0; j < typ.NumField(); j++ {
+		if typ.Field(j).Tag.Get("abi") == name {
+			return v.Field(j), true
+		}
+	}
+	field := v.FieldByName(ToCamelCase(name))
+	return field, field.IsValid()
+}
+
+// indirect recursively dereferences the value until it either gets the value
+// or finds a big.Int
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.Type() == bigT {
+			return v.Elem()
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// isByteSeq reports whether v is a byte slice or a byte array.
+func isByteSeq(v reflect.Value) bool {
+	return (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() == reflect.Uint8
+}
+
+// bytesOf copies the content of a byte slice or array value.
+func bytesOf(v reflect.Value) []byte {
+	out := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(out), v)
+	return out
+}
+
+func typeErr(t Type, v reflect.Value) error {
+	return fmt.Errorf("abi: cannot use %v as type %v", v.Type(), t)
+}
@@ -0,0 +1,189 @@
+package abi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseMethod parses a human readable function signature such as
+// "transfer(address,uint256)" or "balanceOf(address owner)(uint256)" into a
+// Method, so contracts can be called without a JSON ABI at hand. Parameter
+// names are optional and the outputs may be given in a second parameter list,
+// optionally preceded by "returns".
+func ParseMethod(sig string) (Method, error) {
+	name, inputs, rest, err := parseSignature(sig)
+	if err != nil {
+		return Method{}, err
+	}
+	var outputs Arguments
+	rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), "returns"))
+	if rest != "" {
+		if rest[0] != '(' || rest[len(rest)-1] != ')' {
+			return Method{}, fmt.Errorf("abi: invalid outputs in signature %q", sig)
+		}
+		if outputs, err = parseArguments(rest[1 : len(rest)-1]); err != nil {
+			return Method{}, err
+		}
+	}
+	return NewMethod(name, name, Function, "", inputs, outputs), nil
+}
+
+// ParseEvent parses a human readable event signature such as
+// "Transfer(address indexed from, address indexed to, uint256 value)".
+func ParseEvent(sig string) (Event, error) {
+	name, inputs, rest, err := parseSignature(sig)
+	if err != nil {
+		return Event{}, err
+	}
+	anonymous := strings.TrimSpace(rest) == "anonymous"
+	if !anonymous && strings.TrimSpace(rest) != "" {
+		return Event{}, fmt.Errorf("abi: unexpected %q in event signature", rest)
+	}
+	return NewEvent(name, name, anonymous, inputs), nil
+}
+
+// ParseError parses a human readable error signature such as
+// "InsufficientBalance(uint256 available, uint256 required)".
+func ParseError(sig string) (Error, error) {
+	name, inputs, rest, err := parseSignature(sig)
+	if err != nil {
+		return Error{}, err
+	}
+	if strings.TrimSpace(rest) != "" {
+		return Error{}, fmt.Errorf("abi: unexpected %q in error signature", rest)
+	}
+	return NewError(name, inputs), nil
+}
+
+// parseSignature splits "name(params)rest" and parses the parameter list.
+func parseSignature(sig string) (name string, args Arguments, rest string, err error) {
+	sig = strings.TrimSpace(sig)
+	open := strings.Index(sig, "(")
+	if open <= 0 {
+		return "", nil, "", fmt.Errorf("abi: invalid signature %q", sig)
+	}
+	name = strings.TrimSpace(sig[:open])
+	if fields := strings.Fields(name); len(fields) == 2 && (fields[0] == "function" || fields[0] == "event" || fields[0] == "error") {
+		name = fields[1]
+	}
+	end, err := closingParen(sig, open)
+	if err != nil {
+		return "", nil, "", err
+	}
+	args, err = parseArguments(sig[open+1 : end])
+	if err != nil {
+		return "", nil, "", err
+	}
+	return name, args, sig[end+1:], nil
+}
+
+// parseArguments parses a comma separated list of "type [indexed] [name]"
+// entries. Tuples are written as parenthesised type lists, e.g. "(uint256,bool)[]".
+func parseArguments(list string) (Arguments, error) {
+	var args Arguments
+	if strings.TrimSpace(list) == "" {
+		return args, nil
+	}
+	for _, item := range splitTopLevel(list) {
+		marshaling, err := parseArgument(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		typ, err := NewType(marshaling.Type, marshaling.Components)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, Argument{Name: marshaling.Name, Type: typ, Indexed: marshaling.Indexed})
+	}
+	return args, nil
+}
+
+// parseArgument parses a single "type [indexed] [name]" entry.
+func parseArgument(item string) (ArgumentMarshaling, error) {
+	var arg ArgumentMarshaling
+	if item == "" {
+		return arg, fmt.Errorf("abi: empty argument")
+	}
+	if item[0] == '(' {
+		end, err := closingParen(item, 0)
+		if err != nil {
+			return arg, err
+		}
+		for _, component := range splitTopLevel(item[1:end]) {
+			c, err := parseArgument(strings.TrimSpace(component))
+			if err != nil {
+				return arg, err
+			}
+			arg.Components = append(arg.Components, c)
+		}
+		fields := strings.Fields(item[end+1:])
+		arg.Type = "tuple"
+		if len(fields) > 0 && strings.HasPrefix(fields[0], "[") {
+			arg.Type += fields[0]
+			fields = fields[1:]
+		}
+		return arg, parseModifiers(&arg, fields)
+	}
+	fields := strings.Fields(item)
+	arg.Type = fields[0]
+	if strings.HasPrefix(arg.Type, "tuple") {
+		return arg, fmt.Errorf("abi: tuple %q needs its components in parentheses", item)
+	}
+	return arg, parseModifiers(&arg, fields[1:])
+}
+
+// parseModifiers applies the optional "indexed" keyword and name.
+func parseModifiers(arg *ArgumentMarshaling, fields []string) error {
+	if len(fields) > 0 && fields[0] == "indexed" {
+		arg.Indexed = true
+		fields = fields[1:]
+	}
+	switch len(fields) {
+	case 0:
+	case 1:
+		arg.Name = fields[0]
+	default:
+		return fmt.Errorf("abi: unexpected %q in argument", strings.Join(fields, " "))
+	}
+	return nil
+}
+
+// splitTopLevel splits s on the commas which are not nested in parentheses.
+func splitTopLevel(s string) []string {
+	var (
+		parts []string
+		depth int
+		start int
+	)
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// closingParen returns the index of the parenthesis closing the one at open.
+func closingParen(s string, open int) (int, error) {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("abi: unbalanced parentheses in %q", s)
+}
@@ -0,0 +1,235 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/tinychain/tinychain/common"
+)
+
+var (
+	errBadBool     = errors.New("abi: improperly encoded boolean value")
+	errBadOffset   = errors.New("abi: offset out of bounds")
+	errBadPadding  = errors.New("abi: improperly padded value")
+	maxInt64Offset = big.NewInt(1<<63 - 1)
+)
+
+// ReadInteger reads the integer based on its kind and returns the appropriate value.
+func ReadInteger(t Type, b []byte) (interface{}, error) {
+	ret := new(big.Int).SetBytes(b)
+	if t.T == IntTy && ret.Bit(255) == 1 {
+		// Negative value in two's complement
+		ret.Sub(ret, new(big.Int).Add(maxUint256, big.NewInt(1)))
+	}
+	if err := checkIntRange(t, ret); err != nil {
+		return nil, errBadPadding
+	}
+	switch t.GetType().Kind() {
+	case reflect.Uint8:
+		return uint8(ret.Uint64()), nil
+	case reflect.Uint16:
+		return uint16(ret.Uint64()), nil
+	case reflect.Uint32:
+		return uint32(ret.Uint64()), nil
+	case reflect.Uint64:
+		return ret.Uint64(), nil
+	case reflect.Int8:
+		return int8(ret.Int64()), nil
+	case reflect.Int16:
+		return int16(ret.Int64()), nil
+	case reflect.Int32:
+		return int32(ret.Int64()), nil
+	case reflect.Int64:
+		return ret.Int64(), nil
+	default:
+		return ret, nil
+	}
+}
+
+// readBool reads a bool.
+func readBool(word []byte) (bool, error) {
+	for _, b := range word[:31] {
+		if b != 0 {
+			return false, errBadBool
+		}
+	}
+	switch word[31] {
+	case 0:
+		return false, nil
+	case 1:
+		return true, nil
+	default:
+		return false, errBadBool
+	}
+}
+
+// readFixedBytes uses reflection to create a fixed array to be read from.
+func readFixedBytes(t Type, word []byte) (interface{}, error) {
+	array := reflect.New(t.GetType()).Elem()
+	reflect.Copy(array, reflect.ValueOf(word[0:t.Size]))
+	return array.Interface(), nil
+}
+
+// forEachUnpack iteratively unpack elements.
+func forEachUnpack(t Type, output []byte, start, size int) (interface{}, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("cannot marshal input to array, size is negative (%d)", size)
+	}
+	if start+32*size > len(output) {
+		return nil, fmt.Errorf("abi: cannot marshal in to go array: offset %d would go over slice boundary (len=%d)", len(output), start+32*size)
+	}
+
+	// this value will become our slice or our array, depending on the type
+	var refSlice reflect.Value
+	if t.T == SliceTy {
+		// declare our slice
+		refSlice = reflect.MakeSlice(t.GetType(), size, size)
+	} else if t.T == ArrayTy {
+		// declare our array
+		refSlice = reflect.New(t.GetType()).Elem()
+	} else {
+		return nil, fmt.Errorf("abi: invalid type in array/slice unpacking stage")
+	}
+
+	// Arrays have packed elements, resulting in longer unpack steps.
+	// Slices have just 32 bytes per element (pointing to the contents).
+	elemSize := getTypeSize(*t.Elem)
+
+	for i, j := start, 0; j < size; i, j = i+elemSize, j+1 {
+		inter, err := toGoType(i, *t.Elem, output)
+		if err != nil {
+			return nil, err
+		}
+		// append the item to our reflect slice
+		refSlice.Index(j).Set(reflect.ValueOf(inter))
+	}
+	// return the interface
+	return refSlice.Interface(), nil
+}
+
+// forTupleUnpack unpacks the elements of a tuple into an anonymous struct.
+func forTupleUnpack(t Type, output []byte) (interface{}, error) {
+	retval := reflect.New(t.GetType()).Elem()
+	virtualArgs := 0
+	for index, elem := range t.TupleElems {
+		marshalledValue, err := toGoType((index+virtualArgs)*32, *elem, output)
+		if err != nil {
+			return nil, err
+		}
+		if (elem.T == ArrayTy || elem.T == TupleTy) && !isDynamicType(*elem) {
+			// If we have a static array or tuple, like [3]uint256, these are coded as
+			// just like uint256,uint256,uint256.
+			// This means that we need to add two 'virtual' arguments when
+			// we count the index from now on.
+			virtualArgs += getTypeSize(*elem)/32 - 1
+		}
+		retval.Field(index).Set(reflect.ValueOf(marshalledValue))
+	}
+	return retval.Interface(), nil
+}
+
+// toGoType parses the output bytes and recursively assigns the value of these
+// bytes into a go type with accordance with the ABI spec.
+func toGoType(index int, t Type, output []byte) (interface{}, error) {
+	if index+32 > len(output) {
+		return nil, fmt.Errorf("abi: cannot marshal in to go type: length insufficient %d require %d", len(output), index+32)
+	}
+
+	var (
+		returnOutput  []byte
+		begin, length int
+		err           error
+	)
+
+	// if we require a length prefix, find the beginning word and size returned.
+	if t.requiresLengthPrefix() {
+		begin, length, err = lengthPrefixPointsTo(index, output)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		returnOutput = output[index : index+32]
+	}
+
+	switch t.T {
+	case TupleTy:
+		if isDynamicType(t) {
+			begin, err := tuplePointsTo(index, output)
+			if err != nil {
+				return nil, err
+			}
+			return forTupleUnpack(t, output[begin:])
+		}
+		return forTupleUnpack(t, output[index:])
+	case SliceTy:
+		return forEachUnpack(t, output[begin:], 0, length)
+	case ArrayTy:
+		if isDynamicType(*t.Elem) {
+			offset, err := tuplePointsTo(index, output)
+			if err != nil {
+				return nil, err
+			}
+			return forEachUnpack(t, output[offset:], 0, t.Size)
+		}
+		return forEachUnpack(t, output[index:], 0, t.Size)
+	case StringTy: // variable arrays are written at the end of the return bytes
+		return string(output[begin : begin+length]), nil
+	case IntTy, UintTy:
+		return ReadInteger(t, returnOutput)
+	case BoolTy:
+		return readBool(returnOutput)
+	case AddressTy:
+		return common.BytesToAddress(returnOutput), nil
+	case BytesTy:
+		return common.CopyBytes(output[begin : begin+length]), nil
+	case FixedBytesTy, FunctionTy:
+		return readFixedBytes(t, returnOutput)
+	default:
+		return nil, fmt.Errorf("abi: unknown type %v", t.T)
+	}
+}
+
+// lengthPrefixPointsTo interprets a 32 byte slice as an offset and then
+// determines which indices to look to decode the type.
+func lengthPrefixPointsTo(index int, output []byte) (start int, length int, err error) {
+	offset, err := tuplePointsTo(index, output)
+	if err != nil {
+		return 0, 0, err
+	}
+	if offset+32 > len(output) {
+		return 0, 0, fmt.Errorf("abi: cannot marshal in to go slice: offset %d would go over slice boundary (len=%d)", offset+32, len(output))
+	}
+	lengthBig := new(big.Int).SetBytes(output[offset : offset+32])
+	if lengthBig.BitLen() > 63 || lengthBig.Int64() > int64(len(output)-offset-32) {
+		return 0, 0, fmt.Errorf("abi: cannot marshal in to go type: length insufficient %d require %v", len(output), lengthBig)
+	}
+	return offset + 32, int(lengthBig.Int64()), nil
+}
+
+// tuplePointsTo resolves the location reference for dynamic tuple.
+func tuplePointsTo(index int, output []byte) (start int, err error) {
+	offset := new(big.Int).SetBytes(output[index : index+32])
+	if offset.Cmp(maxInt64Offset) > 0 || offset.Uint64() > uint64(len(output)) {
+		return 0, errBadOffset
+	}
+	return int(binary.BigEndian.Uint64(output[index+24 : index+32])), nil
+}
@@ -0,0 +1,110 @@
+package abi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/tinychain/tinychain/common"
+)
+
+// ParseValue converts the textual representation of a value, as typed on a
+// command line, into a Go value Pack accepts for type t. Integers may be
+// decimal or 0x-prefixed hex, addresses and byte strings are hex encoded, and
+// arrays, slices and tuples are written as JSON lists, e.g. `[1, "0x12"]`.
+func ParseValue(t Type, s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	switch t.T {
+	case IntTy, UintTy:
+		n, ok := new(big.Int).SetString(s, 0)
+		if !ok {
+			return nil, fmt.Errorf("abi: invalid integer %q", s)
+		}
+		if err := checkIntRange(t, n); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case BoolTy:
+		return strconv.ParseBool(s)
+	case StringTy:
+		return s, nil
+	case AddressTy:
+		b, err := decodeHex(s)
+		if err != nil || len(b) != common.AddressLength {
+			return nil, fmt.Errorf("abi: invalid address %q", s)
+		}
+		return common.BytesToAddress(b), nil
+	case BytesTy:
+		return decodeHex(s)
+	case FixedBytesTy, FunctionTy:
+		b, err := decodeHex(s)
+		if err != nil || len(b) != t.Size {
+			return nil, fmt.Errorf("abi: invalid %v value %q", t, s)
+		}
+		array := reflect.New(t.GetType()).Elem()
+		reflect.Copy(array, reflect.ValueOf(b))
+		return array.Interface(), nil
+	case SliceTy, ArrayTy, TupleTy:
+		var raws []json.RawMessage
+		if err := json.Unmarshal([]byte(s), &raws); err != nil {
+			return nil, fmt.Errorf("abi: invalid %v value %q: %v", t, s, err)
+		}
+		elems := make([]*Type, len(raws))
+		switch {
+		case t.T == TupleTy && len(raws) != len(t.TupleElems):
+			return nil, fmt.Errorf("abi: tuple %v needs %d values, got %d", t, len(t.TupleElems), len(raws))
+		case t.T == ArrayTy && len(raws) != t.Size:
+			return nil, fmt.Errorf("abi: array %v needs %d values, got %d", t, t.Size, len(raws))
+		}
+		for i := range elems {
+			if t.T == TupleTy {
+				elems[i] = t.TupleElems[i]
+			} else {
+				elems[i] = t.Elem
+			}
+		}
+		values := make([]interface{}, len(raws))
+		for i, raw := range raws {
+			text := string(raw)
+			var str string
+			if err := json.Unmarshal(raw, &str); err == nil {
+				text = str
+			}
+			v, err := ParseValue(*elems[i], text)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("abi: cannot parse values of type %v", t)
+	}
+}
+
+// PackValues parses the textual values with ParseValue and packs them.
+func (arguments Arguments) PackValues(values ...string) ([]byte, error) {
+	if len(values) != len(arguments) {
+		return nil, fmt.Errorf("argument count mismatch: got %d for %d", len(values), len(arguments))
+	}
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		v, err := ParseValue(arguments[i].Type, value)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return arguments.Pack(args...)
+}
+
+func decodeHex(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	return hex.DecodeString(s)
+}
@@ -0,0 +1,218 @@
+package tinywasm
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/tinychain/tinychain/common"
+	"github.com/tinychain/tinychain/core/vm"
+	"github.com/tinychain/tinychain/core/vm/evm/crypto"
+)
+
+// AccountAccess lists the parts of an account which were accessed.
+type AccountAccess struct {
+	Account bool // existence, creation or destruction of the account
+	Balance bool
+	Nonce   bool
+	Code    bool
+	Storage map[common.Hash]struct{}
+}
+
+// AccessSet holds the accessed parts of the accounts keyed by address.
+type AccessSet map[common.Address]*AccountAccess
+
+func (set AccessSet) account(addr common.Address) *AccountAccess {
+	acc := set[addr]
+	if acc == nil {
+		acc = &AccountAccess{Storage: make(map[common.Hash]struct{})}
+		set[addr] = acc
+	}
+	return acc
+}
+
+// StateRecorder is a vm.StateDB recording the state read and written through
+// it. Accesses are recorded even if they are reverted later on.
+type StateRecorder struct {
+	vm.StateDB
+	Reads  AccessSet
+	Writes AccessSet
+}
+
+// NewStateRecorder returns a recorder passing all calls on to statedb.
+func NewStateRecorder(statedb vm.StateDB) *StateRecorder {
+	return &StateRecorder{
+		StateDB: statedb,
+		Reads:   make(AccessSet),
+		Writes:  make(AccessSet),
+	}
+}
+
+func (r *StateRecorder) CreateAccount(addr common.Address) {
+	r.Writes.account(addr).Account = true
+	r.StateDB.CreateAccount(addr)
+}
+
+func (r *StateRecorder) SubBalance(addr common.Address, amount *big.Int) {
+	r.Writes.account(addr).Balance = true
+	r.StateDB.SubBalance(addr, amount)
+}
+
+func (r *StateRecorder) AddBalance(addr common.Address, amount *big.Int) {
+	r.Writes.account(addr).Balance = true
+	r.StateDB.AddBalance(addr, amount)
+}
+
+func (r *StateRecorder) GetBalance(addr common.Address) *big.Int {
+	r.Reads.account(addr).Balance = true
+	return r.StateDB.GetBalance(addr)
+}
+
+func (r *StateRecorder) GetNonce(addr common.Address) uint64 {
+	r.Reads.account(addr).Nonce = true
+	return r.StateDB.GetNonce(addr)
+}
+
+func (r *StateRecorder) SetNonce(addr common.Address, nonce uint64) {
+	r.Writes.account(addr).Nonce = true
+	r.StateDB.SetNonce(addr, nonce)
+}
+
+func (r *StateRecorder) GetCodeHash(addr common.Address) common.Hash {
+	r.Reads.account(addr).Code = true
+	return r.StateDB.GetCodeHash(addr)
+}
+
+func (r *StateRecorder) GetCode(addr common.Address) []byte {
+	r.Reads.account(addr).Code = true
+	return r.StateDB.GetCode(addr)
+}
+
+func (r *StateRecorder) SetCode(addr common.Address, code []byte) {
+	r.Writes.account(addr).Code = true
+	r.StateDB.SetCode(addr, code)
+}
+
+func (r *StateRecorder) GetCodeSize(addr common.Address) int {
+	r.Reads.account(addr).Code = true
+	return r.StateDB.GetCodeSize(addr)
+}
+
+func (r *StateRecorder) GetState(addr common.Address, key common.Hash) []byte {
+	r.Reads.account(addr).Storage[key] = struct{}{}
+	return r.StateDB.GetState(addr, key)
+}
+
+func (r *StateRecorder) SetState(addr common.Address, key common.Hash, value []byte) {
+	r.Writes.account(addr).Storage[key] = struct{}{}
+	r.StateDB.SetState(addr, key, value)
+}
+
+func (r *StateRecorder) Suicide(addr common.Address) bool {
+	r.Writes.account(addr).Account = true
+	return r.StateDB.Suicide(addr)
+}
+
+func (r *StateRecorder) HasSuicided(addr common.Address) bool {
+	r.Reads.account(addr).Account = true
+	return r.StateDB.HasSuicided(addr)
+}
+
+func (r *StateRecorder) Exist(addr common.Address) bool {
+	r.Reads.account(addr).Account = true
+	return r.StateDB.Exist(addr)
+}
+
+func (r *StateRecorder) Empty(addr common.Address) bool {
+	r.Reads.account(addr).Account = true
+	return r.StateDB.Empty(addr)
+}
+
+func (r *StateRecorder) ForEachStorage(addr common.Address, cb func(common.Hash, []byte) bool) {
+	acc := r.Reads.account(addr)
+	r.StateDB.ForEachStorage(addr, func(key common.Hash, value []byte) bool {
+		acc.Storage[key] = struct{}{}
+		return cb(key, value)
+	})
+}
+
+// AccessTuple is an account and the storage slots of it to access.
+type AccessTuple struct {
+	Address     common.Address `json:"address"`
+	StorageKeys []common.Hash  `json:"storageKeys"`
+}
+
+// AccessList is a list of accounts and storage slots, sorted by address and
+// storage key.
+type AccessList []AccessTuple
+
+// CreateAccessList applies msg on top of statedb, without modifying it, and
+// returns the accounts and storage slots it accessed together with the
+// result of the execution.
+//
+// Like eth_createAccessList, the list leaves out the sender, the recipient or
+// created contract and the precompiled contracts.
+//
+// Unlike eth_createAccessList, which only traces the accesses of the
+// contracts, the recorder also sees the fee paid to the coinbase. The coinbase
+// is therefore left out unless its code or storage was accessed, so a
+// contract only reading its balance does not list it either.
+func CreateAccessList(ctx Context, statedb vm.StateDB, cfg Config, msg Message) (AccessList, *ExecutionResult, error) {
+	// The created address uses the nonce of the state, which the message
+	// may not match if its nonce is not checked.
+	nonce := statedb.GetNonce(msg.From)
+	recorder := NewStateRecorder(newOverlayState(statedb, nil))
+
+	gp := GasPool(msg.GasLimit)
+	var usedGas uint64
+	_, result, err := ApplyMessage(ctx, recorder, cfg, msg, &gp, &usedGas)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	excluded := map[common.Address]bool{msg.From: true}
+	if msg.To != nil {
+		excluded[*msg.To] = true
+	} else {
+		excluded[crypto.CreateAddress(msg.From, nonce)] = true
+	}
+	for addr := range activePrecompiles(cfg) {
+		excluded[addr] = true
+	}
+
+	accessed := make(map[common.Address]map[common.Hash]struct{})
+	for _, set := range []AccessSet{recorder.Reads, recorder.Writes} {
+		for addr, acc := range set {
+			if excluded[addr] {
+				continue
+			}
+			if addr == ctx.Coinbase && !acc.Code && len(acc.Storage) == 0 {
+				continue
+			}
+			keys := accessed[addr]
+			if keys == nil {
+				keys = make(map[common.Hash]struct{})
+				accessed[addr] = keys
+			}
+			for key := range acc.Storage {
+				keys[key] = struct{}{}
+			}
+		}
+	}
+
+	list := make(AccessList, 0, len(accessed))
+	for addr, keys := range accessed {
+		tuple := AccessTuple{Address: addr, StorageKeys: make([]common.Hash, 0, len(keys))}
+		for key := range keys {
+			tuple.StorageKeys = append(tuple.StorageKeys, key)
+		}
+		sort.Slice(tuple.StorageKeys, func(i, j int) bool {
+			return bytes.Compare(tuple.StorageKeys[i][:], tuple.StorageKeys[j][:]) < 0
+		})
+		list = append(list, tuple)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return bytes.Compare(list[i].Address[:], list[j].Address[:]) < 0
+	})
+	return list, result, nil
+}
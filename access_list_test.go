@@ -0,0 +1,111 @@
+package tinywasm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/tinychain/tinychain/common"
+)
+
+// balanceContract loads the balance of the zero address and its own storage
+// slot zero.
+var balanceContract = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	// type: () -> (), (i32, i32) -> ()
+	0x01, 0x09, 0x02, 0x60, 0x00, 0x00, 0x60, 0x02, 0x7f, 0x7f, 0x00,
+	// import: ethereum.getExternalBalance and ethereum.storageLoad
+	0x02, 0x36, 0x02,
+	0x08, 'e', 't', 'h', 'e', 'r', 'e', 'u', 'm',
+	0x12, 'g', 'e', 't', 'E', 'x', 't', 'e', 'r', 'n', 'a', 'l', 'B', 'a', 'l', 'a', 'n', 'c', 'e', 0x00, 0x01,
+	0x08, 'e', 't', 'h', 'e', 'r', 'e', 'u', 'm',
+	0x0b, 's', 't', 'o', 'r', 'a', 'g', 'e', 'L', 'o', 'a', 'd', 0x00, 0x01,
+	0x03, 0x02, 0x01, 0x00, // function: main has type 0
+	0x05, 0x03, 0x01, 0x00, 0x01, // memory: one page
+	0x07, 0x11, 0x02, // export: main and memory
+	0x04, 'm', 'a', 'i', 'n', 0x00, 0x02,
+	0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+	// code: getExternalBalance(0, 32); storageLoad(0, 32)
+	0x0a, 0x10, 0x01, 0x0e, 0x00,
+	0x41, 0x00, 0x41, 0x20, 0x10, 0x00,
+	0x41, 0x00, 0x41, 0x20, 0x10, 0x01,
+	0x0b,
+}
+
+func TestStateRecorder(t *testing.T) {
+	var (
+		statedb = newTestStateDB()
+		from    = testAddress(0x1001)
+		to      = testAddress(0x1002)
+		gp      = GasPool(100000)
+		usedGas uint64
+	)
+	statedb.AddBalance(from, big.NewInt(1000000))
+
+	recorder := NewStateRecorder(statedb)
+	msg := Message{From: from, To: &to, Value: big.NewInt(10), GasLimit: 30000, GasPrice: big.NewInt(1), CheckNonce: true}
+	if _, _, err := ApplyMessage(testContext(), recorder, Config{}, msg, &gp, &usedGas); err != nil {
+		t.Fatal(err)
+	}
+	if acc := recorder.Reads[from]; acc == nil || !acc.Balance || !acc.Nonce {
+		t.Errorf("sender reads: have %+v", acc)
+	}
+	if acc := recorder.Writes[from]; acc == nil || !acc.Balance || !acc.Nonce {
+		t.Errorf("sender writes: have %+v", acc)
+	}
+	if acc := recorder.Writes[to]; acc == nil || !acc.Balance || !acc.Account {
+		t.Errorf("recipient writes: have %+v", acc)
+	}
+	if acc := recorder.Writes[testContext().Coinbase]; acc == nil || !acc.Balance {
+		t.Errorf("coinbase writes: have %+v", acc)
+	}
+}
+
+func TestCreateAccessList(t *testing.T) {
+	var (
+		statedb  = newTestStateDB()
+		from     = testAddress(0x1001)
+		contract = testAddress(0x1002)
+	)
+	statedb.AddBalance(from, big.NewInt(1000000))
+	statedb.SetCode(contract, balanceContract)
+
+	msg := Message{From: from, To: &contract, GasLimit: 100000, GasPrice: big.NewInt(1)}
+	list, result, err := CreateAccessList(testContext(), statedb, Config{}, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Failed() {
+		t.Fatalf("execution failed: %v", result.Err)
+	}
+	if len(list) != 1 || list[0].Address != (common.Address{}) || len(list[0].StorageKeys) != 0 {
+		t.Errorf("unexpected access list %+v", list)
+	}
+	// the state is left untouched
+	if statedb.GetNonce(from) != 0 || statedb.GetBalance(from).Int64() != 1000000 {
+		t.Error("state modified")
+	}
+	if statedb.Exist(testContext().Coinbase) {
+		t.Error("coinbase paid")
+	}
+}
+
+func TestCreateAccessListCreation(t *testing.T) {
+	var (
+		statedb = newTestStateDB()
+		from    = testAddress(0x1001)
+	)
+	statedb.AddBalance(from, big.NewInt(1000000))
+
+	// The unchecked nonce of the message differs from the one of the state.
+	msg := Message{From: from, Nonce: 5, GasLimit: 100000, GasPrice: big.NewInt(1)}
+	list, result, err := CreateAccessList(testContext(), statedb, Config{}, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Failed() {
+		t.Fatalf("execution failed: %v", result.Err)
+	}
+	if len(list) != 0 {
+		t.Errorf("unexpected access list %+v", list)
+	}
+}
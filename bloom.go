@@ -0,0 +1,134 @@
+// Copyright 2014 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tinywasm
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/tinychain/tinychain/core/types"
+	"github.com/tinychain/tinychain/core/vm/evm/crypto"
+)
+
+const (
+	// BloomByteLength represents the number of bytes used in a header log bloom.
+	BloomByteLength = 256
+
+	// BloomBitLength represents the number of bits used in a header log bloom.
+	BloomBitLength = 8 * BloomByteLength
+)
+
+// Bloom represents a 2048 bit bloom filter.
+type Bloom [BloomByteLength]byte
+
+// BytesToBloom converts a byte slice to a bloom filter.
+// It panics if b is not of suitable size.
+func BytesToBloom(b []byte) Bloom {
+	var bloom Bloom
+	bloom.SetBytes(b)
+	return bloom
+}
+
+// SetBytes sets the content of b to the given bytes.
+// It panics if d is not of suitable size.
+func (b *Bloom) SetBytes(d []byte) {
+	if len(b) < len(d) {
+		panic(fmt.Sprintf("bloom bytes too big %d %d", len(b), len(d)))
+	}
+	copy(b[BloomByteLength-len(d):], d)
+}
+
+// Add adds d to the filter. Future calls of Test(d) will return true.
+func (b *Bloom) Add(d []byte) {
+	bin := new(big.Int).SetBytes(b[:])
+	bin.Or(bin, bloom9(d))
+	b.SetBytes(bin.Bytes())
+}
+
+// Big converts b to a big integer.
+func (b Bloom) Big() *big.Int {
+	return new(big.Int).SetBytes(b[:])
+}
+
+// Bytes returns the content of the filter.
+func (b Bloom) Bytes() []byte {
+	return b[:]
+}
+
+// Test checks if the given data may be in the filter. False positives are
+// possible, false negatives are not.
+func (b Bloom) Test(d []byte) bool {
+	return BloomLookup(b, d)
+}
+
+// MarshalText encodes b as a hex string with 0x prefix.
+func (b Bloom) MarshalText() ([]byte, error) {
+	return []byte("0x" + hex.EncodeToString(b[:])), nil
+}
+
+// UnmarshalText b as a hex string with 0x prefix.
+func (b *Bloom) UnmarshalText(input []byte) error {
+	if len(input) != 2+2*BloomByteLength || string(input[:2]) != "0x" {
+		return fmt.Errorf("invalid bloom %q", input)
+	}
+	_, err := hex.Decode(b[:], input[2:])
+	return err
+}
+
+// CreateBloom creates the combined bloom filter of the logs of all receipts.
+func CreateBloom(receipts []*Receipt) Bloom {
+	bin := new(big.Int)
+	for _, receipt := range receipts {
+		bin.Or(bin, LogsBloom(receipt.Logs))
+	}
+	return BytesToBloom(bin.Bytes())
+}
+
+// LogsBloom returns the bloom filter bits of the addresses and topics of logs.
+func LogsBloom(logs []*types.Log) *big.Int {
+	bin := new(big.Int)
+	for _, log := range logs {
+		bin.Or(bin, bloom9(log.Address.Bytes()))
+		for _, b := range log.Topics {
+			bin.Or(bin, bloom9(b[:]))
+		}
+	}
+	return bin
+}
+
+// bloom9 sets the three bits selected by the low 11 bits of the first three
+// byte pairs of the keccak256 hash of b.
+func bloom9(b []byte) *big.Int {
+	b = crypto.Keccak256(b)
+
+	r := new(big.Int)
+	for i := 0; i < 6; i += 2 {
+		t := big.NewInt(1)
+		b := (uint(b[i+1]) + (uint(b[i]) << 8)) & 2047
+		r.Or(r, t.Lsh(t, b))
+	}
+	return r
+}
+
+// BloomLookup reports whether topic may be contained in bin.
+func BloomLookup(bin Bloom, topic []byte) bool {
+	bloom := bin.Big()
+	cmp := bloom9(topic)
+
+	return bloom.And(bloom, cmp).Cmp(cmp) == 0
+}
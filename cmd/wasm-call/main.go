@@ -0,0 +1,131 @@
+// wasm-call calls a contract in an empty state and prints the result of the
+// call and the logs it emitted.
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+
+	tinywasm "github.com/tinychain/tiny-wasm"
+	"github.com/tinychain/tiny-wasm/abi"
+	"github.com/tinychain/tinychain/common"
+	"github.com/tinychain/tinychain/core/vm"
+)
+
+var (
+	caller   = common.HexToAddress("0x1001")
+	contract = common.HexToAddress("0x1002")
+)
+
+func main() {
+	log.SetPrefix("wasm-call: ")
+	log.SetFlags(0)
+
+	abiFile := flag.String("abi", "", "decode the events of the logs with the ABI JSON of this file")
+	input := flag.String("input", "", "hex encoded input data of the call")
+	gas := flag.Uint64("gas", 10000000, "gas limit of the call")
+
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	data, err := hex.DecodeString(strings.TrimPrefix(*input, "0x"))
+	if err != nil {
+		log.Fatalf("could not decode input: %v", err)
+	}
+
+	var contractABI *abi.ABI
+	if *abiFile != "" {
+		contractABI, err = readABI(*abiFile)
+		if err != nil {
+			log.Fatalf("could not read ABI: %v", err)
+		}
+	}
+
+	run(os.Stdout, flag.Arg(0), contractABI, data, *gas)
+}
+
+func readABI(fname string) (*abi.ABI, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	contractABI, err := abi.JSON(f)
+	if err != nil {
+		return nil, err
+	}
+	return &contractABI, nil
+}
+
+// run calls the contract of the file fname with input. The logs are decoded
+// with contractABI, or hex-dumped if it is nil.
+func run(w io.Writer, fname string, contractABI *abi.ABI, input []byte, gas uint64) {
+	code, err := ioutil.ReadFile(fname)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := tinywasm.Context{
+		CanTransfer: tinywasm.CanTransfer,
+		Transfer:    tinywasm.Transfer,
+		GetHash:     func(uint64) common.Hash { return common.Hash{} },
+		GasLimit:    gas,
+		BlockHeight: big.NewInt(0),
+		Time:        big.NewInt(0),
+		Difficulty:  big.NewInt(0),
+	}
+	msg := tinywasm.Message{
+		From:     caller,
+		To:       &contract,
+		Value:    new(big.Int),
+		GasLimit: gas,
+		GasPrice: new(big.Int),
+		Data:     input,
+	}
+	state := tinywasm.StateOverride{contract: {Code: &code}}
+
+	res, err := tinywasm.DoCall(ctx, emptyState{}, tinywasm.Config{}, msg, state, nil, 0)
+	if err != nil {
+		log.Fatalf("could not call contract: %v", err)
+	}
+
+	fmt.Fprintf(w, "gas used: %d\n", res.UsedGas)
+	if res.Failed() {
+		fmt.Fprintf(w, "error: %v\n", res.Err)
+	}
+	fmt.Fprintf(w, "return: %x\n\n", res.ReturnData)
+
+	if contractABI != nil {
+		tinywasm.WriteDecodedLogs(w, contractABI, res.Logs)
+	} else {
+		tinywasm.WriteLogs(w, res.Logs)
+	}
+}
+
+// emptyState is a state without any account. DoCall keeps the changes of
+// the call in an overlay and only reads the state beneath it, so the other
+// methods of vm.StateDB are left unimplemented.
+type emptyState struct{ vm.StateDB }
+
+func (emptyState) GetBalance(common.Address) *big.Int                            { return new(big.Int) }
+func (emptyState) GetNonce(common.Address) uint64                                { return 0 }
+func (emptyState) GetCode(common.Address) []byte                                 { return nil }
+func (emptyState) GetCodeHash(common.Address) common.Hash                        { return common.Hash{} }
+func (emptyState) GetCodeSize(common.Address) int                                { return 0 }
+func (emptyState) GetState(common.Address, common.Hash) []byte                   { return nil }
+func (emptyState) HasSuicided(common.Address) bool                               { return false }
+func (emptyState) Exist(common.Address) bool                                     { return false }
+func (emptyState) Empty(common.Address) bool                                     { return true }
+func (emptyState) ForEachStorage(common.Address, func(common.Hash, []byte) bool) {}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	contractABI, err := readABI("testdata/stored.abi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// testdata/stored.wasm emits Stored(7, "hello").
+	out := new(bytes.Buffer)
+	run(out, "testdata/stored.wasm", nil, nil, 10000000)
+	if !strings.Contains(out.String(), "LOG2: ") || !strings.Contains(out.String(), "|hello...........|") {
+		t.Errorf("log missing from output:\n%s", out)
+	}
+	if strings.Contains(out.String(), "error: ") {
+		t.Errorf("call failed:\n%s", out)
+	}
+
+	out.Reset()
+	run(out, "testdata/stored.wasm", contractABI, nil, 10000000)
+	if !strings.Contains(out.String(), "Stored(key: 7, value: hello)") {
+		t.Errorf("decoded event missing from output:\n%s", out)
+	}
+	if strings.Contains(out.String(), "LOG2: ") {
+		t.Errorf("decoded event hex-dumped:\n%s", out)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/tinychain/tinychain/core/vm"
 
 	"golang.org/x/crypto/ripemd160"
+	"github.com/tinychain/tiny-wasm/crypto/bls12381"
 	"github.com/tinychain/tinychain/common"
 	"github.com/tinychain/tinychain/core/vm/evm/crypto"
 	"github.com/tinychain/tinychain/core/vm/evm/crypto/bn256"
@@ -43,8 +44,23 @@ const (
 	Bn256ScalarMulGas       uint64 = 40000  // Gas needed for an elliptic curve scalar multiplication
 	Bn256PairingBaseGas     uint64 = 100000 // Base price for an elliptic curve pairing check
 	Bn256PairingPerPointGas uint64 = 80000  // Per-point price for an elliptic curve pairing check
+
+	Bls12381G1AddGas          uint64 = 375   // Price for BLS12-381 elliptic curve G1 point addition
+	Bls12381G1MulGas          uint64 = 12000 // Price for BLS12-381 elliptic curve G1 point scalar multiplication
+	Bls12381G2AddGas          uint64 = 600   // Price for BLS12-381 elliptic curve G2 point addition
+	Bls12381G2MulGas          uint64 = 22500 // Price for BLS12-381 elliptic curve G2 point scalar multiplication
+	Bls12381PairingBaseGas    uint64 = 37700 // Base gas price for BLS12-381 elliptic curve pairing check
+	Bls12381PairingPerPairGas uint64 = 32600 // Per-point pair gas price for BLS12-381 elliptic curve pairing check
 )
 
+// Bls12381G1MultiExpDiscountTable is the gas discount table for the BLS12-381 G1
+// multi exponentiation operation, indexed by the number of pairs minus one.
+var Bls12381G1MultiExpDiscountTable = [128]uint64{1000, 949, 848, 797, 764, 750, 738, 728, 719, 712, 705, 698, 692, 687, 682, 677, 673, 669, 665, 661, 658, 654, 651, 648, 645, 642, 640, 637, 635, 632, 630, 627, 625, 623, 621, 619, 617, 615, 613, 611, 609, 608, 606, 604, 603, 601, 599, 598, 596, 595, 593, 592, 591, 589, 588, 586, 585, 584, 582, 581, 580, 579, 577, 576, 575, 574, 573, 572, 570, 569, 568, 567, 566, 565, 564, 563, 562, 561, 560, 559, 558, 557, 556, 555, 554, 553, 552, 551, 550, 549, 548, 547, 547, 546, 545, 544, 543, 542, 541, 540, 540, 539, 538, 537, 536, 536, 535, 534, 533, 532, 532, 531, 530, 529, 528, 528, 527, 526, 525, 525, 524, 523, 522, 522, 521, 520, 520, 519}
+
+// Bls12381G2MultiExpDiscountTable is the gas discount table for the BLS12-381 G2
+// multi exponentiation operation, indexed by the number of pairs minus one.
+var Bls12381G2MultiExpDiscountTable = [128]uint64{1000, 1000, 923, 884, 855, 832, 812, 796, 782, 770, 759, 749, 740, 732, 724, 717, 711, 704, 699, 693, 688, 683, 679, 674, 670, 666, 663, 659, 655, 652, 649, 646, 643, 640, 637, 634, 632, 629, 627, 624, 622, 620, 618, 615, 613, 611, 609, 607, 606, 604, 602, 600, 598, 597, 595, 593, 592, 590, 589, 587, 586, 584, 583, 582, 580, 579, 578, 576, 575, 574, 573, 571, 570, 569, 568, 567, 566, 565, 563, 562, 561, 560, 559, 558, 557, 556, 555, 554, 553, 552, 552, 551, 550, 549, 548, 547, 546, 545, 545, 544, 543, 542, 541, 541, 540, 539, 538, 537, 537, 536, 535, 535, 534, 533, 532, 532, 531, 530, 530, 529, 528, 528, 527, 526, 526, 525, 524, 524}
+
 // PrecompiledContract is the basic interface for native Go contracts. The implementation
 // requires a deterministic gas count based on the input size of the Run method of the
 // contract.
@@ -75,6 +91,59 @@ var PrecompiledContractsByzantium = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{8}): &bn256Pairing{},
 }
 
+// PrecompiledContractsBLS contains the Byzantium set of pre-compiled contracts
+// extended with the BLS12-381 operations of EIP-2537.
+var PrecompiledContractsBLS = map[common.Address]PrecompiledContract{
+	common.BytesToAddress([]byte{1}):  &ecrecover{},
+	common.BytesToAddress([]byte{2}):  &sha256hash{},
+	common.BytesToAddress([]byte{3}):  &ripemd160hash{},
+	common.BytesToAddress([]byte{4}):  &dataCopy{},
+	common.BytesToAddress([]byte{5}):  &bigModExp{},
+	common.BytesToAddress([]byte{6}):  &bn256Add{},
+	common.BytesToAddress([]byte{7}):  &bn256ScalarMul{},
+	common.BytesToAddress([]byte{8}):  &bn256Pairing{},
+	common.BytesToAddress([]byte{11}): &bls12381G1Add{},
+	common.BytesToAddress([]byte{12}): &bls12381G1MultiExp{},
+	common.BytesToAddress([]byte{13}): &bls12381G2Add{},
+	common.BytesToAddress([]byte{14}): &bls12381G2MultiExp{},
+	common.BytesToAddress([]byte{15}): &bls12381Pairing{},
+}
+
+// activePrecompiles returns the precompiled contracts enabled by cfg.
+func activePrecompiles(cfg Config) map[common.Address]PrecompiledContract {
+	if cfg.EnableBLS12381 {
+		return PrecompiledContractsBLS
+	}
+	return PrecompiledContractsByzantium
+}
+
+// precompile returns the precompiled contract enabled by cfg at addr, nil if
+// there is none. The gas of the BLS12-381 contracts is multiplied by
+// cfg.BLS12381GasScale.
+func precompile(cfg Config, addr common.Address) PrecompiledContract {
+	p := activePrecompiles(cfg)[addr]
+	if p == nil || cfg.BLS12381GasScale <= 1 || PrecompiledContractsByzantium[addr] != nil {
+		return p
+	}
+	return &scaledPrecompile{p, cfg.BLS12381GasScale}
+}
+
+// scaledPrecompile is a precompiled contract whose required gas is
+// multiplied by scale.
+type scaledPrecompile struct {
+	PrecompiledContract
+	scale uint64
+}
+
+// RequiredGas returns the scaled gas required to execute the pre-compiled contract.
+func (c *scaledPrecompile) RequiredGas(input []byte) uint64 {
+	gas := c.PrecompiledContract.RequiredGas(input)
+	if gas > math.MaxUint64/c.scale {
+		return math.MaxUint64
+	}
+	return gas * c.scale
+}
+
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
 func RunPrecompiledContract(p PrecompiledContract, input []byte, contract *Contract) (ret []byte, err error) {
 	gas := p.RequiredGas(input)
@@ -374,3 +443,216 @@ func (c *bn256Pairing) Run(input []byte) ([]byte, error) {
 	}
 	return false32Byte, nil
 }
+
+var (
+	errBLS12381InvalidInputLength = errors.New("invalid input length")
+	errBLS12381G1PointSubgroup    = errors.New("g1 point is not on correct subgroup")
+	errBLS12381G2PointSubgroup    = errors.New("g2 point is not on correct subgroup")
+)
+
+// bls12381G1Add implements EIP-2537 G1Add precompile.
+type bls12381G1Add struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G1Add) RequiredGas(input []byte) uint64 {
+	return Bls12381G1AddGas
+}
+
+func (c *bls12381G1Add) Run(input []byte) ([]byte, error) {
+	// Implements EIP-2537 G1Add precompile.
+	// > G1 addition call expects `256` bytes as an input that is interpreted as byte concatenation of two G1 points (`128` bytes each).
+	// > Output is an encoding of addition operation result - single G1 point (`128` bytes).
+	if len(input) != 256 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	p0, err := bls12381.DecodeG1(input[:128])
+	if err != nil {
+		return nil, err
+	}
+	p1, err := bls12381.DecodeG1(input[128:])
+	if err != nil {
+		return nil, err
+	}
+	return bls12381.AddG1(p0, p1).Encode(), nil
+}
+
+// bls12381G1MultiExp implements EIP-2537 G1MultiExp precompile.
+type bls12381G1MultiExp struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G1MultiExp) RequiredGas(input []byte) uint64 {
+	// Calculate G1 point, scalar value pair length
+	k := len(input) / 160
+	if k == 0 {
+		// Return 0 gas for small input length
+		return 0
+	}
+	// Lookup discount value for G1 point, scalar value pair length
+	var discount uint64
+	if dLen := len(Bls12381G1MultiExpDiscountTable); k < dLen {
+		discount = Bls12381G1MultiExpDiscountTable[k-1]
+	} else {
+		discount = Bls12381G1MultiExpDiscountTable[dLen-1]
+	}
+	// Calculate gas and return the result
+	return (uint64(k) * Bls12381G1MulGas * discount) / 1000
+}
+
+func (c *bls12381G1MultiExp) Run(input []byte) ([]byte, error) {
+	// Implements EIP-2537 G1MultiExp precompile.
+	// G1 multiplication call expects `160*k` bytes as an input that is interpreted as byte concatenation of `k` slices each of them being a byte concatenation of encoding of G1 point (`128` bytes) and encoding of a scalar value (`32` bytes).
+	// Output is an encoding of multiexponentiation operation result - single G1 point (`128` bytes).
+	k := len(input) / 160
+	if len(input) == 0 || len(input)%160 != 0 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	var (
+		points  = make([]*bls12381.PointG1, k)
+		scalars = make([]*big.Int, k)
+	)
+	// Decode point scalar pairs
+	for i := 0; i < k; i++ {
+		off := 160 * i
+		t0, t1, t2 := off, off+128, off+160
+		p, err := bls12381.DecodeG1(input[t0:t1])
+		if err != nil {
+			return nil, err
+		}
+		if !p.InSubgroup() {
+			return nil, errBLS12381G1PointSubgroup
+		}
+		points[i] = p
+		scalars[i] = new(big.Int).SetBytes(input[t1:t2])
+	}
+	return bls12381.MultiExpG1(points, scalars).Encode(), nil
+}
+
+// bls12381G2Add implements EIP-2537 G2Add precompile.
+type bls12381G2Add struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G2Add) RequiredGas(input []byte) uint64 {
+	return Bls12381G2AddGas
+}
+
+func (c *bls12381G2Add) Run(input []byte) ([]byte, error) {
+	// Implements EIP-2537 G2Add precompile.
+	// > G2 addition call expects `512` bytes as an input that is interpreted as byte concatenation of two G2 points (`256` bytes each).
+	// > Output is an encoding of addition operation result - single G2 point (`256` bytes).
+	if len(input) != 512 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	p0, err := bls12381.DecodeG2(input[:256])
+	if err != nil {
+		return nil, err
+	}
+	p1, err := bls12381.DecodeG2(input[256:])
+	if err != nil {
+		return nil, err
+	}
+	return bls12381.AddG2(p0, p1).Encode(), nil
+}
+
+// bls12381G2MultiExp implements EIP-2537 G2MultiExp precompile.
+type bls12381G2MultiExp struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G2MultiExp) RequiredGas(input []byte) uint64 {
+	// Calculate G2 point, scalar value pair length
+	k := len(input) / 288
+	if k == 0 {
+		// Return 0 gas for small input length
+		return 0
+	}
+	// Lookup discount value for G2 point, scalar value pair length
+	var discount uint64
+	if dLen := len(Bls12381G2MultiExpDiscountTable); k < dLen {
+		discount = Bls12381G2MultiExpDiscountTable[k-1]
+	} else {
+		discount = Bls12381G2MultiExpDiscountTable[dLen-1]
+	}
+	// Calculate gas and return the result
+	return (uint64(k) * Bls12381G2MulGas * discount) / 1000
+}
+
+func (c *bls12381G2MultiExp) Run(input []byte) ([]byte, error) {
+	// Implements EIP-2537 G2MultiExp precompile logic
+	// > G2 multiplication call expects `288*k` bytes as an input that is interpreted as byte concatenation of `k` slices each of them being a byte concatenation of encoding of G2 point (`256` bytes) and encoding of a scalar value (`32` bytes).
+	// > Output is an encoding of multiexponentiation operation result - single G2 point (`256` bytes).
+	k := len(input) / 288
+	if len(input) == 0 || len(input)%288 != 0 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	var (
+		points  = make([]*bls12381.PointG2, k)
+		scalars = make([]*big.Int, k)
+	)
+	// Decode point scalar pairs
+	for i := 0; i < k; i++ {
+		off := 288 * i
+		t0, t1, t2 := off, off+256, off+288
+		p, err := bls12381.DecodeG2(input[t0:t1])
+		if err != nil {
+			return nil, err
+		}
+		if !p.InSubgroup() {
+			return nil, errBLS12381G2PointSubgroup
+		}
+		points[i] = p
+		scalars[i] = new(big.Int).SetBytes(input[t1:t2])
+	}
+	return bls12381.MultiExpG2(points, scalars).Encode(), nil
+}
+
+// bls12381Pairing implements EIP-2537 Pairing precompile.
+type bls12381Pairing struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381Pairing) RequiredGas(input []byte) uint64 {
+	return Bls12381PairingBaseGas + uint64(len(input)/384)*Bls12381PairingPerPairGas
+}
+
+func (c *bls12381Pairing) Run(input []byte) ([]byte, error) {
+	// Implements EIP-2537 Pairing precompile logic.
+	// > Pairing call expects `384*k` bytes as an inputs that is interpreted as byte concatenation of `k` slices. Each slice has the following structure:
+	// > - `128` bytes of G1 point encoding
+	// > - `256` bytes of G2 point encoding
+	// > Output is a `32` bytes where last single byte is `0x01` if pairing result is equal to multiplicative identity in a pairing target field and `0x00` otherwise
+	// > (which is equivalent of Big Endian encoding of Solidity values `uint256(1)` and `uin256(0)` respectively).
+	k := len(input) / 384
+	if len(input) == 0 || len(input)%384 != 0 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	var (
+		g1s = make([]*bls12381.PointG1, k)
+		g2s = make([]*bls12381.PointG2, k)
+	)
+	// Decode pairs
+	for i := 0; i < k; i++ {
+		off := 384 * i
+		t0, t1, t2 := off, off+128, off+384
+
+		p1, err := bls12381.DecodeG1(input[t0:t1])
+		if err != nil {
+			return nil, err
+		}
+		p2, err := bls12381.DecodeG2(input[t1:t2])
+		if err != nil {
+			return nil, err
+		}
+		// 'point is on curve' check already done,
+		// Here we need to apply subgroup checks.
+		if !p1.InSubgroup() {
+			return nil, errBLS12381G1PointSubgroup
+		}
+		if !p2.InSubgroup() {
+			return nil, errBLS12381G2PointSubgroup
+		}
+		g1s[i], g2s[i] = p1, p2
+	}
+	// Compute pairing and set the result
+	if bls12381.PairingCheck(g1s, g2s) {
+		return true32Byte, nil
+	}
+	return false32Byte, nil
+}
@@ -0,0 +1,376 @@
+package tinywasm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/tinychain/tinychain/common"
+
+	"github.com/tinychain/tiny-wasm/wagon/exec"
+)
+
+// precompiledTest defines the input/output pairs for precompiled contract tests.
+type precompiledTest struct {
+	input, expected string
+	gas             uint64
+	name            string
+}
+
+// precompiledFailureTest defines the input/error pairs for precompiled
+// contract failure tests.
+type precompiledFailureTest struct {
+	input         string
+	expectedError error
+	name          string
+}
+
+var blsG1AddTests = []precompiledTest{
+	{
+		input:    "0000000000000000000000000000000017f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb0000000000000000000000000000000008b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e1000000000000000000000000000000000572cbea904d67468808c8eb50a9450c9721db309128012543902d0ac358a62ae28f75bb8f1c7c42c39a8c5529bf0f4e00000000000000000000000000000000166a9d8cabc673a322fda673779d8e3822ba3ecb8670e461f73bb9021d5fd76a4c56d9d4cd16bd1bba86881979749d28",
+		expected: "0000000000000000000000000000000009ece308f9d1f0131765212deca99697b112d61f9be9a5f1f3780a51335b3ff981747a0b2ca2179b96d2c0c9024e522400000000000000000000000000000000032b80d3a6f5b09f8a84623389c5f80ca69a0cddabc3097f9d9c27310fd43be6e745256c634af45ca3473b0590ae30d1",
+		gas:      375,
+		name:     "g1_add_g_2g",
+	},
+	{
+		input:    "0000000000000000000000000000000017f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb0000000000000000000000000000000008b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e10000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		expected: "0000000000000000000000000000000017f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb0000000000000000000000000000000008b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e1",
+		gas:      375,
+		name:     "g1_add_g_infinity",
+	},
+	{
+		input:    "0000000000000000000000000000000017f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb0000000000000000000000000000000008b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e10000000000000000000000000000000017f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb00000000000000000000000000000000114d1d6855d545a8aa7d76c8cf2e21f267816aef1db507c96655b9d5caac42364e6f38ba0ecb751bad54dcd6b939c2ca",
+		expected: "0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		gas:      375,
+		name:     "g1_add_g_neg_g",
+	},
+}
+
+var blsG1MultiExpTests = []precompiledTest{
+	{
+		input:    "0000000000000000000000000000000017f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb0000000000000000000000000000000008b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e10000000000000000000000000000000000000000000000000000000000000002",
+		expected: "000000000000000000000000000000000572cbea904d67468808c8eb50a9450c9721db309128012543902d0ac358a62ae28f75bb8f1c7c42c39a8c5529bf0f4e00000000000000000000000000000000166a9d8cabc673a322fda673779d8e3822ba3ecb8670e461f73bb9021d5fd76a4c56d9d4cd16bd1bba86881979749d28",
+		gas:      12000,
+		name:     "g1_mul_g_2",
+	},
+	{
+		input:    "0000000000000000000000000000000017f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb0000000000000000000000000000000008b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e10000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000572cbea904d67468808c8eb50a9450c9721db309128012543902d0ac358a62ae28f75bb8f1c7c42c39a8c5529bf0f4e00000000000000000000000000000000166a9d8cabc673a322fda673779d8e3822ba3ecb8670e461f73bb9021d5fd76a4c56d9d4cd16bd1bba86881979749d280000000000000000000000000000000000000000000000000000000000000003",
+		expected: "000000000000000000000000000000001928f3beb93519eecf0145da903b40a4c97dca00b21f12ac0df3be9116ef2ef27b2ae6bcd4c5bc2d54ef5a70627efcb700000000000000000000000000000000108dadbaa4b636445639d5ae3089b3c43a8a1d47818edd1839d7383959a41c10fdc66849cfa1b08c5a11ec7e28981a1c",
+		gas:      22776,
+		name:     "g1_multiexp_two_pairs",
+	},
+}
+
+var blsG2AddTests = []precompiledTest{
+	{
+		input:    "00000000000000000000000000000000024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb80000000000000000000000000000000013e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e000000000000000000000000000000000ce5d527727d6e118cc9cdc6da2e351aadfd9baa8cbdd3a76d429a695160d12c923ac9cc3baca289e193548608b82801000000000000000000000000000000000606c4a02ea734cc32acd2b02bc28b99cb3e287e85a763af267492ab572e99ab3f370d275cec1da1aaa9075ff05f79be000000000000000000000000000000001638533957d540a9d2370f17cc7ed5863bc0b995b8825e0ee1ea1e1e4d00dbae81f14b0bf3611b78c952aacab827a053000000000000000000000000000000000a4edef9c1ed7f729f520e47730a124fd70662a904ba1074728114d1031e1572c6c886f6b57ec72a6178288c47c33577000000000000000000000000000000000468fb440d82b0630aeb8dca2b5256789a66da69bf91009cbfe6bd221e47aa8ae88dece9764bf3bd999d95d71e4c9899000000000000000000000000000000000f6d4552fa65dd2638b361543f887136a43253d9c66c411697003f7a13c308f5422e1aa0a59c8967acdefd8b6e36ccf3",
+		expected: "00000000000000000000000000000000122915c824a0857e2ee414a3dccb23ae691ae54329781315a0c75df1c04d6d7a50a030fc866f09d516020ef82324afae0000000000000000000000000000000009380275bbc8e5dcea7dc4dd7e0550ff2ac480905396eda55062650f8d251c96eb480673937cc6d9d6a44aaa56ca66dc000000000000000000000000000000000b21da7955969e61010c7a1abc1a6f0136961d1e3b20b1a7326ac738fef5c721479dfd948b52fdf2455e44813ecfd8920000000000000000000000000000000008f239ba329b3967fe48d718a36cfe5f62a7e42e0bf1c1ed714150a166bfbd6bcf6b3b58b975b9edea56d53f23a0e849",
+		gas:      600,
+		name:     "g2_add_g_2g",
+	},
+	{
+		input:    "00000000000000000000000000000000024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb80000000000000000000000000000000013e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e000000000000000000000000000000000ce5d527727d6e118cc9cdc6da2e351aadfd9baa8cbdd3a76d429a695160d12c923ac9cc3baca289e193548608b82801000000000000000000000000000000000606c4a02ea734cc32acd2b02bc28b99cb3e287e85a763af267492ab572e99ab3f370d275cec1da1aaa9075ff05f79be00000000000000000000000000000000024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb80000000000000000000000000000000013e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e000000000000000000000000000000000d1b3cc2c7027888be51d9ef691d77bcb679afda66c73f17f9ee3837a55024f78c71363275a75d75d86bab79f74782aa0000000000000000000000000000000013fa4d4a0ad8b1ce186ed5061789213d993923066dddaf1040bc3ff59f825c78df74f2d75467e25e0f55f8a00fa030ed",
+		expected: "00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		gas:      600,
+		name:     "g2_add_g_neg_g",
+	},
+}
+
+var blsG2MultiExpTests = []precompiledTest{
+	{
+		input:    "00000000000000000000000000000000024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb80000000000000000000000000000000013e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e000000000000000000000000000000000ce5d527727d6e118cc9cdc6da2e351aadfd9baa8cbdd3a76d429a695160d12c923ac9cc3baca289e193548608b82801000000000000000000000000000000000606c4a02ea734cc32acd2b02bc28b99cb3e287e85a763af267492ab572e99ab3f370d275cec1da1aaa9075ff05f79be0000000000000000000000000000000000000000000000000000000000000002",
+		expected: "000000000000000000000000000000001638533957d540a9d2370f17cc7ed5863bc0b995b8825e0ee1ea1e1e4d00dbae81f14b0bf3611b78c952aacab827a053000000000000000000000000000000000a4edef9c1ed7f729f520e47730a124fd70662a904ba1074728114d1031e1572c6c886f6b57ec72a6178288c47c33577000000000000000000000000000000000468fb440d82b0630aeb8dca2b5256789a66da69bf91009cbfe6bd221e47aa8ae88dece9764bf3bd999d95d71e4c9899000000000000000000000000000000000f6d4552fa65dd2638b361543f887136a43253d9c66c411697003f7a13c308f5422e1aa0a59c8967acdefd8b6e36ccf3",
+		gas:      22500,
+		name:     "g2_mul_g_2",
+	},
+	{
+		input:    "00000000000000000000000000000000024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb80000000000000000000000000000000013e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e000000000000000000000000000000000ce5d527727d6e118cc9cdc6da2e351aadfd9baa8cbdd3a76d429a695160d12c923ac9cc3baca289e193548608b82801000000000000000000000000000000000606c4a02ea734cc32acd2b02bc28b99cb3e287e85a763af267492ab572e99ab3f370d275cec1da1aaa9075ff05f79be0000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000001638533957d540a9d2370f17cc7ed5863bc0b995b8825e0ee1ea1e1e4d00dbae81f14b0bf3611b78c952aacab827a053000000000000000000000000000000000a4edef9c1ed7f729f520e47730a124fd70662a904ba1074728114d1031e1572c6c886f6b57ec72a6178288c47c33577000000000000000000000000000000000468fb440d82b0630aeb8dca2b5256789a66da69bf91009cbfe6bd221e47aa8ae88dece9764bf3bd999d95d71e4c9899000000000000000000000000000000000f6d4552fa65dd2638b361543f887136a43253d9c66c411697003f7a13c308f5422e1aa0a59c8967acdefd8b6e36ccf30000000000000000000000000000000000000000000000000000000000000003",
+		expected: "00000000000000000000000000000000049cd1dbb2d2c3581e54c088135fef36505a6823d61b859437bfc79b617030dc8b40e32bad1fa85b9c0f368af6d38d3c000000000000000000000000000000000d0273f6bf31ed37c3b8d68083ec3d8e20b5f2cc170fa24b9b5be35b34ed013f9a921f1cad1644d4bdb14674247234c80000000000000000000000000000000008b7ae4dbf802c17a6648842922c9467e460a71c88d393ee7af356da123a2f3619e80c3bdcc8e2b1da52f8cd9913ccdd0000000000000000000000000000000005ecf93654b7a1885695aaeeb7caf41b0239dc45e1022be55d37111af2aecef87799638bec572de86a7437898efa7020",
+		gas:      45000,
+		name:     "g2_multiexp_two_pairs",
+	},
+}
+
+var blsPairingTests = []precompiledTest{
+	{
+		input:    "0000000000000000000000000000000017f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb0000000000000000000000000000000008b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e100000000000000000000000000000000024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb80000000000000000000000000000000013e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e000000000000000000000000000000000ce5d527727d6e118cc9cdc6da2e351aadfd9baa8cbdd3a76d429a695160d12c923ac9cc3baca289e193548608b82801000000000000000000000000000000000606c4a02ea734cc32acd2b02bc28b99cb3e287e85a763af267492ab572e99ab3f370d275cec1da1aaa9075ff05f79be0000000000000000000000000000000017f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb00000000000000000000000000000000114d1d6855d545a8aa7d76c8cf2e21f267816aef1db507c96655b9d5caac42364e6f38ba0ecb751bad54dcd6b939c2ca00000000000000000000000000000000024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb80000000000000000000000000000000013e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e000000000000000000000000000000000ce5d527727d6e118cc9cdc6da2e351aadfd9baa8cbdd3a76d429a695160d12c923ac9cc3baca289e193548608b82801000000000000000000000000000000000606c4a02ea734cc32acd2b02bc28b99cb3e287e85a763af267492ab572e99ab3f370d275cec1da1aaa9075ff05f79be",
+		expected: "0000000000000000000000000000000000000000000000000000000000000001",
+		gas:      102900,
+		name:     "pairing_g1_neg_g1",
+	},
+	{
+		input:    "0000000000000000000000000000000017f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb0000000000000000000000000000000008b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e100000000000000000000000000000000024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb80000000000000000000000000000000013e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e000000000000000000000000000000000ce5d527727d6e118cc9cdc6da2e351aadfd9baa8cbdd3a76d429a695160d12c923ac9cc3baca289e193548608b82801000000000000000000000000000000000606c4a02ea734cc32acd2b02bc28b99cb3e287e85a763af267492ab572e99ab3f370d275cec1da1aaa9075ff05f79be",
+		expected: "0000000000000000000000000000000000000000000000000000000000000000",
+		gas:      70300,
+		name:     "pairing_single_pair",
+	},
+	{
+		input:    "000000000000000000000000000000000572cbea904d67468808c8eb50a9450c9721db309128012543902d0ac358a62ae28f75bb8f1c7c42c39a8c5529bf0f4e00000000000000000000000000000000166a9d8cabc673a322fda673779d8e3822ba3ecb8670e461f73bb9021d5fd76a4c56d9d4cd16bd1bba86881979749d2800000000000000000000000000000000122915c824a0857e2ee414a3dccb23ae691ae54329781315a0c75df1c04d6d7a50a030fc866f09d516020ef82324afae0000000000000000000000000000000009380275bbc8e5dcea7dc4dd7e0550ff2ac480905396eda55062650f8d251c96eb480673937cc6d9d6a44aaa56ca66dc000000000000000000000000000000000b21da7955969e61010c7a1abc1a6f0136961d1e3b20b1a7326ac738fef5c721479dfd948b52fdf2455e44813ecfd8920000000000000000000000000000000008f239ba329b3967fe48d718a36cfe5f62a7e42e0bf1c1ed714150a166bfbd6bcf6b3b58b975b9edea56d53f23a0e8490000000000000000000000000000000006e82f6da4520f85c5d27d8f329eccfa05944fd1096b20734c894966d12a9e2a9a9744529d7212d33883113a0cadb90900000000000000000000000000000000022901b141a9daabba0acdf56c7a9ca7819db2bb9b92848d7b0885e0b57c1695d6c307cebda4d19f13259775ba9c632f00000000000000000000000000000000024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb80000000000000000000000000000000013e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e000000000000000000000000000000000ce5d527727d6e118cc9cdc6da2e351aadfd9baa8cbdd3a76d429a695160d12c923ac9cc3baca289e193548608b82801000000000000000000000000000000000606c4a02ea734cc32acd2b02bc28b99cb3e287e85a763af267492ab572e99ab3f370d275cec1da1aaa9075ff05f79be",
+		expected: "0000000000000000000000000000000000000000000000000000000000000001",
+		gas:      102900,
+		name:     "pairing_bilinear",
+	},
+}
+
+var blsFailureTests = map[string][]precompiledFailureTest{
+	"0b": {
+		{
+			input:         "0000000000000000000000000000000017f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb0000000000000000000000000000000008b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e1",
+			expectedError: errBLS12381InvalidInputLength,
+			name:          "g1_add_short_input",
+		},
+		{
+			input:         "0100000000000000000000000000000017f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb0000000000000000000000000000000008b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e10000000000000000000000000000000017f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb0000000000000000000000000000000008b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e1",
+			expectedError: errors.New("bls12381: invalid field element encoding"),
+			name:          "g1_add_invalid_field_element",
+		},
+		{
+			input:         "0000000000000000000000000000000017f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb0000000000000000000000000000000008b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e00000000000000000000000000000000017f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb0000000000000000000000000000000008b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e1",
+			expectedError: errors.New("bls12381: g1 point is not on curve"),
+			name:          "g1_add_point_not_on_curve",
+		},
+	},
+	"0c": {
+		{
+			input:         "",
+			expectedError: errBLS12381InvalidInputLength,
+			name:          "g1_multiexp_empty_input",
+		},
+		{
+			input:         "00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000a989badd40d6212b33cffc3f3763e9bc760f988c9926b26da9dd85e928483446346b8ed00e1de5d5ea93e354abe706c0000000000000000000000000000000000000000000000000000000000000001",
+			expectedError: errBLS12381G1PointSubgroup,
+			name:          "g1_multiexp_point_not_in_subgroup",
+		},
+	},
+	"0f": {
+		{
+			input:         "",
+			expectedError: errBLS12381InvalidInputLength,
+			name:          "pairing_empty_input",
+		},
+		{
+			input:         "00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000a989badd40d6212b33cffc3f3763e9bc760f988c9926b26da9dd85e928483446346b8ed00e1de5d5ea93e354abe706c00000000000000000000000000000000024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb80000000000000000000000000000000013e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e000000000000000000000000000000000ce5d527727d6e118cc9cdc6da2e351aadfd9baa8cbdd3a76d429a695160d12c923ac9cc3baca289e193548608b82801000000000000000000000000000000000606c4a02ea734cc32acd2b02bc28b99cb3e287e85a763af267492ab572e99ab3f370d275cec1da1aaa9075ff05f79be",
+			expectedError: errBLS12381G1PointSubgroup,
+			name:          "pairing_g1_point_not_in_subgroup",
+		},
+	},
+}
+
+func testPrecompiled(addr string, test precompiledTest, t *testing.T) {
+	p := PrecompiledContractsBLS[common.HexToAddress(addr)]
+	in := common.Hex2Bytes(test.input)
+	contract := NewContract(AccountRef(common.HexToAddress("1337")),
+		nil, new(big.Int), p.RequiredGas(in))
+	t.Run(fmt.Sprintf("%s-Gas=%d", test.name, contract.Gas), func(t *testing.T) {
+		if gas := p.RequiredGas(in); gas != test.gas {
+			t.Errorf("Expected gas %d, got %d", test.gas, gas)
+		}
+		if res, err := RunPrecompiledContract(p, in, contract); err != nil {
+			t.Error(err)
+		} else if common.Bytes2Hex(res) != test.expected {
+			t.Errorf("Expected %v, got %v", test.expected, common.Bytes2Hex(res))
+		}
+		// Verify that the precompile did not touch the input buffer
+		exp := common.Hex2Bytes(test.input)
+		if !bytes.Equal(in, exp) {
+			t.Errorf("Precompiled %v modified input data", addr)
+		}
+	})
+}
+
+func testPrecompiledFailure(addr string, test precompiledFailureTest, t *testing.T) {
+	p := PrecompiledContractsBLS[common.HexToAddress(addr)]
+	in := common.Hex2Bytes(test.input)
+	contract := NewContract(AccountRef(common.HexToAddress("31337")),
+		nil, new(big.Int), p.RequiredGas(in))
+	t.Run(test.name, func(t *testing.T) {
+		_, err := RunPrecompiledContract(p, in, contract)
+		if err == nil || err.Error() != test.expectedError.Error() {
+			t.Errorf("Expected error [%v], got [%v]", test.expectedError, err)
+		}
+	})
+}
+
+// Tests the BLS12-381 precompiled contracts of EIP-2537.
+func TestPrecompiledBLS12381G1Add(t *testing.T) {
+	for _, test := range blsG1AddTests {
+		testPrecompiled("0b", test, t)
+	}
+}
+
+func TestPrecompiledBLS12381G1MultiExp(t *testing.T) {
+	for _, test := range blsG1MultiExpTests {
+		testPrecompiled("0c", test, t)
+	}
+}
+
+func TestPrecompiledBLS12381G2Add(t *testing.T) {
+	for _, test := range blsG2AddTests {
+		testPrecompiled("0d", test, t)
+	}
+}
+
+func TestPrecompiledBLS12381G2MultiExp(t *testing.T) {
+	for _, test := range blsG2MultiExpTests {
+		testPrecompiled("0e", test, t)
+	}
+}
+
+func TestPrecompiledBLS12381Pairing(t *testing.T) {
+	for _, test := range blsPairingTests {
+		testPrecompiled("0f", test, t)
+	}
+}
+
+func TestPrecompiledBLS12381Failure(t *testing.T) {
+	for addr, tests := range blsFailureTests {
+		for _, test := range tests {
+			testPrecompiledFailure(addr, test, t)
+		}
+	}
+}
+
+func TestPrecompiledBLS12381Activation(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		statedb, msg := estimateState(nil)
+		addr := common.BytesToAddress([]byte{0x0b})
+		msg.To = &addr
+		msg.Data = common.Hex2Bytes(blsG1AddTests[0].input)
+		msg.GasLimit = 100000
+		res, err := DoCall(testContext(), statedb, Config{EnableBLS12381: enabled}, msg, nil, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Err != nil {
+			t.Fatalf("unexpected error %v", res.Err)
+		}
+		want := ""
+		if enabled {
+			want = blsG1AddTests[0].expected
+		}
+		if have := common.Bytes2Hex(res.ReturnData); have != want {
+			t.Errorf("enabled %v: have %q, want %q", enabled, have, want)
+		}
+	}
+}
+
+func TestBLS12381HostFunction(t *testing.T) {
+	input := common.Hex2Bytes(blsG1AddTests[0].input)
+	g1Add := testImport{"bls12381G1Add", []byte{0x7f, 0x7f, 0x7f}, []byte{0x7f}}
+	for _, tt := range []struct {
+		offset, length int32
+		enabled, ok    bool
+	}{
+		{0, int32(len(input)), true, true},
+		{0, int32(len(input)), false, false},
+		{0, -1, true, false},
+		{-1, int32(len(input)), true, false},
+		{0, 0x7fffffff, true, false},
+		{0xff80, int32(len(input)), true, false},
+	} {
+		// main traps unless the host function succeeds
+		body := append(i32Const(tt.offset), i32Const(tt.length)...)
+		body = append(body, i32Const(int32(len(input)))...)
+		body = append(body, 0x10, 0x00, 0x45, 0x0d, 0x00, 0x00)
+		statedb, msg := estimateState(importModule([]testImport{g1Add}, body, input))
+		msg.GasLimit = 100000
+
+		res, err := DoCall(testContext(), statedb, Config{EnableBLS12381: tt.enabled}, msg, nil, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.ok {
+			if res.Err != nil {
+				t.Errorf("%+v: unexpected error %v", tt, res.Err)
+			}
+			continue
+		}
+		if trap, ok := res.Err.(*TrapError); !ok || trap.Err != exec.ErrUnreachable {
+			t.Errorf("%+v: have %v, want the call to fail", tt, res.Err)
+		}
+	}
+}
+
+func TestPrecompiledBLS12381GasScale(t *testing.T) {
+	cfg := Config{EnableBLS12381: true, BLS12381GasScale: 200}
+	input := common.Hex2Bytes(blsG1AddTests[0].input)
+	if gas := precompile(cfg, common.BytesToAddress([]byte{0x0b})).RequiredGas(input); gas != 200*Bls12381G1AddGas {
+		t.Errorf("have %d gas, want %d", gas, 200*Bls12381G1AddGas)
+	}
+	// the other contracts keep their prices
+	if gas := precompile(cfg, common.BytesToAddress([]byte{0x04})).RequiredGas(input); gas != IdentityBaseGas+8*IdentityPerWordGas {
+		t.Errorf("identity: have %d gas", gas)
+	}
+	cfg.BLS12381GasScale = math.MaxUint64
+	if gas := precompile(cfg, common.BytesToAddress([]byte{0x0b})).RequiredGas(input); gas != math.MaxUint64 {
+		t.Errorf("have %d gas, want the overflow to saturate", gas)
+	}
+}
+
+// blsGasScale is the Config.BLS12381GasScale the BLS12-381 precompiled
+// contracts are benchmarked with, and blsMinMgasPerSecond the throughput, in
+// millions of gas per second on a single core, they must then reach. An
+// operation running below it is underpriced for the crypto/bls12381 backend.
+const (
+	blsGasScale         = 200
+	blsMinMgasPerSecond = 20
+)
+
+// blsPairs returns k concatenated copies of pair.
+func blsPairs(pair []byte, k int) []byte {
+	return bytes.Repeat(pair, k)
+}
+
+// blsScalarPairs returns k concatenated copies of the point p with the
+// largest scalar, the worst case of the double and add multiplication.
+func blsScalarPairs(p []byte, k int) []byte {
+	return blsPairs(append(append([]byte{}, p...), bytes.Repeat([]byte{0xff}, 32)...), k)
+}
+
+// benchmarkPrecompiled runs the precompiled contract at addr on input,
+// reporting its gas scaled by blsGasScale and its throughput, and fails if
+// the latter is below blsMinMgasPerSecond. The single iteration run
+// calibrating b.N is not checked, as it includes the warm up.
+func benchmarkPrecompiled(addr string, input []byte, b *testing.B) {
+	p := precompile(Config{EnableBLS12381: true, BLS12381GasScale: blsGasScale}, common.HexToAddress(addr))
+	gas := p.RequiredGas(input)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := p.Run(input); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	mgas := float64(gas) * float64(b.N) / b.Elapsed().Seconds() / 1e6
+	b.ReportMetric(float64(gas), "gas/op")
+	b.ReportMetric(mgas, "mgas/s")
+	if b.N > 1 && mgas < blsMinMgasPerSecond {
+		b.Errorf("%d gas run at %.2f mgas/s, below %d", gas, mgas, blsMinMgasPerSecond)
+	}
+}
+
+func BenchmarkPrecompiledBLS12381(b *testing.B) {
+	g1 := common.Hex2Bytes(blsG1MultiExpTests[0].input)[:128]
+	g2 := common.Hex2Bytes(blsG2MultiExpTests[0].input)[:256]
+	for _, bm := range []struct {
+		name, addr string
+		input      []byte
+	}{
+		{"G1Add", "0b", common.Hex2Bytes(blsG1AddTests[0].input)},
+		{"G1Mul", "0c", blsScalarPairs(g1, 1)},
+		{"G1MultiExp-8", "0c", blsScalarPairs(g1, 8)},
+		{"G2Add", "0d", common.Hex2Bytes(blsG2AddTests[0].input)},
+		{"G2Mul", "0e", blsScalarPairs(g2, 1)},
+		{"G2MultiExp-8", "0e", blsScalarPairs(g2, 8)},
+		{"Pairing-1", "0f", blsPairs(append(append([]byte{}, g1...), g2...), 1)},
+		{"Pairing-4", "0f", blsPairs(append(append([]byte{}, g1...), g2...), 4)},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			benchmarkPrecompiled(bm.addr, bm.input, b)
+		})
+	}
+}
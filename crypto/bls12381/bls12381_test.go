@@ -0,0 +1,131 @@
+package bls12381
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func randFe12(rnd *rand.Rand) fe12 {
+	var a fe12
+	for i := range a {
+		a[i] = fe2{new(big.Int).Rand(rnd, P), new(big.Int).Rand(rnd, P)}
+	}
+	return a
+}
+
+func TestFe12Arithmetic(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	a := randFe12(rnd)
+	if !a.frobenius().equal(a.exp(P)) {
+		t.Errorf("frobenius does not match exponentiation by p")
+	}
+	if !a.conjugate().equal(a.frobeniusN(6)) {
+		t.Errorf("conjugate does not match frobenius^6")
+	}
+	if !a.mul(a.inv()).isOne() {
+		t.Errorf("a * a^-1 != 1")
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	g1, g2 := G1Generator(), G2Generator()
+	if !g1.IsOnCurve() || !g1.InSubgroup() {
+		t.Errorf("g1 generator is not in G1")
+	}
+	if !g2.IsOnCurve() || !g2.InSubgroup() {
+		t.Errorf("g2 generator is not in G2")
+	}
+}
+
+func TestG1Arithmetic(t *testing.T) {
+	g := G1Generator()
+	if !AddG1(g, g).Equal(MulG1(g, big.NewInt(2))) {
+		t.Errorf("g + g != 2g")
+	}
+	if !AddG1(g, g.Neg()).IsInfinity() {
+		t.Errorf("g - g != 0")
+	}
+	a, b := big.NewInt(123456789), big.NewInt(987654321)
+	sum := MultiExpG1([]*PointG1{g, g}, []*big.Int{a, b})
+	if !sum.Equal(MulG1(g, new(big.Int).Add(a, b))) {
+		t.Errorf("multiexp mismatch")
+	}
+	dec, err := DecodeG1(sum.Encode())
+	if err != nil || !dec.Equal(sum) {
+		t.Errorf("encoding round trip failed: %v", err)
+	}
+}
+
+func TestG2Arithmetic(t *testing.T) {
+	g := G2Generator()
+	if !AddG2(g, g).Equal(MulG2(g, big.NewInt(2))) {
+		t.Errorf("g + g != 2g")
+	}
+	if !AddG2(g, g.Neg()).IsInfinity() {
+		t.Errorf("g - g != 0")
+	}
+	a, b := big.NewInt(123456789), big.NewInt(987654321)
+	sum := MultiExpG2([]*PointG2{g, g}, []*big.Int{a, b})
+	if !sum.Equal(MulG2(g, new(big.Int).Add(a, b))) {
+		t.Errorf("multiexp mismatch")
+	}
+	dec, err := DecodeG2(sum.Encode())
+	if err != nil || !dec.Equal(sum) {
+		t.Errorf("encoding round trip failed: %v", err)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	enc := G1Generator().Encode()
+	enc[127] ^= 1
+	if _, err := DecodeG1(enc); err != errG1NotOnCurve {
+		t.Errorf("expected %v, got %v", errG1NotOnCurve, err)
+	}
+	enc = G1Generator().Encode()
+	enc[0] = 1
+	if _, err := DecodeG1(enc); err != errInvalidFieldElement {
+		t.Errorf("expected %v, got %v", errInvalidFieldElement, err)
+	}
+	enc = G2Generator().Encode()
+	if _, err := DecodeG2(enc[:255]); err != errInvalidG2Length {
+		t.Errorf("expected %v, got %v", errInvalidG2Length, err)
+	}
+	if p, err := DecodeG2(make([]byte, 256)); err != nil || !p.IsInfinity() {
+		t.Errorf("zero encoding is not infinity: %v", err)
+	}
+}
+
+func TestPairingBilinearity(t *testing.T) {
+	g1, g2 := G1Generator(), G2Generator()
+	a, b := big.NewInt(17), big.NewInt(42)
+
+	e := pair(g1, g2)
+	if e.isOne() {
+		t.Fatalf("pairing is degenerate")
+	}
+	if !e.exp(Order).isOne() {
+		t.Errorf("pairing result is not in GT")
+	}
+	lhs := pair(MulG1(g1, a), MulG2(g2, b))
+	rhs := e.exp(new(big.Int).Mul(a, b))
+	if !lhs.equal(rhs) {
+		t.Errorf("e(aP, bQ) != e(P, Q)^ab")
+	}
+	if !PairingCheck(
+		[]*PointG1{MulG1(g1, a), MulG1(g1, new(big.Int).Mul(a, b)).Neg()},
+		[]*PointG2{MulG2(g2, b), g2},
+	) {
+		t.Errorf("e(aP, bQ) * e(-abP, Q) != 1")
+	}
+	if PairingCheck([]*PointG1{g1}, []*PointG2{g2}) {
+		t.Errorf("e(P, Q) == 1")
+	}
+	if !PairingCheck([]*PointG1{G1Infinity()}, []*PointG2{g2}) {
+		t.Errorf("e(0, Q) != 1")
+	}
+	if !bytes.Equal(G1Infinity().Encode(), make([]byte, 128)) {
+		t.Errorf("infinity does not encode to zero")
+	}
+}
@@ -0,0 +1,236 @@
+// Package bls12381 implements the BLS12-381 curve groups and the optimal ate
+// pairing needed by the EIP-2537 precompiled contracts.
+//
+// The implementation favours clarity over speed: field elements are backed by
+// math/big and points are kept in affine coordinates.
+package bls12381
+
+import (
+	"math/big"
+)
+
+var (
+	// P is the modulus of the base field.
+	P = fromHex("1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab")
+
+	// Order is the order r of the groups G1, G2 and GT.
+	Order = fromHex("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001")
+
+	// blsX is the absolute value of the curve parameter x, which is negative.
+	blsX = fromHex("d201000000010000")
+
+	// xi is the non-residue used to build the degree six extension on top of Fp2.
+	xi = fe2{big.NewInt(1), big.NewInt(1)}
+
+	// frobeniusCoeffs holds xi^(i*(p-1)/6), the factors the coefficients of an
+	// Fp12 element pick up when raised to the power p.
+	frobeniusCoeffs [6]fe2
+
+	// hardExp is the hard part (p^4-p^2+1)/r of the final exponentiation.
+	hardExp *big.Int
+)
+
+func init() {
+	e := new(big.Int).Sub(P, big.NewInt(1))
+	e.Div(e, big.NewInt(6))
+	w := xi.exp(e)
+	frobeniusCoeffs[0] = fe2One()
+	for i := 1; i < 6; i++ {
+		frobeniusCoeffs[i] = frobeniusCoeffs[i-1].mul(w)
+	}
+
+	p2 := new(big.Int).Mul(P, P)
+	hardExp = new(big.Int).Mul(p2, p2)
+	hardExp.Sub(hardExp, p2)
+	hardExp.Add(hardExp, big.NewInt(1))
+	if new(big.Int).Mod(hardExp, Order).Sign() != 0 {
+		panic("bls12381: r does not divide p^4-p^2+1")
+	}
+	hardExp.Div(hardExp, Order)
+}
+
+func fromHex(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("bls12381: invalid constant " + s)
+	}
+	return v
+}
+
+func fpAdd(a, b *big.Int) *big.Int {
+	r := new(big.Int).Add(a, b)
+	return r.Mod(r, P)
+}
+
+func fpSub(a, b *big.Int) *big.Int {
+	r := new(big.Int).Sub(a, b)
+	return r.Mod(r, P)
+}
+
+func fpMul(a, b *big.Int) *big.Int {
+	r := new(big.Int).Mul(a, b)
+	return r.Mod(r, P)
+}
+
+func fpNeg(a *big.Int) *big.Int {
+	r := new(big.Int).Neg(a)
+	return r.Mod(r, P)
+}
+
+// fpInv returns the inverse of a, or zero if a is zero.
+func fpInv(a *big.Int) *big.Int {
+	if a.Sign() == 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).ModInverse(a, P)
+}
+
+// fe2 is an element c0 + c1*u of Fp2 = Fp[u]/(u^2+1).
+type fe2 struct {
+	c0, c1 *big.Int
+}
+
+func fe2Zero() fe2 { return fe2{new(big.Int), new(big.Int)} }
+func fe2One() fe2  { return fe2{big.NewInt(1), new(big.Int)} }
+
+func (a fe2) add(b fe2) fe2 { return fe2{fpAdd(a.c0, b.c0), fpAdd(a.c1, b.c1)} }
+func (a fe2) sub(b fe2) fe2 { return fe2{fpSub(a.c0, b.c0), fpSub(a.c1, b.c1)} }
+func (a fe2) neg() fe2      { return fe2{fpNeg(a.c0), fpNeg(a.c1)} }
+func (a fe2) conj() fe2     { return fe2{new(big.Int).Set(a.c0), fpNeg(a.c1)} }
+
+func (a fe2) mul(b fe2) fe2 {
+	return fe2{
+		fpSub(fpMul(a.c0, b.c0), fpMul(a.c1, b.c1)),
+		fpAdd(fpMul(a.c0, b.c1), fpMul(a.c1, b.c0)),
+	}
+}
+
+// mulFp multiplies a by an element of the base field.
+func (a fe2) mulFp(k *big.Int) fe2 { return fe2{fpMul(a.c0, k), fpMul(a.c1, k)} }
+
+// inv returns the inverse of a, or zero if a is zero.
+func (a fe2) inv() fe2 {
+	n := fpInv(fpAdd(fpMul(a.c0, a.c0), fpMul(a.c1, a.c1)))
+	return fe2{fpMul(a.c0, n), fpMul(fpNeg(a.c1), n)}
+}
+
+func (a fe2) exp(e *big.Int) fe2 {
+	r := fe2One()
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		r = r.mul(r)
+		if e.Bit(i) == 1 {
+			r = r.mul(a)
+		}
+	}
+	return r
+}
+
+func (a fe2) isZero() bool     { return a.c0.Sign() == 0 && a.c1.Sign() == 0 }
+func (a fe2) equal(b fe2) bool { return a.c0.Cmp(b.c0) == 0 && a.c1.Cmp(b.c1) == 0 }
+
+// fe12 is an element sum(c[i]*w^i) of Fp12 = Fp2[w]/(w^6-xi).
+type fe12 [6]fe2
+
+func fe12One() fe12 {
+	var r fe12
+	r[0] = fe2One()
+	for i := 1; i < 6; i++ {
+		r[i] = fe2Zero()
+	}
+	return r
+}
+
+func (a fe12) mul(b fe12) fe12 {
+	var t [11]fe2
+	for i := range t {
+		t[i] = fe2Zero()
+	}
+	for i := 0; i < 6; i++ {
+		for j := 0; j < 6; j++ {
+			t[i+j] = t[i+j].add(a[i].mul(b[j]))
+		}
+	}
+	var r fe12
+	for i := 0; i < 6; i++ {
+		r[i] = t[i]
+	}
+	for i := 6; i < 11; i++ {
+		r[i-6] = r[i-6].add(t[i].mul(xi))
+	}
+	return r
+}
+
+// mulFp2 multiplies a by an element of the Fp2 subfield.
+func (a fe12) mulFp2(k fe2) fe12 {
+	var r fe12
+	for i := range a {
+		r[i] = a[i].mul(k)
+	}
+	return r
+}
+
+// frobenius returns a^p.
+func (a fe12) frobenius() fe12 {
+	var r fe12
+	for i := range a {
+		r[i] = a[i].conj().mul(frobeniusCoeffs[i])
+	}
+	return r
+}
+
+// frobeniusN returns a^(p^n).
+func (a fe12) frobeniusN(n int) fe12 {
+	for i := 0; i < n; i++ {
+		a = a.frobenius()
+	}
+	return a
+}
+
+// conjugate returns a^(p^6), which equals the inverse of a for elements of
+// the cyclotomic subgroup.
+func (a fe12) conjugate() fe12 {
+	var r fe12
+	for i := range a {
+		if i%2 == 1 {
+			r[i] = a[i].neg()
+		} else {
+			r[i] = a[i]
+		}
+	}
+	return r
+}
+
+// inv returns the inverse of a, using the fact that the norm of a down to
+// Fp2, the product of a^(p^2k) for k = 0..5, lies in Fp2.
+func (a fe12) inv() fe12 {
+	g := fe12One()
+	t := a
+	for k := 1; k < 6; k++ {
+		t = t.frobeniusN(2)
+		g = g.mul(t)
+	}
+	n := a.mul(g)
+	return g.mulFp2(n[0].inv())
+}
+
+func (a fe12) exp(e *big.Int) fe12 {
+	r := fe12One()
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		r = r.mul(r)
+		if e.Bit(i) == 1 {
+			r = r.mul(a)
+		}
+	}
+	return r
+}
+
+func (a fe12) equal(b fe12) bool {
+	for i := range a {
+		if !a[i].equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a fe12) isOne() bool { return a.equal(fe12One()) }
@@ -0,0 +1,181 @@
+package bls12381
+
+import (
+	"errors"
+	"math/big"
+)
+
+var (
+	errInvalidFieldElement = errors.New("bls12381: invalid field element encoding")
+	errInvalidG1Length     = errors.New("bls12381: invalid g1 point encoding length")
+	errInvalidG2Length     = errors.New("bls12381: invalid g2 point encoding length")
+	errG1NotOnCurve        = errors.New("bls12381: g1 point is not on curve")
+	errG2NotOnCurve        = errors.New("bls12381: g2 point is not on curve")
+)
+
+// g1B is the constant b of the G1 curve y^2 = x^3 + b.
+var g1B = big.NewInt(4)
+
+// PointG1 is an affine point on the G1 curve y^2 = x^3 + 4 over Fp.
+type PointG1 struct {
+	x, y *big.Int
+	inf  bool
+}
+
+// G1Generator returns the generator of G1.
+func G1Generator() *PointG1 {
+	return &PointG1{
+		x: fromHex("17f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb"),
+		y: fromHex("08b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e1"),
+	}
+}
+
+// G1Infinity returns the point at infinity of G1.
+func G1Infinity() *PointG1 {
+	return &PointG1{inf: true}
+}
+
+// IsInfinity reports whether p is the point at infinity.
+func (p *PointG1) IsInfinity() bool {
+	return p.inf
+}
+
+// IsOnCurve reports whether p satisfies the curve equation.
+func (p *PointG1) IsOnCurve() bool {
+	if p.inf {
+		return true
+	}
+	lhs := fpMul(p.y, p.y)
+	rhs := fpAdd(fpMul(fpMul(p.x, p.x), p.x), g1B)
+	return lhs.Cmp(rhs) == 0
+}
+
+// InSubgroup reports whether p lies in the prime order subgroup G1.
+func (p *PointG1) InSubgroup() bool {
+	return MulG1(p, Order).inf
+}
+
+// Equal reports whether p and q are the same point.
+func (p *PointG1) Equal(q *PointG1) bool {
+	if p.inf || q.inf {
+		return p.inf == q.inf
+	}
+	return p.x.Cmp(q.x) == 0 && p.y.Cmp(q.y) == 0
+}
+
+// Neg returns -p.
+func (p *PointG1) Neg() *PointG1 {
+	if p.inf {
+		return G1Infinity()
+	}
+	return &PointG1{x: new(big.Int).Set(p.x), y: fpNeg(p.y)}
+}
+
+// AddG1 returns a + b.
+func AddG1(a, b *PointG1) *PointG1 {
+	if a.inf {
+		return b
+	}
+	if b.inf {
+		return a
+	}
+	var lambda *big.Int
+	if a.x.Cmp(b.x) == 0 {
+		if a.y.Cmp(b.y) != 0 || a.y.Sign() == 0 {
+			return G1Infinity()
+		}
+		// Tangent slope 3x^2 / 2y
+		lambda = fpMul(fpMul(big.NewInt(3), fpMul(a.x, a.x)), fpInv(fpAdd(a.y, a.y)))
+	} else {
+		lambda = fpMul(fpSub(b.y, a.y), fpInv(fpSub(b.x, a.x)))
+	}
+	x := fpSub(fpSub(fpMul(lambda, lambda), a.x), b.x)
+	y := fpSub(fpMul(lambda, fpSub(a.x, x)), a.y)
+	return &PointG1{x: x, y: y}
+}
+
+// MulG1 returns k*p.
+func MulG1(p *PointG1, k *big.Int) *PointG1 {
+	r := G1Infinity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		r = AddG1(r, r)
+		if k.Bit(i) == 1 {
+			r = AddG1(r, p)
+		}
+	}
+	return r
+}
+
+// MultiExpG1 returns the sum of scalars[i]*points[i].
+func MultiExpG1(points []*PointG1, scalars []*big.Int) *PointG1 {
+	r := G1Infinity()
+	for i := range points {
+		r = AddG1(r, MulG1(points[i], scalars[i]))
+	}
+	return r
+}
+
+// DecodeG1 decodes a G1 point from the 128 byte EIP-2537 encoding, consisting
+// of the x and y coordinates each padded to 64 bytes. The all zero encoding is
+// the point at infinity. The point is checked to be on the curve, but not to be
+// in the prime order subgroup.
+func DecodeG1(in []byte) (*PointG1, error) {
+	if len(in) != 128 {
+		return nil, errInvalidG1Length
+	}
+	if allZero(in) {
+		return G1Infinity(), nil
+	}
+	x, err := decodeFieldElement(in[:64])
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeFieldElement(in[64:])
+	if err != nil {
+		return nil, err
+	}
+	p := &PointG1{x: x, y: y}
+	if !p.IsOnCurve() {
+		return nil, errG1NotOnCurve
+	}
+	return p, nil
+}
+
+// Encode returns the 128 byte EIP-2537 encoding of p.
+func (p *PointG1) Encode() []byte {
+	out := make([]byte, 128)
+	if p.inf {
+		return out
+	}
+	encodeFieldElement(out[:64], p.x)
+	encodeFieldElement(out[64:], p.y)
+	return out
+}
+
+// decodeFieldElement decodes a base field element padded to 64 bytes. The top
+// 16 bytes must be zero and the value must be smaller than the modulus.
+func decodeFieldElement(in []byte) (*big.Int, error) {
+	if !allZero(in[:16]) {
+		return nil, errInvalidFieldElement
+	}
+	v := new(big.Int).SetBytes(in[16:])
+	if v.Cmp(P) >= 0 {
+		return nil, errInvalidFieldElement
+	}
+	return v, nil
+}
+
+// encodeFieldElement writes v into out, a 64 byte big endian buffer.
+func encodeFieldElement(out []byte, v *big.Int) {
+	b := v.Bytes()
+	copy(out[len(out)-len(b):], b)
+}
+
+func allZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
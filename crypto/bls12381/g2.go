@@ -0,0 +1,161 @@
+package bls12381
+
+import (
+	"math/big"
+)
+
+// g2B is the constant b' = 4*xi of the twisted curve y^2 = x^3 + b' over Fp2.
+var g2B = xi.mulFp(big.NewInt(4))
+
+// PointG2 is an affine point on the G2 curve y^2 = x^3 + 4(u+1) over Fp2.
+type PointG2 struct {
+	x, y fe2
+	inf  bool
+}
+
+// G2Generator returns the generator of G2.
+func G2Generator() *PointG2 {
+	return &PointG2{
+		x: fe2{
+			fromHex("024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb8"),
+			fromHex("13e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e"),
+		},
+		y: fe2{
+			fromHex("0ce5d527727d6e118cc9cdc6da2e351aadfd9baa8cbdd3a76d429a695160d12c923ac9cc3baca289e193548608b82801"),
+			fromHex("0606c4a02ea734cc32acd2b02bc28b99cb3e287e85a763af267492ab572e99ab3f370d275cec1da1aaa9075ff05f79be"),
+		},
+	}
+}
+
+// G2Infinity returns the point at infinity of G2.
+func G2Infinity() *PointG2 {
+	return &PointG2{inf: true}
+}
+
+// IsInfinity reports whether p is the point at infinity.
+func (p *PointG2) IsInfinity() bool {
+	return p.inf
+}
+
+// IsOnCurve reports whether p satisfies the curve equation.
+func (p *PointG2) IsOnCurve() bool {
+	if p.inf {
+		return true
+	}
+	lhs := p.y.mul(p.y)
+	rhs := p.x.mul(p.x).mul(p.x).add(g2B)
+	return lhs.equal(rhs)
+}
+
+// InSubgroup reports whether p lies in the prime order subgroup G2.
+func (p *PointG2) InSubgroup() bool {
+	return MulG2(p, Order).inf
+}
+
+// Equal reports whether p and q are the same point.
+func (p *PointG2) Equal(q *PointG2) bool {
+	if p.inf || q.inf {
+		return p.inf == q.inf
+	}
+	return p.x.equal(q.x) && p.y.equal(q.y)
+}
+
+// Neg returns -p.
+func (p *PointG2) Neg() *PointG2 {
+	if p.inf {
+		return G2Infinity()
+	}
+	return &PointG2{x: p.x, y: p.y.neg()}
+}
+
+// AddG2 returns a + b.
+func AddG2(a, b *PointG2) *PointG2 {
+	if a.inf {
+		return b
+	}
+	if b.inf {
+		return a
+	}
+	var lambda fe2
+	if a.x.equal(b.x) {
+		if !a.y.equal(b.y) || a.y.isZero() {
+			return G2Infinity()
+		}
+		lambda = g2Tangent(a)
+	} else {
+		lambda = g2Chord(a, b)
+	}
+	x := lambda.mul(lambda).sub(a.x).sub(b.x)
+	y := lambda.mul(a.x.sub(x)).sub(a.y)
+	return &PointG2{x: x, y: y}
+}
+
+// g2Tangent returns the slope of the tangent to the curve at a.
+func g2Tangent(a *PointG2) fe2 {
+	return a.x.mul(a.x).mulFp(big.NewInt(3)).mul(a.y.add(a.y).inv())
+}
+
+// g2Chord returns the slope of the line through a and b.
+func g2Chord(a, b *PointG2) fe2 {
+	return b.y.sub(a.y).mul(b.x.sub(a.x).inv())
+}
+
+// MulG2 returns k*p.
+func MulG2(p *PointG2, k *big.Int) *PointG2 {
+	r := G2Infinity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		r = AddG2(r, r)
+		if k.Bit(i) == 1 {
+			r = AddG2(r, p)
+		}
+	}
+	return r
+}
+
+// MultiExpG2 returns the sum of scalars[i]*points[i].
+func MultiExpG2(points []*PointG2, scalars []*big.Int) *PointG2 {
+	r := G2Infinity()
+	for i := range points {
+		r = AddG2(r, MulG2(points[i], scalars[i]))
+	}
+	return r
+}
+
+// DecodeG2 decodes a G2 point from the 256 byte EIP-2537 encoding, consisting
+// of x.c0, x.c1, y.c0 and y.c1 each padded to 64 bytes. The all zero encoding
+// is the point at infinity. The point is checked to be on the curve, but not to
+// be in the prime order subgroup.
+func DecodeG2(in []byte) (*PointG2, error) {
+	if len(in) != 256 {
+		return nil, errInvalidG2Length
+	}
+	if allZero(in) {
+		return G2Infinity(), nil
+	}
+	var c [4]*big.Int
+	for i := range c {
+		v, err := decodeFieldElement(in[i*64 : (i+1)*64])
+		if err != nil {
+			return nil, err
+		}
+		c[i] = v
+	}
+	p := &PointG2{x: fe2{c[0], c[1]}, y: fe2{c[2], c[3]}}
+	if !p.IsOnCurve() {
+		return nil, errG2NotOnCurve
+	}
+	return p, nil
+}
+
+// Encode returns the 256 byte EIP-2537 encoding of p.
+func (p *PointG2) Encode() []byte {
+	out := make([]byte, 256)
+	if p.inf {
+		return out
+	}
+	encodeFieldElement(out[0:64], p.x.c0)
+	encodeFieldElement(out[64:128], p.x.c1)
+	encodeFieldElement(out[128:192], p.y.c0)
+	encodeFieldElement(out[192:256], p.y.c1)
+	return out
+}
@@ -0,0 +1,65 @@
+package bls12381
+
+// PairingCheck reports whether the product of the pairings e(g1[i], g2[i]) is
+// the identity of GT. Pairs containing a point at infinity contribute nothing
+// to the product. The points are expected to be in their prime order subgroups.
+func PairingCheck(g1 []*PointG1, g2 []*PointG2) bool {
+	f := fe12One()
+	for i := range g1 {
+		if g1[i].inf || g2[i].inf {
+			continue
+		}
+		f = f.mul(millerLoop(g1[i], g2[i]))
+	}
+	return finalExp(f).isOne()
+}
+
+// pair computes the optimal ate pairing e(p, q).
+func pair(p *PointG1, q *PointG2) fe12 {
+	if p.inf || q.inf {
+		return fe12One()
+	}
+	return finalExp(millerLoop(p, q))
+}
+
+// millerLoop evaluates f_{x,q}(p). The running point t is kept on the twist,
+// lines are evaluated at p after untwisting (x, y) -> (x/w^2, y/w^3) and
+// vertical lines are dropped, as they vanish under the final exponentiation.
+func millerLoop(p *PointG1, q *PointG2) fe12 {
+	f := fe12One()
+	t := q
+	for i := blsX.BitLen() - 2; i >= 0; i-- {
+		f = f.mul(f)
+		f = f.mul(lineEval(g2Tangent(t), t, p))
+		t = AddG2(t, t)
+		if blsX.Bit(i) == 1 {
+			f = f.mul(lineEval(g2Chord(t, q), t, p))
+			t = AddG2(t, q)
+		}
+	}
+	// The curve parameter x is negative
+	return f.conjugate()
+}
+
+// lineEval evaluates at p the line through t with slope lambda on the twist.
+// After untwisting, the line is yP - lambda/w*(xP - xT/w^2) - yT/w^3; it is
+// scaled by xi = w^6, which the final exponentiation removes.
+func lineEval(lambda fe2, t *PointG2, p *PointG1) fe12 {
+	var l fe12
+	for i := range l {
+		l[i] = fe2Zero()
+	}
+	l[0] = xi.mulFp(p.y)
+	l[3] = lambda.mul(t.x).sub(t.y)
+	l[5] = lambda.mulFp(p.x).neg()
+	return l
+}
+
+// finalExp raises f to the power (p^12-1)/r.
+func finalExp(f fe12) fe12 {
+	// Easy part: f^((p^6-1)(p^2+1))
+	f = f.conjugate().mul(f.inv())
+	f = f.frobeniusN(2).mul(f)
+	// Hard part: f^((p^4-p^2+1)/r)
+	return f.exp(hardExp)
+}
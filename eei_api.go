@@ -5,6 +5,7 @@ import (
 	"github.com/tinychain/tiny-wasm/wagon/exec"
 	"github.com/tinychain/tinychain/common"
 	"github.com/tinychain/tinychain/core/types"
+	"github.com/tinychain/tinychain/core/vm"
 	"math/big"
 )
 
@@ -57,6 +58,52 @@ const (
 
 type eeiApi struct{}
 
+// eeiFuncs returns the host functions of the `ethereum` module keyed by their
+// import name.
+func eeiFuncs() map[string]interface{} {
+	api := &eeiApi{}
+	return map[string]interface{}{
+		"useGas":              api.useGas,
+		"getAddress":          api.getAddress,
+		"getExternalBalance":  api.getExternalBalance,
+		"getBlockHash":        api.getBlockHash,
+		"call":                api.call,
+		"callDataCopy":        api.callDataCopy,
+		"getCallDataSize":     api.getCallDataSize,
+		"callCode":            api.callCode,
+		"callDelegate":        api.callDelegate,
+		"callStatic":          api.callStatic,
+		"storageStore":        api.storageStore,
+		"storageLoad":         api.storageLoad,
+		"getCaller":           api.getCaller,
+		"getCallValue":        api.getCallValue,
+		"codeCopy":            api.codeCopy,
+		"getCodeSize":         api.getCodeSize,
+		"getBlockCoinbase":    api.getBlockCoinbase,
+		"create":              api.create,
+		"getBlockDifficulty":  api.getBlockDifficulty,
+		"externalCodeCopy":    api.externalCodeCopy,
+		"getExternalCodeSize": api.getExternalCodeSize,
+		"getGasLeft":          api.getGasLeft,
+		"getBlockGasLimit":    api.getBlockGasLimit,
+		"getTxGasPrice":       api.getTxGasPrice,
+		"log":                 api.log,
+		"getBlockNumber":      api.getBlockNumber,
+		"getTxOrigin":         api.getTxOrigin,
+		"finish":              api.finish,
+		"revert":              api.revert,
+		"getReturnDataSize":   api.getReturnDataSize,
+		"returnDataCopy":      api.returnDataCopy,
+		"selfDestruct":        api.selfDestruct,
+		"getBlockTimestamp":   api.getBlockTimestamp,
+		"bls12381G1Add":       api.bls12381G1Add,
+		"bls12381G1MultiExp":  api.bls12381G1MultiExp,
+		"bls12381G2Add":       api.bls12381G2Add,
+		"bls12381G2MultiExp":  api.bls12381G2MultiExp,
+		"bls12381Pairing":     api.bls12381Pairing,
+	}
+}
+
 func (*eeiApi) useGas(p *exec.Process, w *WasmIntptr, amount int64) {
 	w.useGas(uint64(amount))
 }
@@ -224,15 +271,6 @@ func (*eeiApi) getBlockCoinbase(p *exec.Process, w *WasmIntptr, resultOffset int
 func (*eeiApi) create(p *exec.Process, w *WasmIntptr, valueOffset, dataOffset, length, resultOffset int32) int32 {
 	w.useGas(GasCostCreate)
 
-	oldVM := w.vm
-	oldContract := w.contract
-	defer func() {
-		w.vm = oldVM
-		w.contract = oldContract
-	}()
-
-	w.terminateType = TerminateInvalid
-
 	if int(valueOffset)+u128Len > len(w.vm.Memory()) {
 		return ErrEEICallFailure
 	}
@@ -244,23 +282,31 @@ func (*eeiApi) create(p *exec.Process, w *WasmIntptr, valueOffset, dataOffset, l
 	code := loadFromMem(p, dataOffset, length)
 	val := loadFromMem(p, valueOffset, u128Len)
 
-	w.terminateType = TerminateFinish
+	oldVM := w.vm
+	oldContract := w.contract
+	defer func() {
+		w.vm = oldVM
+		w.contract = oldContract
+		w.terminateType = TerminateFinish
+	}()
 
 	// EIP150 says that the calling contract should keep 1/64th of the
 	// leftover gas.
 	gas := w.contract.Gas - w.contract.Gas/64
-	_, addr, leftGas, _ := w.evm.Create(w.contract, code, gas, new(big.Int).SetBytes(val))
+	w.useGas(gas)
+	_, addr, leftGas, err := w.evm.Create(w.contract, code, gas, new(big.Int).SetBytes(val))
+	oldContract.Gas += leftGas
 
-	switch w.terminateType {
-	case TerminateFinish:
-		oldContract.Gas += leftGas
+	switch {
+	case err == nil:
 		p.WriteAt(addr.Bytes(), int64(resultOffset))
 		return EEICallSuccess
-	case TerminateRevert:
-		oldContract.Gas += gas
+	case err == errExecutionReverted:
 		return ErrEEICallRevert
 	default:
-		oldContract.Gas += leftGas
+		if err == vm.ErrOutOfGas || err == vm.ErrCodeStoreOutOfGas {
+			w.evm.createOutOfGas = true
+		}
 		return ErrEEICallFailure
 	}
 }
@@ -387,6 +433,33 @@ func (*eeiApi) getBlockTimestamp(p *exec.Process, w *WasmIntptr) int64 {
 	return w.evm.Time.Int64()
 }
 
+// bls12381G1Add adds two G1 points, see EIP-2537. The input and the 128 byte
+// result use the EIP encoding and are copied to and from memory verbatim.
+func (*eeiApi) bls12381G1Add(p *exec.Process, w *WasmIntptr, dataOffset, dataLength, resultOffset int32) int32 {
+	return runPrecompile(p, w, 11, dataOffset, dataLength, resultOffset)
+}
+
+// bls12381G1MultiExp computes a G1 multi exponentiation, see EIP-2537.
+func (*eeiApi) bls12381G1MultiExp(p *exec.Process, w *WasmIntptr, dataOffset, dataLength, resultOffset int32) int32 {
+	return runPrecompile(p, w, 12, dataOffset, dataLength, resultOffset)
+}
+
+// bls12381G2Add adds two G2 points, see EIP-2537.
+func (*eeiApi) bls12381G2Add(p *exec.Process, w *WasmIntptr, dataOffset, dataLength, resultOffset int32) int32 {
+	return runPrecompile(p, w, 13, dataOffset, dataLength, resultOffset)
+}
+
+// bls12381G2MultiExp computes a G2 multi exponentiation, see EIP-2537.
+func (*eeiApi) bls12381G2MultiExp(p *exec.Process, w *WasmIntptr, dataOffset, dataLength, resultOffset int32) int32 {
+	return runPrecompile(p, w, 14, dataOffset, dataLength, resultOffset)
+}
+
+// bls12381Pairing runs a pairing check, see EIP-2537. The 32 byte result ends
+// with 0x01 if the check succeeds.
+func (*eeiApi) bls12381Pairing(p *exec.Process, w *WasmIntptr, dataOffset, dataLength, resultOffset int32) int32 {
+	return runPrecompile(p, w, 15, dataOffset, dataLength, resultOffset)
+}
+
 // swapEndian swap big endian to little endian or reverse.
 func swapEndian(src []byte) []byte {
 	rect := make([]byte, len(src))
@@ -441,8 +514,9 @@ func call(w *WasmIntptr, toContract *Contract, input []byte, snapshot int) int32
 
 	w.vm = beforeVM
 	w.contract = beforeContract
+	defer func() { w.terminateType = TerminateFinish }()
 
-	if err != nil {
+	if err != nil && err != errExecutionReverted {
 		w.StateDB().RevertToSnapshot(snapshot)
 		// TODO: need to clear all gas?
 		return ErrEEICallFailure
@@ -461,3 +535,32 @@ func call(w *WasmIntptr, toContract *Contract, input []byte, snapshot int) int32
 		return ErrEEICallFailure
 	}
 }
+
+// runPrecompile runs the precompiled contract at address addr natively on behalf of
+// the calling contract, charging the copy of its input and its required gas, and
+// fails if it isn't enabled or the input is out of the memory. Unlike the other EEI
+// functions the input and output are raw byte strings, so they are not endian swapped.
+func runPrecompile(p *exec.Process, w *WasmIntptr, addr byte, dataOffset, dataLength, resultOffset int32) int32 {
+	c := precompile(w.evm.vmConfig, common.BytesToAddress([]byte{addr}))
+	if c == nil {
+		return ErrEEICallFailure
+	}
+	if dataOffset < 0 || dataLength < 0 || int64(dataOffset)+int64(dataLength) > int64(p.MemSize()) {
+		return ErrEEICallFailure
+	}
+	w.useGas(GasCostCopy * uint64(dataLength))
+	input := make([]byte, dataLength)
+	if _, err := p.ReadAt(input, int64(dataOffset)); err != nil {
+		return ErrEEICallFailure
+	}
+	w.useGas(c.RequiredGas(input))
+
+	ret, err := c.Run(input)
+	if err != nil {
+		return ErrEEICallFailure
+	}
+	if _, err := p.WriteAt(ret, int64(resultOffset)); err != nil {
+		return ErrEEICallFailure
+	}
+	return EEICallSuccess
+}
@@ -8,6 +8,20 @@ import (
 
 type eeiDebugApi struct{}
 
+// eeiDebugFuncs returns the host functions of the `debug` module keyed by
+// their import name.
+func eeiDebugFuncs() map[string]interface{} {
+	api := &eeiDebugApi{}
+	return map[string]interface{}{
+		"print32":         api.print32,
+		"print64":         api.print64,
+		"printMem":        api.printMem,
+		"printMemHex":     api.printMemHex,
+		"printStorage":    api.printStorage,
+		"printStorageHex": api.printStorageHex,
+	}
+}
+
 func (*eeiDebugApi) print32(p *exec.Process, w *WasmIntptr, value int32) {
 	fmt.Println(value)
 }
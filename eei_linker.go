@@ -20,6 +20,8 @@ func moduleResolver(w *WasmIntptr, name string) (*wasm.Module, error) {
 		m.Types.Entries = w.debugFuncSet.entries
 		m.FunctionIndexSpace = w.debugFuncSet.funcs
 		m.Export.Entries = w.debugFuncSet.exports
+
+		return m, nil
 	}
 
 	return nil, fmt.Errorf("unknow module name %s", name)
@@ -0,0 +1,128 @@
+package tinywasm
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/tinychain/tiny-wasm/abi"
+	"github.com/tinychain/tinychain/common"
+	"github.com/tinychain/tinychain/core/vm"
+)
+
+// RevertError is returned by EstimateGas if the message reverts with any
+// amount of gas.
+type RevertError struct {
+	Reason string // revert reason, if encoded as Error(string)
+	Data   []byte // raw revert data
+}
+
+func (e *RevertError) Error() string {
+	if e.Reason == "" {
+		return errExecutionReverted.Error()
+	}
+	return fmt.Sprintf("%v: %s", errExecutionReverted, e.Reason)
+}
+
+func newRevertError(data []byte) *RevertError {
+	reason, err := abi.UnpackRevert(data)
+	if err != nil {
+		reason = ""
+	}
+	return &RevertError{Reason: reason, Data: common.CopyBytes(data)}
+}
+
+// EstimateGas binary searches the lowest gas limit with which msg executes
+// successfully on statedb, after applying the overrides. The state is never
+// modified.
+//
+// The gas limit of the message, or the block gas limit if not set, and the
+// gas the sender can afford bound the search. An execution with a lower limit
+// fails if it runs out of gas or if a contract creation by a contract runs out
+// of gas, as it only receives 63/64 of the gas left. If the message fails even
+// with the highest gas limit a *RevertError is returned for reverts.
+func EstimateGas(ctx Context, statedb vm.StateDB, cfg Config, msg Message, state StateOverride, block *BlockOverrides) (uint64, error) {
+	block.apply(&ctx)
+
+	lo, err := IntrinsicGas(msg.Data, msg.To == nil)
+	if err != nil {
+		return 0, err
+	}
+	lo--
+
+	hi := msg.GasLimit
+	if hi <= lo {
+		hi = ctx.GasLimit
+	}
+	// Cap the limit to the gas the sender can pay for
+	if msg.GasPrice != nil && msg.GasPrice.Sign() != 0 {
+		balance := doBalance(statedb, state, msg.From)
+		if msg.Value != nil {
+			if balance.Cmp(msg.Value) < 0 {
+				return 0, errInsufficientFundsCost
+			}
+			balance.Sub(balance, msg.Value)
+		}
+		allowance := new(big.Int).Div(balance, msg.GasPrice)
+		if allowance.IsUint64() && hi > allowance.Uint64() {
+			hi = allowance.Uint64()
+		}
+	}
+	limit := hi
+
+	// failed reports whether msg fails with the given gas limit.
+	failed := func(gas uint64) (bool, *CallResult, error) {
+		msg.GasLimit = gas
+		res, evm, err := doCall(ctx, statedb, cfg, msg, state, 0)
+		if err != nil {
+			if err == ErrIntrinsicGas {
+				return true, nil, nil // Special case, raise gas limit
+			}
+			return true, nil, err // Bail out
+		}
+		return res.Failed() || evm.createOutOfGas, res, nil
+	}
+
+	// Make sure the message succeeds with the highest limit
+	fail, res, err := failed(hi)
+	if err != nil {
+		return 0, err
+	}
+	if fail {
+		if res == nil {
+			return 0, ErrIntrinsicGas
+		}
+		if res.Err == errExecutionReverted {
+			return 0, newRevertError(res.Revert())
+		}
+		if res.Err == nil || res.Err == vm.ErrOutOfGas {
+			return 0, fmt.Errorf("gas required exceeds allowance (%d)", limit)
+		}
+		return 0, res.Err
+	}
+	// Any lower limit than the gas used runs out of gas
+	if res.UsedGas-1 > lo {
+		lo = res.UsedGas - 1
+	}
+
+	for lo+1 < hi {
+		mid := lo + (hi-lo)/2
+		fail, _, err := failed(mid)
+		if err != nil {
+			return 0, err
+		}
+		if fail {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi, nil
+}
+
+// doBalance returns the balance of addr after the overrides.
+func doBalance(statedb vm.StateDB, state StateOverride, addr common.Address) *big.Int {
+	if account, ok := state[addr]; ok && account.Balance != nil {
+		return new(big.Int).Set(account.Balance)
+	}
+	return new(big.Int).Set(statedb.GetBalance(addr))
+}
@@ -0,0 +1,189 @@
+package tinywasm
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/tinychain/tiny-wasm/wagon/wasm/leb128"
+)
+
+// testImport is a function imported from the ethereum module.
+type testImport struct {
+	name    string
+	params  []byte
+	results []byte
+}
+
+func wasmSection(id byte, payload []byte) []byte {
+	return append(leb128.AppendUleb128([]byte{id}, uint64(len(payload))), payload...)
+}
+
+func wasmName(name string) []byte {
+	return append(leb128.AppendUleb128(nil, uint64(len(name))), name...)
+}
+
+// importModule assembles a contract importing fns whose `main` function runs
+// the instructions of body. The memory is initialised with data.
+func importModule(fns []testImport, body []byte, data []byte) []byte {
+	code := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+
+	types := []byte{byte(len(fns) + 1), 0x60, 0x00, 0x00}
+	imports := []byte{byte(len(fns))}
+	for i, fn := range fns {
+		types = append(types, 0x60, byte(len(fn.params)))
+		types = append(types, fn.params...)
+		types = append(types, byte(len(fn.results)))
+		types = append(types, fn.results...)
+
+		imports = append(imports, wasmName("ethereum")...)
+		imports = append(imports, wasmName(fn.name)...)
+		imports = append(imports, 0x00, byte(i+1))
+	}
+	code = append(code, wasmSection(0x01, types)...)
+	code = append(code, wasmSection(0x02, imports)...)
+	code = append(code, wasmSection(0x03, []byte{0x01, 0x00})...)
+	code = append(code, wasmSection(0x05, []byte{0x01, 0x00, 0x01})...)
+
+	exports := []byte{0x02}
+	exports = append(exports, wasmName("main")...)
+	exports = append(exports, 0x00, byte(len(fns)))
+	exports = append(exports, wasmName("memory")...)
+	exports = append(exports, 0x02, 0x00)
+	code = append(code, wasmSection(0x07, exports)...)
+
+	fn := append(append([]byte{0x00}, body...), 0x0b)
+	code = append(code, wasmSection(0x0a, append(leb128.AppendUleb128([]byte{0x01}, uint64(len(fn))), fn...))...)
+
+	if data != nil {
+		segment := append([]byte{0x01, 0x00, 0x41, 0x00, 0x0b}, leb128.AppendUleb128(nil, uint64(len(data)))...)
+		code = append(code, wasmSection(0x0b, append(segment, data...))...)
+	}
+	return code
+}
+
+func i32Const(v int32) []byte { return leb128.AppendSleb128([]byte{0x41}, int64(v)) }
+func i64Const(v int64) []byte { return leb128.AppendSleb128([]byte{0x42}, v) }
+
+var (
+	useGasImport = testImport{"useGas", []byte{0x7e}, nil}
+	revertImport = testImport{"revert", []byte{0x7f, 0x7f}, nil}
+	createImport = testImport{"create", []byte{0x7f, 0x7f, 0x7f, 0x7f}, []byte{0x7f}}
+	logImport    = testImport{"log", []byte{0x7f, 0x7f, 0x7f, 0x7f, 0x7f, 0x7f, 0x7f}, nil}
+)
+
+// gasContract burns gas with useGas.
+func gasContract(gas int64) []byte {
+	return importModule([]testImport{useGasImport}, append(i64Const(gas), 0x10, 0x00), nil)
+}
+
+func estimateState(code []byte) (*testStateDB, Message) {
+	var (
+		statedb  = newTestStateDB()
+		from     = testAddress(0x1001)
+		contract = testAddress(0x1002)
+	)
+	statedb.AddBalance(from, big.NewInt(1000000000))
+	statedb.SetCode(contract, code)
+	return statedb, Message{From: from, To: &contract, GasPrice: big.NewInt(1)}
+}
+
+func TestEstimateGasTransfer(t *testing.T) {
+	statedb, msg := estimateState(nil)
+	gas, err := EstimateGas(testContext(), statedb, Config{}, msg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gas != TxGas {
+		t.Errorf("have %d, want %d", gas, TxGas)
+	}
+}
+
+func TestEstimateGasExact(t *testing.T) {
+	statedb, msg := estimateState(gasContract(50000))
+	gas, err := EstimateGas(testContext(), statedb, Config{}, msg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gas != TxGas+50000 {
+		t.Errorf("have %d, want %d", gas, TxGas+50000)
+	}
+
+	msg.GasLimit = gas - 1
+	res, err := DoCall(testContext(), statedb, Config{}, msg, nil, nil, 0)
+	if err != nil || !res.Failed() {
+		t.Errorf("expected out of gas below the estimate, have %v, %v", res, err)
+	}
+}
+
+func TestEstimateGasCreateReservation(t *testing.T) {
+	const childGas = 100000
+	child := gasContract(childGas)
+
+	// create(value at 0, code at 16, len, result at 0)
+	body := append(i32Const(0), i32Const(16)...)
+	body = append(body, i32Const(int32(len(child)))...)
+	body = append(body, i32Const(0)...)
+	body = append(body, 0x10, 0x00, 0x1a) // call create, drop
+	data := append(make([]byte, 16), swapEndian(child)...)
+
+	statedb, msg := estimateState(importModule([]testImport{createImport}, body, data))
+	gas, err := EstimateGas(testContext(), statedb, Config{}, msg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if min := TxGas + GasCostCreate + childGas; gas <= min {
+		t.Errorf("estimate %d doesn't cover the 1/64 reserve above %d", gas, min)
+	}
+	for _, test := range []struct {
+		gas      uint64
+		outOfGas bool
+	}{{gas, false}, {gas - 1, true}} {
+		msg.GasLimit = test.gas
+		res, evm, err := doCall(testContext(), statedb, Config{}, msg, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Failed() || evm.createOutOfGas != test.outOfGas {
+			t.Errorf("gas %d: failed %v, create out of gas %v", test.gas, res.Err, evm.createOutOfGas)
+		}
+	}
+}
+
+func TestEstimateGasRevert(t *testing.T) {
+	// abi encoding of Error("boom")
+	reason := []byte{0x08, 0xc3, 0x79, 0xa0}
+	reason = append(reason, make([]byte, 31)...)
+	reason = append(reason, 0x20)
+	reason = append(reason, make([]byte, 31)...)
+	reason = append(reason, 0x04)
+	reason = append(reason, append([]byte("boom"), make([]byte, 28)...)...)
+
+	body := append(i32Const(0), i32Const(int32(len(reason)))...)
+	body = append(body, 0x10, 0x00)
+	statedb, msg := estimateState(importModule([]testImport{revertImport}, body, swapEndian(reason)))
+
+	_, err := EstimateGas(testContext(), statedb, Config{}, msg, nil, nil)
+	revert, ok := err.(*RevertError)
+	if !ok {
+		t.Fatalf("expected revert error, have %v", err)
+	}
+	if revert.Reason != "boom" || !bytes.Equal(revert.Data, reason) {
+		t.Errorf("revert reason %q, data %x", revert.Reason, revert.Data)
+	}
+	if revert.Error() != "evm: execution reverted: boom" {
+		t.Errorf("unexpected message %q", revert.Error())
+	}
+}
+
+func TestEstimateGasOutOfGas(t *testing.T) {
+	statedb, msg := estimateState(gasContract(1 << 40))
+	_, err := EstimateGas(testContext(), statedb, Config{}, msg, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "gas required exceeds allowance") {
+		t.Errorf("expected allowance error, have %v", err)
+	}
+	if _, ok := err.(*RevertError); ok {
+		t.Error("out of gas reported as revert")
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
 	"github.com/tinychain/tinychain/common"
 	"github.com/tinychain/tinychain/core/vm"
 	"github.com/tinychain/tinychain/core/vm/evm/crypto"
@@ -35,6 +36,10 @@ var (
 	errReturnDataOutOfBounds = errors.New("evm: return data out of bounds")
 	errExecutionReverted     = errors.New("evm: execution reverted")
 	errMaxCodeSizeExceeded   = errors.New("evm: max code size exceeded")
+
+	// ErrExecutionAborted is returned if the execution was stopped by
+	// EVM.Cancel.
+	ErrExecutionAborted = errors.New("evm: execution aborted")
 )
 
 type (
@@ -50,11 +55,17 @@ type (
 // run runs the given contract and takes care of running precompiles with a fallback to the byte code interpreter.
 func run(evm *EVM, contract *Contract, input []byte) ([]byte, error) {
 	if contract.CodeAddr != nil {
-		precompiles := PrecompiledContractsByzantium
-		if p := precompiles[*contract.CodeAddr]; p != nil {
+		if p := precompile(evm.vmConfig, *contract.CodeAddr); p != nil {
 			return RunPrecompiledContract(p, input, contract)
 		}
 	}
+	// Calls to accounts without code, e.g. plain value transfers, succeed
+	// without running anything. The interpreter can't run empty code, which
+	// would otherwise fail them with ErrNoCompatibleInterpreter, consuming
+	// all their gas and reverting the transfer.
+	if len(contract.Code) == 0 {
+		return nil, nil
+	}
 
 	if evm.interpreter.CanRun(contract.Code) {
 
@@ -78,6 +89,26 @@ type Config struct {
 	EWASMInterpreter string
 	// Type of the EVM interpreter
 	EVMInterpreter string
+	// StripMetadata removes the contract metadata section from the runtime
+	// code before it is stored, see MetadataSectionName.
+	StripMetadata bool
+	// EnableBLS12381 activates the BLS12-381 precompiled contracts of
+	// EIP-2537 at the addresses 0x0b to 0x0f, and the EEI functions running
+	// them.
+	EnableBLS12381 bool
+	// BLS12381GasScale multiplies the EIP-2537 gas of the BLS12-381
+	// precompiled contracts, zero or one leaving it as it is. The EIP prices
+	// assembly implementations: with the math/big one of crypto/bls12381 a
+	// scale of 200 keeps every operation above 20 Mgas/s on a single core,
+	// see BenchmarkPrecompiledBLS12381.
+	BLS12381GasScale uint64
+	// WasmFeatures are the WebAssembly features beyond the MVP the contracts
+	// of the chain may use.
+	WasmFeatures wasm.Features
+	// WasmDecodeConfig limits the size of the contracts decoded by the
+	// WebAssembly interpreter, and may defer the decoding of their function
+	// bodies to their first use.
+	WasmDecodeConfig wasm.DecodeConfig
 }
 
 // Interpreter is used to run Ethereum based contracts and will utilise the
@@ -135,10 +166,13 @@ type EVM struct {
 	// available gas is calculated in gasCall* according to the 63/64 rule and later
 	// applied in opCall*.
 	callGasTemp uint64
+	// createOutOfGas is set when a contract creation by a contract ran out of
+	// the gas passed to it, see eeiApi.create.
+	createOutOfGas bool
 }
 
 // NewEVM returns a new EVM. The returned EVM is not thread safe and should
-// only ever be used *once*.
+// only ever be used *once*. Use an EVMPool to reuse instances.
 func NewEVM(ctx Context, StateDB vm.StateDB, vmConfig Config) *EVM {
 	evm := &EVM{
 		Context:  ctx,
@@ -166,6 +200,11 @@ func (evm *EVM) Cancel() {
 	atomic.StoreInt32(&evm.abort, 1)
 }
 
+// Cancelled returns true if Cancel has been called
+func (evm *EVM) Cancelled() bool {
+	return atomic.LoadInt32(&evm.abort) == 1
+}
+
 // Interpreter returns the current interpreter
 func (evm *EVM) Interpreter() Interpreter {
 	return evm.interpreter
@@ -194,7 +233,7 @@ func (evm *EVM) Call(caller vm.ContractRef, addr common.Address, input []byte, g
 		snapshot = evm.StateDB.Snapshot()
 	)
 	if !evm.StateDB.Exist(addr) {
-		precompiles := PrecompiledContractsByzantium
+		precompiles := activePrecompiles(evm.vmConfig)
 		if precompiles[addr] == nil && value.Sign() == 0 {
 			// Calling a non existing account, don't do anything, but ping the tracer
 			if evm.vmConfig.Debug && evm.depth == 0 {
@@ -393,6 +432,9 @@ func (evm *EVM) create(caller vm.ContractRef, code []byte, gas uint64, value *bi
 	start := time.Now()
 
 	ret, err := run(evm, contract, nil)
+	if err == nil && evm.vmConfig.StripMetadata {
+		ret = StripMetadata(ret)
+	}
 
 	// check whether the max code size has been exceeded
 	maxCodeSizeExceeded := len(ret) > MaxCodeSize
@@ -26,6 +26,8 @@ import (
 	"io"
 	"math/big"
 	"time"
+
+	"github.com/tinychain/tiny-wasm/abi"
 )
 
 // Storage represents a contract's storage.
@@ -252,3 +254,17 @@ func WriteLogs(writer io.Writer, logs []*types.Log) {
 		fmt.Fprintln(writer)
 	}
 }
+
+// WriteDecodedLogs writes vm logs to the given writer, printing the events
+// known to contractABI by name with their decoded arguments. Logs which can't
+// be decoded are written as by WriteLogs.
+func WriteDecodedLogs(writer io.Writer, contractABI *abi.ABI, logs []*types.Log) {
+	for _, log := range logs {
+		event, err := contractABI.DecodeLog(log.Topics, log.Data)
+		if err != nil {
+			WriteLogs(writer, []*types.Log{log})
+			continue
+		}
+		fmt.Fprintf(writer, "EVENT %x bn=%d txi=%x\n%v\n\n", log.Address, log.BlockHeight, log.TxIndex, event)
+	}
+}
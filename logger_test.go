@@ -17,10 +17,15 @@
 package tinywasm
 
 import (
+	"bytes"
 	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/tinychain/tinychain/common"
+	"github.com/tinychain/tinychain/core/types"
+
+	"github.com/tinychain/tiny-wasm/abi"
 )
 
 type dummyContractRef struct {
@@ -67,3 +72,47 @@ func TestStoreCapture(t *testing.T) {
 		t.Errorf("expected %x, got %x", exp, logger.changedValues[contract.Address()][index])
 	}
 }
+
+func TestWriteDecodedLogs(t *testing.T) {
+	contractABI, err := abi.JSON(strings.NewReader(`[{"type":"event","name":"Stored","inputs":[{"name":"key","type":"uint256","indexed":true},{"name":"value","type":"string"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := contractABI.Events["Stored"].Inputs.NonIndexed().Pack("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := common.BigToHash(big.NewInt(7))
+
+	// The contract emits the event from the data, ID and key laid out in
+	// its memory.
+	mem := swapEndian(data)
+	mem = append(mem, swapEndian(contractABI.Events["Stored"].ID.Bytes())...)
+	mem = append(mem, swapEndian(key.Bytes())...)
+	body := append(i32Const(0), i32Const(int32(len(data)))...)
+	body = append(body, i32Const(2)...)
+	body = append(body, i32Const(int32(len(data)))...)
+	body = append(body, i32Const(int32(len(data)+32))...)
+	body = append(body, i32Const(0)...)
+	body = append(body, i32Const(0)...)
+	body = append(body, 0x10, 0x00)
+	statedb, msg := estimateState(importModule([]testImport{logImport}, body, mem))
+
+	res, err := DoCall(testContext(), statedb, Config{}, msg, nil, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Failed() {
+		t.Fatalf("execution failed: %v", res.Err)
+	}
+	logs := append(res.Logs, &types.Log{Topics: []common.Hash{{0x01}}, Data: []byte{0xff}})
+	out := new(bytes.Buffer)
+	WriteDecodedLogs(out, &contractABI, logs)
+
+	if !strings.Contains(out.String(), "Stored(key: 7, value: hello)") {
+		t.Errorf("decoded event missing from output:\n%s", out)
+	}
+	if !strings.Contains(out.String(), "LOG1: ") {
+		t.Errorf("undecodable log missing from output:\n%s", out)
+	}
+}
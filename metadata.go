@@ -0,0 +1,142 @@
+package tinywasm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/tinychain/tinychain/common"
+	"github.com/tinychain/tinychain/core/vm"
+
+	"github.com/tinychain/tiny-wasm/abi"
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+	"github.com/tinychain/tiny-wasm/wagon/wasm/leb128"
+)
+
+// MetadataSectionName is the name of the custom section holding the contract
+// metadata. Custom sections are ignored by the interpreter, so embedding the
+// metadata does not change how a contract executes.
+const MetadataSectionName = "contract.metadata"
+
+var (
+	ErrNoMetadata  = errors.New("metadata: contract has no metadata section")
+	errNotWasmCode = errors.New("metadata: code is not a wasm module")
+)
+
+// ContractMetadata describes a contract to tools. It is stored JSON encoded
+// in the MetadataSectionName custom section of the contract code.
+type ContractMetadata struct {
+	ABI      json.RawMessage `json:"abi"`                // JSON ABI of the contract
+	Source   string          `json:"source,omitempty"`   // source file, URL or content hash
+	Language string          `json:"language,omitempty"` // source language
+	Compiler string          `json:"compiler,omitempty"` // compiler name and version
+	Settings json.RawMessage `json:"settings,omitempty"` // compiler settings
+}
+
+// ParseABI decodes the JSON ABI held by the metadata.
+func (m *ContractMetadata) ParseABI() (abi.ABI, error) {
+	if len(m.ABI) == 0 {
+		return abi.ABI{}, errors.New("metadata: no abi")
+	}
+	return abi.JSON(bytes.NewReader(m.ABI))
+}
+
+// EmbedMetadata returns a copy of code with meta stored in its metadata
+// section. An existing metadata section is replaced.
+func EmbedMetadata(code []byte, meta *ContractMetadata) ([]byte, error) {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	stripped, err := stripCustomSection(code, MetadataSectionName)
+	if err != nil {
+		return nil, err
+	}
+	section := &wasm.SectionCustom{Name: MetadataSectionName, Data: data}
+	payload := new(bytes.Buffer)
+	if err := section.WritePayload(payload); err != nil {
+		return nil, err
+	}
+	out := leb128.AppendUleb128(stripped, uint64(wasm.SectionIDCustom))
+	out = leb128.AppendUleb128(out, uint64(payload.Len()))
+	return append(out, payload.Bytes()...), nil
+}
+
+// ExtractMetadata decodes the metadata embedded in code.
+func ExtractMetadata(code []byte) (*ContractMetadata, error) {
+	// The function bodies are left undecoded, only the sections are needed.
+	module, err := wasm.DecodeModuleConfig(bytes.NewReader(code), wasm.DecodeConfig{LazyBodies: true})
+	if err != nil {
+		return nil, err
+	}
+	section := module.Custom(MetadataSectionName)
+	if section == nil {
+		return nil, ErrNoMetadata
+	}
+	meta := new(ContractMetadata)
+	if err := json.Unmarshal(section.Data, meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// ReadMetadata extracts the metadata of the contract deployed at addr.
+func ReadMetadata(statedb vm.StateDB, addr common.Address) (*ContractMetadata, error) {
+	code := statedb.GetCode(addr)
+	if len(code) == 0 {
+		return nil, ErrNoMetadata
+	}
+	return ExtractMetadata(code)
+}
+
+// StripMetadata removes the metadata section from code. Code that is not a
+// wasm module or carries no metadata is returned unchanged.
+func StripMetadata(code []byte) []byte {
+	stripped, err := stripCustomSection(code, MetadataSectionName)
+	if err != nil {
+		return code
+	}
+	return stripped
+}
+
+// stripCustomSection copies the wasm binary in code, leaving out all custom
+// sections with the given name. Every other section is copied byte by byte so
+// the result decodes to the same module.
+func stripCustomSection(code []byte, name string) ([]byte, error) {
+	if len(code) < 8 || binary.LittleEndian.Uint32(code) != wasm.Magic {
+		return nil, errNotWasmCode
+	}
+	out := append([]byte{}, code[:8]...)
+	r := bytes.NewReader(code[8:])
+	for r.Len() > 0 {
+		start := len(code) - r.Len()
+		id, err := leb128.ReadVarUint32(r)
+		if err != nil {
+			return nil, err
+		}
+		size, err := leb128.ReadVarUint32(r)
+		if err != nil {
+			return nil, err
+		}
+		if uint64(size) > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		payload := len(code) - r.Len()
+		end := payload + int(size)
+		r.Seek(int64(size), io.SeekCurrent)
+
+		if wasm.SectionID(id) == wasm.SectionIDCustom {
+			section := new(wasm.SectionCustom)
+			if err := section.ReadPayload(bytes.NewReader(code[payload:end])); err != nil {
+				return nil, err
+			}
+			if section.Name == name {
+				continue
+			}
+		}
+		out = append(out, code[start:end]...)
+	}
+	return out, nil
+}
@@ -0,0 +1,96 @@
+package tinywasm
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/tinychain/tinychain/common"
+	"github.com/tinychain/tinychain/core/vm"
+)
+
+// testModule is a module with a single () -> () type and a "name" custom
+// section.
+var testModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+	0x00, 0x05, 0x04, 'n', 'a', 'm', 'e',
+}
+
+// codeState is a StateDB only serving the code of a single account.
+type codeState struct {
+	vm.StateDB
+	addr common.Address
+	code []byte
+}
+
+func (s *codeState) GetCode(addr common.Address) []byte {
+	if addr == s.addr {
+		return s.code
+	}
+	return nil
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	meta := &ContractMetadata{
+		ABI:      json.RawMessage(`[{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}]}]`),
+		Source:   "token.rs",
+		Compiler: "rustc 1.40.0",
+	}
+	code, err := EmbedMetadata(testModule, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(code, testModule) {
+		t.Errorf("embedding changed the original sections")
+	}
+
+	addr := common.HexToAddress("0x01")
+	have, err := ReadMetadata(&codeState{addr: addr, code: code}, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have.Source != meta.Source || have.Compiler != meta.Compiler || !bytes.Equal(have.ABI, meta.ABI) {
+		t.Errorf("metadata mismatch: have %+v, want %+v", have, meta)
+	}
+	parsed, err := have.ParseABI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := parsed.Methods["transfer"]; !ok {
+		t.Errorf("transfer missing from abi")
+	}
+
+	// Embedding again replaces the section instead of adding another one.
+	meta.Source = "other.rs"
+	again, err := EmbedMetadata(code, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err = ExtractMetadata(again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have.Source != "other.rs" || bytes.Count(again, []byte(MetadataSectionName)) != 1 {
+		t.Errorf("metadata section was not replaced")
+	}
+
+	if stripped := StripMetadata(again); !bytes.Equal(stripped, testModule) {
+		t.Errorf("strip mismatch: have %x, want %x", stripped, testModule)
+	}
+	if _, err := ReadMetadata(&codeState{addr: addr, code: testModule}, addr); err != ErrNoMetadata {
+		t.Errorf("have %v, want %v", err, ErrNoMetadata)
+	}
+}
+
+func TestStripMetadataInvalidCode(t *testing.T) {
+	for _, code := range [][]byte{
+		nil,
+		{0x60, 0x80, 0x60, 0x40, 0x52, 0x00, 0x00, 0x00},
+		append(append([]byte{}, testModule...), 0x00, 0x10, 0x01),
+	} {
+		if stripped := StripMetadata(code); !bytes.Equal(stripped, code) {
+			t.Errorf("%x: code was modified", code)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package tinywasm
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/tinychain/tinychain/common"
+	"github.com/tinychain/tinychain/core/types"
+	"github.com/tinychain/tinychain/core/vm"
+	"github.com/tinychain/tinychain/core/vm/evm/crypto"
+)
+
+// stateKind identifies the part of an account a stateKey refers to.
+type stateKind uint8
+
+const (
+	kindAccount stateKind = iota // existence of the account as a whole
+	kindBalance
+	kindNonce
+	kindCode
+	kindStorage
+)
+
+// stateKey is a single piece of state read or written by a transaction.
+type stateKey struct {
+	addr common.Address
+	kind stateKind
+	slot common.Hash // storage slot, kindStorage only
+}
+
+// overlayAccount holds the changes made to an account in an overlay.
+type overlayAccount struct {
+	balance      *big.Int // difference to the balance in the base state
+	balanceDirty bool     // set by any balance change, even a zero one, as it touches the account
+	nonce        uint64
+	nonceDirty   bool
+	code         []byte
+	codeDirty    bool
+	storage      map[common.Hash][]byte
+	cleared      bool // storage of the base state is dropped
+	created      bool // storage, nonce and code of the base state are dropped
+	suicided     bool
+}
+
+// overlayState is a vm.StateDB buffering all changes on top of a base state,
+// which is only ever read. It records the state read by the transaction
+// executing on it, so that its changes can later be checked against the
+// changes committed to the base state in the meantime.
+//
+// Balance changes are kept as differences to the base state, adding to a
+// balance without looking at it first, like paying the coinbase, is not a read.
+type overlayState struct {
+	base vm.StateDB
+	mu   *sync.Mutex // guards base, shared by the overlays of a block
+
+	accounts  map[common.Address]*overlayAccount
+	reads     map[stateKey]struct{}
+	refund    uint64
+	logs      []*types.Log
+	preimages map[common.Hash][]byte
+
+	journal   []func()
+	revisions []int // journal length at each snapshot
+}
+
+func newOverlayState(base vm.StateDB, mu *sync.Mutex) *overlayState {
+	if mu == nil {
+		mu = new(sync.Mutex)
+	}
+	return &overlayState{
+		base:      base,
+		mu:        mu,
+		accounts:  make(map[common.Address]*overlayAccount),
+		reads:     make(map[stateKey]struct{}),
+		preimages: make(map[common.Hash][]byte),
+	}
+}
+
+func (s *overlayState) account(addr common.Address) *overlayAccount {
+	acc := s.accounts[addr]
+	if acc == nil {
+		acc = &overlayAccount{balance: new(big.Int), storage: make(map[common.Hash][]byte)}
+		s.accounts[addr] = acc
+	}
+	return acc
+}
+
+func (s *overlayState) read(addr common.Address, kind stateKind, slot common.Hash) {
+	s.reads[stateKey{addr, kind, slot}] = struct{}{}
+}
+
+// readBase runs fn on the base state, which must not be accessed
+// concurrently.
+func (s *overlayState) readBase(fn func(vm.StateDB)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.base)
+}
+
+func (s *overlayState) CreateAccount(addr common.Address) {
+	acc := s.account(addr)
+	prev := *acc
+	s.journal = append(s.journal, func() { *acc = prev })
+
+	acc.created, acc.cleared = true, true
+	acc.suicided = false
+	acc.nonce, acc.nonceDirty = 0, true
+	acc.code, acc.codeDirty = nil, true
+	acc.storage = make(map[common.Hash][]byte)
+}
+
+func (s *overlayState) addBalance(addr common.Address, amount *big.Int) {
+	acc := s.account(addr)
+	prev, prevDirty := acc.balance, acc.balanceDirty
+	s.journal = append(s.journal, func() { acc.balance, acc.balanceDirty = prev, prevDirty })
+
+	acc.balance = new(big.Int).Add(acc.balance, amount)
+	acc.balanceDirty = true
+}
+
+func (s *overlayState) SubBalance(addr common.Address, amount *big.Int) {
+	s.addBalance(addr, new(big.Int).Neg(amount))
+}
+
+func (s *overlayState) AddBalance(addr common.Address, amount *big.Int) {
+	s.addBalance(addr, amount)
+}
+
+func (s *overlayState) GetBalance(addr common.Address) *big.Int {
+	s.read(addr, kindBalance, common.Hash{})
+
+	var balance *big.Int
+	s.readBase(func(base vm.StateDB) { balance = new(big.Int).Set(base.GetBalance(addr)) })
+	if acc := s.accounts[addr]; acc != nil {
+		balance.Add(balance, acc.balance)
+	}
+	return balance
+}
+
+func (s *overlayState) GetNonce(addr common.Address) uint64 {
+	s.read(addr, kindNonce, common.Hash{})
+	if acc := s.accounts[addr]; acc != nil && acc.nonceDirty {
+		return acc.nonce
+	}
+	var nonce uint64
+	s.readBase(func(base vm.StateDB) { nonce = base.GetNonce(addr) })
+	return nonce
+}
+
+func (s *overlayState) SetNonce(addr common.Address, nonce uint64) {
+	acc := s.account(addr)
+	prev, prevDirty := acc.nonce, acc.nonceDirty
+	s.journal = append(s.journal, func() { acc.nonce, acc.nonceDirty = prev, prevDirty })
+
+	acc.nonce, acc.nonceDirty = nonce, true
+}
+
+func (s *overlayState) GetCode(addr common.Address) []byte {
+	s.read(addr, kindCode, common.Hash{})
+	if acc := s.accounts[addr]; acc != nil && acc.codeDirty {
+		return acc.code
+	}
+	var code []byte
+	s.readBase(func(base vm.StateDB) { code = base.GetCode(addr) })
+	return code
+}
+
+func (s *overlayState) GetCodeHash(addr common.Address) common.Hash {
+	s.read(addr, kindCode, common.Hash{})
+	if acc := s.accounts[addr]; acc != nil && acc.codeDirty {
+		return crypto.Keccak256Hash(acc.code)
+	}
+	var hash common.Hash
+	s.readBase(func(base vm.StateDB) { hash = base.GetCodeHash(addr) })
+	return hash
+}
+
+func (s *overlayState) SetCode(addr common.Address, code []byte) {
+	acc := s.account(addr)
+	prev, prevDirty := acc.code, acc.codeDirty
+	s.journal = append(s.journal, func() { acc.code, acc.codeDirty = prev, prevDirty })
+
+	acc.code, acc.codeDirty = code, true
+}
+
+func (s *overlayState) GetCodeSize(addr common.Address) int {
+	return len(s.GetCode(addr))
+}
+
+func (s *overlayState) AddRefund(gas uint64) {
+	prev := s.refund
+	s.journal = append(s.journal, func() { s.refund = prev })
+	s.refund += gas
+}
+
+func (s *overlayState) SubRefund(gas uint64) {
+	prev := s.refund
+	s.journal = append(s.journal, func() { s.refund = prev })
+	if gas > s.refund {
+		panic("refund counter below zero")
+	}
+	s.refund -= gas
+}
+
+func (s *overlayState) GetRefund() uint64 {
+	return s.refund
+}
+
+func (s *overlayState) GetState(addr common.Address, key common.Hash) []byte {
+	s.read(addr, kindStorage, key)
+	if acc := s.accounts[addr]; acc != nil {
+		if val, ok := acc.storage[key]; ok {
+			return val
+		}
+		if acc.cleared {
+			return nil
+		}
+	}
+	var val []byte
+	s.readBase(func(base vm.StateDB) { val = base.GetState(addr, key) })
+	return val
+}
+
+func (s *overlayState) SetState(addr common.Address, key common.Hash, value []byte) {
+	acc := s.account(addr)
+	prev, prevOk := acc.storage[key]
+	s.journal = append(s.journal, func() {
+		if prevOk {
+			acc.storage[key] = prev
+		} else {
+			delete(acc.storage, key)
+		}
+	})
+
+	acc.storage[key] = value
+}
+
+// clearStorage drops the storage of the account in the base state.
+func (s *overlayState) clearStorage(addr common.Address) {
+	acc := s.account(addr)
+	prev, prevCleared := acc.storage, acc.cleared
+	s.journal = append(s.journal, func() { acc.storage, acc.cleared = prev, prevCleared })
+
+	acc.storage = make(map[common.Hash][]byte)
+	acc.cleared = true
+}
+
+func (s *overlayState) Suicide(addr common.Address) bool {
+	if !s.Exist(addr) {
+		return false
+	}
+	balance := s.GetBalance(addr)
+
+	acc := s.account(addr)
+	prev := *acc
+	s.journal = append(s.journal, func() { *acc = prev })
+
+	acc.suicided = true
+	acc.balance = new(big.Int).Sub(acc.balance, balance)
+	acc.balanceDirty = true
+	return true
+}
+
+func (s *overlayState) HasSuicided(addr common.Address) bool {
+	s.read(addr, kindAccount, common.Hash{})
+	if acc := s.accounts[addr]; acc != nil && acc.suicided {
+		return true
+	}
+	var suicided bool
+	s.readBase(func(base vm.StateDB) { suicided = base.HasSuicided(addr) })
+	return suicided
+}
+
+// readAccount records the reads the existence of an account depends on.
+func (s *overlayState) readAccount(addr common.Address) {
+	s.read(addr, kindAccount, common.Hash{})
+	s.read(addr, kindBalance, common.Hash{})
+	s.read(addr, kindNonce, common.Hash{})
+	s.read(addr, kindCode, common.Hash{})
+}
+
+func (s *overlayState) Exist(addr common.Address) bool {
+	s.readAccount(addr)
+	if acc := s.accounts[addr]; acc != nil && (acc.created || acc.balanceDirty || acc.nonceDirty || acc.codeDirty) {
+		return true
+	}
+	var exist bool
+	s.readBase(func(base vm.StateDB) { exist = base.Exist(addr) })
+	return exist
+}
+
+func (s *overlayState) Empty(addr common.Address) bool {
+	s.readAccount(addr)
+	return s.GetNonce(addr) == 0 && s.GetBalance(addr).Sign() == 0 && len(s.GetCode(addr)) == 0
+}
+
+func (s *overlayState) Snapshot() int {
+	s.revisions = append(s.revisions, len(s.journal))
+	return len(s.revisions) - 1
+}
+
+func (s *overlayState) RevertToSnapshot(id int) {
+	if id < 0 || id >= len(s.revisions) {
+		panic("revision id cannot be reverted")
+	}
+	n := s.revisions[id]
+	for i := len(s.journal) - 1; i >= n; i-- {
+		s.journal[i]()
+	}
+	s.journal = s.journal[:n]
+	s.revisions = s.revisions[:id]
+}
+
+func (s *overlayState) AddLog(log *types.Log) {
+	n := len(s.logs)
+	s.journal = append(s.journal, func() { s.logs = s.logs[:n] })
+	s.logs = append(s.logs, log)
+}
+
+func (s *overlayState) AddPreimage(hash common.Hash, preimage []byte) {
+	if _, ok := s.preimages[hash]; !ok {
+		s.journal = append(s.journal, func() { delete(s.preimages, hash) })
+		s.preimages[hash] = common.CopyBytes(preimage)
+	}
+}
+
+func (s *overlayState) ForEachStorage(addr common.Address, cb func(common.Hash, []byte) bool) {
+	s.read(addr, kindAccount, common.Hash{})
+	acc := s.accounts[addr]
+
+	storage := make(map[common.Hash][]byte)
+	if acc == nil || !acc.cleared {
+		s.readBase(func(base vm.StateDB) {
+			base.ForEachStorage(addr, func(key common.Hash, val []byte) bool {
+				storage[key] = val
+				return true
+			})
+		})
+	}
+	if acc != nil {
+		for key, val := range acc.storage {
+			storage[key] = val
+		}
+	}
+	for key, val := range storage {
+		if !cb(key, val) {
+			return
+		}
+	}
+}
+
+// writes returns the state changed by the overlay.
+func (s *overlayState) writes() map[stateKey]struct{} {
+	writes := make(map[stateKey]struct{})
+	for addr, acc := range s.accounts {
+		if acc.cleared || acc.suicided {
+			writes[stateKey{addr: addr, kind: kindAccount}] = struct{}{}
+		}
+		if acc.balanceDirty {
+			writes[stateKey{addr: addr, kind: kindBalance}] = struct{}{}
+		}
+		if acc.nonceDirty {
+			writes[stateKey{addr: addr, kind: kindNonce}] = struct{}{}
+		}
+		if acc.codeDirty {
+			writes[stateKey{addr: addr, kind: kindCode}] = struct{}{}
+		}
+		for key := range acc.storage {
+			writes[stateKey{addr, kindStorage, key}] = struct{}{}
+		}
+	}
+	return writes
+}
+
+// commit writes the changes of the overlay to the base state.
+func (s *overlayState) commit() {
+	for addr, acc := range s.accounts {
+		if acc.created {
+			s.base.CreateAccount(addr)
+		} else if acc.cleared {
+			var keys []common.Hash
+			s.base.ForEachStorage(addr, func(key common.Hash, _ []byte) bool {
+				keys = append(keys, key)
+				return true
+			})
+			for _, key := range keys {
+				if _, ok := acc.storage[key]; !ok {
+					s.base.SetState(addr, key, nil)
+				}
+			}
+		}
+		if acc.balanceDirty {
+			switch acc.balance.Sign() {
+			case 1:
+				s.base.AddBalance(addr, acc.balance)
+			case -1:
+				s.base.SubBalance(addr, new(big.Int).Neg(acc.balance))
+			default:
+				// zero changes, or changes cancelling out, still touch
+				// the account like they do on the base state
+				s.base.AddBalance(addr, new(big.Int))
+			}
+		}
+		if acc.nonceDirty {
+			s.base.SetNonce(addr, acc.nonce)
+		}
+		if acc.codeDirty {
+			s.base.SetCode(addr, acc.code)
+		}
+		for key, val := range acc.storage {
+			s.base.SetState(addr, key, val)
+		}
+		if acc.suicided {
+			s.base.Suicide(addr)
+		}
+	}
+	for _, log := range s.logs {
+		s.base.AddLog(log)
+	}
+	for hash, preimage := range s.preimages {
+		s.base.AddPreimage(hash, preimage)
+	}
+}
@@ -0,0 +1,135 @@
+package tinywasm
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/tinychain/tinychain/core/vm"
+)
+
+// BlockResult holds the outcome of executing the messages of a block.
+type BlockResult struct {
+	Receipts []*Receipt
+	Results  []*ExecutionResult
+	UsedGas  uint64
+
+	// Reexecuted counts the messages which conflicted with an earlier
+	// message of the block and had to be executed again.
+	Reexecuted int
+}
+
+// ParallelExecutor executes the messages of a block optimistically in
+// parallel. Every message first runs on its own overlay of the block state,
+// which records the state it reads and buffers the state it writes. The
+// overlays are then committed in block order: a message which read state
+// written by an earlier message is executed again on top of the state
+// committed so far. The resulting state, receipts and results are the same as
+// when applying the messages one after the other with ApplyMessage.
+type ParallelExecutor struct {
+	pool    *EVMPool
+	workers int
+}
+
+// NewParallelExecutor returns an executor running up to workers messages at
+// the same time. With workers <= 0 it runs one per CPU.
+func NewParallelExecutor(vmConfig Config, workers int) *ParallelExecutor {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &ParallelExecutor{pool: NewEVMPool(vmConfig), workers: workers}
+}
+
+// execution is the optimistic execution of a single message.
+type execution struct {
+	state   *overlayState
+	receipt *Receipt
+	result  *ExecutionResult
+	err     error
+}
+
+// Execute applies msgs to statedb. statedb is only accessed by one goroutine
+// at a time, the functions of ctx and the tracer of the configuration however
+// are called concurrently. If a message can't be applied, the messages before
+// it are left committed to statedb and the error is returned.
+func (p *ParallelExecutor) Execute(ctx Context, statedb vm.StateDB, msgs []Message, gp *GasPool) (*BlockResult, error) {
+	var (
+		mu    sync.Mutex
+		execs = make([]*execution, len(msgs))
+		next  = make(chan int)
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				// The block gas pool is only checked when committing.
+				gp := GasPool(msgs[i].GasLimit)
+				execs[i] = p.execute(ctx, newOverlayState(statedb, &mu), msgs[i], &gp)
+			}
+		}()
+	}
+	for i := range msgs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var (
+		block   = &BlockResult{}
+		written = make(map[stateKey]struct{})
+	)
+	for i, msg := range msgs {
+		exec := execs[i]
+		if conflicts(exec.state.reads, written) {
+			gp := GasPool(msg.GasLimit)
+			exec = p.execute(ctx, newOverlayState(statedb, nil), msg, &gp)
+			block.Reexecuted++
+		}
+		if exec.err != nil {
+			return nil, fmt.Errorf("could not apply message %d: %v", i, exec.err)
+		}
+		// Same as buying and refunding the gas in the block gas pool.
+		if gp.Gas() < msg.GasLimit {
+			return nil, fmt.Errorf("could not apply message %d: %v", i, ErrGasLimitReached)
+		}
+		gp.SubGas(exec.result.UsedGas)
+
+		exec.state.commit()
+		for key := range exec.state.writes() {
+			written[key] = struct{}{}
+		}
+
+		block.UsedGas += exec.result.UsedGas
+		exec.receipt.CumulativeGasUsed = block.UsedGas
+		block.Receipts = append(block.Receipts, exec.receipt)
+		block.Results = append(block.Results, exec.result)
+	}
+	return block, nil
+}
+
+// execute applies msg on state with an EVM from the pool.
+func (p *ParallelExecutor) execute(ctx Context, state *overlayState, msg Message, gp *GasPool) *execution {
+	collector := newLogCollector(state)
+	evm := p.pool.Get(messageContext(ctx, msg), collector)
+	defer p.pool.Put(evm)
+
+	var usedGas uint64
+	receipt, result, err := applyMessage(evm, collector, msg, gp, &usedGas)
+	return &execution{state: state, receipt: receipt, result: result, err: err}
+}
+
+// conflicts reports whether any of the reads is of state written.
+func conflicts(reads, written map[stateKey]struct{}) bool {
+	for key := range reads {
+		if _, ok := written[key]; ok {
+			return true
+		}
+		// Creating or destroying an account replaces all of its state.
+		if _, ok := written[stateKey{addr: key.addr, kind: kindAccount}]; ok {
+			return true
+		}
+	}
+	return false
+}
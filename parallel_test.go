@@ -0,0 +1,231 @@
+package tinywasm
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/tinychain/tinychain/common"
+)
+
+func testAddress(n int) common.Address {
+	return common.BigToAddress(big.NewInt(int64(n)))
+}
+
+// copy returns a deep copy of the state.
+func (s *testStateDB) copy() *testStateDB {
+	cpy := newTestStateDB()
+	for addr, acc := range s.accounts {
+		cpy.accounts[addr] = acc.copy()
+	}
+	return cpy
+}
+
+func diffStates(have, want *testStateDB) error {
+	if len(have.accounts) != len(want.accounts) {
+		return fmt.Errorf("have %d accounts, want %d", len(have.accounts), len(want.accounts))
+	}
+	for addr, w := range want.accounts {
+		h := have.accounts[addr]
+		switch {
+		case h == nil:
+			return fmt.Errorf("account %x missing", addr)
+		case h.balance.Cmp(w.balance) != 0:
+			return fmt.Errorf("account %x: balance %v, want %v", addr, h.balance, w.balance)
+		case h.nonce != w.nonce:
+			return fmt.Errorf("account %x: nonce %d, want %d", addr, h.nonce, w.nonce)
+		case !bytes.Equal(h.code, w.code):
+			return fmt.Errorf("account %x: code mismatch", addr)
+		case len(h.storage) != len(w.storage):
+			return fmt.Errorf("account %x: %d slots, want %d", addr, len(h.storage), len(w.storage))
+		}
+		for key, val := range w.storage {
+			if !bytes.Equal(h.storage[key], val) {
+				return fmt.Errorf("account %x: slot %x is %x, want %x", addr, key, h.storage[key], val)
+			}
+		}
+	}
+	return nil
+}
+
+// checkParallel executes msgs in parallel and one after the other and
+// compares the outcome.
+func checkParallel(t *testing.T, statedb *testStateDB, msgs []Message) *BlockResult {
+	t.Helper()
+
+	var (
+		serial  = statedb.copy()
+		serialg = GasPool(10000000)
+		usedGas uint64
+	)
+	var receipts []*Receipt
+	for i, msg := range msgs {
+		receipt, _, err := ApplyMessage(testContext(), serial, Config{}, msg, &serialg, &usedGas)
+		if err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		receipts = append(receipts, receipt)
+	}
+
+	gp := GasPool(10000000)
+	block, err := NewParallelExecutor(Config{}, 4).Execute(testContext(), statedb, msgs, &gp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := diffStates(statedb, serial); err != nil {
+		t.Error(err)
+	}
+	if block.UsedGas != usedGas || gp != serialg {
+		t.Errorf("used gas %d, gas pool %d, want %d, %d", block.UsedGas, gp, usedGas, serialg)
+	}
+	for i, receipt := range block.Receipts {
+		want := receipts[i]
+		if receipt.Status != want.Status || receipt.GasUsed != want.GasUsed ||
+			receipt.CumulativeGasUsed != want.CumulativeGasUsed || receipt.ContractAddress != want.ContractAddress {
+			t.Errorf("receipt %d: have %+v, want %+v", i, receipt, want)
+		}
+	}
+	return block
+}
+
+func TestParallelIndependent(t *testing.T) {
+	statedb := newTestStateDB()
+	var msgs []Message
+	for i := 0; i < 16; i++ {
+		from, to := testAddress(0x1000+i), testAddress(0x2000+i)
+		statedb.AddBalance(from, big.NewInt(1000000))
+		msgs = append(msgs, Message{From: from, To: &to, Value: big.NewInt(int64(i + 1)), GasLimit: 30000, GasPrice: big.NewInt(1), CheckNonce: true})
+	}
+	block := checkParallel(t, statedb, msgs)
+	if block.Reexecuted != 0 {
+		t.Errorf("%d messages reexecuted, want none", block.Reexecuted)
+	}
+}
+
+func TestParallelConflicts(t *testing.T) {
+	var (
+		statedb = newTestStateDB()
+		a, b, c = testAddress(0x1001), testAddress(0x1002), testAddress(0x1003)
+		d       = testAddress(0x1004)
+	)
+	statedb.AddBalance(a, big.NewInt(1000000))
+	statedb.AddBalance(c, big.NewInt(1000000))
+
+	msgs := []Message{
+		{From: a, To: &b, Value: big.NewInt(500000), GasLimit: 30000, GasPrice: big.NewInt(1), CheckNonce: true},
+		// same sender, the nonce depends on the first message
+		{From: a, To: &c, Value: big.NewInt(1000), GasLimit: 30000, GasPrice: big.NewInt(1), Nonce: 1, CheckNonce: true},
+		// b is only funded by the first message
+		{From: b, To: &d, Value: big.NewInt(1000), GasLimit: 30000, GasPrice: big.NewInt(1), CheckNonce: true},
+		// independent contract creation
+		{From: c, Data: testContract(0x0b), GasLimit: 200000, GasPrice: big.NewInt(1), CheckNonce: true},
+	}
+	block := checkParallel(t, statedb, msgs)
+	if block.Reexecuted != 3 {
+		t.Errorf("%d messages reexecuted, want 3", block.Reexecuted)
+	}
+}
+
+func TestParallelZeroValue(t *testing.T) {
+	var (
+		statedb = newTestStateDB()
+		a, b, c = testAddress(0x1001), testAddress(0x1002), testAddress(0x1003)
+	)
+	statedb.AddBalance(a, big.NewInt(1000000))
+	statedb.AddBalance(b, big.NewInt(1))
+
+	// Without gas price the coinbase only exists once paid a zero fee.
+	msgs := []Message{
+		{From: a, To: &b, Value: new(big.Int), GasLimit: 30000, GasPrice: new(big.Int), CheckNonce: true},
+		{From: b, To: &c, Value: new(big.Int), GasLimit: 30000, GasPrice: new(big.Int), CheckNonce: true},
+	}
+	checkParallel(t, statedb, msgs)
+	if !statedb.Exist(testContext().Coinbase) {
+		t.Error("coinbase not touched")
+	}
+}
+
+func TestParallelRejected(t *testing.T) {
+	var (
+		statedb = newTestStateDB()
+		a, b    = testAddress(0x1001), testAddress(0x1002)
+		gp      = GasPool(10000000)
+	)
+	statedb.AddBalance(a, big.NewInt(1000000))
+
+	msgs := []Message{
+		{From: a, To: &b, Value: big.NewInt(1), GasLimit: 30000, GasPrice: big.NewInt(1), CheckNonce: true},
+		{From: a, To: &b, Value: big.NewInt(1), GasLimit: 30000, GasPrice: big.NewInt(1), Nonce: 5, CheckNonce: true},
+	}
+	if _, err := NewParallelExecutor(Config{}, 2).Execute(testContext(), statedb, msgs, &gp); err == nil {
+		t.Fatal("expected nonce error")
+	}
+	// the first message stays applied
+	if statedb.GetNonce(a) != 1 || statedb.GetBalance(b).Int64() != 1 {
+		t.Errorf("first message not committed")
+	}
+}
+
+func TestOverlayState(t *testing.T) {
+	var (
+		base    = newTestStateDB()
+		a, b, c = testAddress(0x1001), testAddress(0x1002), testAddress(0x1003)
+		key     = common.HexToHash("0x01")
+		overlay = newOverlayState(base, nil)
+	)
+	base.AddBalance(a, big.NewInt(100))
+	base.SetState(a, key, []byte{1})
+
+	// Blind balance changes are not reads.
+	overlay.AddBalance(b, big.NewInt(5))
+	// Changes cancelling out touch the account.
+	overlay.AddBalance(c, big.NewInt(5))
+	overlay.SubBalance(c, big.NewInt(5))
+	if _, ok := overlay.reads[stateKey{addr: b, kind: kindBalance}]; ok {
+		t.Error("blind balance change recorded as read")
+	}
+	if balance := overlay.GetBalance(a); balance.Int64() != 100 {
+		t.Errorf("balance: have %v", balance)
+	}
+
+	id := overlay.Snapshot()
+	overlay.SubBalance(a, big.NewInt(40))
+	overlay.SetState(a, key, []byte{2})
+	overlay.SetNonce(a, 7)
+	if got := overlay.GetState(a, key); !bytes.Equal(got, []byte{2}) {
+		t.Errorf("state: have %x", got)
+	}
+	overlay.RevertToSnapshot(id)
+	if got := overlay.GetState(a, key); !bytes.Equal(got, []byte{1}) {
+		t.Errorf("state after revert: have %x", got)
+	}
+	if overlay.GetNonce(a) != 0 || overlay.GetBalance(a).Int64() != 100 {
+		t.Error("nonce or balance not reverted")
+	}
+
+	overlay.CreateAccount(a)
+	if got := overlay.GetState(a, key); got != nil {
+		t.Errorf("storage of created account: have %x", got)
+	}
+	if base.GetBalance(b).Sign() != 0 {
+		t.Error("base state modified before commit")
+	}
+
+	overlay.commit()
+	if base.GetBalance(b).Int64() != 5 || base.GetBalance(a).Int64() != 100 || base.GetState(a, key) != nil {
+		t.Error("overlay not committed")
+	}
+	if !base.Exist(c) {
+		t.Error("touched account not committed")
+	}
+	writes := overlay.writes()
+	for _, key := range []stateKey{{addr: a, kind: kindAccount}, {addr: b, kind: kindBalance}} {
+		if _, ok := writes[key]; !ok {
+			t.Errorf("write of %+v not recorded", key)
+		}
+	}
+	if !conflicts(map[stateKey]struct{}{{a, kindStorage, key}: {}}, writes) {
+		t.Error("storage read of a recreated account doesn't conflict")
+	}
+}
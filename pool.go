@@ -0,0 +1,64 @@
+package tinywasm
+
+import (
+	"sync"
+
+	"github.com/tinychain/tinychain/core/vm"
+)
+
+// EVMPool hands out EVM instances sharing the same configuration. The host
+// module tables are built once per process, so getting an EVM from the pool
+// only resets its per-transaction fields.
+//
+// The pool is safe for concurrent use, the EVMs it returns are not: each one
+// must be used by a single goroutine and returned with Put once the
+// transaction is done.
+type EVMPool struct {
+	vmConfig Config
+	pool     sync.Pool
+}
+
+// NewEVMPool returns a pool of EVMs configured with vmConfig.
+func NewEVMPool(vmConfig Config) *EVMPool {
+	p := &EVMPool{vmConfig: vmConfig}
+	p.pool.New = func() interface{} {
+		return NewEVM(Context{}, nil, p.vmConfig)
+	}
+	return p
+}
+
+// Get returns an EVM executing on statedb within the given context.
+func (p *EVMPool) Get(ctx Context, statedb vm.StateDB) *EVM {
+	evm := p.pool.Get().(*EVM)
+	evm.reset(ctx, statedb)
+	return evm
+}
+
+// Put returns evm to the pool. The EVM must not be used afterwards.
+func (p *EVMPool) Put(evm *EVM) {
+	evm.reset(Context{}, nil)
+	p.pool.Put(evm)
+}
+
+// reset prepares the EVM for a new transaction.
+func (evm *EVM) reset(ctx Context, statedb vm.StateDB) {
+	evm.Context = ctx
+	evm.StateDB = statedb
+	evm.depth = 0
+	evm.abort = 0
+	evm.callGasTemp = 0
+	evm.createOutOfGas = false
+	if w, ok := evm.interpreter.(*WasmIntptr); ok {
+		w.reset()
+	}
+}
+
+// reset clears the execution fields left over from a previous transaction.
+// The host function sets are kept.
+func (w *WasmIntptr) reset() {
+	w.vm = nil
+	w.contract = nil
+	w.readonly = false
+	w.terminateType = TerminateFinish
+	w.returnData = nil
+}
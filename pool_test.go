@@ -0,0 +1,112 @@
+package tinywasm
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/tinychain/tinychain/common"
+)
+
+// callerContract returns its caller's address through the getCaller and
+// finish host functions.
+var callerContract = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	// type: () -> (), (i32) -> (), (i32, i32) -> ()
+	0x01, 0x0d, 0x03, 0x60, 0x00, 0x00, 0x60, 0x01, 0x7f, 0x00, 0x60, 0x02, 0x7f, 0x7f, 0x00,
+	// import: ethereum.getCaller and ethereum.finish
+	0x02, 0x28, 0x02,
+	0x08, 'e', 't', 'h', 'e', 'r', 'e', 'u', 'm', 0x09, 'g', 'e', 't', 'C', 'a', 'l', 'l', 'e', 'r', 0x00, 0x01,
+	0x08, 'e', 't', 'h', 'e', 'r', 'e', 'u', 'm', 0x06, 'f', 'i', 'n', 'i', 's', 'h', 0x00, 0x02,
+	0x03, 0x02, 0x01, 0x00, // function: main has type 0
+	0x05, 0x03, 0x01, 0x00, 0x01, // memory: one page
+	0x07, 0x11, 0x02, // export: main and memory
+	0x04, 'm', 'a', 'i', 'n', 0x00, 0x02,
+	0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+	// code: getCaller(0); finish(0, 20)
+	0x0a, 0x0e, 0x01, 0x0c, 0x00,
+	0x41, 0x00, 0x10, 0x00,
+	0x41, 0x00, 0x41, 0x14, 0x10, 0x01,
+	0x0b,
+}
+
+func TestHostFuncSetsShared(t *testing.T) {
+	a := NewWasmIntptr(&EVM{})
+	b := NewWasmIntptr(&EVM{})
+	if a.eeiFuncSet != b.eeiFuncSet || a.debugFuncSet != b.debugFuncSet {
+		t.Fatal("host function sets are rebuilt per interpreter")
+	}
+	for name := range eeiFuncs() {
+		if _, ok := a.GetHandler(name); !ok {
+			t.Errorf("handler %s not registered", name)
+		}
+		if _, ok := a.eeiFuncSet.exports[name]; !ok {
+			t.Errorf("%s not exported", name)
+		}
+	}
+	sig := a.eeiFuncSet.entries[a.eeiFuncSet.exports["getCaller"].Index]
+	if len(sig.ParamTypes) != 1 || len(sig.ReturnTypes) != 0 {
+		t.Errorf("getCaller signature: have %v -> %v", sig.ParamTypes, sig.ReturnTypes)
+	}
+	sig = a.eeiFuncSet.entries[a.eeiFuncSet.exports["getCallDataSize"].Index]
+	if len(sig.ParamTypes) != 0 || len(sig.ReturnTypes) != 1 {
+		t.Errorf("getCallDataSize signature: have %v -> %v", sig.ParamTypes, sig.ReturnTypes)
+	}
+}
+
+func TestEVMPool(t *testing.T) {
+	contract := common.HexToAddress("0x1001")
+	pool := NewEVMPool(Config{})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			statedb := newTestStateDB()
+			statedb.SetCode(contract, callerContract)
+			caller := common.BigToAddress(big.NewInt(int64(0x2000 + i)))
+
+			for j := 0; j < 4; j++ {
+				evm := pool.Get(testContext(), statedb)
+				ret, _, err := evm.Call(AccountRef(caller), contract, nil, 100000, new(big.Int))
+				pool.Put(evm)
+				if err != nil {
+					errs <- fmt.Errorf("caller %d: %v", i, err)
+					return
+				}
+				if !bytes.Equal(ret, caller.Bytes()) {
+					errs <- fmt.Errorf("caller %d: returned %x", i, ret)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestEVMPoolReset(t *testing.T) {
+	pool := NewEVMPool(Config{})
+	evm := pool.Get(testContext(), newTestStateDB())
+	evm.Cancel()
+	evm.depth = 3
+	evm.interpreter.SetReadOnly(true)
+	pool.Put(evm)
+
+	statedb := newTestStateDB()
+	evm = pool.Get(testContext(), statedb)
+	if evm.StateDB != statedb {
+		t.Error("state database not set")
+	}
+	if evm.abort != 0 || evm.depth != 0 || evm.interpreter.IsReadOnly() {
+		t.Errorf("evm not reset: abort %d, depth %d, readonly %v", evm.abort, evm.depth, evm.interpreter.IsReadOnly())
+	}
+}
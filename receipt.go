@@ -0,0 +1,75 @@
+package tinywasm
+
+import (
+	"github.com/tinychain/tinychain/common"
+	"github.com/tinychain/tinychain/core/types"
+	"github.com/tinychain/tinychain/core/vm"
+)
+
+const (
+	// ReceiptStatusFailed is the status code of a transaction if execution failed.
+	ReceiptStatusFailed = uint64(0)
+
+	// ReceiptStatusSuccessful is the status code of a transaction if execution succeeded.
+	ReceiptStatusSuccessful = uint64(1)
+)
+
+// Receipt represents the results of a transaction.
+type Receipt struct {
+	Status            uint64
+	CumulativeGasUsed uint64
+	Bloom             Bloom
+	Logs              []*types.Log
+
+	// ContractAddress is set for contract creations only
+	ContractAddress common.Address
+	GasUsed         uint64
+}
+
+// NewReceipt creates a receipt for a transaction which used gasUsed and
+// emitted logs. The bloom is derived from the logs.
+func NewReceipt(failed bool, cumulativeGasUsed, gasUsed uint64, logs []*types.Log) *Receipt {
+	r := &Receipt{
+		CumulativeGasUsed: cumulativeGasUsed,
+		GasUsed:           gasUsed,
+		Logs:              logs,
+		Bloom:             BytesToBloom(LogsBloom(logs).Bytes()),
+	}
+	if failed {
+		r.Status = ReceiptStatusFailed
+	} else {
+		r.Status = ReceiptStatusSuccessful
+	}
+	return r
+}
+
+// logCollector wraps a StateDB to collect the logs added during a single
+// message. Logs added after a snapshot are dropped again when the state is
+// reverted to it.
+type logCollector struct {
+	vm.StateDB
+	logs  []*types.Log
+	marks map[int]int
+}
+
+func newLogCollector(statedb vm.StateDB) *logCollector {
+	return &logCollector{StateDB: statedb, marks: make(map[int]int)}
+}
+
+func (c *logCollector) AddLog(log *types.Log) {
+	c.StateDB.AddLog(log)
+	c.logs = append(c.logs, log)
+}
+
+func (c *logCollector) Snapshot() int {
+	id := c.StateDB.Snapshot()
+	c.marks[id] = len(c.logs)
+	return id
+}
+
+func (c *logCollector) RevertToSnapshot(id int) {
+	c.StateDB.RevertToSnapshot(id)
+	if n, ok := c.marks[id]; ok {
+		c.logs = c.logs[:n]
+	}
+}
@@ -0,0 +1,129 @@
+package tinywasm
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/tinychain/tinychain/common"
+	"github.com/tinychain/tinychain/core/types"
+	"github.com/tinychain/tinychain/core/vm"
+)
+
+// OverrideAccount indicates the overriding fields of an account during a
+// simulated call. Nil fields are left as they are. State replaces the whole
+// storage of the account, StateDiff only the given slots, at most one of them
+// may be set.
+type OverrideAccount struct {
+	Nonce     *uint64
+	Code      *[]byte
+	Balance   *big.Int
+	State     map[common.Hash][]byte
+	StateDiff map[common.Hash][]byte
+}
+
+// StateOverride is the collection of overridden accounts.
+type StateOverride map[common.Address]OverrideAccount
+
+// apply overrides the accounts in the overlay.
+func (diff StateOverride) apply(state *overlayState) error {
+	for addr, account := range diff {
+		if account.Nonce != nil {
+			state.SetNonce(addr, *account.Nonce)
+		}
+		if account.Code != nil {
+			state.SetCode(addr, *account.Code)
+		}
+		if account.Balance != nil {
+			state.AddBalance(addr, new(big.Int).Sub(account.Balance, state.GetBalance(addr)))
+		}
+		if account.State != nil && account.StateDiff != nil {
+			return fmt.Errorf("account %s has both 'state' and 'stateDiff'", common.Hex(addr.Bytes()))
+		}
+		if account.State != nil {
+			state.clearStorage(addr)
+			for key, value := range account.State {
+				state.SetState(addr, key, value)
+			}
+		}
+		for key, value := range account.StateDiff {
+			state.SetState(addr, key, value)
+		}
+	}
+	return nil
+}
+
+// BlockOverrides is a set of header fields to override during a simulated
+// call. Nil fields are left as they are.
+type BlockOverrides struct {
+	Number   *big.Int
+	Time     *big.Int
+	Coinbase *common.Address
+	GasLimit *uint64
+}
+
+// apply overrides the block information of ctx.
+func (o *BlockOverrides) apply(ctx *Context) {
+	if o == nil {
+		return
+	}
+	if o.Number != nil {
+		ctx.BlockHeight = new(big.Int).Set(o.Number)
+	}
+	if o.Time != nil {
+		ctx.Time = new(big.Int).Set(o.Time)
+	}
+	if o.Coinbase != nil {
+		ctx.Coinbase = *o.Coinbase
+	}
+	if o.GasLimit != nil {
+		ctx.GasLimit = *o.GasLimit
+	}
+}
+
+// CallResult is the outcome of a simulated call.
+type CallResult struct {
+	*ExecutionResult
+	Logs []*types.Log
+}
+
+// DoCall executes msg against statedb like eth_call: the state and block
+// overrides are applied first and none of the changes are ever written to
+// statedb. A message without gas limit gets the gas limit of the block. With
+// a non-zero timeout the execution is aborted once it has elapsed.
+//
+// An error is returned if the message can't be executed at all or timed out,
+// a failing execution is reported by the Err of the result.
+func DoCall(ctx Context, statedb vm.StateDB, cfg Config, msg Message, state StateOverride, block *BlockOverrides, timeout time.Duration) (*CallResult, error) {
+	block.apply(&ctx)
+	if msg.GasLimit == 0 {
+		msg.GasLimit = ctx.GasLimit
+	}
+	res, _, err := doCall(ctx, statedb, cfg, msg, state, timeout)
+	return res, err
+}
+
+// doCall applies msg on an overlay of statedb and returns the EVM it ran in.
+func doCall(ctx Context, statedb vm.StateDB, cfg Config, msg Message, state StateOverride, timeout time.Duration) (*CallResult, *EVM, error) {
+	overlay := newOverlayState(statedb, nil)
+	if err := state.apply(overlay); err != nil {
+		return nil, nil, err
+	}
+	collector := newLogCollector(overlay)
+	evm := NewEVM(messageContext(ctx, msg), collector, cfg)
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, evm.Cancel)
+		defer timer.Stop()
+	}
+
+	gp := GasPool(msg.GasLimit)
+	var usedGas uint64
+	receipt, result, err := applyMessage(evm, collector, msg, &gp, &usedGas)
+	if err != nil {
+		return nil, nil, err
+	}
+	if evm.Cancelled() {
+		return nil, nil, fmt.Errorf("%v (timeout = %v)", ErrExecutionAborted, timeout)
+	}
+	return &CallResult{ExecutionResult: result, Logs: receipt.Logs}, evm, nil
+}
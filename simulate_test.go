@@ -0,0 +1,139 @@
+package tinywasm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinychain/tinychain/common"
+)
+
+// blockNumberContract returns the block number, the i64 stored in memory is
+// byte swapped by finish.
+var blockNumberContract = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	// type: () -> (), (i32, i32) -> (), () -> i64
+	0x01, 0x0d, 0x03, 0x60, 0x00, 0x00, 0x60, 0x02, 0x7f, 0x7f, 0x00, 0x60, 0x00, 0x01, 0x7e,
+	// import: ethereum.getBlockNumber and ethereum.finish
+	0x02, 0x2d, 0x02,
+	0x08, 'e', 't', 'h', 'e', 'r', 'e', 'u', 'm',
+	0x0e, 'g', 'e', 't', 'B', 'l', 'o', 'c', 'k', 'N', 'u', 'm', 'b', 'e', 'r', 0x00, 0x02,
+	0x08, 'e', 't', 'h', 'e', 'r', 'e', 'u', 'm', 0x06, 'f', 'i', 'n', 'i', 's', 'h', 0x00, 0x01,
+	0x03, 0x02, 0x01, 0x00, // function: main has type 0
+	0x05, 0x03, 0x01, 0x00, 0x01, // memory: one page
+	0x07, 0x11, 0x02, // export: main and memory
+	0x04, 'm', 'a', 'i', 'n', 0x00, 0x02,
+	0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+	// code: i64.store(0, getBlockNumber()); finish(0, 8)
+	0x0a, 0x11, 0x01, 0x0f, 0x00,
+	0x41, 0x00, 0x10, 0x00, 0x37, 0x03, 0x00,
+	0x41, 0x00, 0x41, 0x08, 0x10, 0x01,
+	0x0b,
+}
+
+func TestDoCallOverrides(t *testing.T) {
+	var (
+		statedb  = newTestStateDB()
+		from     = testAddress(0x1001)
+		contract = testAddress(0x1002)
+		code     = blockNumberContract
+		balance  = big.NewInt(1000000)
+		nonce    = uint64(3)
+		key      = common.HexToHash("0x01")
+	)
+	state := StateOverride{
+		from:     {Balance: balance, Nonce: &nonce},
+		contract: {Code: &code, State: map[common.Hash][]byte{key: {1}}},
+	}
+	block := &BlockOverrides{Number: big.NewInt(1234)}
+
+	// from has no funds without the balance override
+	msg := Message{From: from, To: &contract, Value: big.NewInt(1), GasLimit: 100000, GasPrice: big.NewInt(1), Nonce: nonce, CheckNonce: true}
+	res, err := DoCall(testContext(), statedb, Config{}, msg, state, block, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Failed() {
+		t.Fatalf("call failed: %v", res.Err)
+	}
+	if number := binary.BigEndian.Uint64(res.ReturnData); number != 1234 {
+		t.Errorf("block number: have %d, want 1234", number)
+	}
+	if res.UsedGas <= TxGas {
+		t.Errorf("used gas %d", res.UsedGas)
+	}
+	if len(statedb.accounts) != 0 {
+		t.Errorf("state modified: %d accounts", len(statedb.accounts))
+	}
+
+	// without overrides the sender can't pay
+	if _, err := DoCall(testContext(), statedb, Config{}, msg, nil, nil, 0); err == nil {
+		t.Error("expected error without overrides")
+	}
+}
+
+func TestStateOverrideStorage(t *testing.T) {
+	var (
+		statedb = newTestStateDB()
+		addr    = testAddress(0x1001)
+		a, b    = common.HexToHash("0x01"), common.HexToHash("0x02")
+	)
+	statedb.SetState(addr, a, []byte{1})
+	statedb.SetState(addr, b, []byte{2})
+
+	overlay := newOverlayState(statedb, nil)
+	diff := StateOverride{addr: {StateDiff: map[common.Hash][]byte{a: {3}}}}
+	if err := diff.apply(overlay); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(overlay.GetState(addr, a), []byte{3}) || !bytes.Equal(overlay.GetState(addr, b), []byte{2}) {
+		t.Error("state diff not applied")
+	}
+
+	overlay = newOverlayState(statedb, nil)
+	replace := StateOverride{addr: {State: map[common.Hash][]byte{a: {3}}}}
+	if err := replace.apply(overlay); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(overlay.GetState(addr, a), []byte{3}) || overlay.GetState(addr, b) != nil {
+		t.Error("state not replaced")
+	}
+
+	both := StateOverride{addr: {State: map[common.Hash][]byte{}, StateDiff: map[common.Hash][]byte{}}}
+	if err := both.apply(newOverlayState(statedb, nil)); err == nil {
+		t.Error("expected error for state and state diff")
+	}
+}
+
+// loopContract never terminates: (loop (br 0))
+var loopContract = importModule(nil, []byte{0x03, 0x40, 0x0c, 0x00, 0x0b}, nil)
+
+func TestDoCallTimeout(t *testing.T) {
+	statedb, msg := estimateState(loopContract)
+	msg.GasLimit = 100000
+
+	_, err := DoCall(testContext(), statedb, Config{}, msg, nil, nil, 20*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), ErrExecutionAborted.Error()) {
+		t.Errorf("expected aborted error, have %v", err)
+	}
+}
+
+func TestEVMCancel(t *testing.T) {
+	statedb, msg := estimateState(loopContract)
+	evm := NewEVM(testContext(), statedb, Config{})
+	time.AfterFunc(20*time.Millisecond, evm.Cancel)
+
+	_, gas, err := evm.Call(AccountRef(msg.From), *msg.To, nil, 100000, new(big.Int))
+	if err != ErrExecutionAborted {
+		t.Errorf("have %v, want %v", err, ErrExecutionAborted)
+	}
+	if gas != 0 {
+		t.Errorf("%d gas left after abort", gas)
+	}
+	if !evm.Cancelled() {
+		t.Error("evm not cancelled")
+	}
+}
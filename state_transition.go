@@ -0,0 +1,282 @@
+// Copyright 2014 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tinywasm
+
+import (
+	"errors"
+	"math"
+	"math/big"
+
+	"github.com/tinychain/tinychain/common"
+	"github.com/tinychain/tinychain/core/vm"
+)
+
+const (
+	TxGas                 uint64 = 21000 // Per transaction not creating a contract
+	TxGasContractCreation uint64 = 53000 // Per transaction that creates a contract
+	TxDataZeroGas         uint64 = 4     // Per byte of data attached to a transaction that equals zero
+	TxDataNonZeroGas      uint64 = 68    // Per byte of data attached to a transaction that is not equal to zero
+)
+
+var (
+	ErrNonceTooHigh          = errors.New("nonce too high")
+	ErrNonceTooLow           = errors.New("nonce too low")
+	ErrInsufficientFundsGas  = errors.New("insufficient funds for gas * price")
+	ErrIntrinsicGas          = errors.New("intrinsic gas too low")
+	errGasUintOverflow       = errors.New("gas uint64 overflow")
+	errInsufficientFundsCost = errors.New("insufficient funds for gas * price + value")
+)
+
+// Message represents a transaction to be applied to the state.
+type Message struct {
+	From       common.Address
+	To         *common.Address // nil means contract creation
+	Nonce      uint64
+	Value      *big.Int
+	GasLimit   uint64
+	GasPrice   *big.Int
+	Data       []byte
+	CheckNonce bool
+}
+
+// IntrinsicGas computes the 'intrinsic gas' for a message with the given data.
+func IntrinsicGas(data []byte, contractCreation bool) (uint64, error) {
+	// Set the starting gas for the raw transaction
+	var gas uint64
+	if contractCreation {
+		gas = TxGasContractCreation
+	} else {
+		gas = TxGas
+	}
+	// Bump the required gas by the amount of transactional data
+	if len(data) > 0 {
+		// Zero and non-zero bytes are priced differently
+		var nz uint64
+		for _, byt := range data {
+			if byt != 0 {
+				nz++
+			}
+		}
+		// Make sure we don't exceed uint64 for all data combinations
+		if (math.MaxUint64-gas)/TxDataNonZeroGas < nz {
+			return 0, errGasUintOverflow
+		}
+		gas += nz * TxDataNonZeroGas
+
+		z := uint64(len(data)) - nz
+		if (math.MaxUint64-gas)/TxDataZeroGas < z {
+			return 0, errGasUintOverflow
+		}
+		gas += z * TxDataZeroGas
+	}
+	return gas, nil
+}
+
+// ExecutionResult includes all output after executing a message.
+type ExecutionResult struct {
+	UsedGas         uint64         // Total gas used, after refunds
+	Err             error          // Any error encountered during the execution
+	ReturnData      []byte         // Returned data from the evm
+	ContractAddress common.Address // Address of the created contract, if any
+}
+
+// Failed returns whether the execution failed, either by reverting or by
+// running into an error.
+func (result *ExecutionResult) Failed() bool { return result.Err != nil }
+
+// Revert returns the revert data if the execution was reverted.
+func (result *ExecutionResult) Revert() []byte {
+	if result.Err != errExecutionReverted {
+		return nil
+	}
+	return common.CopyBytes(result.ReturnData)
+}
+
+// StateTransition applies a message to the state: it checks the nonce, buys
+// the gas from the sender, runs the call or contract creation, refunds the
+// gas left over and pays the used gas to the coinbase.
+type StateTransition struct {
+	gp         *GasPool
+	msg        Message
+	gas        uint64
+	initialGas uint64
+	state      vm.StateDB
+	evm        *EVM
+}
+
+// NewStateTransition initialises and returns a new state transition object.
+func NewStateTransition(evm *EVM, msg Message, gp *GasPool) *StateTransition {
+	if msg.Value == nil {
+		msg.Value = new(big.Int)
+	}
+	if msg.GasPrice == nil {
+		msg.GasPrice = new(big.Int)
+	}
+	return &StateTransition{
+		gp:    gp,
+		evm:   evm,
+		msg:   msg,
+		state: evm.StateDB,
+	}
+}
+
+// buyGas charges the gas limit of the message to the sender, who must also be
+// able to afford the transferred value.
+func (st *StateTransition) buyGas() error {
+	mgval := new(big.Int).Mul(new(big.Int).SetUint64(st.msg.GasLimit), st.msg.GasPrice)
+	balance := st.state.GetBalance(st.msg.From)
+	if balance.Cmp(mgval) < 0 {
+		return ErrInsufficientFundsGas
+	}
+	if balance.Cmp(new(big.Int).Add(mgval, st.msg.Value)) < 0 {
+		return errInsufficientFundsCost
+	}
+	if err := st.gp.SubGas(st.msg.GasLimit); err != nil {
+		return err
+	}
+	st.gas += st.msg.GasLimit
+	st.initialGas = st.msg.GasLimit
+	st.state.SubBalance(st.msg.From, mgval)
+	return nil
+}
+
+// checkNonce makes sure the nonce of the message is correct.
+func (st *StateTransition) checkNonce() error {
+	if !st.msg.CheckNonce {
+		return nil
+	}
+	nonce := st.state.GetNonce(st.msg.From)
+	if nonce < st.msg.Nonce {
+		return ErrNonceTooHigh
+	} else if nonce > st.msg.Nonce {
+		return ErrNonceTooLow
+	}
+	return nil
+}
+
+// TransitionDb will transition the state by applying the current message and
+// returning the evm execution result with following fields.
+//
+//   - used gas: total gas used, after refunds
+//   - returndata: the returned data from evm
+//   - concrete execution error: various EVM errors which abort the execution,
+//     e.g. ErrOutOfGas, ErrExecutionReverted
+//
+// However if any consensus issue encountered, return the error directly with
+// nil evm execution result, and the state is left untouched.
+func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
+	msg := st.msg
+	contractCreation := msg.To == nil
+
+	if err := st.checkNonce(); err != nil {
+		return nil, err
+	}
+	gas, err := IntrinsicGas(msg.Data, contractCreation)
+	if err != nil {
+		return nil, err
+	}
+	if msg.GasLimit < gas {
+		return nil, ErrIntrinsicGas
+	}
+	if err := st.buyGas(); err != nil {
+		return nil, err
+	}
+	st.gas -= gas
+
+	var (
+		sender   = AccountRef(msg.From)
+		ret      []byte
+		contract common.Address
+		vmerr    error
+	)
+	if contractCreation {
+		ret, contract, st.gas, vmerr = st.evm.Create(sender, msg.Data, st.gas, msg.Value)
+	} else {
+		// Increment the nonce for the next transaction
+		st.state.SetNonce(msg.From, st.state.GetNonce(msg.From)+1)
+		ret, st.gas, vmerr = st.evm.Call(sender, *msg.To, msg.Data, st.gas, msg.Value)
+	}
+	st.refundGas()
+	st.state.AddBalance(st.evm.Coinbase(), new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), msg.GasPrice))
+
+	result := &ExecutionResult{
+		UsedGas:    st.gasUsed(),
+		Err:        vmerr,
+		ReturnData: ret,
+	}
+	if contractCreation && vmerr == nil {
+		result.ContractAddress = contract
+	}
+	return result, nil
+}
+
+func (st *StateTransition) refundGas() {
+	// Apply refund counter, capped to half of the used gas.
+	refund := st.gasUsed() / 2
+	if refund > st.state.GetRefund() {
+		refund = st.state.GetRefund()
+	}
+	st.gas += refund
+
+	// Return ETH for remaining gas, exchanged at the original rate.
+	remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gas), st.msg.GasPrice)
+	st.state.AddBalance(st.msg.From, remaining)
+
+	// Also return remaining gas to the block gas counter so it is
+	// available for the next transaction.
+	st.gp.AddGas(st.gas)
+}
+
+// gasUsed returns the amount of gas used up by the state transition.
+func (st *StateTransition) gasUsed() uint64 {
+	return st.initialGas - st.gas
+}
+
+// ApplyMessage applies the message to the state in a new EVM and returns its
+// receipt together with the execution result. The gas used by the message is
+// added to usedGas, the cumulative gas of the block.
+//
+// An error is only returned if the message can't be included in a block, a
+// failing execution is reported by the receipt status instead.
+func ApplyMessage(ctx Context, statedb vm.StateDB, cfg Config, msg Message, gp *GasPool, usedGas *uint64) (*Receipt, *ExecutionResult, error) {
+	collector := newLogCollector(statedb)
+	evm := NewEVM(messageContext(ctx, msg), collector, cfg)
+	return applyMessage(evm, collector, msg, gp, usedGas)
+}
+
+// messageContext returns ctx with the transaction fields of msg set.
+func messageContext(ctx Context, msg Message) Context {
+	ctx.Origin = msg.From
+	ctx.GasPrice = msg.GasPrice
+	if ctx.GasPrice == nil {
+		ctx.GasPrice = new(big.Int)
+	}
+	return ctx
+}
+
+// applyMessage applies msg in evm, whose state database is collector.
+func applyMessage(evm *EVM, collector *logCollector, msg Message, gp *GasPool, usedGas *uint64) (*Receipt, *ExecutionResult, error) {
+	result, err := NewStateTransition(evm, msg, gp).TransitionDb()
+	if err != nil {
+		return nil, nil, err
+	}
+	*usedGas += result.UsedGas
+
+	receipt := NewReceipt(result.Failed(), *usedGas, result.UsedGas, collector.logs)
+	receipt.ContractAddress = result.ContractAddress
+	return receipt, result, nil
+}
@@ -0,0 +1,386 @@
+package tinywasm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/tinychain/tinychain/common"
+	"github.com/tinychain/tinychain/core/types"
+	"github.com/tinychain/tinychain/core/vm"
+	"github.com/tinychain/tinychain/core/vm/evm/crypto"
+)
+
+// testAccount is an account of testStateDB.
+type testAccount struct {
+	balance  *big.Int
+	nonce    uint64
+	code     []byte
+	storage  map[common.Hash][]byte
+	suicided bool
+}
+
+func (a *testAccount) copy() *testAccount {
+	cpy := *a
+	cpy.balance = new(big.Int).Set(a.balance)
+	cpy.storage = make(map[common.Hash][]byte, len(a.storage))
+	for k, v := range a.storage {
+		cpy.storage[k] = v
+	}
+	return &cpy
+}
+
+// testStateDB is an in-memory StateDB. Snapshots are full copies of the state.
+type testStateDB struct {
+	vm.StateDB
+	accounts  map[common.Address]*testAccount
+	refund    uint64
+	logs      []*types.Log
+	snapshots []testSnapshot
+}
+
+type testSnapshot struct {
+	accounts map[common.Address]*testAccount
+	refund   uint64
+	logs     int
+}
+
+func newTestStateDB() *testStateDB {
+	return &testStateDB{accounts: make(map[common.Address]*testAccount)}
+}
+
+func (s *testStateDB) account(addr common.Address) *testAccount {
+	if acc := s.accounts[addr]; acc != nil {
+		return acc
+	}
+	acc := &testAccount{balance: new(big.Int), storage: make(map[common.Hash][]byte)}
+	s.accounts[addr] = acc
+	return acc
+}
+
+func (s *testStateDB) CreateAccount(addr common.Address) {
+	balance := new(big.Int)
+	if acc := s.accounts[addr]; acc != nil {
+		balance = acc.balance
+	}
+	s.accounts[addr] = &testAccount{balance: balance, storage: make(map[common.Hash][]byte)}
+}
+
+func (s *testStateDB) SubBalance(addr common.Address, amount *big.Int) {
+	s.account(addr).balance.Sub(s.account(addr).balance, amount)
+}
+
+func (s *testStateDB) AddBalance(addr common.Address, amount *big.Int) {
+	s.account(addr).balance.Add(s.account(addr).balance, amount)
+}
+
+func (s *testStateDB) GetBalance(addr common.Address) *big.Int {
+	if acc := s.accounts[addr]; acc != nil {
+		return new(big.Int).Set(acc.balance)
+	}
+	return new(big.Int)
+}
+
+func (s *testStateDB) GetNonce(addr common.Address) uint64 {
+	if acc := s.accounts[addr]; acc != nil {
+		return acc.nonce
+	}
+	return 0
+}
+
+func (s *testStateDB) SetNonce(addr common.Address, nonce uint64) { s.account(addr).nonce = nonce }
+
+func (s *testStateDB) GetCode(addr common.Address) []byte {
+	if acc := s.accounts[addr]; acc != nil {
+		return acc.code
+	}
+	return nil
+}
+
+func (s *testStateDB) GetCodeHash(addr common.Address) common.Hash {
+	acc := s.accounts[addr]
+	if acc == nil {
+		return common.Hash{}
+	}
+	return crypto.Keccak256Hash(acc.code)
+}
+
+func (s *testStateDB) SetCode(addr common.Address, code []byte) { s.account(addr).code = code }
+func (s *testStateDB) GetCodeSize(addr common.Address) int      { return len(s.GetCode(addr)) }
+
+func (s *testStateDB) AddRefund(gas uint64) { s.refund += gas }
+func (s *testStateDB) SubRefund(gas uint64) { s.refund -= gas }
+func (s *testStateDB) GetRefund() uint64    { return s.refund }
+
+func (s *testStateDB) GetState(addr common.Address, key common.Hash) []byte {
+	if acc := s.accounts[addr]; acc != nil {
+		return acc.storage[key]
+	}
+	return nil
+}
+
+func (s *testStateDB) SetState(addr common.Address, key common.Hash, value []byte) {
+	s.account(addr).storage[key] = value
+}
+
+func (s *testStateDB) Suicide(addr common.Address) bool {
+	acc := s.accounts[addr]
+	if acc == nil {
+		return false
+	}
+	acc.suicided = true
+	acc.balance = new(big.Int)
+	return true
+}
+
+func (s *testStateDB) HasSuicided(addr common.Address) bool {
+	acc := s.accounts[addr]
+	return acc != nil && acc.suicided
+}
+
+func (s *testStateDB) Exist(addr common.Address) bool { return s.accounts[addr] != nil }
+
+func (s *testStateDB) Empty(addr common.Address) bool {
+	acc := s.accounts[addr]
+	return acc == nil || (acc.nonce == 0 && acc.balance.Sign() == 0 && len(acc.code) == 0)
+}
+
+func (s *testStateDB) Snapshot() int {
+	accounts := make(map[common.Address]*testAccount, len(s.accounts))
+	for addr, acc := range s.accounts {
+		accounts[addr] = acc.copy()
+	}
+	s.snapshots = append(s.snapshots, testSnapshot{accounts, s.refund, len(s.logs)})
+	return len(s.snapshots) - 1
+}
+
+func (s *testStateDB) RevertToSnapshot(id int) {
+	snap := s.snapshots[id]
+	s.accounts, s.refund, s.logs = snap.accounts, snap.refund, s.logs[:snap.logs]
+	s.snapshots = s.snapshots[:id]
+}
+
+func (s *testStateDB) AddLog(log *types.Log)                                         { s.logs = append(s.logs, log) }
+func (s *testStateDB) AddPreimage(common.Hash, []byte)                               {}
+func (s *testStateDB) ForEachStorage(common.Address, func(common.Hash, []byte) bool) {}
+
+// testContract assembles a module exporting `memory` and a `main` function
+// with the given body, which must include the final end opcode.
+func testContract(body ...byte) []byte {
+	code := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00, // type: () -> ()
+		0x03, 0x02, 0x01, 0x00, // function: main has type 0
+		0x05, 0x03, 0x01, 0x00, 0x01, // memory: one page
+		0x07, 0x11, 0x02, // export: main and memory
+		0x04, 'm', 'a', 'i', 'n', 0x00, 0x00,
+		0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+	}
+	fn := append([]byte{0x00}, body...) // no locals
+	code = append(code, 0x0a, byte(len(fn)+2), 0x01, byte(len(fn)))
+	return append(code, fn...)
+}
+
+func testContext() Context {
+	return Context{
+		CanTransfer: CanTransfer,
+		Transfer:    Transfer,
+		GetHash:     func(uint64) common.Hash { return common.Hash{} },
+		Coinbase:    common.HexToAddress("0xc0"),
+		BlockHeight: big.NewInt(1),
+		Time:        big.NewInt(1),
+		GasLimit:    10000000,
+	}
+}
+
+func TestIntrinsicGas(t *testing.T) {
+	for i, test := range []struct {
+		data   []byte
+		create bool
+		gas    uint64
+	}{
+		{nil, false, TxGas},
+		{nil, true, TxGasContractCreation},
+		{[]byte{0, 1, 0, 2}, false, TxGas + 2*TxDataZeroGas + 2*TxDataNonZeroGas},
+	} {
+		gas, err := IntrinsicGas(test.data, test.create)
+		if err != nil || gas != test.gas {
+			t.Errorf("test %d: have %d, %v, want %d", i, gas, err, test.gas)
+		}
+	}
+}
+
+func TestApplyMessageTransfer(t *testing.T) {
+	var (
+		statedb = newTestStateDB()
+		ctx     = testContext()
+		from    = common.HexToAddress("0x1001")
+		to      = common.HexToAddress("0x1002")
+		gp      = GasPool(100000)
+		usedGas = uint64(100)
+	)
+	statedb.AddBalance(from, big.NewInt(1000000))
+
+	msg := Message{From: from, To: &to, Value: big.NewInt(10), GasLimit: 30000, GasPrice: big.NewInt(2), CheckNonce: true}
+	receipt, result, err := ApplyMessage(ctx, statedb, Config{}, msg, &gp, &usedGas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != ReceiptStatusSuccessful || receipt.GasUsed != TxGas || receipt.CumulativeGasUsed != 100+TxGas {
+		t.Errorf("unexpected receipt %+v", receipt)
+	}
+	if result.Failed() || result.UsedGas != TxGas || usedGas != 100+TxGas {
+		t.Errorf("unexpected result %+v, cumulative gas %d", result, usedGas)
+	}
+	if gp.Gas() != 100000-TxGas {
+		t.Errorf("gas pool: have %d, want %d", gp.Gas(), 100000-TxGas)
+	}
+	if balance := statedb.GetBalance(from); balance.Int64() != 1000000-10-2*int64(TxGas) {
+		t.Errorf("sender balance: have %v", balance)
+	}
+	if balance := statedb.GetBalance(to); balance.Int64() != 10 {
+		t.Errorf("recipient balance: have %v", balance)
+	}
+	if balance := statedb.GetBalance(ctx.Coinbase); balance.Int64() != 2*int64(TxGas) {
+		t.Errorf("coinbase balance: have %v", balance)
+	}
+	if statedb.GetNonce(from) != 1 {
+		t.Errorf("nonce not bumped")
+	}
+}
+
+// Calls to accounts without code succeed without running anything, with or
+// without value and whether the account exists or not.
+func TestCallEmptyAccount(t *testing.T) {
+	var (
+		statedb = newTestStateDB()
+		from    = common.HexToAddress("0x1001")
+		empty   = common.HexToAddress("0x1002")
+		created = common.HexToAddress("0x1003")
+		gas     = uint64(50000)
+	)
+	statedb.AddBalance(from, big.NewInt(1000))
+	statedb.AddBalance(empty, big.NewInt(1))
+	evm := NewEVM(testContext(), statedb, Config{})
+	caller := NewContract(AccountRef(from), AccountRef(from), new(big.Int), gas)
+
+	for i, call := range []func() ([]byte, uint64, error){
+		func() ([]byte, uint64, error) { return evm.Call(caller, empty, nil, gas, big.NewInt(0)) },
+		func() ([]byte, uint64, error) { return evm.Call(caller, empty, []byte{1}, gas, big.NewInt(10)) },
+		func() ([]byte, uint64, error) { return evm.Call(caller, created, nil, gas, big.NewInt(10)) },
+		func() ([]byte, uint64, error) { return evm.CallCode(caller, empty, nil, gas, big.NewInt(10)) },
+		func() ([]byte, uint64, error) { return evm.DelegateCall(caller, empty, nil, gas) },
+		func() ([]byte, uint64, error) { return evm.StaticCall(caller, empty, nil, gas) },
+	} {
+		ret, leftOverGas, err := call()
+		if err != nil || ret != nil || leftOverGas != gas {
+			t.Errorf("call %d: have (%x, %d, %v), want (nil, %d, nil)", i, ret, leftOverGas, err, gas)
+		}
+	}
+	if balance := statedb.GetBalance(empty); balance.Int64() != 11 {
+		t.Errorf("balance of the existing account: have %v, want 11", balance)
+	}
+	if balance := statedb.GetBalance(created); balance.Int64() != 10 {
+		t.Errorf("balance of the created account: have %v, want 10", balance)
+	}
+}
+
+func TestApplyMessageRejected(t *testing.T) {
+	var (
+		statedb = newTestStateDB()
+		from    = common.HexToAddress("0x1001")
+		to      = common.HexToAddress("0x1002")
+	)
+	statedb.AddBalance(from, big.NewInt(100000))
+	statedb.SetNonce(from, 1)
+
+	for i, test := range []struct {
+		msg  Message
+		pool uint64
+		err  error
+	}{
+		{Message{From: from, To: &to, Nonce: 0, GasLimit: TxGas, CheckNonce: true}, TxGas, ErrNonceTooLow},
+		{Message{From: from, To: &to, Nonce: 2, GasLimit: TxGas, CheckNonce: true}, TxGas, ErrNonceTooHigh},
+		{Message{From: from, To: &to, GasLimit: TxGas - 1}, TxGas, ErrIntrinsicGas},
+		{Message{From: from, To: &to, GasLimit: TxGas, GasPrice: big.NewInt(5)}, TxGas, ErrInsufficientFundsGas},
+		{Message{From: from, To: &to, GasLimit: TxGas, GasPrice: big.NewInt(4), Value: big.NewInt(20000)}, TxGas, errInsufficientFundsCost},
+		{Message{From: from, To: &to, GasLimit: TxGas}, TxGas - 1, ErrGasLimitReached},
+	} {
+		var usedGas uint64
+		gp := GasPool(test.pool)
+		if _, _, err := ApplyMessage(testContext(), statedb, Config{}, test.msg, &gp, &usedGas); err != test.err {
+			t.Errorf("test %d: have %v, want %v", i, err, test.err)
+		}
+		if gp.Gas() != test.pool || usedGas != 0 {
+			t.Errorf("test %d: rejected message used gas", i)
+		}
+	}
+	if statedb.GetBalance(from).Int64() != 100000 || statedb.GetNonce(from) != 1 {
+		t.Errorf("rejected messages modified the state")
+	}
+}
+
+func TestApplyMessageCreate(t *testing.T) {
+	var (
+		statedb = newTestStateDB()
+		from    = common.HexToAddress("0x1001")
+		gp      = GasPool(1000000)
+		usedGas uint64
+	)
+	statedb.AddBalance(from, big.NewInt(1000000))
+
+	// A constructor finishing without return data deploys an empty contract
+	msg := Message{From: from, Data: testContract(0x0b), GasLimit: 100000, GasPrice: big.NewInt(1)}
+	receipt, _, err := ApplyMessage(testContext(), statedb, Config{}, msg, &gp, &usedGas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != ReceiptStatusSuccessful {
+		t.Fatalf("creation failed")
+	}
+	if want := crypto.CreateAddress(from, 0); receipt.ContractAddress != want {
+		t.Errorf("contract address: have %x, want %x", receipt.ContractAddress, want)
+	}
+
+	// A trapping constructor fails and consumes all gas
+	msg.Data = testContract(0x00, 0x0b)
+	receipt, result, err := ApplyMessage(testContext(), statedb, Config{}, msg, &gp, &usedGas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != ReceiptStatusFailed || !result.Failed() || receipt.GasUsed != msg.GasLimit || receipt.ContractAddress != (common.Address{}) {
+		t.Errorf("unexpected receipt %+v", receipt)
+	}
+	if statedb.GetNonce(from) != 2 {
+		t.Errorf("nonce: have %d, want 2", statedb.GetNonce(from))
+	}
+	if receipt.CumulativeGasUsed != usedGas || gp.Gas() != 1000000-usedGas {
+		t.Errorf("gas accounting mismatch: cumulative %d, pool %d", receipt.CumulativeGasUsed, gp.Gas())
+	}
+}
+
+func TestReceiptBloom(t *testing.T) {
+	addr := common.HexToAddress("0x0a")
+	topic := common.HexToHash("0x1234")
+	receipt := NewReceipt(false, 0, 0, []*types.Log{{Address: addr, Topics: []common.Hash{topic}}})
+
+	if !receipt.Bloom.Test(addr.Bytes()) || !receipt.Bloom.Test(topic.Bytes()) {
+		t.Errorf("bloom misses log content")
+	}
+	if receipt.Bloom.Test(common.HexToHash("0x5678").Bytes()) {
+		t.Errorf("bloom has unexpected topic")
+	}
+	if CreateBloom([]*Receipt{receipt}) != receipt.Bloom {
+		t.Errorf("block bloom differs from receipt bloom")
+	}
+}
+
+func TestLogCollectorRevert(t *testing.T) {
+	c := newLogCollector(newTestStateDB())
+	c.AddLog(&types.Log{})
+	id := c.Snapshot()
+	c.AddLog(&types.Log{})
+	c.RevertToSnapshot(id)
+	if len(c.logs) != 1 {
+		t.Errorf("have %d logs, want 1", len(c.logs))
+	}
+}
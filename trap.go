@@ -0,0 +1,53 @@
+package tinywasm
+
+import (
+	"fmt"
+
+	"github.com/tinychain/tinychain/common"
+	"github.com/tinychain/tinychain/core/vm"
+
+	"github.com/tinychain/tiny-wasm/wagon/exec"
+)
+
+// TrapFrame is a wasm function of a contract that was being executed when
+// the contract trapped.
+type TrapFrame struct {
+	Contract common.Address
+	exec.Frame
+}
+
+func (f TrapFrame) String() string {
+	return fmt.Sprintf("%s:%v", common.Hex(f.Contract.Bytes()), f.Frame)
+}
+
+// TrapError is returned by the execution of a contract that trapped, e.g. on
+// an out of bounds memory access or an unreachable instruction. Its message
+// is the message of the cause.
+type TrapError struct {
+	Err    error       // the cause of the trap
+	Frames []TrapFrame // the functions being executed, innermost first
+}
+
+func (e *TrapError) Error() string {
+	return e.Err.Error()
+}
+
+// Trace formats the cause of the trap followed by its frames, one per line.
+func (e *TrapError) Trace() string {
+	return exec.FormatTrace(e.Err, len(e.Frames), func(i int) string {
+		return e.Frames[i].String()
+	})
+}
+
+// trapError attaches the contract address to the frames of a trap. Running
+// out of gas in a host function isn't reported as a trap.
+func trapError(addr common.Address, trap *exec.Trap) error {
+	if trap.Err == vm.ErrOutOfGas {
+		return vm.ErrOutOfGas
+	}
+	frames := make([]TrapFrame, len(trap.Frames))
+	for i, frame := range trap.Frames {
+		frames[i] = TrapFrame{Contract: addr, Frame: frame}
+	}
+	return &TrapError{Err: trap.Err, Frames: frames}
+}
@@ -0,0 +1,42 @@
+package tinywasm
+
+import (
+	"testing"
+
+	"github.com/tinychain/tiny-wasm/wagon/exec"
+)
+
+func TestTrapError(t *testing.T) {
+	// main: unreachable
+	statedb, msg := estimateState(importModule(nil, []byte{0x00}, nil))
+	msg.GasLimit = 100000
+	res, err := DoCall(testContext(), statedb, Config{}, msg, nil, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trap, ok := res.Err.(*TrapError)
+	if !ok {
+		t.Fatalf("expected trap, have %v", res.Err)
+	}
+	if trap.Err != exec.ErrUnreachable {
+		t.Errorf("unexpected cause %v", trap.Err)
+	}
+	want := TrapFrame{Contract: *msg.To, Frame: exec.Frame{Index: 0, Offset: 0}}
+	if len(trap.Frames) != 1 || trap.Frames[0] != want {
+		t.Errorf("have frames %v, want [%v]", trap.Frames, want)
+	}
+	const trace = "exec: reached unreachable\n\tat 0x0000000000000000000000000000000000001002:func[0]+0x0"
+	if trap.Trace() != trace {
+		t.Errorf("have trace %q, want %q", trap.Trace(), trace)
+	}
+
+	// Running out of gas isn't a trap
+	statedb, msg = estimateState(gasContract(1 << 40))
+	msg.GasLimit = 100000
+	if res, err = DoCall(testContext(), statedb, Config{}, msg, nil, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := res.Err.(*TrapError); ok {
+		t.Errorf("out of gas reported as trap: %v", res.Err)
+	}
+}
@@ -1,12 +1,20 @@
 package tinywasm
 
 import (
+	"github.com/tinychain/tiny-wasm/wagon/exec"
 	"github.com/tinychain/tiny-wasm/wagon/wasm"
 	"reflect"
 )
 
-func goType2WasmType(kind reflect.Kind) wasm.ValueType {
-	switch kind {
+var externRefType = reflect.TypeOf(exec.ExternRef(0))
+
+// goType2WasmType returns the wasm type of a parameter or result of a host
+// function. exec.ExternRef values are passed as opaque externref handles.
+func goType2WasmType(t reflect.Type) wasm.ValueType {
+	if t == externRefType {
+		return wasm.ValueTypeExternref
+	}
+	switch t.Kind() {
 	case reflect.Int, reflect.Int32, reflect.Struct:
 		return wasm.ValueTypeI32
 	case reflect.Ptr, reflect.Uint, reflect.Int64, reflect.Uint64:
@@ -209,8 +209,10 @@ func printFull(w io.Writer, fname string, m *wasm.Module) {
 func printDis(w io.Writer, fname string, m *wasm.Module) {
 	fmt.Fprintf(w, "%s: module version: %#x\n\n", fname, m.Version)
 	fmt.Fprintf(w, "code disassembly:\n")
+	// the imported functions come first in the function index space
+	imported := len(m.FunctionIndexSpace) - len(m.Function.Types)
 	for i := range m.Function.Types {
-		f := m.GetFunction(i)
+		f := m.GetFunction(imported + i)
 		fmt.Fprintf(w, "\nfunc[%d]: %v\n", i, f.Sig)
 		dis, err := disasm.NewDisassembly(*f, m)
 		if err != nil {
@@ -221,6 +223,10 @@ func printDis(w io.Writer, fname string, m *wasm.Module) {
 			n := 1
 			buf := new(bytes.Buffer)
 			str := new(bytes.Buffer)
+			if code.Op.Prefix != 0 {
+				fmt.Fprintf(buf, "%02x ", code.Op.Prefix)
+				n++
+			}
 			fmt.Fprintf(buf, "%02x", code.Op.Code)
 			fmt.Fprintf(str, "%v", code.Op.Name)
 			for _, im := range code.Immediates {
@@ -11,6 +11,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/tinychain/tiny-wasm/wagon/exec"
 	"github.com/tinychain/tiny-wasm/wagon/wasm"
@@ -42,10 +43,11 @@ func run(w io.Writer, fname string, verify bool) {
 	}
 	defer f.Close()
 
-	m, err := wasm.ReadModule(f, importer)
+	m, err := wasm.ReadModule(f, nil)
 	if err != nil {
 		log.Fatalf("could not read module: %v", err)
 	}
+	m.Features = features
 
 	if verify {
 		err = validate.VerifyModule(m)
@@ -58,23 +60,26 @@ func run(w io.Writer, fname string, verify bool) {
 		log.Fatalf("module has no export section")
 	}
 
-	vm, err := exec.NewVM(m)
+	store := exec.NewStore()
+	if err := instantiateImports(store, m); err != nil {
+		log.Fatalf("could not instantiate imported modules: %v", err)
+	}
+	vm, err := store.Instantiate("", m)
 	if err != nil {
 		log.Fatalf("could not create VM: %v", err)
 	}
 
 	for name, e := range m.Export.Entries {
 		i := int64(e.Index)
-		fidx := m.Function.Types[int(i)]
-		ftype := m.Types.Entries[int(fidx)]
-		switch len(ftype.ReturnTypes) {
-		case 1:
-			fmt.Fprintf(w, "%s() %s => ", name, ftype.ReturnTypes[0])
-		case 0:
+		ftype := m.GetFunction(int(i)).Sig
+		if len(ftype.ReturnTypes) == 0 {
 			fmt.Fprintf(w, "%s() => ", name)
-		default:
-			log.Printf("running exported functions with more than one return value is not supported")
-			continue
+		} else {
+			types := make([]string, len(ftype.ReturnTypes))
+			for i, t := range ftype.ReturnTypes {
+				types[i] = t.String()
+			}
+			fmt.Fprintf(w, "%s() %s => ", name, strings.Join(types, " "))
 		}
 		if len(ftype.ParamTypes) > 0 {
 			log.Printf("running exported functions with input parameters is not supported")
@@ -86,12 +91,48 @@ func run(w io.Writer, fname string, verify bool) {
 			log.Printf("err=%v", err)
 			continue
 		}
-		if len(ftype.ReturnTypes) == 0 {
+		switch len(ftype.ReturnTypes) {
+		case 0:
+			fmt.Fprintf(w, "\n")
+		case 1:
+			fmt.Fprintf(w, "%[1]v (%[1]T)\n", o)
+		default:
+			for i, v := range o.([]interface{}) {
+				if i > 0 {
+					fmt.Fprintf(w, ", ")
+				}
+				fmt.Fprintf(w, "%[1]v (%[1]T)", v)
+			}
 			fmt.Fprintf(w, "\n")
+		}
+	}
+}
+
+// features are the features beyond the MVP enabled in the modules run.
+const features = wasm.FeatureSatFloatToInt | wasm.FeatureBulkMemory | wasm.FeatureMultiValue | wasm.FeatureReferenceTypes | wasm.FeatureSIMD | wasm.FeatureSignExt
+
+// instantiateImports instantiates in the store the modules imported by m
+// which aren't yet, along with their own imports.
+func instantiateImports(store *exec.Store, m *wasm.Module) error {
+	if m.Import == nil {
+		return nil
+	}
+	for _, entry := range m.Import.Entries {
+		if store.Instance(entry.ModuleName) != nil {
 			continue
 		}
-		fmt.Fprintf(w, "%[1]v (%[1]T)\n", o)
+		imported, err := importer(entry.ModuleName)
+		if err != nil {
+			return err
+		}
+		if err := instantiateImports(store, imported); err != nil {
+			return err
+		}
+		if _, err := store.Instantiate(entry.ModuleName, imported); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 func importer(name string) (*wasm.Module, error) {
@@ -104,6 +145,7 @@ func importer(name string) (*wasm.Module, error) {
 	if err != nil {
 		return nil, err
 	}
+	m.Features = features
 	err = validate.VerifyModule(m)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,128 @@
+// Copyright 2018 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// wast-run runs WebAssembly spec test scripts and reports the commands
+// which fail, or all of them in verbose mode, and the number of passed,
+// failed and skipped commands of every script.
+//
+// The skip list holds one command per line, as the base name of its script
+// and its line, e.g. "i32.wast:112"; the text following a # is a comment.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+	"github.com/tinychain/tiny-wasm/wagon/wast"
+)
+
+func main() {
+	log.SetPrefix("wast-run: ")
+	log.SetFlags(0)
+
+	verbose := flag.Bool("v", false, "report the result of every command")
+	skipFile := flag.String("skip", "", "file listing the commands to skip")
+
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var skips map[string][]int
+	if *skipFile != "" {
+		f, err := os.Open(*skipFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		skips, err = readSkips(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("could not read skip list: %v", err)
+		}
+	}
+
+	failed := false
+	for _, fname := range flag.Args() {
+		ok, err := run(os.Stdout, fname, skips[filepath.Base(fname)], *verbose)
+		if err != nil {
+			log.Fatalf("could not run %s: %v", fname, err)
+		}
+		failed = failed || !ok
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// features are the features beyond the MVP enabled in the modules run.
+const features = wasm.FeatureSatFloatToInt | wasm.FeatureBulkMemory | wasm.FeatureMultiValue | wasm.FeatureReferenceTypes | wasm.FeatureSIMD | wasm.FeatureSignExt
+
+// run runs the script fname, skipping the commands at the lines of skip,
+// and reports whether none failed.
+func run(w io.Writer, fname string, skip []int, verbose bool) (bool, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	results, err := wast.RunScript(f, wast.ScriptConfig{Features: features, Skip: skip})
+	if err != nil {
+		return false, err
+	}
+
+	name := filepath.Base(fname)
+	var passed, failed, skipped int
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			skipped++
+		case r.Err != nil:
+			failed++
+		default:
+			passed++
+		}
+		if verbose || r.Skipped || r.Err != nil {
+			fmt.Fprintf(w, "%s:%v\n", name, r)
+		}
+	}
+	fmt.Fprintf(w, "%s: %d passed, %d failed, %d skipped\n", name, passed, failed, skipped)
+	return failed == 0, nil
+}
+
+// readSkips reads a skip list and returns the lines to skip by script.
+func readSkips(r io.Reader) (map[string][]int, error) {
+	skips := make(map[string][]int)
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		i := strings.LastIndexByte(line, ':')
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: missing the line of the command", n)
+		}
+		l, err := strconv.Atoi(line[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid line %q", n, line[i+1:])
+		}
+		skips[line[:i]] = append(skips[line[:i]], l)
+	}
+	return skips, s.Err()
+}
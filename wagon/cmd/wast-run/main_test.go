@@ -0,0 +1,74 @@
+// Copyright 2018 The go-interpreter Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	f, err := os.Open("testdata/script.skip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	skips, err := readSkips(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		skip    []int
+		verbose bool
+		ok      bool
+		want    string
+	}{
+		{
+			want: "testdata/script.wast.txt",
+		},
+		{
+			skip: skips["script.wast"],
+			ok:   true,
+			want: "testdata/script.wast.skip.txt",
+		},
+		{
+			skip:    skips["script.wast"],
+			verbose: true,
+			ok:      true,
+			want:    "testdata/script.wast.verbose.txt",
+		},
+	} {
+		t.Run(tc.want, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			ok, err := run(out, "../../wast/testdata/script.wast", tc.skip, tc.verbose)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tc.ok {
+				t.Errorf("got %v, want %v", ok, tc.ok)
+			}
+
+			want, err := ioutil.ReadFile(tc.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := string(out.Bytes()), string(want); got != want {
+				t.Fatalf("invalid output.\ngot:\n%s\nwant:\n%s\n", got, want)
+			}
+		})
+	}
+}
+
+func TestReadSkips(t *testing.T) {
+	for _, src := range []string{"script.wast", "script.wast:x"} {
+		if _, err := readSkips(bytes.NewBufferString(src)); err == nil {
+			t.Errorf("%q: no error", src)
+		}
+	}
+}
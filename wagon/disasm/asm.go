@@ -18,6 +18,21 @@ import (
 func Assemble(instr []Instr) ([]byte, error) {
 	body := new(bytes.Buffer)
 	for _, ins := range instr {
+		if ins.Op.Prefix != 0 {
+			body.WriteByte(ins.Op.Prefix)
+			leb128.WriteVarUint32(body, uint32(ins.Op.Code))
+			for _, imm := range ins.Immediates {
+				switch imm := imm.(type) {
+				case uint32:
+					leb128.WriteVarUint32(body, imm)
+				case uint8:
+					body.WriteByte(imm)
+				case [16]byte:
+					body.Write(imm[:])
+				}
+			}
+			continue
+		}
 		body.WriteByte(ins.Op.Code)
 		switch op := ins.Op.Code; op {
 		case ops.Block, ops.Loop, ops.If:
@@ -36,7 +51,14 @@ func Assemble(instr []Instr) ([]byte, error) {
 			if op == ops.CallIndirect {
 				leb128.WriteVarUint32(body, ins.Immediates[1].(uint32))
 			}
-		case ops.GetLocal, ops.SetLocal, ops.TeeLocal, ops.GetGlobal, ops.SetGlobal:
+		case ops.SelectT:
+			leb128.WriteVarUint32(body, ins.Immediates[0].(uint32))
+			for _, t := range ins.Immediates[1:] {
+				leb128.WriteVarint64(body, int64(t.(wasm.ValueType)))
+			}
+		case ops.RefNull:
+			leb128.WriteVarint64(body, int64(ins.Immediates[0].(wasm.ValueType)))
+		case ops.GetLocal, ops.SetLocal, ops.TeeLocal, ops.GetGlobal, ops.SetGlobal, ops.RefFunc, ops.TableGet, ops.TableSet:
 			leb128.WriteVarUint32(body, ins.Immediates[0].(uint32))
 		case ops.I32Const:
 			leb128.WriteVarint64(body, int64(ins.Immediates[0].(int32)))
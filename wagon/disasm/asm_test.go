@@ -20,6 +20,11 @@ var testPaths = []string{
 	"../exec/testdata/spec",
 }
 
+// testFeatures are the features the modules of testPaths need, by file name.
+var testFeatures = map[string]wasm.Features{
+	"sign_extension.wasm": wasm.FeatureSignExt,
+}
+
 func TestAssemble(t *testing.T) {
 	for _, dir := range testPaths {
 		fnames, err := filepath.Glob(filepath.Join(dir, "*.wasm"))
@@ -27,6 +27,8 @@ type Instr struct {
 	// Valid value types are:
 	// - (u)(int/float)(32/64)
 	// - wasm.BlockType
+	// - wasm.ValueType
+	// - [16]byte, for v128.const and i8x16.shuffle
 	Immediates  []interface{}
 	NewStack    *StackInfo // non-nil if the instruction creates or unwinds a stack.
 	Block       *BlockInfo // non-nil if the instruction starts or ends a new block.
@@ -39,12 +41,15 @@ type Instr struct {
 	// If the operator is br_table (ops.BrTable), this is a list of StackInfo
 	// fields for each of the blocks/branches referenced by the operator.
 	Branches []StackInfo
+	// Offset is the offset of the instruction in the bytecode of the function
+	// body.
+	Offset int
 }
 
 // StackInfo stores details about a new stack created or unwinded by an instruction.
 type StackInfo struct {
 	StackTopDiff int64 // The difference between the stack depths at the end of the block
-	PreserveTop  bool  // Whether the value on the top of the stack should be preserved while unwinding
+	Preserve     int64 // The number of values on the top of the stack to preserve while unwinding
 	IsReturn     bool  // Whether the unwind is equivalent to a return
 }
 
@@ -90,6 +95,12 @@ var ErrStackUnderflow = errors.New("disasm: stack underflow")
 // parent module as an argument for locating any other functions referenced by
 // fn.
 func NewDisassembly(fn wasm.Function, module *wasm.Module) (*Disassembly, error) {
+	if len(fn.Sig.ReturnTypes) > 1 && !module.Features.Has(wasm.FeatureMultiValue) {
+		return nil, wasm.ErrMultipleResults
+	}
+	if err := fn.Body.Decode(); err != nil {
+		return nil, err
+	}
 	code := fn.Body.Code
 	instrs, err := Disassemble(code)
 	if err != nil {
@@ -105,14 +116,29 @@ func NewDisassembly(fn wasm.Function, module *wasm.Module) (*Disassembly, error)
 	// stack is maintained indepepdently for calculating discard values
 	stackDepths := &stack.Stack{}
 	stackDepths.Push(0)
-	blockIndices := &stack.Stack{} // a stack of indices to operators which start new blocks
+	blockIndices := &stack.Stack{}    // a stack of indices to operators which start new blocks
+	var blockSigs []*wasm.FunctionSig // the signatures of the blocks in blockIndices
+	// labelArity returns the number of values taken by a branch to the
+	// block at the given depth: the parameters of a loop, as the branch
+	// starts it again, and the results of other blocks.
+	labelArity := func(depth uint32) int64 {
+		index := blockIndices.Get(blockIndices.Len() - 1 - int(depth))
+		sig := blockSigs[len(blockSigs)-1-int(depth)]
+		if disas.Code[index].Op.Code == ops.Loop {
+			return int64(len(sig.ParamTypes))
+		}
+		return int64(len(sig.ReturnTypes))
+	}
 	curIndex := 0
 	var lastOpReturn bool
 
 	for _, instr := range instrs {
 		logger.Printf("stack top is %d", stackDepths.Top())
 		opStr := instr.Op
-		op := opStr.Code
+		if !opStr.Enabled(module.Features) {
+			return nil, ops.DisabledError(opStr.Name)
+		}
+		op := opStr.FirstByte()
 		if op == ops.End || op == ops.Else {
 			// There are two possible cases here:
 			// 1. The corresponding block/if/loop instruction
@@ -151,10 +177,31 @@ func NewDisassembly(fn wasm.Function, module *wasm.Module) (*Disassembly, error)
 			if !instr.Unreachable {
 				stackDepths.SetTop(stackDepths.Top() - 1)
 			}
-		case ops.Select:
+		case ops.Select, ops.SelectT:
 			if !instr.Unreachable {
 				stackDepths.SetTop(stackDepths.Top() - 2)
 			}
+		case ops.TableSet:
+			if !instr.Unreachable {
+				stackDepths.SetTop(stackDepths.Top() - 2)
+			}
+		case ops.RefNull:
+			if !instr.Unreachable {
+				top := stackDepths.Top() + 1
+				stackDepths.SetTop(top)
+				disas.checkMaxDepth(int(top))
+			}
+		case ops.TableGet, ops.RefIsNull:
+			// both pop an operand and push their result
+		case ops.MiscPrefix:
+			if !instr.Unreachable {
+				switch opStr.Code {
+				case ops.TableGrow:
+					stackDepths.SetTop(stackDepths.Top() - 1)
+				case ops.TableFill:
+					stackDepths.SetTop(stackDepths.Top() - 3)
+				}
+			}
 		case ops.Return:
 			if !instr.Unreachable {
 				stackDepths.SetTop(stackDepths.Top() - uint64(len(fn.Sig.ReturnTypes)))
@@ -165,10 +212,11 @@ func NewDisassembly(fn wasm.Function, module *wasm.Module) (*Disassembly, error)
 			// The max depth reached while execing the current block
 			curDepth := stackDepths.Top()
 			blockStartIndex := blockIndices.Pop()
-			blockSig := disas.Code[blockStartIndex].Block.Signature
+			sig := blockSigs[len(blockSigs)-1]
+			blockSigs = blockSigs[:len(blockSigs)-1]
 			instr.Block = &BlockInfo{
 				Start:     false,
-				Signature: blockSig,
+				Signature: disas.Code[blockStartIndex].Block.Signature,
 			}
 			if op == ops.End {
 				instr.Block.BlockStartIndex = int(blockStartIndex)
@@ -179,16 +227,17 @@ func NewDisassembly(fn wasm.Function, module *wasm.Module) (*Disassembly, error)
 			}
 
 			// The max depth reached while execing the last block
-			// If the signature of the current block is not empty,
-			// this will be incremented.
+			// If the block has results, this will be incremented
+			// by their number.
 			// Same with ops.Br/BrIf, we subtract 2 instead of 1
 			// to get the depth of the *parent* block of the branch
 			// we want to take.
 			prevDepthIndex := stackDepths.Len() - 2
 			prevDepth := stackDepths.Get(prevDepthIndex)
 
-			if op != ops.Else && blockSig != wasm.BlockTypeEmpty && !instr.Unreachable {
-				stackDepths.Set(prevDepthIndex, prevDepth+1)
+			results := len(sig.ReturnTypes)
+			if op != ops.Else && results != 0 && !instr.Unreachable {
+				stackDepths.Set(prevDepthIndex, prevDepth+uint64(results))
 				disas.checkMaxDepth(int(stackDepths.Get(prevDepthIndex)))
 			}
 
@@ -199,9 +248,9 @@ func NewDisassembly(fn wasm.Function, module *wasm.Module) (*Disassembly, error)
 				}
 				instr.NewStack = &StackInfo{
 					StackTopDiff: int64(elemsDiscard),
-					PreserveTop:  blockSig != wasm.BlockTypeEmpty,
+					Preserve:     int64(results),
 				}
-				logger.Printf("discard %d elements, preserve top: %v", elemsDiscard, instr.NewStack.PreserveTop)
+				logger.Printf("discard %d elements, preserve %d", elemsDiscard, instr.NewStack.Preserve)
 			} else {
 				instr.NewStack = &StackInfo{}
 			}
@@ -214,17 +263,30 @@ func NewDisassembly(fn wasm.Function, module *wasm.Module) (*Disassembly, error)
 
 			stackDepths.Pop()
 			if op == ops.Else {
-				stackDepths.Push(0)
+				// the else branch starts again from the
+				// parameters of the block
+				stackDepths.Push(prevDepth + uint64(len(sig.ParamTypes)))
 				blockIndices.Push(uint64(curIndex))
+				blockSigs = append(blockSigs, sig)
 				if !instr.Unreachable {
 					blockPolymorphicOps = append(blockPolymorphicOps, []int{})
 				}
 			}
 
 		case ops.Block, ops.Loop, ops.If:
-			sig := uint32(instr.Immediates[0].(wasm.BlockType))
+			blockType := instr.Immediates[0].(wasm.BlockType)
+			sig, err := module.BlockSignature(blockType)
+			if err != nil {
+				return nil, err
+			}
 			logger.Printf("if, depth is %d", stackDepths.Top())
-			stackDepths.Push(stackDepths.Top())
+			// The parameters of the block are moved from the stack
+			// of its parent to its own stack.
+			top := stackDepths.Top()
+			if !instr.Unreachable {
+				stackDepths.SetTop(top - uint64(len(sig.ParamTypes)))
+			}
+			stackDepths.Push(top)
 			// If this new block is unreachable, its
 			// entire instruction sequence is unreachable
 			// as well. To make sure that isInstrReachable
@@ -237,10 +299,11 @@ func NewDisassembly(fn wasm.Function, module *wasm.Module) (*Disassembly, error)
 			}
 			instr.Block = &BlockInfo{
 				Start:     true,
-				Signature: wasm.BlockType(sig),
+				Signature: blockType,
 			}
 
 			blockIndices.Push(uint64(curIndex))
+			blockSigs = append(blockSigs, sig)
 		case ops.Br, ops.BrIf:
 			depth := instr.Immediates[0].(uint32)
 			if int(depth) == blockIndices.Len() {
@@ -258,12 +321,9 @@ func NewDisassembly(fn wasm.Function, module *wasm.Module) (*Disassembly, error)
 					return nil, ErrStackUnderflow
 				}
 
-				// No need to subtract 2 here, we are getting the block
-				// we need to branch to.
-				index := blockIndices.Get(blockIndices.Len() - 1 - int(depth))
 				instr.NewStack = &StackInfo{
 					StackTopDiff: int64(elemsDiscard),
-					PreserveTop:  disas.Code[index].Block.Signature != wasm.BlockTypeEmpty,
+					Preserve:     labelArity(depth),
 				}
 			}
 			if op == ops.Br {
@@ -290,9 +350,8 @@ func NewDisassembly(fn wasm.Function, module *wasm.Module) (*Disassembly, error)
 					if elemsDiscard < 0 {
 						return nil, ErrStackUnderflow
 					}
-					index := blockIndices.Get(blockIndices.Len() - 1 - int(entry))
 					info.StackTopDiff = int64(elemsDiscard)
-					info.PreserveTop = disas.Code[index].Block.Signature != wasm.BlockTypeEmpty
+					info.Preserve = labelArity(entry)
 				}
 				instr.Branches = append(instr.Branches, info)
 			}
@@ -310,9 +369,8 @@ func NewDisassembly(fn wasm.Function, module *wasm.Module) (*Disassembly, error)
 				if elemsDiscard < 0 {
 					return nil, ErrStackUnderflow
 				}
-				index := blockIndices.Get(blockIndices.Len() - 1 - int(defaultTarget))
 				info.StackTopDiff = int64(elemsDiscard)
-				info.PreserveTop = disas.Code[index].Block.Signature != wasm.BlockTypeEmpty
+				info.Preserve = labelArity(defaultTarget)
 			}
 			instr.Branches = append(instr.Branches, info)
 			pushPolymorphicOp(blockPolymorphicOps, curIndex)
@@ -374,6 +432,7 @@ func Disassemble(code []byte) ([]Instr, error) {
 	reader := bytes.NewReader(code)
 	var out []Instr
 	for {
+		offset := len(code) - reader.Len()
 		op, err := reader.ReadByte()
 		if err == io.EOF {
 			break
@@ -381,12 +440,13 @@ func Disassemble(code []byte) ([]Instr, error) {
 			return nil, err
 		}
 
-		opStr, err := ops.New(op)
+		opStr, err := ops.Read(op, reader)
 		if err != nil {
 			return nil, err
 		}
 		instr := Instr{
-			Op: opStr,
+			Op:     opStr,
+			Offset: offset,
 		}
 
 		switch op {
@@ -428,12 +488,37 @@ func Disassemble(code []byte) ([]Instr, error) {
 			}
 			instr.Immediates = append(instr.Immediates, index)
 			if op == ops.CallIndirect {
-				reserved, err := leb128.ReadVarUint32(reader)
+				table, err := leb128.ReadVarUint32(reader)
+				if err != nil {
+					return nil, err
+				}
+				instr.Immediates = append(instr.Immediates, table)
+			}
+		case ops.SelectT:
+			n, err := leb128.ReadVarUint32(reader)
+			if err != nil {
+				return nil, err
+			}
+			instr.Immediates = append(instr.Immediates, n)
+			for i := uint32(0); i < n; i++ {
+				t, err := leb128.ReadVarint32(reader)
 				if err != nil {
 					return nil, err
 				}
-				instr.Immediates = append(instr.Immediates, reserved)
+				instr.Immediates = append(instr.Immediates, wasm.ValueType(t))
+			}
+		case ops.RefNull:
+			t, err := leb128.ReadVarint32(reader)
+			if err != nil {
+				return nil, err
 			}
+			instr.Immediates = append(instr.Immediates, wasm.ValueType(t))
+		case ops.RefFunc, ops.TableGet, ops.TableSet:
+			index, err := leb128.ReadVarUint32(reader)
+			if err != nil {
+				return nil, err
+			}
+			instr.Immediates = append(instr.Immediates, index)
 		case ops.GetLocal, ops.SetLocal, ops.TeeLocal, ops.GetGlobal, ops.SetGlobal:
 			index, err := leb128.ReadVarUint32(reader)
 			if err != nil {
@@ -485,8 +570,77 @@ func Disassemble(code []byte) ([]Instr, error) {
 				return nil, err
 			}
 			instr.Immediates = append(instr.Immediates, uint8(res))
+		case ops.MiscPrefix:
+			// indices are read as uint32, reserved memory indices as uint8
+			var kinds []bool
+			switch opStr.Code {
+			case ops.MemoryInit:
+				kinds = []bool{true, false}
+			case ops.DataDrop, ops.ElemDrop:
+				kinds = []bool{true}
+			case ops.MemoryCopy:
+				kinds = []bool{false, false}
+			case ops.MemoryFill:
+				kinds = []bool{false}
+			case ops.TableInit, ops.TableCopy:
+				kinds = []bool{true, true}
+			case ops.TableGrow, ops.TableSize, ops.TableFill:
+				kinds = []bool{true}
+			}
+			for _, index := range kinds {
+				res, err := leb128.ReadVarUint32(reader)
+				if err != nil {
+					return nil, err
+				}
+				if index {
+					instr.Immediates = append(instr.Immediates, res)
+				} else {
+					instr.Immediates = append(instr.Immediates, uint8(res))
+				}
+			}
+		case ops.SIMDPrefix:
+			imms, err := readSIMDImmediates(opStr, reader)
+			if err != nil {
+				return nil, err
+			}
+			instr.Immediates = imms
 		}
 		out = append(out, instr)
 	}
 	return out, nil
 }
+
+// readSIMDImmediates reads the immediates of an operator following the 0xfd
+// prefix: the flags and offset of memory_immediate as uint32, lane indices as
+// uint8, and the 16 bytes of v128.const and i8x16.shuffle.
+func readSIMDImmediates(op ops.Op, reader *bytes.Reader) ([]interface{}, error) {
+	var imms []interface{}
+	switch op.Code {
+	case ops.V128Load, ops.V128Load8x8S, ops.V128Load8x8U, ops.V128Load16x4S, ops.V128Load16x4U, ops.V128Load32x2S, ops.V128Load32x2U, ops.V128Load8Splat, ops.V128Load16Splat, ops.V128Load32Splat, ops.V128Load64Splat, ops.V128Store, ops.V128Load32Zero, ops.V128Load64Zero,
+		ops.V128Load8Lane, ops.V128Load16Lane, ops.V128Load32Lane, ops.V128Load64Lane, ops.V128Store8Lane, ops.V128Store16Lane, ops.V128Store32Lane, ops.V128Store64Lane:
+		for i := 0; i < 2; i++ {
+			res, err := leb128.ReadVarUint32(reader)
+			if err != nil {
+				return nil, err
+			}
+			imms = append(imms, res)
+		}
+	case ops.V128Const, ops.I8x16Shuffle:
+		var b [16]byte
+		if _, err := io.ReadFull(reader, b[:]); err != nil {
+			return nil, err
+		}
+		return append(imms, b), nil
+	}
+	switch op.Code {
+	case ops.V128Load8Lane, ops.V128Load16Lane, ops.V128Load32Lane, ops.V128Load64Lane, ops.V128Store8Lane, ops.V128Store16Lane, ops.V128Store32Lane, ops.V128Store64Lane,
+		ops.I8x16ExtractLaneS, ops.I8x16ExtractLaneU, ops.I8x16ReplaceLane, ops.I16x8ExtractLaneS, ops.I16x8ExtractLaneU, ops.I16x8ReplaceLane, ops.I32x4ExtractLane, ops.I32x4ReplaceLane,
+		ops.I64x2ExtractLane, ops.I64x2ReplaceLane, ops.F32x4ExtractLane, ops.F32x4ReplaceLane, ops.F64x2ExtractLane, ops.F64x2ReplaceLane:
+		lane, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		imms = append(imms, lane)
+	}
+	return imms, nil
+}
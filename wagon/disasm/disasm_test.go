@@ -33,7 +33,11 @@ func TestDisassemble(t *testing.T) {
 				if err != nil {
 					t.Fatalf("error reading module %v", err)
 				}
+				m.Features = testFeatures[filepath.Base(name)]
 				for _, f := range m.FunctionIndexSpace {
+					if f.IsImport() {
+						continue
+					}
 					_, err := disasm.NewDisassembly(f, m)
 					if err != nil {
 						t.Fatalf("disassemble failed: %v", err)
@@ -0,0 +1,118 @@
+// Copyright 2017 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package exec
+
+import (
+	"errors"
+
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+)
+
+// ErrOutOfBoundsTableAccess is the error value used while trapping the VM
+// when a bulk table operator accesses elements out of the bounds of a table
+// or an element segment.
+var ErrOutOfBoundsTableAccess = errors.New("exec: out of bounds table access")
+
+// popRange pops the destination, source and length operands of a bulk
+// operator and returns them as dst, src and n.
+func (vm *VM) popRange() (dst, src, n uint64) {
+	n = uint64(vm.popUint32())
+	src = uint64(vm.popUint32())
+	dst = uint64(vm.popUint32())
+	return dst, src, n
+}
+
+// charge calls the meter, if any, for n bytes or elements.
+func (vm *VM) charge(n uint64) {
+	if vm.meter != nil && n != 0 {
+		vm.meter(n)
+	}
+}
+
+func (vm *VM) memoryInit() {
+	index := vm.fetchUint32()
+	_ = vm.fetchInt8() // reserved memory index
+	var data []byte
+	if !vm.dataDropped[index] {
+		data = vm.module.Data.Entries[index].Data
+	}
+	dst, src, n := vm.popRange()
+	if src+n > uint64(len(data)) || dst+n > uint64(len(vm.memory.bytes)) {
+		panic(ErrOutOfBoundsMemoryAccess)
+	}
+	vm.charge(n)
+	copy(vm.memory.bytes[dst:dst+n], data[src:])
+}
+
+func (vm *VM) dataDrop() {
+	vm.dataDropped[vm.fetchUint32()] = true
+}
+
+func (vm *VM) memoryCopy() {
+	_, _ = vm.fetchInt8(), vm.fetchInt8() // reserved memory indices
+	dst, src, n := vm.popRange()
+	if src+n > uint64(len(vm.memory.bytes)) || dst+n > uint64(len(vm.memory.bytes)) {
+		panic(ErrOutOfBoundsMemoryAccess)
+	}
+	vm.charge(n)
+	copy(vm.memory.bytes[dst:dst+n], vm.memory.bytes[src:])
+}
+
+func (vm *VM) memoryFill() {
+	_ = vm.fetchInt8() // reserved memory index
+	dst, val, n := vm.popRange()
+	if dst+n > uint64(len(vm.memory.bytes)) {
+		panic(ErrOutOfBoundsMemoryAccess)
+	}
+	vm.charge(n)
+	mem := vm.memory.bytes[dst : dst+n]
+	if len(mem) == 0 {
+		return
+	}
+	// fill by doubling the filled prefix, letting copy do the work
+	mem[0] = byte(val)
+	for i := 1; i < len(mem); i *= 2 {
+		copy(mem[i:], mem[:i])
+	}
+}
+
+func (vm *VM) tableInit() {
+	index := vm.fetchUint32()
+	t := vm.tables[vm.fetchUint32()]
+	var elems []uint32
+	if !vm.elemDropped[index] {
+		elems = vm.module.Elements.Entries[index].Elems
+	}
+	dst, src, n := vm.popRange()
+	if src+n > uint64(len(elems)) || dst+n > uint64(len(t.elems)) {
+		panic(ErrOutOfBoundsTableAccess)
+	}
+	vm.charge(n)
+	table := t.elems[dst : dst+n]
+	copy(table, elems[src:])
+	// the elements of the tables of an instance are function addresses
+	if vm.funcAddrs != nil {
+		for i, elem := range table {
+			if elem != wasm.NullElement {
+				table[i] = vm.funcAddrs[elem]
+			}
+		}
+	}
+}
+
+func (vm *VM) elemDrop() {
+	vm.elemDropped[vm.fetchUint32()] = true
+}
+
+func (vm *VM) tableCopy() {
+	to := vm.tables[vm.fetchUint32()]
+	from := vm.tables[vm.fetchUint32()]
+	dst, src, n := vm.popRange()
+	if src+n > uint64(len(from.elems)) || dst+n > uint64(len(to.elems)) {
+		panic(ErrOutOfBoundsTableAccess)
+	}
+	vm.charge(n)
+	copy(to.elems[dst:dst+n], from.elems[src:])
+}
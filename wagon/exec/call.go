@@ -4,7 +4,11 @@
 
 package exec
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+)
 
 var (
 	// ErrSignatureMismatch is the error value used while trapping the VM when
@@ -25,33 +29,45 @@ func (vm *VM) call() {
 
 func (vm *VM) callIndirect() {
 	index := vm.fetchUint32()
-	fnExpect := vm.module.Types.Entries[index]
-	_ = vm.fetchUint32() // reserved (https://github.com/WebAssembly/design/blob/27ac254c854994103c24834a994be16f74f54186/BinaryEncoding.md#call-operators-described-here)
+	fnExpect := &vm.module.Types.Entries[index]
+	table := vm.tables[vm.fetchUint32()]
 	tableIndex := vm.popUint32()
-	if int(tableIndex) >= len(vm.module.TableIndexSpace[0]) {
+	if int(tableIndex) >= len(table.elems) || table.elems[tableIndex] == wasm.NullElement {
 		panic(ErrUndefinedElementIndex)
 	}
-	elemIndex := vm.module.TableIndexSpace[0][tableIndex]
-	fnActual := vm.module.FunctionIndexSpace[elemIndex]
+	elemIndex := table.elems[tableIndex]
 
-	if len(fnExpect.ParamTypes) != len(fnActual.Sig.ParamTypes) {
-		panic(ErrSignatureMismatch)
+	// the elements of the table of an instance are function addresses
+	callee := vm
+	if vm.store != nil {
+		ref := vm.store.funcs[elemIndex]
+		callee, elemIndex = ref.vm, ref.index
 	}
-	if len(fnExpect.ReturnTypes) != len(fnActual.Sig.ReturnTypes) {
+	if !sameSig(fnExpect, callee.module.FunctionIndexSpace[elemIndex].Sig) {
 		panic(ErrSignatureMismatch)
 	}
 
-	for i := range fnExpect.ParamTypes {
-		if fnExpect.ParamTypes[i] != fnActual.Sig.ParamTypes[i] {
-			panic(ErrSignatureMismatch)
-		}
+	if callee != vm {
+		vm.callInstance(callee, elemIndex)
+		return
 	}
+	vm.funcs[elemIndex].call(vm, int64(elemIndex))
+}
 
-	for i := range fnExpect.ReturnTypes {
-		if fnExpect.ReturnTypes[i] != fnActual.Sig.ReturnTypes[i] {
-			panic(ErrSignatureMismatch)
+// sameSig reports whether the function signatures a and b are the same.
+func sameSig(a, b *wasm.FunctionSig) bool {
+	if len(a.ParamTypes) != len(b.ParamTypes) || len(a.ReturnTypes) != len(b.ReturnTypes) {
+		return false
+	}
+	for i := range a.ParamTypes {
+		if a.ParamTypes[i] != b.ParamTypes[i] {
+			return false
 		}
 	}
-
-	vm.funcs[elemIndex].call(vm, int64(elemIndex))
+	for i := range a.ReturnTypes {
+		if a.ReturnTypes[i] != b.ReturnTypes[i] {
+			return false
+		}
+	}
+	return true
 }
@@ -91,3 +91,81 @@ func (vm *VM) f64ConvertUI64() {
 func (vm *VM) f64PromoteF32() {
 	vm.pushFloat64(float64(vm.popFloat32()))
 }
+
+func (vm *VM) i32Extend8S() {
+	vm.pushInt32(int32(int8(vm.popInt32())))
+}
+
+func (vm *VM) i32Extend16S() {
+	vm.pushInt32(int32(int16(vm.popInt32())))
+}
+
+func (vm *VM) i64Extend8S() {
+	vm.pushInt64(int64(int8(vm.popInt64())))
+}
+
+func (vm *VM) i64Extend16S() {
+	vm.pushInt64(int64(int16(vm.popInt64())))
+}
+
+func (vm *VM) i64Extend32S() {
+	vm.pushInt64(int64(int32(vm.popInt64())))
+}
+
+// truncSatS truncates f towards zero to a signed integer in [min, max],
+// saturating out of range values and returning 0 for NaN.
+func truncSatS(f float64, min, max int64) int64 {
+	switch {
+	case f != f:
+		return 0
+	case f <= float64(min):
+		return min
+	case f >= float64(max):
+		return max
+	}
+	return int64(math.Trunc(f))
+}
+
+// truncSatU truncates f towards zero to an unsigned integer in [0, max],
+// saturating out of range values and returning 0 for NaN.
+func truncSatU(f float64, max uint64) uint64 {
+	switch {
+	case f != f, f <= 0:
+		return 0
+	case f >= float64(max):
+		return max
+	}
+	return uint64(math.Trunc(f))
+}
+
+func (vm *VM) i32TruncSatSF32() {
+	vm.pushInt32(int32(truncSatS(float64(vm.popFloat32()), math.MinInt32, math.MaxInt32)))
+}
+
+func (vm *VM) i32TruncSatUF32() {
+	vm.pushUint32(uint32(truncSatU(float64(vm.popFloat32()), math.MaxUint32)))
+}
+
+func (vm *VM) i32TruncSatSF64() {
+	vm.pushInt32(int32(truncSatS(vm.popFloat64(), math.MinInt32, math.MaxInt32)))
+}
+
+func (vm *VM) i32TruncSatUF64() {
+	vm.pushUint32(uint32(truncSatU(vm.popFloat64(), math.MaxUint32)))
+}
+
+func (vm *VM) i64TruncSatSF32() {
+	vm.pushInt64(truncSatS(float64(vm.popFloat32()), math.MinInt64, math.MaxInt64))
+}
+
+func (vm *VM) i64TruncSatUF32() {
+	vm.pushUint64(truncSatU(float64(vm.popFloat32()), math.MaxUint64))
+}
+
+func (vm *VM) i64TruncSatSF64() {
+	vm.pushInt64(truncSatS(vm.popFloat64(), math.MinInt64, math.MaxInt64))
+}
+
+func (vm *VM) i64TruncSatUF64() {
+	vm.pushUint64(truncSatU(vm.popFloat64(), math.MaxUint64))
+}
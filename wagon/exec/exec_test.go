@@ -41,6 +41,11 @@ type file struct {
 	Tests    []testCase `json:"tests"`
 }
 
+// moduleFeatures are the features the modules need, by file name.
+var moduleFeatures = map[string]wasm.Features{
+	"sign_extension.wasm": wasm.FeatureSignExt,
+}
+
 var reValue = regexp.MustCompile(`(.+)\:(.+)`)
 
 func parseFloat(str string, bitSize int) float64 {
@@ -253,6 +258,37 @@ func panics(fn func()) (panicked bool, msg string) {
 	return
 }
 
+// newEnvStore returns a store with the "env" instance the modules compiled
+// from C import their linear memory, table and base addresses from.
+func newEnvStore(t testing.TB) *exec.Store {
+	zero := []byte{0x41, 0x00, 0x0b} // i32.const 0
+	env := &wasm.Module{
+		Memory: &wasm.SectionMemories{
+			Entries: []wasm.Memory{{Limits: wasm.ResizableLimits{Flags: 1, Initial: 256, Maximum: 256}}},
+		},
+		Table: &wasm.SectionTables{
+			Entries: []wasm.Table{{ElementType: wasm.ElemTypeAnyFunc, Limits: wasm.ResizableLimits{Flags: 1}}},
+		},
+		GlobalIndexSpace: []wasm.GlobalEntry{
+			{Type: wasm.GlobalVar{Type: wasm.ValueTypeI32}, Init: zero},
+			{Type: wasm.GlobalVar{Type: wasm.ValueTypeI32}, Init: zero},
+		},
+		Export: &wasm.SectionExports{
+			Entries: map[string]wasm.ExportEntry{
+				"memory":     {FieldStr: "memory", Kind: wasm.ExternalMemory},
+				"table":      {FieldStr: "table", Kind: wasm.ExternalTable},
+				"memoryBase": {FieldStr: "memoryBase", Kind: wasm.ExternalGlobal, Index: 0},
+				"tableBase":  {FieldStr: "tableBase", Kind: wasm.ExternalGlobal, Index: 1},
+			},
+		},
+	}
+	store := exec.NewStore()
+	if _, err := store.Instantiate("env", env); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
 func runTest(fileName string, testCases []testCase, t testing.TB) {
 	file, err := os.Open(fileName)
 	if err != nil {
@@ -264,11 +300,17 @@ func runTest(fileName string, testCases []testCase, t testing.TB) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	module.Features = moduleFeatures[filepath.Base(fileName)]
 	if err = validate.VerifyModule(module); err != nil {
 		t.Fatalf("%s: %v", fileName, err)
 	}
 
-	vm, err := exec.NewVM(module)
+	var vm *exec.VM
+	if module.HasUnresolvedImports() {
+		vm, err = newEnvStore(t).Instantiate("", module)
+	} else {
+		vm, err = exec.NewVM(module)
+	}
 	if err != nil {
 		t.Fatalf("%s: %v", fileName, err)
 	}
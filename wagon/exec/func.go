@@ -19,10 +19,11 @@ type function interface {
 type compiledFunction struct {
 	code           []byte
 	branchTables   []*compile.BranchTable
-	maxDepth       int  // maximum stack depth reached while executing the function body
-	totalLocalVars int  // number of local variables used by the function
-	args           int  // number of arguments the function accepts
-	returns        bool // whether the function returns a value
+	offsets        []compile.Offset // offsets of the instructions in the function body
+	maxDepth       int              // maximum stack depth reached while executing the function body
+	totalLocalVars int              // number of local variables used by the function
+	args           int              // number of arguments the function accepts
+	returns        int              // number of values the function returns
 }
 
 type goFunction struct {
@@ -137,31 +138,43 @@ func (fn goFunction) call(vm *VM, index int64) {
 	}
 }
 
-func (compiled compiledFunction) call(vm *VM, index int64) {
-	newStack := make([]uint64, compiled.maxDepth)
-	locals := make([]uint64, compiled.totalLocalVars)
+// importedFunction is a function imported from another instance of the
+// store, it is executed in the context of that instance.
+type importedFunction struct {
+	vm    *VM
+	index uint32 // index of the function in the function index space of vm
+}
 
-	for i := compiled.args - 1; i >= 0; i-- {
-		locals[i] = vm.popUint64()
-	}
+func (fn importedFunction) call(vm *VM, index int64) {
+	vm.callInstance(fn.vm, fn.index)
+}
 
-	//save execution context
-	prevCtxt := vm.ctx
+// lazyFunction is a function whose body was read lazily, compiled on its
+// first call.
+type lazyFunction struct{}
 
-	vm.ctx = context{
-		stack:   newStack,
-		locals:  locals,
-		code:    compiled.code,
-		pc:      0,
-		curFunc: index,
+func (lazyFunction) call(vm *VM, index int64) {
+	if err := vm.compileLazy(index); err != nil {
+		panic(err)
 	}
+	vm.funcs[index].call(vm, index)
+}
 
-	rtrn := vm.execCode(compiled)
+func (compiled compiledFunction) call(vm *VM, index int64) {
+	if vm.depth+len(vm.frames)+1 >= vm.maxCallDepth() {
+		panic(ErrCallStackExhausted)
+	}
 
-	//restore execution context
-	vm.ctx = prevCtxt
+	//save execution context, execCode restores it once the function returns
+	vm.frames = append(vm.frames, vm.ctx)
+	vm.enter(compiled, index)
+	vm.poll()
+}
 
-	if compiled.returns {
-		vm.pushUint64(rtrn)
+// maxCallDepth returns MaxCallDepth, or its default value.
+func (vm *VM) maxCallDepth() int {
+	if vm.MaxCallDepth == 0 {
+		return DefaultMaxCallDepth
 	}
+	return vm.MaxCallDepth
 }
@@ -5,6 +5,8 @@
 package exec
 
 import (
+	"math"
+
 	ops "github.com/tinychain/tiny-wasm/wagon/wasm/operators"
 )
 
@@ -143,6 +145,272 @@ func (vm *VM) newFuncTable() {
 	vm.funcTable[ops.F64ConvertUI64] = vm.f64ConvertUI64
 	vm.funcTable[ops.F64PromoteF32] = vm.f64PromoteF32
 
+	vm.funcTable[ops.I32Extend8S] = vm.i32Extend8S
+	vm.funcTable[ops.I32Extend16S] = vm.i32Extend16S
+	vm.funcTable[ops.I64Extend8S] = vm.i64Extend8S
+	vm.funcTable[ops.I64Extend16S] = vm.i64Extend16S
+	vm.funcTable[ops.I64Extend32S] = vm.i64Extend32S
+
+	vm.funcTable[ops.MiscPrefix] = vm.misc
+	vm.miscTable[ops.I32TruncSatSF32] = vm.i32TruncSatSF32
+	vm.miscTable[ops.I32TruncSatUF32] = vm.i32TruncSatUF32
+	vm.miscTable[ops.I32TruncSatSF64] = vm.i32TruncSatSF64
+	vm.miscTable[ops.I32TruncSatUF64] = vm.i32TruncSatUF64
+	vm.miscTable[ops.I64TruncSatSF32] = vm.i64TruncSatSF32
+	vm.miscTable[ops.I64TruncSatUF32] = vm.i64TruncSatUF32
+	vm.miscTable[ops.I64TruncSatSF64] = vm.i64TruncSatSF64
+	vm.miscTable[ops.I64TruncSatUF64] = vm.i64TruncSatUF64
+	vm.miscTable[ops.MemoryInit] = vm.memoryInit
+	vm.miscTable[ops.DataDrop] = vm.dataDrop
+	vm.miscTable[ops.MemoryCopy] = vm.memoryCopy
+	vm.miscTable[ops.MemoryFill] = vm.memoryFill
+	vm.miscTable[ops.TableInit] = vm.tableInit
+	vm.miscTable[ops.ElemDrop] = vm.elemDrop
+	vm.miscTable[ops.TableCopy] = vm.tableCopy
+	vm.miscTable[ops.TableGrow] = vm.tableGrow
+	vm.miscTable[ops.TableSize] = vm.tableSize
+	vm.miscTable[ops.TableFill] = vm.tableFill
+
+	vm.funcTable[ops.SIMDPrefix] = vm.vector
+	vm.simdTable[ops.V128Load] = vm.v128Load
+	vm.simdTable[ops.V128Load8x8S] = vm.loadExtend(8, true)
+	vm.simdTable[ops.V128Load8x8U] = vm.loadExtend(8, false)
+	vm.simdTable[ops.V128Load16x4S] = vm.loadExtend(16, true)
+	vm.simdTable[ops.V128Load16x4U] = vm.loadExtend(16, false)
+	vm.simdTable[ops.V128Load32x2S] = vm.loadExtend(32, true)
+	vm.simdTable[ops.V128Load32x2U] = vm.loadExtend(32, false)
+	vm.simdTable[ops.V128Load8Splat] = vm.loadSplat(8)
+	vm.simdTable[ops.V128Load16Splat] = vm.loadSplat(16)
+	vm.simdTable[ops.V128Load32Splat] = vm.loadSplat(32)
+	vm.simdTable[ops.V128Load64Splat] = vm.loadSplat(64)
+	vm.simdTable[ops.V128Store] = vm.v128Store
+	vm.simdTable[ops.V128Const] = vm.v128Const
+	vm.simdTable[ops.I8x16Shuffle] = vm.i8x16Shuffle
+	vm.simdTable[ops.I8x16Swizzle] = vm.i8x16Swizzle
+	vm.simdTable[ops.I8x16Splat] = vm.splat(8)
+	vm.simdTable[ops.I16x8Splat] = vm.splat(16)
+	vm.simdTable[ops.I32x4Splat] = vm.splat(32)
+	vm.simdTable[ops.I64x2Splat] = vm.splat(64)
+	vm.simdTable[ops.F32x4Splat] = vm.splat(32)
+	vm.simdTable[ops.F64x2Splat] = vm.splat(64)
+	vm.simdTable[ops.I8x16ExtractLaneS] = vm.extractLane(8, true)
+	vm.simdTable[ops.I8x16ExtractLaneU] = vm.extractLane(8, false)
+	vm.simdTable[ops.I8x16ReplaceLane] = vm.replaceLane(8)
+	vm.simdTable[ops.I16x8ExtractLaneS] = vm.extractLane(16, true)
+	vm.simdTable[ops.I16x8ExtractLaneU] = vm.extractLane(16, false)
+	vm.simdTable[ops.I16x8ReplaceLane] = vm.replaceLane(16)
+	vm.simdTable[ops.I32x4ExtractLane] = vm.extractLane(32, false)
+	vm.simdTable[ops.I32x4ReplaceLane] = vm.replaceLane(32)
+	vm.simdTable[ops.I64x2ExtractLane] = vm.extractLane(64, false)
+	vm.simdTable[ops.I64x2ReplaceLane] = vm.replaceLane(64)
+	vm.simdTable[ops.F32x4ExtractLane] = vm.extractLane(32, false)
+	vm.simdTable[ops.F32x4ReplaceLane] = vm.replaceLane(32)
+	vm.simdTable[ops.F64x2ExtractLane] = vm.extractLane(64, false)
+	vm.simdTable[ops.F64x2ReplaceLane] = vm.replaceLane(64)
+	vm.simdTable[ops.I8x16Eq] = vm.binop(8, eq)
+	vm.simdTable[ops.I8x16Ne] = vm.binop(8, ne)
+	vm.simdTable[ops.I8x16LtS] = vm.binop(8, ltS(8))
+	vm.simdTable[ops.I8x16LtU] = vm.binop(8, ltU)
+	vm.simdTable[ops.I8x16GtS] = vm.binop(8, gtS(8))
+	vm.simdTable[ops.I8x16GtU] = vm.binop(8, gtU)
+	vm.simdTable[ops.I8x16LeS] = vm.binop(8, leS(8))
+	vm.simdTable[ops.I8x16LeU] = vm.binop(8, leU)
+	vm.simdTable[ops.I8x16GeS] = vm.binop(8, geS(8))
+	vm.simdTable[ops.I8x16GeU] = vm.binop(8, geU)
+	vm.simdTable[ops.I16x8Eq] = vm.binop(16, eq)
+	vm.simdTable[ops.I16x8Ne] = vm.binop(16, ne)
+	vm.simdTable[ops.I16x8LtS] = vm.binop(16, ltS(16))
+	vm.simdTable[ops.I16x8LtU] = vm.binop(16, ltU)
+	vm.simdTable[ops.I16x8GtS] = vm.binop(16, gtS(16))
+	vm.simdTable[ops.I16x8GtU] = vm.binop(16, gtU)
+	vm.simdTable[ops.I16x8LeS] = vm.binop(16, leS(16))
+	vm.simdTable[ops.I16x8LeU] = vm.binop(16, leU)
+	vm.simdTable[ops.I16x8GeS] = vm.binop(16, geS(16))
+	vm.simdTable[ops.I16x8GeU] = vm.binop(16, geU)
+	vm.simdTable[ops.I32x4Eq] = vm.binop(32, eq)
+	vm.simdTable[ops.I32x4Ne] = vm.binop(32, ne)
+	vm.simdTable[ops.I32x4LtS] = vm.binop(32, ltS(32))
+	vm.simdTable[ops.I32x4LtU] = vm.binop(32, ltU)
+	vm.simdTable[ops.I32x4GtS] = vm.binop(32, gtS(32))
+	vm.simdTable[ops.I32x4GtU] = vm.binop(32, gtU)
+	vm.simdTable[ops.I32x4LeS] = vm.binop(32, leS(32))
+	vm.simdTable[ops.I32x4LeU] = vm.binop(32, leU)
+	vm.simdTable[ops.I32x4GeS] = vm.binop(32, geS(32))
+	vm.simdTable[ops.I32x4GeU] = vm.binop(32, geU)
+	vm.simdTable[ops.F32x4Eq] = vm.binop(32, f32Cmp(func(x, y float32) bool { return x == y }))
+	vm.simdTable[ops.F32x4Ne] = vm.binop(32, f32Cmp(func(x, y float32) bool { return x != y }))
+	vm.simdTable[ops.F32x4Lt] = vm.binop(32, f32Cmp(func(x, y float32) bool { return x < y }))
+	vm.simdTable[ops.F32x4Gt] = vm.binop(32, f32Cmp(func(x, y float32) bool { return x > y }))
+	vm.simdTable[ops.F32x4Le] = vm.binop(32, f32Cmp(func(x, y float32) bool { return x <= y }))
+	vm.simdTable[ops.F32x4Ge] = vm.binop(32, f32Cmp(func(x, y float32) bool { return x >= y }))
+	vm.simdTable[ops.F64x2Eq] = vm.binop(64, f64Cmp(func(x, y float64) bool { return x == y }))
+	vm.simdTable[ops.F64x2Ne] = vm.binop(64, f64Cmp(func(x, y float64) bool { return x != y }))
+	vm.simdTable[ops.F64x2Lt] = vm.binop(64, f64Cmp(func(x, y float64) bool { return x < y }))
+	vm.simdTable[ops.F64x2Gt] = vm.binop(64, f64Cmp(func(x, y float64) bool { return x > y }))
+	vm.simdTable[ops.F64x2Le] = vm.binop(64, f64Cmp(func(x, y float64) bool { return x <= y }))
+	vm.simdTable[ops.F64x2Ge] = vm.binop(64, f64Cmp(func(x, y float64) bool { return x >= y }))
+	vm.simdTable[ops.V128Not] = vm.v128Not
+	vm.simdTable[ops.V128And] = vm.v128And
+	vm.simdTable[ops.V128AndNot] = vm.v128AndNot
+	vm.simdTable[ops.V128Or] = vm.v128Or
+	vm.simdTable[ops.V128Xor] = vm.v128Xor
+	vm.simdTable[ops.V128Bitselect] = vm.v128Bitselect
+	vm.simdTable[ops.V128AnyTrue] = vm.v128AnyTrue
+	vm.simdTable[ops.V128Load8Lane] = vm.loadLane(8)
+	vm.simdTable[ops.V128Load16Lane] = vm.loadLane(16)
+	vm.simdTable[ops.V128Load32Lane] = vm.loadLane(32)
+	vm.simdTable[ops.V128Load64Lane] = vm.loadLane(64)
+	vm.simdTable[ops.V128Store8Lane] = vm.storeLane(8)
+	vm.simdTable[ops.V128Store16Lane] = vm.storeLane(16)
+	vm.simdTable[ops.V128Store32Lane] = vm.storeLane(32)
+	vm.simdTable[ops.V128Store64Lane] = vm.storeLane(64)
+	vm.simdTable[ops.V128Load32Zero] = vm.loadZero(32)
+	vm.simdTable[ops.V128Load64Zero] = vm.loadZero(64)
+	vm.simdTable[ops.F32x4DemoteF64x2Zero] = vm.convert(64, 32, 0, func(x uint64) uint64 { return uint64(math.Float32bits(float32(math.Float64frombits(x)))) })
+	vm.simdTable[ops.F64x2PromoteLowF32x4] = vm.convert(32, 64, 0, func(x uint64) uint64 { return math.Float64bits(float64(math.Float32frombits(uint32(x)))) })
+	vm.simdTable[ops.I8x16Abs] = vm.unop(8, abs(8))
+	vm.simdTable[ops.I8x16Neg] = vm.unop(8, neg)
+	vm.simdTable[ops.I8x16Popcnt] = vm.unop(8, popcnt)
+	vm.simdTable[ops.I8x16AllTrue] = vm.allTrue(8)
+	vm.simdTable[ops.I8x16Bitmask] = vm.bitmask(8)
+	vm.simdTable[ops.I8x16NarrowI16x8S] = vm.narrow(8, true)
+	vm.simdTable[ops.I8x16NarrowI16x8U] = vm.narrow(8, false)
+	vm.simdTable[ops.F32x4Ceil] = vm.unop(32, f32Unop(f32(math.Ceil)))
+	vm.simdTable[ops.F32x4Floor] = vm.unop(32, f32Unop(f32(math.Floor)))
+	vm.simdTable[ops.F32x4Trunc] = vm.unop(32, f32Unop(f32(math.Trunc)))
+	vm.simdTable[ops.F32x4Nearest] = vm.unop(32, f32Unop(f32(math.RoundToEven)))
+	vm.simdTable[ops.I8x16Shl] = vm.shift(8, shl)
+	vm.simdTable[ops.I8x16ShrS] = vm.shift(8, shrS(8))
+	vm.simdTable[ops.I8x16ShrU] = vm.shift(8, shrU)
+	vm.simdTable[ops.I8x16Add] = vm.binop(8, add)
+	vm.simdTable[ops.I8x16AddSatS] = vm.binop(8, addSatS(8))
+	vm.simdTable[ops.I8x16AddSatU] = vm.binop(8, addSatU(8))
+	vm.simdTable[ops.I8x16Sub] = vm.binop(8, sub)
+	vm.simdTable[ops.I8x16SubSatS] = vm.binop(8, subSatS(8))
+	vm.simdTable[ops.I8x16SubSatU] = vm.binop(8, subSatU(8))
+	vm.simdTable[ops.F64x2Ceil] = vm.unop(64, f64Unop(math.Ceil))
+	vm.simdTable[ops.F64x2Floor] = vm.unop(64, f64Unop(math.Floor))
+	vm.simdTable[ops.I8x16MinS] = vm.binop(8, minS(8))
+	vm.simdTable[ops.I8x16MinU] = vm.binop(8, minU)
+	vm.simdTable[ops.I8x16MaxS] = vm.binop(8, maxS(8))
+	vm.simdTable[ops.I8x16MaxU] = vm.binop(8, maxU)
+	vm.simdTable[ops.F64x2Trunc] = vm.unop(64, f64Unop(math.Trunc))
+	vm.simdTable[ops.I8x16AvgrU] = vm.binop(8, avgrU)
+	vm.simdTable[ops.I16x8ExtaddPairwiseI8x16S] = vm.extaddPairwise(8, true)
+	vm.simdTable[ops.I16x8ExtaddPairwiseI8x16U] = vm.extaddPairwise(8, false)
+	vm.simdTable[ops.I32x4ExtaddPairwiseI16x8S] = vm.extaddPairwise(16, true)
+	vm.simdTable[ops.I32x4ExtaddPairwiseI16x8U] = vm.extaddPairwise(16, false)
+	vm.simdTable[ops.I16x8Abs] = vm.unop(16, abs(16))
+	vm.simdTable[ops.I16x8Neg] = vm.unop(16, neg)
+	vm.simdTable[ops.I16x8Q15mulrSatS] = vm.binop(16, q15mulrSatS)
+	vm.simdTable[ops.I16x8AllTrue] = vm.allTrue(16)
+	vm.simdTable[ops.I16x8Bitmask] = vm.bitmask(16)
+	vm.simdTable[ops.I16x8NarrowI32x4S] = vm.narrow(16, true)
+	vm.simdTable[ops.I16x8NarrowI32x4U] = vm.narrow(16, false)
+	vm.simdTable[ops.I16x8ExtendLowI8x16S] = vm.extend(8, 0, true)
+	vm.simdTable[ops.I16x8ExtendHighI8x16S] = vm.extend(8, 8, true)
+	vm.simdTable[ops.I16x8ExtendLowI8x16U] = vm.extend(8, 0, false)
+	vm.simdTable[ops.I16x8ExtendHighI8x16U] = vm.extend(8, 8, false)
+	vm.simdTable[ops.I16x8Shl] = vm.shift(16, shl)
+	vm.simdTable[ops.I16x8ShrS] = vm.shift(16, shrS(16))
+	vm.simdTable[ops.I16x8ShrU] = vm.shift(16, shrU)
+	vm.simdTable[ops.I16x8Add] = vm.binop(16, add)
+	vm.simdTable[ops.I16x8AddSatS] = vm.binop(16, addSatS(16))
+	vm.simdTable[ops.I16x8AddSatU] = vm.binop(16, addSatU(16))
+	vm.simdTable[ops.I16x8Sub] = vm.binop(16, sub)
+	vm.simdTable[ops.I16x8SubSatS] = vm.binop(16, subSatS(16))
+	vm.simdTable[ops.I16x8SubSatU] = vm.binop(16, subSatU(16))
+	vm.simdTable[ops.F64x2Nearest] = vm.unop(64, f64Unop(math.RoundToEven))
+	vm.simdTable[ops.I16x8Mul] = vm.binop(16, mul)
+	vm.simdTable[ops.I16x8MinS] = vm.binop(16, minS(16))
+	vm.simdTable[ops.I16x8MinU] = vm.binop(16, minU)
+	vm.simdTable[ops.I16x8MaxS] = vm.binop(16, maxS(16))
+	vm.simdTable[ops.I16x8MaxU] = vm.binop(16, maxU)
+	vm.simdTable[ops.I16x8AvgrU] = vm.binop(16, avgrU)
+	vm.simdTable[ops.I16x8ExtmulLowI8x16S] = vm.extmul(8, 0, true)
+	vm.simdTable[ops.I16x8ExtmulHighI8x16S] = vm.extmul(8, 8, true)
+	vm.simdTable[ops.I16x8ExtmulLowI8x16U] = vm.extmul(8, 0, false)
+	vm.simdTable[ops.I16x8ExtmulHighI8x16U] = vm.extmul(8, 8, false)
+	vm.simdTable[ops.I32x4Abs] = vm.unop(32, abs(32))
+	vm.simdTable[ops.I32x4Neg] = vm.unop(32, neg)
+	vm.simdTable[ops.I32x4AllTrue] = vm.allTrue(32)
+	vm.simdTable[ops.I32x4Bitmask] = vm.bitmask(32)
+	vm.simdTable[ops.I32x4ExtendLowI16x8S] = vm.extend(16, 0, true)
+	vm.simdTable[ops.I32x4ExtendHighI16x8S] = vm.extend(16, 4, true)
+	vm.simdTable[ops.I32x4ExtendLowI16x8U] = vm.extend(16, 0, false)
+	vm.simdTable[ops.I32x4ExtendHighI16x8U] = vm.extend(16, 4, false)
+	vm.simdTable[ops.I32x4Shl] = vm.shift(32, shl)
+	vm.simdTable[ops.I32x4ShrS] = vm.shift(32, shrS(32))
+	vm.simdTable[ops.I32x4ShrU] = vm.shift(32, shrU)
+	vm.simdTable[ops.I32x4Add] = vm.binop(32, add)
+	vm.simdTable[ops.I32x4Sub] = vm.binop(32, sub)
+	vm.simdTable[ops.I32x4Mul] = vm.binop(32, mul)
+	vm.simdTable[ops.I32x4MinS] = vm.binop(32, minS(32))
+	vm.simdTable[ops.I32x4MinU] = vm.binop(32, minU)
+	vm.simdTable[ops.I32x4MaxS] = vm.binop(32, maxS(32))
+	vm.simdTable[ops.I32x4MaxU] = vm.binop(32, maxU)
+	vm.simdTable[ops.I32x4DotI16x8S] = vm.i32x4DotI16x8S
+	vm.simdTable[ops.I32x4ExtmulLowI16x8S] = vm.extmul(16, 0, true)
+	vm.simdTable[ops.I32x4ExtmulHighI16x8S] = vm.extmul(16, 4, true)
+	vm.simdTable[ops.I32x4ExtmulLowI16x8U] = vm.extmul(16, 0, false)
+	vm.simdTable[ops.I32x4ExtmulHighI16x8U] = vm.extmul(16, 4, false)
+	vm.simdTable[ops.I64x2Abs] = vm.unop(64, abs(64))
+	vm.simdTable[ops.I64x2Neg] = vm.unop(64, neg)
+	vm.simdTable[ops.I64x2AllTrue] = vm.allTrue(64)
+	vm.simdTable[ops.I64x2Bitmask] = vm.bitmask(64)
+	vm.simdTable[ops.I64x2ExtendLowI32x4S] = vm.extend(32, 0, true)
+	vm.simdTable[ops.I64x2ExtendHighI32x4S] = vm.extend(32, 2, true)
+	vm.simdTable[ops.I64x2ExtendLowI32x4U] = vm.extend(32, 0, false)
+	vm.simdTable[ops.I64x2ExtendHighI32x4U] = vm.extend(32, 2, false)
+	vm.simdTable[ops.I64x2Shl] = vm.shift(64, shl)
+	vm.simdTable[ops.I64x2ShrS] = vm.shift(64, shrS(64))
+	vm.simdTable[ops.I64x2ShrU] = vm.shift(64, shrU)
+	vm.simdTable[ops.I64x2Add] = vm.binop(64, add)
+	vm.simdTable[ops.I64x2Sub] = vm.binop(64, sub)
+	vm.simdTable[ops.I64x2Mul] = vm.binop(64, mul)
+	vm.simdTable[ops.I64x2Eq] = vm.binop(64, eq)
+	vm.simdTable[ops.I64x2Ne] = vm.binop(64, ne)
+	vm.simdTable[ops.I64x2LtS] = vm.binop(64, ltS(64))
+	vm.simdTable[ops.I64x2GtS] = vm.binop(64, gtS(64))
+	vm.simdTable[ops.I64x2LeS] = vm.binop(64, leS(64))
+	vm.simdTable[ops.I64x2GeS] = vm.binop(64, geS(64))
+	vm.simdTable[ops.I64x2ExtmulLowI32x4S] = vm.extmul(32, 0, true)
+	vm.simdTable[ops.I64x2ExtmulHighI32x4S] = vm.extmul(32, 2, true)
+	vm.simdTable[ops.I64x2ExtmulLowI32x4U] = vm.extmul(32, 0, false)
+	vm.simdTable[ops.I64x2ExtmulHighI32x4U] = vm.extmul(32, 2, false)
+	vm.simdTable[ops.F32x4Abs] = vm.unop(32, func(x uint64) uint64 { return x &^ (1 << 31) })
+	vm.simdTable[ops.F32x4Neg] = vm.unop(32, func(x uint64) uint64 { return x ^ (1 << 31) })
+	vm.simdTable[ops.F32x4Sqrt] = vm.unop(32, f32Unop(f32(math.Sqrt)))
+	vm.simdTable[ops.F32x4Add] = vm.binop(32, f32Binop(func(x, y float32) float32 { return x + y }))
+	vm.simdTable[ops.F32x4Sub] = vm.binop(32, f32Binop(func(x, y float32) float32 { return x - y }))
+	vm.simdTable[ops.F32x4Mul] = vm.binop(32, f32Binop(func(x, y float32) float32 { return x * y }))
+	vm.simdTable[ops.F32x4Div] = vm.binop(32, f32Binop(func(x, y float32) float32 { return x / y }))
+	vm.simdTable[ops.F32x4Min] = vm.binop(32, f32Binop(f32x2(math.Min)))
+	vm.simdTable[ops.F32x4Max] = vm.binop(32, f32Binop(f32x2(math.Max)))
+	vm.simdTable[ops.F32x4Pmin] = vm.binop(32, f32Binop(f32x2(pmin)))
+	vm.simdTable[ops.F32x4Pmax] = vm.binop(32, f32Binop(f32x2(pmax)))
+	vm.simdTable[ops.F64x2Abs] = vm.unop(64, func(x uint64) uint64 { return x &^ (1 << 63) })
+	vm.simdTable[ops.F64x2Neg] = vm.unop(64, func(x uint64) uint64 { return x ^ (1 << 63) })
+	vm.simdTable[ops.F64x2Sqrt] = vm.unop(64, f64Unop(math.Sqrt))
+	vm.simdTable[ops.F64x2Add] = vm.binop(64, f64Binop(func(x, y float64) float64 { return x + y }))
+	vm.simdTable[ops.F64x2Sub] = vm.binop(64, f64Binop(func(x, y float64) float64 { return x - y }))
+	vm.simdTable[ops.F64x2Mul] = vm.binop(64, f64Binop(func(x, y float64) float64 { return x * y }))
+	vm.simdTable[ops.F64x2Div] = vm.binop(64, f64Binop(func(x, y float64) float64 { return x / y }))
+	vm.simdTable[ops.F64x2Min] = vm.binop(64, f64Binop(math.Min))
+	vm.simdTable[ops.F64x2Max] = vm.binop(64, f64Binop(math.Max))
+	vm.simdTable[ops.F64x2Pmin] = vm.binop(64, f64Binop(pmin))
+	vm.simdTable[ops.F64x2Pmax] = vm.binop(64, f64Binop(pmax))
+	vm.simdTable[ops.I32x4TruncSatF32x4S] = vm.convert(32, 32, 0, func(x uint64) uint64 {
+		return uint64(truncSatS(float64(math.Float32frombits(uint32(x))), math.MinInt32, math.MaxInt32))
+	})
+	vm.simdTable[ops.I32x4TruncSatF32x4U] = vm.convert(32, 32, 0, func(x uint64) uint64 { return truncSatU(float64(math.Float32frombits(uint32(x))), math.MaxUint32) })
+	vm.simdTable[ops.F32x4ConvertI32x4S] = vm.convert(32, 32, 0, func(x uint64) uint64 { return uint64(math.Float32bits(float32(int32(x)))) })
+	vm.simdTable[ops.F32x4ConvertI32x4U] = vm.convert(32, 32, 0, func(x uint64) uint64 { return uint64(math.Float32bits(float32(uint32(x)))) })
+	vm.simdTable[ops.I32x4TruncSatF64x2SZero] = vm.convert(64, 32, 0, func(x uint64) uint64 { return uint64(truncSatS(math.Float64frombits(x), math.MinInt32, math.MaxInt32)) })
+	vm.simdTable[ops.I32x4TruncSatF64x2UZero] = vm.convert(64, 32, 0, func(x uint64) uint64 { return truncSatU(math.Float64frombits(x), math.MaxUint32) })
+	vm.simdTable[ops.F64x2ConvertLowI32x4S] = vm.convert(32, 64, 0, func(x uint64) uint64 { return math.Float64bits(float64(int32(x))) })
+	vm.simdTable[ops.F64x2ConvertLowI32x4U] = vm.convert(32, 64, 0, func(x uint64) uint64 { return math.Float64bits(float64(uint32(x))) })
+
 	vm.funcTable[ops.I32Load] = vm.i32Load
 	vm.funcTable[ops.I64Load] = vm.i64Load
 	vm.funcTable[ops.F32Load] = vm.f32Load
@@ -171,6 +439,13 @@ func (vm *VM) newFuncTable() {
 
 	vm.funcTable[ops.Drop] = vm.drop
 	vm.funcTable[ops.Select] = vm.selectOp
+	vm.funcTable[ops.SelectT] = vm.selectOp
+
+	vm.funcTable[ops.TableGet] = vm.tableGet
+	vm.funcTable[ops.TableSet] = vm.tableSet
+	vm.funcTable[ops.RefNull] = vm.refNull
+	vm.funcTable[ops.RefIsNull] = vm.refIsNull
+	vm.funcTable[ops.RefFunc] = vm.refFunc
 
 	vm.funcTable[ops.GetLocal] = vm.getLocal
 	vm.funcTable[ops.SetLocal] = vm.setLocal
@@ -184,3 +459,10 @@ func (vm *VM) newFuncTable() {
 	vm.funcTable[ops.Call] = vm.call
 	vm.funcTable[ops.CallIndirect] = vm.callIndirect
 }
+
+// misc executes the operator following the 0xfc prefix.
+func (vm *VM) misc() {
+	op := vm.ctx.code[vm.ctx.pc]
+	vm.ctx.pc++
+	vm.miscTable[op]()
+}
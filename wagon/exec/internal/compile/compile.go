@@ -54,10 +54,10 @@ import (
 // Instead of creating a new stack every time we enter a control structure,
 // we record the current stack height on encountering a control operator.
 // After we leave the sequence, the stack height is restored using the discard
-// operator. A block with a signature will push a value of that type on the parent
-// stack (that is, the stack of the parent block where this block started). The
-// OpDiscardPreserveTop operator allows us to preserve this value while
-// discarding the remaining ones.
+// operator. A block with results will push them on the parent stack (that is,
+// the stack of the parent block where this block started). The
+// OpDiscardPreserveTop and OpDiscardPreserve operators allow us to preserve
+// these values while discarding the remaining ones.
 
 // Branches are rewritten as
 //     <jmp> <addr>
@@ -70,24 +70,28 @@ var (
 	// OpJmpZ jumps to the given address if the value at the top of the stack is zero.
 	OpJmpZ byte = 0x03
 	// OpJmpNz jumps to the given address if the value at the top of the
-	// stack is not zero. It also discards elements and optionally preserves
-	// the topmost value on the stack
+	// stack is not zero. It also discards elements while preserving a given
+	// number of values on the top of the stack.
 	OpJmpNz byte = 0x0d
 	// OpDiscard discards a given number of elements from the execution stack.
 	OpDiscard byte = 0x0b
 	// OpDiscardPreserveTop discards a given number of elements from the
 	// execution stack, while preserving the value on the top of the stack.
 	OpDiscardPreserveTop byte = 0x05
+	// OpDiscardPreserve discards a given number of elements from the
+	// execution stack, while preserving a given number of values on the top
+	// of the stack.
+	OpDiscardPreserve byte = 0x02
 )
 
 // Target is the "target" of a br_table instruction.
 // Unlike other control instructions, br_table does jumps and discarding all
 // by itself.
 type Target struct {
-	Addr        int64 // The absolute address of the target
-	Discard     int64 // The number of elements to discard
-	PreserveTop bool  // Whether the top of the stack is to be preserved
-	Return      bool  // Whether to return in order to take this branch/target
+	Addr     int64 // The absolute address of the target
+	Discard  int64 // The number of elements to discard
+	Preserve int64 // The number of values on the top of the stack to preserve
+	Return   bool  // Whether to return in order to take this branch/target
 }
 
 // BranchTable is the structure pointed to by a rewritten br_table instruction.
@@ -102,6 +106,13 @@ type BranchTable struct {
 	blocksLen     int      // The length of the blocks map in Compile when this table was initialized
 }
 
+// Offset maps an address in the compiled code to the offset of the
+// instruction it was compiled from in the bytecode of the function body.
+type Offset struct {
+	Addr   int64 // The address of the first compiled instruction
+	Offset int   // The offset of the original instruction
+}
+
 // block stores the information relevant for a block created by a control operator
 // sequence (if...else...end, loop...end, and block...end)
 type block struct {
@@ -131,12 +142,15 @@ type block struct {
 	branchTables []*BranchTable   // All branch tables that were defined in this block.
 }
 
-// Compile rewrites WebAssembly bytecode from its disassembly.
+// Compile rewrites WebAssembly bytecode from its disassembly. The returned
+// offsets are sorted by address, an instruction compiled to no code at all
+// shares the address of the next one.
 // TODO(vibhavp): Add options for optimizing code. Operators like i32.reinterpret/f32
 // are no-ops, and can be safely removed.
-func Compile(disassembly []disasm.Instr) ([]byte, []*BranchTable) {
+func Compile(disassembly []disasm.Instr) ([]byte, []*BranchTable, []Offset) {
 	buffer := new(bytes.Buffer)
 	branchTables := []*BranchTable{}
+	offsets := []Offset{}
 
 	curBlockDepth := -1
 	blocks := make(map[int]*block) // maps nesting depths (labels) to blocks
@@ -146,12 +160,28 @@ func Compile(disassembly []disasm.Instr) ([]byte, []*BranchTable) {
 		if instr.Unreachable {
 			continue
 		}
-		switch instr.Op.Code {
+		addr := int64(buffer.Len())
+		if n := len(offsets); n > 0 && offsets[n-1].Addr == addr {
+			offsets = offsets[:n-1]
+		}
+		offsets = append(offsets, Offset{Addr: addr, Offset: instr.Offset})
+		switch instr.Op.FirstByte() {
 		case ops.I32Load, ops.I64Load, ops.F32Load, ops.F64Load, ops.I32Load8s, ops.I32Load8u, ops.I32Load16s, ops.I32Load16u, ops.I64Load8s, ops.I64Load8u, ops.I64Load16s, ops.I64Load16u, ops.I64Load32s, ops.I64Load32u, ops.I32Store, ops.I64Store, ops.F32Store, ops.F64Store, ops.I32Store8, ops.I32Store16, ops.I64Store8, ops.I64Store16, ops.I64Store32:
 			// memory_immediate has two fields, the alignment and the offset.
 			// The former is simply an optimization hint and can be safely
 			// discarded.
 			instr.Immediates = []interface{}{instr.Immediates[1].(uint32)}
+		case ops.SelectT:
+			// the types of the operands only matter to validation
+			instr.Immediates = nil
+		case ops.SIMDPrefix:
+			// likewise, only the offset of the memory_immediate of the
+			// SIMD memory operators, their uint32 immediates, is kept
+			if len(instr.Immediates) > 1 {
+				if _, ok := instr.Immediates[0].(uint32); ok {
+					instr.Immediates = instr.Immediates[1:]
+				}
+			}
 		case ops.If:
 			curBlockDepth++
 			buffer.WriteByte(OpJmpZ)
@@ -184,12 +214,7 @@ func Compile(disassembly []disasm.Instr) ([]byte, []*BranchTable) {
 			ifInstr := disassembly[instr.Block.ElseIfIndex] // the corresponding `if` instruction for this else
 			if ifInstr.NewStack != nil && ifInstr.NewStack.StackTopDiff != 0 {
 				// add code for jumping out of a taken if branch
-				if ifInstr.NewStack.PreserveTop {
-					buffer.WriteByte(OpDiscardPreserveTop)
-				} else {
-					buffer.WriteByte(OpDiscard)
-				}
-				binary.Write(buffer, binary.LittleEndian, ifInstr.NewStack.StackTopDiff)
+				writeDiscard(buffer, ifInstr.NewStack)
 			}
 			buffer.WriteByte(OpJmp)
 			ifBlockEndOffset := int64(buffer.Len())
@@ -210,16 +235,9 @@ func Compile(disassembly []disasm.Instr) ([]byte, []*BranchTable) {
 
 			if instr.NewStack.StackTopDiff != 0 {
 				// when exiting a block, discard elements to
-				// restore stack height.
-				if instr.NewStack.PreserveTop {
-					// this is true when the block has a
-					// signature, and therefore pushes
-					// a value on to the stack
-					buffer.WriteByte(OpDiscardPreserveTop)
-				} else {
-					buffer.WriteByte(OpDiscard)
-				}
-				binary.Write(buffer, binary.LittleEndian, instr.NewStack.StackTopDiff)
+				// restore stack height, preserving the results
+				// of the block.
+				writeDiscard(buffer, instr.NewStack)
 			}
 
 			if !block.loopBlock { // is a normal block
@@ -244,12 +262,7 @@ func Compile(disassembly []disasm.Instr) ([]byte, []*BranchTable) {
 			continue
 		case ops.Br:
 			if instr.NewStack != nil && instr.NewStack.StackTopDiff != 0 {
-				if instr.NewStack.PreserveTop {
-					buffer.WriteByte(OpDiscardPreserveTop)
-				} else {
-					buffer.WriteByte(OpDiscard)
-				}
-				binary.Write(buffer, binary.LittleEndian, instr.NewStack.StackTopDiff)
+				writeDiscard(buffer, instr.NewStack)
 			}
 			buffer.WriteByte(OpJmp)
 			label := int(instr.Immediates[0].(uint32))
@@ -266,15 +279,14 @@ func Compile(disassembly []disasm.Instr) ([]byte, []*BranchTable) {
 			// write the jump address
 			binary.Write(buffer, binary.LittleEndian, int64(0))
 
-			var stackTopDiff int64
-			// write whether we need to preserve the top
-			if instr.NewStack == nil || !instr.NewStack.PreserveTop || instr.NewStack.StackTopDiff == 0 {
-				buffer.WriteByte(byte(0))
-			} else {
+			var preserve, stackTopDiff int64
+			if instr.NewStack != nil {
+				preserve = instr.NewStack.Preserve
 				stackTopDiff = instr.NewStack.StackTopDiff
-				buffer.WriteByte(byte(1))
 			}
-			// write the number of elements on the stack we need to discard
+			// write the number of values on the top of the stack to
+			// preserve, and the number of elements to discard
+			binary.Write(buffer, binary.LittleEndian, preserve)
 			binary.Write(buffer, binary.LittleEndian, stackTopDiff)
 			continue
 		case ops.BrTable:
@@ -293,14 +305,14 @@ func Compile(disassembly []disasm.Instr) ([]byte, []*BranchTable) {
 
 				branchTable.Targets[i].Return = branch.IsReturn
 				branchTable.Targets[i].Discard = branch.StackTopDiff
-				branchTable.Targets[i].PreserveTop = branch.PreserveTop
+				branchTable.Targets[i].Preserve = branch.Preserve
 			}
 			defaultLabel := int64(instr.Immediates[len(instr.Immediates)-1].(uint32))
 			branchTable.DefaultTarget.Addr = defaultLabel
 			defaultBranch := instr.Branches[targetCount]
 			branchTable.DefaultTarget.Return = defaultBranch.IsReturn
 			branchTable.DefaultTarget.Discard = defaultBranch.StackTopDiff
-			branchTable.DefaultTarget.PreserveTop = defaultBranch.PreserveTop
+			branchTable.DefaultTarget.Preserve = defaultBranch.Preserve
 			branchTables = append(branchTables, branchTable)
 			for _, block := range blocks {
 				block.branchTables = append(block.branchTables, branchTable)
@@ -310,6 +322,9 @@ func Compile(disassembly []disasm.Instr) ([]byte, []*BranchTable) {
 			binary.Write(buffer, binary.LittleEndian, int64(len(branchTables)-1))
 		}
 
+		if instr.Op.Prefix != 0 {
+			buffer.WriteByte(instr.Op.Prefix)
+		}
 		buffer.WriteByte(instr.Op.Code)
 		for _, imm := range instr.Immediates {
 			err := binary.Write(buffer, binary.LittleEndian, imm)
@@ -333,7 +348,21 @@ func Compile(disassembly []disasm.Instr) ([]byte, []*BranchTable) {
 	for _, table := range branchTables {
 		table.patchedAddrs = nil
 	}
-	return buffer.Bytes(), branchTables
+	return buffer.Bytes(), branchTables, offsets
+}
+
+// writeDiscard writes the operator unwinding the stack as described by info.
+func writeDiscard(buffer *bytes.Buffer, info *disasm.StackInfo) {
+	switch info.Preserve {
+	case 0:
+		buffer.WriteByte(OpDiscard)
+	case 1:
+		buffer.WriteByte(OpDiscardPreserveTop)
+	default:
+		buffer.WriteByte(OpDiscardPreserve)
+		binary.Write(buffer, binary.LittleEndian, info.Preserve)
+	}
+	binary.Write(buffer, binary.LittleEndian, info.StackTopDiff)
 }
 
 // replace the address starting at start with addr
@@ -20,14 +20,14 @@ func (vm *VM) fetchBaseAddr() int {
 // inBounds returns true when the next vm.fetchBaseAddr() + offset
 // indices are in bounds accesses to the linear memory.
 func (vm *VM) inBounds(offset int) bool {
-	addr := endianess.Uint32(vm.ctx.code[vm.ctx.pc:]) + uint32(vm.ctx.stack[len(vm.ctx.stack)-1])
-	return int(addr)+offset < len(vm.memory)
+	addr := endianess.Uint32(vm.ctx.code[vm.ctx.pc:]) + uint32(vm.stack[len(vm.stack)-1])
+	return int(addr)+offset < len(vm.memory.bytes)
 }
 
 // curMem returns a slice to the memeory segment pointed to by
 // the current base address on the bytecode stream.
 func (vm *VM) curMem() []byte {
-	return vm.memory[vm.fetchBaseAddr():]
+	return vm.memory.bytes[vm.fetchBaseAddr():]
 }
 
 func (vm *VM) i32Load() {
@@ -41,14 +41,14 @@ func (vm *VM) i32Load8s() {
 	if !vm.inBounds(0) {
 		panic(ErrOutOfBoundsMemoryAccess)
 	}
-	vm.pushInt32(int32(int8(vm.memory[vm.fetchBaseAddr()])))
+	vm.pushInt32(int32(int8(vm.memory.bytes[vm.fetchBaseAddr()])))
 }
 
 func (vm *VM) i32Load8u() {
 	if !vm.inBounds(0) {
 		panic(ErrOutOfBoundsMemoryAccess)
 	}
-	vm.pushUint32(uint32(uint8(vm.memory[vm.fetchBaseAddr()])))
+	vm.pushUint32(uint32(uint8(vm.memory.bytes[vm.fetchBaseAddr()])))
 }
 
 func (vm *VM) i32Load16s() {
@@ -76,14 +76,14 @@ func (vm *VM) i64Load8s() {
 	if !vm.inBounds(0) {
 		panic(ErrOutOfBoundsMemoryAccess)
 	}
-	vm.pushInt64(int64(int8(vm.memory[vm.fetchBaseAddr()])))
+	vm.pushInt64(int64(int8(vm.memory.bytes[vm.fetchBaseAddr()])))
 }
 
 func (vm *VM) i64Load8u() {
 	if !vm.inBounds(0) {
 		panic(ErrOutOfBoundsMemoryAccess)
 	}
-	vm.pushUint64(uint64(uint8(vm.memory[vm.fetchBaseAddr()])))
+	vm.pushUint64(uint64(uint8(vm.memory.bytes[vm.fetchBaseAddr()])))
 }
 
 func (vm *VM) i64Load16s() {
@@ -157,7 +157,7 @@ func (vm *VM) i32Store8() {
 	if !vm.inBounds(0) {
 		panic(ErrOutOfBoundsMemoryAccess)
 	}
-	vm.memory[vm.fetchBaseAddr()] = v
+	vm.memory.bytes[vm.fetchBaseAddr()] = v
 }
 
 func (vm *VM) i32Store16() {
@@ -181,7 +181,7 @@ func (vm *VM) i64Store8() {
 	if !vm.inBounds(0) {
 		panic(ErrOutOfBoundsMemoryAccess)
 	}
-	vm.memory[vm.fetchBaseAddr()] = v
+	vm.memory.bytes[vm.fetchBaseAddr()] = v
 }
 
 func (vm *VM) i64Store16() {
@@ -202,13 +202,13 @@ func (vm *VM) i64Store32() {
 
 func (vm *VM) currentMemory() {
 	_ = vm.fetchInt8() // reserved (https://github.com/WebAssembly/design/blob/27ac254c854994103c24834a994be16f74f54186/BinaryEncoding.md#memory-related-operators-described-here)
-	vm.pushInt32(int32(len(vm.memory) / wasmPageSize))
+	vm.pushInt32(int32(len(vm.memory.bytes) / wasmPageSize))
 }
 
 func (vm *VM) growMemory() {
 	_ = vm.fetchInt8() // reserved (https://github.com/WebAssembly/design/blob/27ac254c854994103c24834a994be16f74f54186/BinaryEncoding.md#memory-related-operators-described-here)
-	curLen := len(vm.memory) / wasmPageSize
+	curLen := len(vm.memory.bytes) / wasmPageSize
 	n := vm.popInt32()
-	vm.memory = append(vm.memory, make([]byte, n*wasmPageSize)...)
+	vm.memory.bytes = append(vm.memory.bytes, make([]byte, n*wasmPageSize)...)
 	vm.pushInt32(int32(curLen))
 }
@@ -5,7 +5,7 @@
 package exec
 
 func (vm *VM) drop() {
-	vm.ctx.stack = vm.ctx.stack[:len(vm.ctx.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
 }
 
 func (vm *VM) selectOp() {
@@ -17,5 +17,9 @@ func (vm *VM) selectOp() {
 		vm.pushUint64(val1)
 	} else {
 		vm.pushUint64(val2)
+		if vm.simd {
+			top := len(vm.stack) - 1
+			vm.setHigh(top, vm.high(top+1))
+		}
 	}
 }
@@ -0,0 +1,667 @@
+// Copyright 2017 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package exec
+
+import (
+	"math"
+	"math/bits"
+)
+
+// v128 is a vector value, its low and high 64 bits. Lane i of a shape whose
+// lanes have n bits is made of the bits [i*n, (i+1)*n) of the vector, whose
+// bytes are in little endian order like in memory.
+//
+// A v128 operand takes a single slot of the stack, holding its low half, its
+// high half being kept at the same index of the highs of the VM. Likewise
+// for the locals and globals. Only the VMs of modules enabling the SIMD
+// feature keep highs, and the other operands leave stale values in them.
+type v128 [2]uint64
+
+// lane returns lane i of the vector, whose lanes have n bits.
+func (v v128) lane(n, i uint) uint64 {
+	per := 64 / n
+	x := v[i/per] >> (n * (i % per))
+	if n < 64 {
+		x &= 1<<n - 1
+	}
+	return x
+}
+
+// setLane sets lane i of the vector, whose lanes have n bits, to the low n
+// bits of x.
+func (v *v128) setLane(n, i uint, x uint64) {
+	per := 64 / n
+	shift := n * (i % per)
+	mask := ^uint64(0)
+	if n < 64 {
+		mask = 1<<n - 1
+	}
+	v[i/per] = v[i/per]&^(mask<<shift) | (x&mask)<<shift
+}
+
+// sext sign-extends the n-bit value x.
+func sext(n uint, x uint64) int64 {
+	return int64(x<<(64-n)) >> (64 - n)
+}
+
+// satS saturates v to a signed integer of n bits.
+func satS(n uint, v int64) uint64 {
+	min, max := int64(-1)<<(n-1), int64(1)<<(n-1)-1
+	switch {
+	case v < min:
+		return uint64(min)
+	case v > max:
+		return uint64(max)
+	}
+	return uint64(v)
+}
+
+// satU saturates v to an unsigned integer of n bits.
+func satU(n uint, v int64) uint64 {
+	switch {
+	case v < 0:
+		return 0
+	case v > 1<<n-1:
+		return 1<<n - 1
+	}
+	return uint64(v)
+}
+
+func boolMask(b bool) uint64 {
+	if b {
+		return ^uint64(0)
+	}
+	return 0
+}
+
+// high returns the high half of the operand at index i of the stack.
+func (vm *VM) high(i int) uint64 {
+	if i < len(vm.highs) {
+		return vm.highs[i]
+	}
+	return 0
+}
+
+// setHigh sets the high half of the operand at index i of the stack.
+func (vm *VM) setHigh(i int, h uint64) {
+	if n := i + 1; n > len(vm.highs) {
+		vm.highs = append(vm.highs, make([]uint64, n-len(vm.highs))...)
+	}
+	vm.highs[i] = h
+}
+
+func (vm *VM) pushV128(v v128) {
+	vm.pushUint64(v[0])
+	vm.setHigh(len(vm.stack)-1, v[1])
+}
+
+func (vm *VM) popV128() v128 {
+	h := vm.high(len(vm.stack) - 1)
+	return v128{vm.popUint64(), h}
+}
+
+func (vm *VM) fetchV128() v128 {
+	v := v128{endianess.Uint64(vm.ctx.code[vm.ctx.pc:]), endianess.Uint64(vm.ctx.code[vm.ctx.pc+8:])}
+	vm.ctx.pc += 16
+	return v
+}
+
+func (vm *VM) fetchLane() uint {
+	lane := vm.ctx.code[vm.ctx.pc]
+	vm.ctx.pc++
+	return uint(lane)
+}
+
+// vector executes the operator following the 0xfd prefix.
+func (vm *VM) vector() {
+	op := vm.ctx.code[vm.ctx.pc]
+	vm.ctx.pc++
+	vm.simdTable[op]()
+}
+
+// vectorAddr pops the base address of a SIMD memory operator, fetches its
+// offset and returns the effective address of an access of n bytes.
+func (vm *VM) vectorAddr(n uint64) uint64 {
+	addr := uint64(vm.fetchUint32()) + uint64(vm.popUint32())
+	if addr+n > uint64(len(vm.memory.bytes)) {
+		panic(ErrOutOfBoundsMemoryAccess)
+	}
+	return addr
+}
+
+func (vm *VM) v128Load() {
+	addr := vm.vectorAddr(16)
+	mem := vm.memory.bytes[addr:]
+	vm.pushV128(v128{endianess.Uint64(mem), endianess.Uint64(mem[8:])})
+}
+
+func (vm *VM) v128Store() {
+	v := vm.popV128()
+	addr := vm.vectorAddr(16)
+	mem := vm.memory.bytes[addr:]
+	endianess.PutUint64(mem, v[0])
+	endianess.PutUint64(mem[8:], v[1])
+}
+
+// loadExtend returns v128.load8x8_s, etc.: it loads 64 bits and extends
+// their lanes of n bits to 2*n bits.
+func (vm *VM) loadExtend(n uint, signed bool) func() {
+	return func() {
+		addr := vm.vectorAddr(8)
+		in := v128{endianess.Uint64(vm.memory.bytes[addr:])}
+		var v v128
+		for i := uint(0); i < 64/n; i++ {
+			x := in.lane(n, i)
+			if signed {
+				x = uint64(sext(n, x))
+			}
+			v.setLane(2*n, i, x)
+		}
+		vm.pushV128(v)
+	}
+}
+
+// loadSplat returns v128.load8_splat, etc.: it loads n bits into all the
+// lanes.
+func (vm *VM) loadSplat(n uint) func() {
+	return func() {
+		addr := vm.vectorAddr(uint64(n / 8))
+		x := vm.loadBits(addr, n)
+		var v v128
+		for i := uint(0); i < 128/n; i++ {
+			v.setLane(n, i, x)
+		}
+		vm.pushV128(v)
+	}
+}
+
+// loadZero returns v128.load32_zero and v128.load64_zero: it loads n bits
+// into the first lane, the others being zero.
+func (vm *VM) loadZero(n uint) func() {
+	return func() {
+		addr := vm.vectorAddr(uint64(n / 8))
+		var v v128
+		v.setLane(n, 0, vm.loadBits(addr, n))
+		vm.pushV128(v)
+	}
+}
+
+// loadLane returns v128.load8_lane, etc.: it loads n bits into a lane of
+// the vector operand.
+func (vm *VM) loadLane(n uint) func() {
+	return func() {
+		v := vm.popV128()
+		addr := vm.vectorAddr(uint64(n / 8))
+		v.setLane(n, vm.fetchLane(), vm.loadBits(addr, n))
+		vm.pushV128(v)
+	}
+}
+
+// storeLane returns v128.store8_lane, etc.: it stores a lane of n bits.
+func (vm *VM) storeLane(n uint) func() {
+	return func() {
+		v := vm.popV128()
+		addr := vm.vectorAddr(uint64(n / 8))
+		x := v.lane(n, vm.fetchLane())
+		mem := vm.memory.bytes[addr:]
+		for i := uint(0); i < n/8; i++ {
+			mem[i] = byte(x >> (8 * i))
+		}
+	}
+}
+
+// loadBits returns the n bits at addr, which must be in bounds.
+func (vm *VM) loadBits(addr uint64, n uint) uint64 {
+	var x uint64
+	mem := vm.memory.bytes[addr:]
+	for i := uint(0); i < n/8; i++ {
+		x |= uint64(mem[i]) << (8 * i)
+	}
+	return x
+}
+
+func (vm *VM) v128Const() {
+	vm.pushV128(vm.fetchV128())
+}
+
+func (vm *VM) i8x16Shuffle() {
+	lanes := vm.fetchV128()
+	b := vm.popV128()
+	a := vm.popV128()
+	var v v128
+	for i := uint(0); i < 16; i++ {
+		j := uint(lanes.lane(8, i))
+		if j < 16 {
+			v.setLane(8, i, a.lane(8, j))
+		} else {
+			v.setLane(8, i, b.lane(8, j-16))
+		}
+	}
+	vm.pushV128(v)
+}
+
+func (vm *VM) i8x16Swizzle() {
+	s := vm.popV128()
+	a := vm.popV128()
+	var v v128
+	for i := uint(0); i < 16; i++ {
+		if j := uint(s.lane(8, i)); j < 16 {
+			v.setLane(8, i, a.lane(8, j))
+		}
+	}
+	vm.pushV128(v)
+}
+
+// splat returns i8x16.splat, etc.: it sets all the lanes of n bits to the
+// scalar operand.
+func (vm *VM) splat(n uint) func() {
+	return func() {
+		x := vm.popUint64()
+		var v v128
+		for i := uint(0); i < 128/n; i++ {
+			v.setLane(n, i, x)
+		}
+		vm.pushV128(v)
+	}
+}
+
+// extractLane returns i8x16.extract_lane_s, etc.: it pushes a lane of n
+// bits, sign-extended to 32 bits if signed.
+func (vm *VM) extractLane(n uint, signed bool) func() {
+	return func() {
+		x := vm.popV128().lane(n, vm.fetchLane())
+		if signed {
+			x = uint64(uint32(sext(n, x)))
+		}
+		vm.pushUint64(x)
+	}
+}
+
+// replaceLane returns i8x16.replace_lane, etc.: it sets a lane of n bits to
+// the scalar operand.
+func (vm *VM) replaceLane(n uint) func() {
+	return func() {
+		x := vm.popUint64()
+		v := vm.popV128()
+		v.setLane(n, vm.fetchLane(), x)
+		vm.pushV128(v)
+	}
+}
+
+// unop returns an operator applying f to the lanes of n bits of its operand.
+func (vm *VM) unop(n uint, f func(x uint64) uint64) func() {
+	return func() {
+		a := vm.popV128()
+		var v v128
+		for i := uint(0); i < 128/n; i++ {
+			v.setLane(n, i, f(a.lane(n, i)))
+		}
+		vm.pushV128(v)
+	}
+}
+
+// binop returns an operator applying f to the lanes of n bits of its
+// operands.
+func (vm *VM) binop(n uint, f func(x, y uint64) uint64) func() {
+	return func() {
+		b := vm.popV128()
+		a := vm.popV128()
+		var v v128
+		for i := uint(0); i < 128/n; i++ {
+			v.setLane(n, i, f(a.lane(n, i), b.lane(n, i)))
+		}
+		vm.pushV128(v)
+	}
+}
+
+// shift returns an operator shifting the lanes of n bits of its operand by
+// the scalar operand modulo n.
+func (vm *VM) shift(n uint, f func(x uint64, s uint) uint64) func() {
+	return func() {
+		s := uint(vm.popUint32()) % n
+		a := vm.popV128()
+		var v v128
+		for i := uint(0); i < 128/n; i++ {
+			v.setLane(n, i, f(a.lane(n, i), s))
+		}
+		vm.pushV128(v)
+	}
+}
+
+// allTrue returns i8x16.all_true, etc.
+func (vm *VM) allTrue(n uint) func() {
+	return func() {
+		a := vm.popV128()
+		for i := uint(0); i < 128/n; i++ {
+			if a.lane(n, i) == 0 {
+				vm.pushBool(false)
+				return
+			}
+		}
+		vm.pushBool(true)
+	}
+}
+
+// bitmask returns i8x16.bitmask, etc.: it pushes the sign bits of the lanes
+// of n bits.
+func (vm *VM) bitmask(n uint) func() {
+	return func() {
+		a := vm.popV128()
+		var mask uint32
+		for i := uint(0); i < 128/n; i++ {
+			mask |= uint32(a.lane(n, i)>>(n-1)) << i
+		}
+		vm.pushUint32(mask)
+	}
+}
+
+// narrow returns i8x16.narrow_i16x8_s, etc.: it saturates the lanes of 2*n
+// bits of both operands to n bits.
+func (vm *VM) narrow(n uint, signed bool) func() {
+	return func() {
+		b := vm.popV128()
+		a := vm.popV128()
+		half := 64 / n
+		var v v128
+		for i := uint(0); i < half; i++ {
+			for j, in := range [2]v128{a, b} {
+				x := sext(2*n, in.lane(2*n, i))
+				if signed {
+					v.setLane(n, uint(j)*half+i, satS(n, x))
+				} else {
+					v.setLane(n, uint(j)*half+i, satU(n, x))
+				}
+			}
+		}
+		vm.pushV128(v)
+	}
+}
+
+// convert returns an operator setting the lanes of m bits of its result to
+// f of the lanes of n bits of its operand, starting with lane first. The
+// lanes beyond the ones of the operand are zero.
+func (vm *VM) convert(n, m, first uint, f func(x uint64) uint64) func() {
+	count := 128 / n
+	if 128/m < count {
+		count = 128 / m
+	}
+	return func() {
+		a := vm.popV128()
+		var v v128
+		for i := uint(0); i < count; i++ {
+			v.setLane(m, i, f(a.lane(n, first+i)))
+		}
+		vm.pushV128(v)
+	}
+}
+
+// extend returns i16x8.extend_low_i8x16_s, etc.: it extends lanes of n bits
+// to 2*n bits, starting with lane first.
+func (vm *VM) extend(n, first uint, signed bool) func() {
+	return vm.convert(n, 2*n, first, func(x uint64) uint64 {
+		if signed {
+			return uint64(sext(n, x))
+		}
+		return x
+	})
+}
+
+// extmul returns i16x8.extmul_low_i8x16_s, etc.: it multiplies the lanes of
+// n bits of its operands, starting with lane first, into lanes of 2*n bits.
+func (vm *VM) extmul(n, first uint, signed bool) func() {
+	return func() {
+		b := vm.popV128()
+		a := vm.popV128()
+		var v v128
+		for i := uint(0); i < 64/n; i++ {
+			x, y := a.lane(n, first+i), b.lane(n, first+i)
+			if signed {
+				v.setLane(2*n, i, uint64(sext(n, x)*sext(n, y)))
+			} else {
+				v.setLane(2*n, i, x*y)
+			}
+		}
+		vm.pushV128(v)
+	}
+}
+
+// extaddPairwise returns i16x8.extadd_pairwise_i8x16_s, etc.: it adds the
+// pairs of adjacent lanes of n bits into lanes of 2*n bits.
+func (vm *VM) extaddPairwise(n uint, signed bool) func() {
+	return func() {
+		a := vm.popV128()
+		var v v128
+		for i := uint(0); i < 64/n; i++ {
+			x, y := a.lane(n, 2*i), a.lane(n, 2*i+1)
+			if signed {
+				v.setLane(2*n, i, uint64(sext(n, x)+sext(n, y)))
+			} else {
+				v.setLane(2*n, i, x+y)
+			}
+		}
+		vm.pushV128(v)
+	}
+}
+
+func (vm *VM) i32x4DotI16x8S() {
+	b := vm.popV128()
+	a := vm.popV128()
+	var v v128
+	for i := uint(0); i < 4; i++ {
+		x := sext(16, a.lane(16, 2*i)) * sext(16, b.lane(16, 2*i))
+		y := sext(16, a.lane(16, 2*i+1)) * sext(16, b.lane(16, 2*i+1))
+		v.setLane(32, i, uint64(x+y))
+	}
+	vm.pushV128(v)
+}
+
+func (vm *VM) v128Not() {
+	a := vm.popV128()
+	vm.pushV128(v128{^a[0], ^a[1]})
+}
+
+func (vm *VM) v128And() {
+	b := vm.popV128()
+	a := vm.popV128()
+	vm.pushV128(v128{a[0] & b[0], a[1] & b[1]})
+}
+
+func (vm *VM) v128AndNot() {
+	b := vm.popV128()
+	a := vm.popV128()
+	vm.pushV128(v128{a[0] &^ b[0], a[1] &^ b[1]})
+}
+
+func (vm *VM) v128Or() {
+	b := vm.popV128()
+	a := vm.popV128()
+	vm.pushV128(v128{a[0] | b[0], a[1] | b[1]})
+}
+
+func (vm *VM) v128Xor() {
+	b := vm.popV128()
+	a := vm.popV128()
+	vm.pushV128(v128{a[0] ^ b[0], a[1] ^ b[1]})
+}
+
+func (vm *VM) v128Bitselect() {
+	c := vm.popV128()
+	b := vm.popV128()
+	a := vm.popV128()
+	vm.pushV128(v128{a[0]&c[0] | b[0]&^c[0], a[1]&c[1] | b[1]&^c[1]})
+}
+
+func (vm *VM) v128AnyTrue() {
+	a := vm.popV128()
+	vm.pushBool(a[0]|a[1] != 0)
+}
+
+// Lane functions of the integer operators, the lanes being the low n bits
+// of their arguments and results.
+
+func add(x, y uint64) uint64 { return x + y }
+func sub(x, y uint64) uint64 { return x - y }
+func mul(x, y uint64) uint64 { return x * y }
+func neg(x uint64) uint64    { return -x }
+func eq(x, y uint64) uint64  { return boolMask(x == y) }
+func ne(x, y uint64) uint64  { return boolMask(x != y) }
+func ltU(x, y uint64) uint64 { return boolMask(x < y) }
+func gtU(x, y uint64) uint64 { return boolMask(x > y) }
+func leU(x, y uint64) uint64 { return boolMask(x <= y) }
+func geU(x, y uint64) uint64 { return boolMask(x >= y) }
+
+func minU(x, y uint64) uint64 {
+	if x < y {
+		return x
+	}
+	return y
+}
+
+func maxU(x, y uint64) uint64 {
+	if x > y {
+		return x
+	}
+	return y
+}
+
+func avgrU(x, y uint64) uint64 { return (x + y + 1) / 2 }
+
+func shl(x uint64, s uint) uint64  { return x << s }
+func shrU(x uint64, s uint) uint64 { return x >> s }
+
+func popcnt(x uint64) uint64 { return uint64(bits.OnesCount64(x)) }
+
+// The lane functions of the signed integer operators and of the saturating
+// ones depend on the number of bits n of the lanes.
+
+func ltS(n uint) func(x, y uint64) uint64 {
+	return func(x, y uint64) uint64 { return boolMask(sext(n, x) < sext(n, y)) }
+}
+
+func gtS(n uint) func(x, y uint64) uint64 {
+	return func(x, y uint64) uint64 { return boolMask(sext(n, x) > sext(n, y)) }
+}
+
+func leS(n uint) func(x, y uint64) uint64 {
+	return func(x, y uint64) uint64 { return boolMask(sext(n, x) <= sext(n, y)) }
+}
+
+func geS(n uint) func(x, y uint64) uint64 {
+	return func(x, y uint64) uint64 { return boolMask(sext(n, x) >= sext(n, y)) }
+}
+
+func minS(n uint) func(x, y uint64) uint64 {
+	return func(x, y uint64) uint64 {
+		if sext(n, x) < sext(n, y) {
+			return x
+		}
+		return y
+	}
+}
+
+func maxS(n uint) func(x, y uint64) uint64 {
+	return func(x, y uint64) uint64 {
+		if sext(n, x) > sext(n, y) {
+			return x
+		}
+		return y
+	}
+}
+
+func abs(n uint) func(x uint64) uint64 {
+	return func(x uint64) uint64 {
+		if sext(n, x) < 0 {
+			return -x
+		}
+		return x
+	}
+}
+
+func shrS(n uint) func(x uint64, s uint) uint64 {
+	return func(x uint64, s uint) uint64 { return uint64(sext(n, x) >> s) }
+}
+
+func addSatS(n uint) func(x, y uint64) uint64 {
+	return func(x, y uint64) uint64 { return satS(n, sext(n, x)+sext(n, y)) }
+}
+
+func addSatU(n uint) func(x, y uint64) uint64 {
+	return func(x, y uint64) uint64 { return satU(n, int64(x+y)) }
+}
+
+func subSatS(n uint) func(x, y uint64) uint64 {
+	return func(x, y uint64) uint64 { return satS(n, sext(n, x)-sext(n, y)) }
+}
+
+func subSatU(n uint) func(x, y uint64) uint64 {
+	return func(x, y uint64) uint64 { return satU(n, int64(x)-int64(y)) }
+}
+
+func q15mulrSatS(x, y uint64) uint64 {
+	return satS(16, (sext(16, x)*sext(16, y)+0x4000)>>15)
+}
+
+// Lane functions of the floating point operators.
+
+func f32Unop(f func(x float32) float32) func(x uint64) uint64 {
+	return func(x uint64) uint64 {
+		return uint64(math.Float32bits(f(math.Float32frombits(uint32(x)))))
+	}
+}
+
+func f32Binop(f func(x, y float32) float32) func(x, y uint64) uint64 {
+	return func(x, y uint64) uint64 {
+		return uint64(math.Float32bits(f(math.Float32frombits(uint32(x)), math.Float32frombits(uint32(y)))))
+	}
+}
+
+func f32Cmp(f func(x, y float32) bool) func(x, y uint64) uint64 {
+	return func(x, y uint64) uint64 {
+		return boolMask(f(math.Float32frombits(uint32(x)), math.Float32frombits(uint32(y))))
+	}
+}
+
+func f64Unop(f func(x float64) float64) func(x uint64) uint64 {
+	return func(x uint64) uint64 {
+		return math.Float64bits(f(math.Float64frombits(x)))
+	}
+}
+
+func f64Binop(f func(x, y float64) float64) func(x, y uint64) uint64 {
+	return func(x, y uint64) uint64 {
+		return math.Float64bits(f(math.Float64frombits(x), math.Float64frombits(y)))
+	}
+}
+
+func f64Cmp(f func(x, y float64) bool) func(x, y uint64) uint64 {
+	return func(x, y uint64) uint64 {
+		return boolMask(f(math.Float64frombits(x), math.Float64frombits(y)))
+	}
+}
+
+// f32 returns the float64 lane function f as a float32 one.
+func f32(f func(x float64) float64) func(x float32) float32 {
+	return func(x float32) float32 { return float32(f(float64(x))) }
+}
+
+// f32x2 returns the float64 lane function f of two arguments as a float32
+// one.
+func f32x2(f func(x, y float64) float64) func(x, y float32) float32 {
+	return func(x, y float32) float32 { return float32(f(float64(x), float64(y))) }
+}
+
+func pmin(x, y float64) float64 {
+	if y < x {
+		return y
+	}
+	return x
+}
+
+func pmax(x, y float64) float64 {
+	if x < y {
+		return y
+	}
+	return x
+}
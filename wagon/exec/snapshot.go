@@ -0,0 +1,432 @@
+// Copyright 2017 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package exec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync/atomic"
+
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+)
+
+// snapshotVersion is the version of the encoding written by (*VM).Snapshot.
+const snapshotVersion = 4
+
+// maxMemoryPages is the number of pages of the largest linear memory a 32 bit
+// address can index, the maximum of the memories declaring none.
+const maxMemoryPages = 1 << 16
+
+var (
+	// ErrNotPaused is returned by (*VM).Snapshot and (*VM).Resume when the
+	// execution of the VM is not paused.
+	ErrNotPaused = errors.New("exec: execution is not paused")
+	// ErrInvalidSnapshot is returned by (*VM).Restore when the snapshot is
+	// malformed or doesn't match the module of the VM.
+	ErrInvalidSnapshot = errors.New("exec: invalid snapshot")
+)
+
+// UnsupportedSnapshotVersionError is returned by (*VM).Restore when the
+// snapshot was encoded with an unknown version.
+type UnsupportedSnapshotVersionError uint8
+
+func (e UnsupportedSnapshotVersionError) Error() string {
+	return fmt.Sprintf("exec: unsupported snapshot version: %d", uint8(e))
+}
+
+// Pause requests the execution to pause. It may be called from any
+// goroutine. The execution stops at the next backward branch or function
+// call and ExecCode or Resume return ErrPaused. The state of the paused VM
+// can then be saved with Snapshot, or the execution continued with Resume.
+func (vm *VM) Pause() {
+	atomic.StoreInt32(&vm.pause, 1)
+}
+
+// Paused reports whether the execution of the VM is paused.
+func (vm *VM) Paused() bool {
+	return vm.paused
+}
+
+// Resume continues a paused execution, either paused by Pause or restored
+// by Restore, and returns like ExecCode.
+func (vm *VM) Resume() (rtrn interface{}, err error) {
+	defer vm.recoverPanic(&err)
+	if !vm.paused {
+		return nil, ErrNotPaused
+	}
+	vm.paused = false
+	return vm.run()
+}
+
+// Snapshot returns the encoded state of a paused execution: the linear
+// memory, the globals, the tables, the dropped segments and, for every function being executed, its operand
+// stack, locals and program counter, with the high halves of their v128 values. A VM created from the same module can
+// continue the execution after restoring the snapshot with Restore.
+//
+// Host functions are not part of the snapshot, any state they keep must be
+// saved separately. Neither are the other instances of the store of the VM,
+// only the memory, globals and tables it shares with them.
+func (vm *VM) Snapshot() ([]byte, error) {
+	if !vm.paused {
+		return nil, ErrNotPaused
+	}
+	var buf bytes.Buffer
+	w := snapshotWriter{&buf}
+
+	buf.WriteByte(snapshotVersion)
+	w.uvarint(uint64(vm.entry))
+	w.uvarint(uint64(len(vm.memory.bytes)))
+	buf.Write(vm.memory.bytes)
+	globals := make([]uint64, len(vm.globals))
+	for i, global := range vm.globals {
+		globals[i] = *global
+	}
+	w.uint64s(globals)
+	var globalHighs []uint64
+	for _, high := range vm.globalHighs {
+		if high != nil {
+			globalHighs = append(globalHighs, *high)
+		}
+	}
+	w.uint64s(globalHighs)
+	w.uvarint(uint64(len(vm.tables)))
+	for _, t := range vm.tables {
+		w.uint32s(t.elems)
+	}
+	w.bools(vm.dataDropped)
+	w.bools(vm.elemDropped)
+
+	frames := append(vm.frames[:len(vm.frames):len(vm.frames)], vm.ctx)
+	w.uvarint(uint64(len(frames)))
+	for i, frame := range frames {
+		// the operands of a frame end where the next frame's begin
+		top := len(vm.stack)
+		if i+1 < len(frames) {
+			top = frames[i+1].stackBase
+		}
+		w.uvarint(uint64(frame.curFunc))
+		w.uvarint(uint64(frame.pc))
+		w.uint64s(vm.localStack[frame.localBase : frame.localBase+len(frame.locals)])
+		w.uint64s(vm.stack[frame.stackBase:top])
+		var localHighs, highs []uint64
+		if vm.simd {
+			localHighs = vm.localHighs[frame.localBase : frame.localBase+len(frame.locals)]
+			for i := frame.stackBase; i < top; i++ {
+				highs = append(highs, vm.high(i))
+			}
+		}
+		w.uint64s(localHighs)
+		w.uint64s(highs)
+	}
+	return buf.Bytes(), nil
+}
+
+// Restore replaces the state of the VM with the snapshot, which must have
+// been taken from a VM of the same module. The VM is left paused, so that
+// Resume continues the execution.
+func (vm *VM) Restore(snapshot []byte) error {
+	if len(snapshot) == 0 {
+		return ErrInvalidSnapshot
+	}
+	if snapshot[0] != snapshotVersion {
+		return UnsupportedSnapshotVersionError(snapshot[0])
+	}
+	r := snapshotReader{data: snapshot[1:]}
+
+	entry := int64(r.uvarint())
+	if err := vm.compileLazy(entry); err != nil {
+		return ErrInvalidSnapshot
+	}
+	if _, ok := vm.compiled(entry); !ok {
+		return ErrInvalidSnapshot
+	}
+	memory := r.bytes(r.uvarint())
+	if len(memory)%wasmPageSize != 0 || !validLength(uint64(len(memory)/wasmPageSize), vm.memory.limits, maxMemoryPages) {
+		return ErrInvalidSnapshot
+	}
+	globals := r.uint64s()
+	globalHighs := r.uint64s()
+	if r.uvarint() != uint64(len(vm.tables)) {
+		return ErrInvalidSnapshot
+	}
+	tables := make([][]uint32, len(vm.tables))
+	for i := range tables {
+		tables[i] = r.uint32s()
+		if r.err == nil && !vm.validElems(vm.tables[i], tables[i]) {
+			return ErrInvalidSnapshot
+		}
+	}
+	dataDropped := r.bools()
+	elemDropped := r.bools()
+	v128Globals := 0
+	for _, high := range vm.globalHighs {
+		if high != nil {
+			v128Globals++
+		}
+	}
+	if r.err != nil || len(globals) != len(vm.globals) || len(globalHighs) != v128Globals ||
+		len(dataDropped) != len(vm.dataDropped) || len(elemDropped) != len(vm.elemDropped) {
+		return ErrInvalidSnapshot
+	}
+
+	n := r.uvarint()
+	if n == 0 || n > uint64(len(r.data)) {
+		return ErrInvalidSnapshot
+	}
+	var (
+		frames     = make([]context, n)
+		stack      []uint64
+		localStack []uint64
+		highs      []uint64
+		localHighs []uint64
+	)
+	for i := range frames {
+		index := int64(r.uvarint())
+		pc := r.uvarint()
+		locals := r.uint64s()
+		operands := r.uint64s()
+		frameLocalHighs := r.uint64s()
+		frameHighs := r.uint64s()
+		if r.err != nil {
+			return ErrInvalidSnapshot
+		}
+		if vm.simd && (len(frameLocalHighs) != len(locals) || len(frameHighs) != len(operands)) ||
+			!vm.simd && (len(frameLocalHighs) != 0 || len(frameHighs) != 0) {
+			return ErrInvalidSnapshot
+		}
+		if err := vm.compileLazy(index); err != nil {
+			return ErrInvalidSnapshot
+		}
+		compiled, ok := vm.compiled(index)
+		if !ok || !compiled.validPC(pc) || len(locals) != compiled.totalLocalVars || len(operands) > compiled.maxDepth {
+			return ErrInvalidSnapshot
+		}
+		frames[i] = context{
+			code:      compiled.code,
+			pc:        int64(pc),
+			curFunc:   index,
+			stackBase: len(stack),
+			localBase: len(localStack),
+		}
+		stack = append(stack, operands...)
+		localStack = append(localStack, locals...)
+		highs = append(highs, frameHighs...)
+		localHighs = append(localHighs, frameLocalHighs...)
+	}
+	for i := range frames {
+		base := frames[i].localBase
+		end := len(localStack)
+		if i+1 < len(frames) {
+			end = frames[i+1].localBase
+		}
+		frames[i].locals = localStack[base:end:end]
+	}
+	if frames[0].curFunc != entry || len(r.data) != 0 {
+		return ErrInvalidSnapshot
+	}
+
+	vm.entry = entry
+	vm.memory.bytes = append([]byte(nil), memory...)
+	for i, global := range globals {
+		*vm.globals[i] = global
+	}
+	for _, high := range vm.globalHighs {
+		if high != nil {
+			*high, globalHighs = globalHighs[0], globalHighs[1:]
+		}
+	}
+	// the tables may have grown since the snapshot
+	for i, elems := range tables {
+		vm.tables[i].elems = append([]uint32(nil), elems...)
+	}
+	copy(vm.dataDropped, dataDropped)
+	copy(vm.elemDropped, elemDropped)
+	vm.stack = stack
+	vm.localStack = localStack
+	vm.highs = highs
+	vm.localHighs = localHighs
+	vm.frames = frames[:n-1]
+	vm.ctx = frames[n-1]
+	vm.abort = false
+	vm.depth, vm.invoked = 0, 0
+	vm.paused = true
+	return nil
+}
+
+// compiled returns the compiled function at index.
+func (vm *VM) compiled(index int64) (compiledFunction, bool) {
+	if index < 0 || index >= int64(len(vm.funcs)) {
+		return compiledFunction{}, false
+	}
+	compiled, ok := vm.funcs[index].(compiledFunction)
+	return compiled, ok
+}
+
+// validPC reports whether pc is the address of an instruction of the
+// function, or of its end, the only ones an execution is paused at.
+func (compiled compiledFunction) validPC(pc uint64) bool {
+	if pc == uint64(len(compiled.code)) {
+		return true
+	}
+	offsets := compiled.offsets
+	i := sort.Search(len(offsets), func(i int) bool {
+		return uint64(offsets[i].Addr) >= pc
+	})
+	return i < len(offsets) && uint64(offsets[i].Addr) == pc
+}
+
+// validElems reports whether elems can be the elements of the table t: its
+// length is within the limits of t and, for a table of functions, every
+// element is null or the index, or the address in the store, of a function.
+func (vm *VM) validElems(t *table, elems []uint32) bool {
+	if !validLength(uint64(len(elems)), t.limits, math.MaxUint32) {
+		return false
+	}
+	if t.typ != wasm.ElemTypeAnyFunc {
+		return true
+	}
+	funcs := len(vm.funcs)
+	if vm.store != nil {
+		funcs = len(vm.store.funcs)
+	}
+	for _, elem := range elems {
+		if elem != wasm.NullElement && int64(elem) >= int64(funcs) {
+			return false
+		}
+	}
+	return true
+}
+
+// validLength reports whether n is within limits, or up to max if they don't
+// declare a maximum.
+func validLength(n uint64, limits wasm.ResizableLimits, max uint64) bool {
+	if limits.Flags&1 != 0 {
+		max = uint64(limits.Maximum)
+	}
+	return n >= uint64(limits.Initial) && n <= max
+}
+
+type snapshotWriter struct {
+	buf *bytes.Buffer
+}
+
+func (w snapshotWriter) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (w snapshotWriter) uint64s(vs []uint64) {
+	w.uvarint(uint64(len(vs)))
+	var b [8]byte
+	for _, v := range vs {
+		endianess.PutUint64(b[:], v)
+		w.buf.Write(b[:])
+	}
+}
+
+func (w snapshotWriter) uint32s(vs []uint32) {
+	w.uvarint(uint64(len(vs)))
+	for _, v := range vs {
+		w.uvarint(uint64(v))
+	}
+}
+
+func (w snapshotWriter) bools(vs []bool) {
+	w.uvarint(uint64(len(vs)))
+	for _, v := range vs {
+		if v {
+			w.buf.WriteByte(1)
+		} else {
+			w.buf.WriteByte(0)
+		}
+	}
+}
+
+// snapshotReader decodes a snapshot. The first error is kept in err, after
+// which all reads return zero values.
+type snapshotReader struct {
+	data []byte
+	err  error
+}
+
+func (r *snapshotReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = ErrInvalidSnapshot
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *snapshotReader) bytes(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > uint64(len(r.data)) {
+		r.err = ErrInvalidSnapshot
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *snapshotReader) uint64s() []uint64 {
+	n := r.uvarint()
+	if r.err == nil && n > uint64(len(r.data))/8 {
+		r.err = ErrInvalidSnapshot
+	}
+	b := r.bytes(n * 8)
+	if r.err != nil {
+		return nil
+	}
+	vs := make([]uint64, n)
+	for i := range vs {
+		vs[i] = endianess.Uint64(b[i*8:])
+	}
+	return vs
+}
+
+func (r *snapshotReader) uint32s() []uint32 {
+	n := r.uvarint()
+	if r.err == nil && n > uint64(len(r.data)) {
+		r.err = ErrInvalidSnapshot
+	}
+	if r.err != nil {
+		return nil
+	}
+	vs := make([]uint32, n)
+	for i := range vs {
+		v := r.uvarint()
+		if v > math.MaxUint32 {
+			r.err = ErrInvalidSnapshot
+		}
+		vs[i] = uint32(v)
+	}
+	return vs
+}
+
+func (r *snapshotReader) bools() []bool {
+	b := r.bytes(r.uvarint())
+	if r.err != nil {
+		return nil
+	}
+	vs := make([]bool, len(b))
+	for i, v := range b {
+		if v > 1 {
+			r.err = ErrInvalidSnapshot
+		}
+		vs[i] = v == 1
+	}
+	return vs
+}
@@ -0,0 +1,411 @@
+// Copyright 2017 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package exec
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+)
+
+var (
+	// ErrUnknownInstance is the cause of an ImportError when no instance is
+	// registered in the store under the module name of the import.
+	ErrUnknownInstance = errors.New("exec: unknown instance")
+	// ErrIncompatibleImport is the cause of an ImportError when the type or
+	// the limits of the export don't match the ones of the import.
+	ErrIncompatibleImport = errors.New("exec: incompatible import type")
+	// ErrForeignInstance is returned by (*Store).Register when the VM is an
+	// instance of another store.
+	ErrForeignInstance = errors.New("exec: instance of another store")
+)
+
+// ImportError is returned by (*Store).Instantiate when an import of the
+// module can't be linked to the exports of the instances of the store.
+type ImportError struct {
+	ModuleName string
+	FieldName  string
+	Err        error // the cause of the error
+}
+
+func (e ImportError) Error() string {
+	return fmt.Sprintf("exec: cannot import %s.%s: %v", e.ModuleName, e.FieldName, e.Err)
+}
+
+// Store holds instances of modules linked together. The memories, tables
+// and globals an instance imports are shared with the instance exporting
+// them, and the functions it imports are executed in the context of the
+// instance exporting them, with its memory, tables and globals. The host
+// functions an instance imports are executed in its own context.
+//
+// The functions of the instances have an address in the store, which the
+// funcref values of the instances hold, so that a function stored in a table by an
+// instance can be called indirectly by another.
+//
+// The settings of a VM, such as MaxCallDepth or its cancel flag, apply to the
+// functions executed in its context, whichever instance calls them. An
+// execution can only be paused while it doesn't call another instance.
+type Store struct {
+	instances map[string]*VM
+	funcs     []funcRef // functions by address
+}
+
+// funcRef is the function at index in the function index space of vm.
+type funcRef struct {
+	vm    *VM
+	index uint32
+}
+
+// NewStore creates an empty store.
+func NewStore() *Store {
+	return &Store{instances: make(map[string]*VM)}
+}
+
+// Instance returns the instance registered under name, nil if there is none.
+func (s *Store) Instance(name string) *VM {
+	return s.instances[name]
+}
+
+// Register registers vm under name, for the modules instantiated afterwards
+// to import its exports. vm is either an instance of the store or created by
+// NewVM, in which case it becomes one: its functions are given an address
+// and the elements of its tables of functions, and its funcref globals, are
+// replaced by their addresses.
+func (s *Store) Register(name string, vm *VM) error {
+	if vm.store != nil && vm.store != s {
+		return ErrForeignInstance
+	}
+	if vm.store == nil {
+		vm.store = s
+		vm.funcAddrs = make([]uint32, len(vm.funcs))
+		for i := range vm.funcs {
+			vm.funcAddrs[i] = s.addFunc(vm, i)
+		}
+		for _, t := range vm.tables {
+			if t.typ == wasm.ElemTypeExternRef {
+				continue
+			}
+			for i, elem := range t.elems {
+				if elem != wasm.NullElement {
+					t.elems[i] = vm.funcAddrs[elem]
+				}
+			}
+		}
+		for i, global := range vm.module.GlobalIndexSpace {
+			if global.Type.Type == wasm.ValueTypeFuncref && uint32(*vm.globals[i]) != wasm.NullElement {
+				*vm.globals[i] = uint64(vm.funcAddrs[*vm.globals[i]])
+			}
+		}
+	}
+	s.instances[name] = vm
+	return nil
+}
+
+// Instantiate creates an instance of module and registers it under name,
+// unless name is empty. The module is usually read by wasm.ReadModule
+// without a resolver: its imports are linked to the exports of the
+// instances registered under their module name. The active segments of the
+// module are then copied to the tables and linear memory of the instance,
+// imported or not, and its start function is executed.
+func (s *Store) Instantiate(name string, module *wasm.Module) (*VM, error) {
+	vm := &VM{
+		module: module,
+		memory: &linearMemory{},
+		store:  s,
+	}
+
+	var memories int
+	if module.Import != nil {
+		for _, entry := range module.Import.Entries {
+			if err := vm.link(entry); err != nil {
+				return nil, ImportError{entry.ModuleName, entry.FieldName, err}
+			}
+			if entry.Type.Kind() == wasm.ExternalMemory {
+				memories++
+			}
+		}
+	}
+
+	if module.Memory != nil {
+		for _, entry := range module.Memory.Entries {
+			if memories++; memories > 1 {
+				return nil, ErrMultipleLinearMemories
+			}
+			vm.memory = &linearMemory{
+				bytes:  make([]byte, uint(entry.Limits.Initial)*wasmPageSize),
+				limits: entry.Limits,
+			}
+		}
+	}
+	if module.Table != nil {
+		for _, entry := range module.Table.Entries {
+			t := &table{
+				elems:  make([]uint32, entry.Limits.Initial),
+				limits: entry.Limits,
+				typ:    entry.ElementType,
+			}
+			for i := range t.elems {
+				t.elems[i] = wasm.NullElement
+			}
+			vm.tables = append(vm.tables, t)
+		}
+	}
+
+	imported := len(vm.funcs)
+	if err := vm.compile(vm.funcs); err != nil {
+		return nil, err
+	}
+	for i := imported; i < len(vm.funcs); i++ {
+		vm.funcAddrs = append(vm.funcAddrs, s.addFunc(vm, i))
+	}
+	if err := vm.initGlobals(vm.globals); err != nil {
+		return nil, err
+	}
+	if err := vm.initSegments(); err != nil {
+		return nil, err
+	}
+
+	if module.Start != nil {
+		if _, err := vm.ExecCode(int64(module.Start.Index)); err != nil {
+			return nil, err
+		}
+	}
+	if name != "" {
+		s.instances[name] = vm
+	}
+	return vm, nil
+}
+
+// addFunc gives the function at index of vm an address in the store.
+func (s *Store) addFunc(vm *VM, index int) uint32 {
+	s.funcs = append(s.funcs, funcRef{vm, uint32(index)})
+	return uint32(len(s.funcs) - 1)
+}
+
+// link links an import of the module of vm to the export of the instance it
+// names, adding it to the index spaces of vm.
+func (vm *VM) link(entry wasm.ImportEntry) error {
+	exporter := vm.store.instances[entry.ModuleName]
+	if exporter == nil {
+		return ErrUnknownInstance
+	}
+	var (
+		export wasm.ExportEntry
+		ok     bool
+	)
+	if exporter.module.Export != nil {
+		export, ok = exporter.module.Export.Entries[entry.FieldName]
+	}
+	if !ok {
+		return wasm.ExportNotFoundError{ModuleName: entry.ModuleName, FieldName: entry.FieldName}
+	}
+	if export.Kind != entry.Type.Kind() {
+		return wasm.KindMismatchError{
+			ModuleName: entry.ModuleName,
+			FieldName:  entry.FieldName,
+			Import:     entry.Type.Kind(),
+			Export:     export.Kind,
+		}
+	}
+
+	index := export.Index
+	switch imp := entry.Type.(type) {
+	case wasm.FuncImport:
+		if vm.module.Types == nil || int(imp.Type) >= len(vm.module.Types.Entries) {
+			return wasm.InvalidFunctionIndexError(imp.Type)
+		}
+		if int(index) >= len(exporter.funcs) {
+			return wasm.InvalidFunctionIndexError(index)
+		}
+		if !sameSig(&vm.module.Types.Entries[imp.Type], exporter.module.FunctionIndexSpace[index].Sig) {
+			return ErrIncompatibleImport
+		}
+		switch fn := exporter.funcs[index].(type) {
+		case compiledFunction, lazyFunction:
+			vm.funcs = append(vm.funcs, importedFunction{exporter, index})
+			vm.funcAddrs = append(vm.funcAddrs, exporter.funcAddrs[index])
+		case importedFunction:
+			vm.funcs = append(vm.funcs, fn)
+			vm.funcAddrs = append(vm.funcAddrs, exporter.funcAddrs[index])
+		default:
+			// host functions are executed in the context of the
+			// instance calling them
+			vm.funcs = append(vm.funcs, fn)
+			vm.funcAddrs = append(vm.funcAddrs, vm.store.addFunc(vm, len(vm.funcs)-1))
+		}
+	case wasm.GlobalVarImport:
+		global := exporter.module.GetGlobal(int(index))
+		if global == nil {
+			return wasm.InvalidGlobalIndexError(index)
+		}
+		if global.Type != imp.Type {
+			return ErrIncompatibleImport
+		}
+		vm.globals = append(vm.globals, exporter.globals[index])
+		vm.globalHighs = append(vm.globalHighs, exporter.globalHighs[index])
+	case wasm.MemoryImport:
+		if index != 0 {
+			return wasm.InvalidLinearMemoryIndexError(index)
+		}
+		size := uint64(len(exporter.memory.bytes)) / wasmPageSize
+		if !matchLimits(size, exporter.memory.limits, imp.Type.Limits) {
+			return ErrIncompatibleImport
+		}
+		vm.memory = exporter.memory
+	case wasm.TableImport:
+		if int(index) >= len(exporter.tables) {
+			return wasm.InvalidTableIndexError(index)
+		}
+		t := exporter.tables[index]
+		if t.typ != imp.Type.ElementType || !matchLimits(uint64(len(t.elems)), t.limits, imp.Type.Limits) {
+			return ErrIncompatibleImport
+		}
+		vm.tables = append(vm.tables, t)
+	default:
+		return wasm.InvalidExternalError(entry.Type.Kind())
+	}
+	return nil
+}
+
+// matchLimits reports whether a memory or table of the given size and
+// limits can be imported with the limits of the import.
+func matchLimits(size uint64, limits, imported wasm.ResizableLimits) bool {
+	if size < uint64(imported.Initial) {
+		return false
+	}
+	if imported.Flags&1 == 0 {
+		return true
+	}
+	return limits.Flags&1 != 0 && limits.Maximum <= imported.Maximum
+}
+
+// initSegments copies the active segments of the module of vm to its tables
+// and linear memory, in order.
+func (vm *VM) initSegments() error {
+	if vm.module.Elements != nil {
+		for _, entry := range vm.module.Elements.Entries {
+			if entry.Passive || entry.Declarative {
+				continue
+			}
+			if int(entry.Index) >= len(vm.tables) {
+				return wasm.InvalidTableIndexError(entry.Index)
+			}
+			t := vm.tables[entry.Index]
+			offset, err := vm.initExpr(entry.Offset)
+			if err != nil {
+				return err
+			}
+			offset = uint64(uint32(offset))
+			if offset+uint64(len(entry.Elems)) > uint64(len(t.elems)) {
+				return ErrOutOfBoundsTableAccess
+			}
+			for i, elem := range entry.Elems {
+				if elem == wasm.NullElement {
+					t.elems[offset+uint64(i)] = elem
+					continue
+				}
+				if int(elem) >= len(vm.funcAddrs) {
+					return wasm.InvalidFunctionIndexError(elem)
+				}
+				t.elems[offset+uint64(i)] = vm.funcAddrs[elem]
+			}
+		}
+	}
+	if vm.module.Data != nil {
+		for _, entry := range vm.module.Data.Entries {
+			if entry.Passive {
+				continue
+			}
+			offset, err := vm.initExpr(entry.Offset)
+			if err != nil {
+				return err
+			}
+			offset = uint64(uint32(offset))
+			if offset+uint64(len(entry.Data)) > uint64(len(vm.memory.bytes)) {
+				return ErrOutOfBoundsMemoryAccess
+			}
+			copy(vm.memory.bytes[offset:], entry.Data)
+		}
+	}
+	return nil
+}
+
+// callInstance calls the function at index of the instance callee, popping
+// its arguments from the stack of vm and pushing its results.
+func (vm *VM) callInstance(callee *VM, index uint32) {
+	args := len(vm.stack) - len(callee.module.FunctionIndexSpace[index].Sig.ParamTypes)
+	var highs []uint64
+	if vm.simd {
+		for i := args; i < len(vm.stack); i++ {
+			highs = append(highs, vm.high(i))
+		}
+	}
+	res, resHighs := callee.invoke(index, vm.stack[args:], highs, vm.depth+len(vm.frames)+1)
+	vm.stack = append(vm.stack[:args], res...)
+	if vm.simd {
+		for i, h := range resHighs {
+			vm.setHigh(args+i, h)
+		}
+	}
+	if callee.abort {
+		callee.abort = false
+		vm.abort = true
+	}
+}
+
+// invoke executes the function at index with args, on top of the execution
+// of vm in progress if any, at the given depth of calls. The high halves of
+// the v128 arguments are in highs, if any. It returns the results of the
+// function and their high halves, which are only valid until vm is executed
+// again.
+func (vm *VM) invoke(index uint32, args, highs []uint64, depth int) ([]uint64, []uint64) {
+	base := len(vm.stack)
+	vm.stack = append(vm.stack, args...)
+	if vm.simd {
+		for i, h := range highs {
+			vm.setHigh(base+i, h)
+		}
+	}
+	if err := vm.compileLazy(int64(index)); err != nil {
+		panic(err)
+	}
+	compiled, ok := vm.funcs[index].(compiledFunction)
+	if !ok {
+		vm.funcs[index].call(vm, int64(index))
+		res := vm.stack[base:]
+		vm.stack = vm.stack[:base]
+		return res, nil
+	}
+	if depth >= vm.maxCallDepth() {
+		panic(ErrCallStackExhausted)
+	}
+
+	frames, locals, outer := len(vm.frames), len(vm.localStack), vm.depth
+	vm.frames = append(vm.frames, vm.ctx)
+	vm.depth = depth - len(vm.frames)
+	vm.invoked++
+	vm.enter(compiled, int64(index))
+	vm.poll()
+	res := vm.execCode(len(vm.frames))
+	vm.invoked--
+	vm.depth = outer
+
+	// return to the execution in progress
+	vm.ctx = vm.frames[frames]
+	vm.frames = vm.frames[:frames]
+	vm.localStack = vm.localStack[:locals]
+	if vm.ctx.code != nil {
+		vm.ctx.locals = vm.localStack[vm.ctx.localBase:locals:locals]
+	}
+	var resHighs []uint64
+	if vm.simd {
+		for i := range res {
+			resHighs = append(resHighs, vm.high(base+i))
+		}
+	}
+	vm.stack = vm.stack[:base]
+	return res, resHighs
+}
@@ -0,0 +1,139 @@
+// Copyright 2018 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package exec
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+)
+
+// storeLib is a module exporting the function 0 loading an i32 from its
+// memory, the function 1 incrementing and returning its mutable global, the
+// global, initialized to 100, its memory, holding 42 at address 0, and its
+// table, holding the function 2 returning 7 at index 0.
+var storeLib = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	// () -> i32, (i32) -> i32
+	0x01, 0x0a, 0x02, 0x60, 0x00, 0x01, 0x7f, 0x60, 0x01, 0x7f, 0x01, 0x7f,
+	0x03, 0x04, 0x03, 0x01, 0x00, 0x00,
+	0x04, 0x04, 0x01, 0x70, 0x00, 0x02,
+	0x05, 0x03, 0x01, 0x00, 0x01,
+	// (global (mut i32) (i32.const 100))
+	0x06, 0x07, 0x01, 0x7f, 0x01, 0x41, 0xe4, 0x00, 0x0b,
+	// "load", "bump", "counter", "mem" and "tab"
+	0x07, 0x25, 0x05,
+	0x04, 0x6c, 0x6f, 0x61, 0x64, 0x00, 0x00, 0x04, 0x62, 0x75, 0x6d, 0x70, 0x00, 0x01,
+	0x07, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x65, 0x72, 0x03, 0x00,
+	0x03, 0x6d, 0x65, 0x6d, 0x02, 0x00, 0x03, 0x74, 0x61, 0x62, 0x01, 0x00,
+	// (elem (i32.const 0) 2)
+	0x09, 0x07, 0x01, 0x00, 0x41, 0x00, 0x0b, 0x01, 0x02,
+	0x0a, 0x1a, 0x03,
+	// (i32.load (get_local 0))
+	0x07, 0x00, 0x20, 0x00, 0x28, 0x02, 0x00, 0x0b,
+	// (set_global 0 (i32.add (get_global 0) (i32.const 1))) (get_global 0)
+	0x0b, 0x00, 0x23, 0x00, 0x41, 0x01, 0x6a, 0x24, 0x00, 0x23, 0x00, 0x0b,
+	// (i32.const 7)
+	0x04, 0x00, 0x41, 0x07, 0x0b,
+	// (data (i32.const 0) "\2a")
+	0x0b, 0x07, 0x01, 0x00, 0x41, 0x00, 0x0b, 0x01, 0x2a,
+}
+
+// storeMain is a module importing the exports of storeLib. Its function 2
+// stores its argument at address 4, the function 3 returns 8 and is put in
+// the table at index 1, the function 4 calls the function at its argument
+// in the table and the function 5 calls the imported function 1 and returns
+// the imported global. It stores 99 at address 8.
+var storeMain = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	// () -> i32, (i32) -> i32, (i32) -> ()
+	0x01, 0x0e, 0x03, 0x60, 0x00, 0x01, 0x7f, 0x60, 0x01, 0x7f, 0x01, 0x7f, 0x60, 0x01, 0x7f, 0x00,
+	// "lib" "load", "lib" "bump", "lib" "counter", "lib" "mem" and "lib" "tab"
+	0x02, 0x3d, 0x05,
+	0x03, 0x6c, 0x69, 0x62, 0x04, 0x6c, 0x6f, 0x61, 0x64, 0x00, 0x01,
+	0x03, 0x6c, 0x69, 0x62, 0x04, 0x62, 0x75, 0x6d, 0x70, 0x00, 0x00,
+	0x03, 0x6c, 0x69, 0x62, 0x07, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x65, 0x72, 0x03, 0x7f, 0x01,
+	0x03, 0x6c, 0x69, 0x62, 0x03, 0x6d, 0x65, 0x6d, 0x02, 0x00, 0x01,
+	0x03, 0x6c, 0x69, 0x62, 0x03, 0x74, 0x61, 0x62, 0x01, 0x70, 0x00, 0x02,
+	0x03, 0x05, 0x04, 0x02, 0x00, 0x01, 0x00,
+	// (elem (i32.const 1) 3)
+	0x09, 0x07, 0x01, 0x00, 0x41, 0x01, 0x0b, 0x01, 0x03,
+	0x0a, 0x20, 0x04,
+	// (i32.store (i32.const 4) (get_local 0))
+	0x09, 0x00, 0x41, 0x04, 0x20, 0x00, 0x36, 0x02, 0x00, 0x0b,
+	// (i32.const 8)
+	0x04, 0x00, 0x41, 0x08, 0x0b,
+	// (call_indirect 0 (get_local 0))
+	0x07, 0x00, 0x20, 0x00, 0x11, 0x00, 0x00, 0x0b,
+	// (drop (call 1)) (get_global 0)
+	0x07, 0x00, 0x10, 0x01, 0x1a, 0x23, 0x00, 0x0b,
+	// (data (i32.const 8) "\63")
+	0x0b, 0x07, 0x01, 0x00, 0x41, 0x08, 0x0b, 0x01, 0x63,
+}
+
+func TestStore(t *testing.T) {
+	lib, err := wasm.ReadModule(bytes.NewReader(storeLib), nil)
+	if err != nil {
+		t.Fatalf("Could not read module: %v", err)
+	}
+	main, err := wasm.ReadModule(bytes.NewReader(storeMain), nil)
+	if err != nil {
+		t.Fatalf("Could not read module: %v", err)
+	}
+	if _, err := NewVM(main); err != ErrUnresolvedImports {
+		t.Fatalf("have %v, want %v", err, ErrUnresolvedImports)
+	}
+	want := ImportError{"lib", "load", ErrUnknownInstance}
+	if _, err := NewStore().Instantiate("main", main); err != want {
+		t.Fatalf("have %v, want %v", err, want)
+	}
+
+	store := NewStore()
+	libVM, err := store.Instantiate("lib", lib)
+	if err != nil {
+		t.Fatalf("Could not instantiate lib: %v", err)
+	}
+	mainVM, err := store.Instantiate("main", main)
+	if err != nil {
+		t.Fatalf("Could not instantiate main: %v", err)
+	}
+	if store.Instance("main") != mainVM {
+		t.Error("main is not registered")
+	}
+
+	// the memory is shared
+	if _, err := mainVM.ExecCode(2, 5); err != nil {
+		t.Fatal(err)
+	}
+	mem := libVM.Memory()
+	if mem[0] != 42 || mem[4] != 5 || mem[8] != 99 {
+		t.Errorf("memory: have %v, want [42 0 0 0 5 0 0 0 99]", mem[:9])
+	}
+
+	for _, tt := range []struct {
+		vm   *VM
+		fn   int64
+		args []uint64
+		want uint32
+	}{
+		// the global is shared and bumped in the context of lib
+		{mainVM, 5, nil, 101},
+		{libVM, 1, nil, 102},
+		{mainVM, 5, nil, 103},
+		{libVM, 0, []uint64{4}, 5},
+		// the table holds functions of both instances
+		{mainVM, 4, []uint64{0}, 7},
+		{mainVM, 4, []uint64{1}, 8},
+	} {
+		have, err := tt.vm.ExecCode(tt.fn, tt.args...)
+		if err != nil {
+			t.Fatalf("func[%d]%v: %v", tt.fn, tt.args, err)
+		}
+		if have != tt.want {
+			t.Errorf("func[%d]%v: have %v, want %v", tt.fn, tt.args, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2017 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package exec
+
+import (
+	"math"
+
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+)
+
+// ExternRef is an opaque host reference, the value of an externref. Host
+// functions take and return externref values as ExternRef arguments and
+// results, the meaning of which is up to them, e.g. the index of a resource
+// the host keeps for the contract.
+type ExternRef uint32
+
+// NullExternRef is the null externref, the value of ref.null extern and of
+// the uninitialized elements of a table of ExternRef.
+const NullExternRef = ExternRef(wasm.NullElement)
+
+// funcRef returns the funcref value of the function at index of the
+// function index space: its address once the VM is an instance of a Store.
+func (vm *VM) funcRef(index uint32) uint32 {
+	if vm.funcAddrs != nil {
+		return vm.funcAddrs[index]
+	}
+	return index
+}
+
+func (vm *VM) refNull() {
+	_ = vm.fetchInt8() // reference type
+	vm.pushUint32(wasm.NullElement)
+}
+
+func (vm *VM) refIsNull() {
+	vm.pushBool(vm.popUint32() == wasm.NullElement)
+}
+
+func (vm *VM) refFunc() {
+	vm.pushUint32(vm.funcRef(vm.fetchUint32()))
+}
+
+func (vm *VM) tableGet() {
+	t := vm.tables[vm.fetchUint32()]
+	i := vm.popUint32()
+	if int(i) >= len(t.elems) {
+		panic(ErrOutOfBoundsTableAccess)
+	}
+	vm.pushUint32(t.elems[i])
+}
+
+func (vm *VM) tableSet() {
+	t := vm.tables[vm.fetchUint32()]
+	val := vm.popUint32()
+	i := vm.popUint32()
+	if int(i) >= len(t.elems) {
+		panic(ErrOutOfBoundsTableAccess)
+	}
+	t.elems[i] = val
+}
+
+func (vm *VM) tableSize() {
+	vm.pushUint32(uint32(len(vm.tables[vm.fetchUint32()].elems)))
+}
+
+// tableGrow grows the table by n elements set to val and pushes its previous
+// size, or -1 if the table can't grow beyond its maximum size.
+func (vm *VM) tableGrow() {
+	t := vm.tables[vm.fetchUint32()]
+	n := uint64(vm.popUint32())
+	val := vm.popUint32()
+	size := uint64(len(t.elems))
+	max := uint64(math.MaxUint32)
+	if t.limits.Flags&1 != 0 {
+		max = uint64(t.limits.Maximum)
+	}
+	if size+n > max {
+		vm.pushInt32(-1)
+		return
+	}
+	vm.charge(n)
+	for i := uint64(0); i < n; i++ {
+		t.elems = append(t.elems, val)
+	}
+	vm.pushUint32(uint32(size))
+}
+
+func (vm *VM) tableFill() {
+	t := vm.tables[vm.fetchUint32()]
+	n := uint64(vm.popUint32())
+	val := vm.popUint32()
+	i := uint64(vm.popUint32())
+	if i+n > uint64(len(t.elems)) {
+		panic(ErrOutOfBoundsTableAccess)
+	}
+	vm.charge(n)
+	elems := t.elems[i : i+n]
+	for j := range elems {
+		elems[j] = val
+	}
+}
@@ -0,0 +1,40 @@
+(module
+  (type $ret (func (result i32)))
+  (table 3 funcref)
+  (memory 1)
+  (data "\01\02\03\04")
+  (elem func $f)
+  (func $f (type $ret) (i32.const 42))
+  (func (export "init")
+    (memory.init 0 (i32.const 8) (i32.const 1) (i32.const 3))
+    (data.drop 0)
+    (memory.copy (i32.const 16) (i32.const 8) (i32.const 3))
+    (memory.fill (i32.const 0) (i32.const 0xaa) (i32.const 4))
+    (table.init 0 (i32.const 1) (i32.const 0) (i32.const 1))
+    (elem.drop 0)
+    (table.copy (i32.const 2) (i32.const 1) (i32.const 1)))
+  (func (export "call") (result i32) (call_indirect (type $ret) (i32.const 2)))
+  (func (export "reinit") (memory.init 0 (i32.const 0) (i32.const 0) (i32.const 1)))
+  (func (export "load8_u") (param i32) (result i32) (i32.load8_u (local.get 0)))
+)
+
+;; the table is only initialized by table.init
+(assert_trap (invoke "call") "uninitialized element")
+(invoke "init")
+(assert_return (invoke "load8_u" (i32.const 0)) (i32.const 0xaa))
+(assert_return (invoke "load8_u" (i32.const 3)) (i32.const 0xaa))
+(assert_return (invoke "load8_u" (i32.const 4)) (i32.const 0))
+(assert_return (invoke "load8_u" (i32.const 7)) (i32.const 0))
+(assert_return (invoke "load8_u" (i32.const 8)) (i32.const 2))
+(assert_return (invoke "load8_u" (i32.const 9)) (i32.const 3))
+(assert_return (invoke "load8_u" (i32.const 10)) (i32.const 4))
+(assert_return (invoke "load8_u" (i32.const 11)) (i32.const 0))
+(assert_return (invoke "load8_u" (i32.const 16)) (i32.const 2))
+(assert_return (invoke "load8_u" (i32.const 17)) (i32.const 3))
+(assert_return (invoke "load8_u" (i32.const 18)) (i32.const 4))
+(assert_return (invoke "load8_u" (i32.const 19)) (i32.const 0))
+(assert_return (invoke "call") (i32.const 42))
+
+;; dropped segments are empty
+(assert_trap (invoke "reinit") "out of bounds memory access")
+(assert_trap (invoke "init") "out of bounds memory access")
@@ -0,0 +1,27 @@
+(module
+  (func $swap (export "swap") (param i32 i32) (result i32 i32) (local.get 1) (local.get 0))
+  (func (export "br_if") (param i32) (result i32 i32)
+    (block (result i32 i32)
+      (i64.const 5)
+      (call $swap (local.get 0) (i32.const 10))
+      (br_if 0 (local.get 0))
+      (drop) (drop) (drop)
+      (i32.const 1) (i32.const 2)))
+  (func (export "if") (param i32) (result i32 i32)
+    (i32.const 5)
+    (if (param i32) (result i32 i32) (local.get 0)
+      (then (i32.const 1))
+      (else (i32.const 2) (i32.add) (i32.const 0))))
+  (func (export "loop") (param i32) (result i32)
+    (i32.const 0)
+    (loop (param i32) (result i32)
+      (local.get 0) (i32.add)
+      (br_if 0 (local.tee 0 (i32.sub (local.get 0) (i32.const 1))))))
+)
+
+(assert_return (invoke "swap" (i32.const 1) (i32.const 2)) (i32.const 2) (i32.const 1))
+(assert_return (invoke "br_if" (i32.const 3)) (i32.const 10) (i32.const 3))
+(assert_return (invoke "br_if" (i32.const 0)) (i32.const 1) (i32.const 2))
+(assert_return (invoke "if" (i32.const 1)) (i32.const 5) (i32.const 1))
+(assert_return (invoke "if" (i32.const 0)) (i32.const 7) (i32.const 0))
+(assert_return (invoke "loop" (i32.const 4)) (i32.const 10))
@@ -0,0 +1,30 @@
+(module
+  (type $ret (func (result i32)))
+  (table $empty 2 funcref)
+  (table $funcs 1 funcref)
+  (table $externs 1 3 externref)
+  (elem (table $funcs) (i32.const 0) funcref (ref.func $seven))
+  (elem declare func $seven)
+  (func $seven (type $ret) (i32.const 7))
+  (func (export "call") (param i32) (result i32) (call_indirect $funcs (type $ret) (local.get 0)))
+  (func (export "grow") (param externref i32) (result i32) (table.grow $externs (local.get 0) (local.get 1)))
+  (func (export "get") (param i32) (result externref) (table.get $externs (local.get 0)))
+  (func (export "size") (result i32) (table.size $externs))
+  (func (export "is_null") (result i32) (ref.is_null (table.get $empty (i32.const 0))))
+  (func (export "set_call") (result i32)
+    (table.set $empty (i32.const 1) (ref.func $seven))
+    (call_indirect (type $ret) (i32.const 1)))
+)
+
+(assert_return (invoke "call" (i32.const 0)) (i32.const 7))
+(assert_return (invoke "set_call") (i32.const 7))
+(assert_return (invoke "is_null") (i32.const 1))
+(assert_return (invoke "size") (i32.const 1))
+(assert_return (invoke "grow" (ref.extern 5) (i32.const 2)) (i32.const 1))
+;; the table can't grow beyond its maximum
+(assert_return (invoke "grow" (ref.extern 5) (i32.const 1)) (i32.const -1))
+(assert_return (invoke "size") (i32.const 3))
+(assert_return (invoke "get" (i32.const 0)) (ref.null extern))
+(assert_return (invoke "get" (i32.const 2)) (ref.extern 5))
+(assert_trap (invoke "get" (i32.const 3)) "out of bounds table access")
+(assert_trap (invoke "call" (i32.const 1)) "uninitialized element")
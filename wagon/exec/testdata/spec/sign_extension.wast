@@ -0,0 +1,50 @@
+(module
+  (func (export "i32.extend8_s") (param $x i32) (result i32) (i32.extend8_s (get_local $x)))
+  (func (export "i32.extend16_s") (param $x i32) (result i32) (i32.extend16_s (get_local $x)))
+  (func (export "i64.extend8_s") (param $x i64) (result i64) (i64.extend8_s (get_local $x)))
+  (func (export "i64.extend16_s") (param $x i64) (result i64) (i64.extend16_s (get_local $x)))
+  (func (export "i64.extend32_s") (param $x i64) (result i64) (i64.extend32_s (get_local $x)))
+)
+
+(assert_return (invoke "i32.extend8_s" (i32.const 0)) (i32.const 0))
+(assert_return (invoke "i32.extend8_s" (i32.const 0x7f)) (i32.const 127))
+(assert_return (invoke "i32.extend8_s" (i32.const 0x80)) (i32.const -128))
+(assert_return (invoke "i32.extend8_s" (i32.const 0xff)) (i32.const -1))
+(assert_return (invoke "i32.extend8_s" (i32.const 0x01234500)) (i32.const 0))
+(assert_return (invoke "i32.extend8_s" (i32.const 0xfedcba80)) (i32.const -0x80))
+(assert_return (invoke "i32.extend8_s" (i32.const -1)) (i32.const -1))
+
+(assert_return (invoke "i32.extend16_s" (i32.const 0)) (i32.const 0))
+(assert_return (invoke "i32.extend16_s" (i32.const 0x7fff)) (i32.const 32767))
+(assert_return (invoke "i32.extend16_s" (i32.const 0x8000)) (i32.const -32768))
+(assert_return (invoke "i32.extend16_s" (i32.const 0xffff)) (i32.const -1))
+(assert_return (invoke "i32.extend16_s" (i32.const 0x01230000)) (i32.const 0))
+(assert_return (invoke "i32.extend16_s" (i32.const 0xfedc8000)) (i32.const -0x8000))
+(assert_return (invoke "i32.extend16_s" (i32.const -1)) (i32.const -1))
+
+(assert_return (invoke "i64.extend8_s" (i64.const 0)) (i64.const 0))
+(assert_return (invoke "i64.extend8_s" (i64.const 0x7f)) (i64.const 127))
+(assert_return (invoke "i64.extend8_s" (i64.const 0x80)) (i64.const -128))
+(assert_return (invoke "i64.extend8_s" (i64.const 0xff)) (i64.const -1))
+(assert_return (invoke "i64.extend8_s" (i64.const 0x0123456789abcd00)) (i64.const 0))
+(assert_return (invoke "i64.extend8_s" (i64.const 0xfedcba9876543280)) (i64.const -0x80))
+(assert_return (invoke "i64.extend8_s" (i64.const -1)) (i64.const -1))
+
+(assert_return (invoke "i64.extend16_s" (i64.const 0)) (i64.const 0))
+(assert_return (invoke "i64.extend16_s" (i64.const 0x7fff)) (i64.const 32767))
+(assert_return (invoke "i64.extend16_s" (i64.const 0x8000)) (i64.const -32768))
+(assert_return (invoke "i64.extend16_s" (i64.const 0xffff)) (i64.const -1))
+(assert_return (invoke "i64.extend16_s" (i64.const 0x123456789abc0000)) (i64.const 0))
+(assert_return (invoke "i64.extend16_s" (i64.const 0xfedcba9876548000)) (i64.const -0x8000))
+(assert_return (invoke "i64.extend16_s" (i64.const -1)) (i64.const -1))
+
+(assert_return (invoke "i64.extend32_s" (i64.const 0)) (i64.const 0))
+(assert_return (invoke "i64.extend32_s" (i64.const 0x7fff)) (i64.const 32767))
+(assert_return (invoke "i64.extend32_s" (i64.const 0x8000)) (i64.const 32768))
+(assert_return (invoke "i64.extend32_s" (i64.const 0xffff)) (i64.const 65535))
+(assert_return (invoke "i64.extend32_s" (i64.const 0x7fffffff)) (i64.const 0x7fffffff))
+(assert_return (invoke "i64.extend32_s" (i64.const 0x80000000)) (i64.const -0x80000000))
+(assert_return (invoke "i64.extend32_s" (i64.const 0xffffffff)) (i64.const -1))
+(assert_return (invoke "i64.extend32_s" (i64.const 0x0123456700000000)) (i64.const 0))
+(assert_return (invoke "i64.extend32_s" (i64.const 0xfedcba9880000000)) (i64.const -0x80000000))
+(assert_return (invoke "i64.extend32_s" (i64.const -1)) (i64.const -1))
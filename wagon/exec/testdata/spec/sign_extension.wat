@@ -0,0 +1,23 @@
+(module
+  (type (;0;) (func (param i32) (result i32)))
+  (type (;1;) (func (param i64) (result i64)))
+  (func (;0;) (type 0) (param i32) (result i32)
+    get_local 0
+    i32.extend8_s)
+  (func (;1;) (type 0) (param i32) (result i32)
+    get_local 0
+    i32.extend16_s)
+  (func (;2;) (type 1) (param i64) (result i64)
+    get_local 0
+    i64.extend8_s)
+  (func (;3;) (type 1) (param i64) (result i64)
+    get_local 0
+    i64.extend16_s)
+  (func (;4;) (type 1) (param i64) (result i64)
+    get_local 0
+    i64.extend32_s)
+  (export "i32.extend8_s" (func 0))
+  (export "i32.extend16_s" (func 1))
+  (export "i64.extend8_s" (func 2))
+  (export "i64.extend16_s" (func 3))
+  (export "i64.extend32_s" (func 4)))
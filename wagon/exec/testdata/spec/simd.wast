@@ -0,0 +1,38 @@
+(module
+  (memory 1)
+  (global $g (mut v128) (v128.const i32x4 1 2 3 4))
+  (func (export "add") (param i32 i32) (result i32)
+    (i32x4.extract_lane 1 (i32x4.add (i32x4.splat (local.get 0)) (i32x4.splat (local.get 1)))))
+  (func (export "select") (result i64) (local v128)
+    (local.set 0 (v128.const i64x2 5 7))
+    (i64x2.extract_lane 1
+      (select
+        (v128.const i64x2 0 0)
+        (block (result v128) (i32.const 9) (local.get 0) (br 0))
+        (i32.const 0))))
+  (func (export "load") (param i32) (result i32)
+    (v128.store (local.get 0) (v128.const i8x16 1 2 3 4 5 6 7 8 9 10 11 12 13 14 15 16))
+    (i8x16.extract_lane_u 15 (v128.load (local.get 0))))
+  (func (export "square") (result i32)
+    (global.set $g (i32x4.mul (global.get $g) (global.get $g)))
+    (i32x4.extract_lane 3 (global.get $g)))
+  (func (export "add_sat") (result i32)
+    (i8x16.extract_lane_s 0 (i8x16.add_sat_s (i8x16.splat (i32.const 100)) (i8x16.splat (i32.const 100)))))
+  (func $double (param v128) (result v128) (i64x2.add (local.get 0) (local.get 0)))
+  (func (export "call") (result i64) (i64x2.extract_lane 1 (call $double (v128.const i64x2 1 2))))
+  (func (export "bitmask") (result i32)
+    (i8x16.bitmask
+      (i8x16.shuffle 1 0 2 3 4 5 6 7 8 9 10 11 12 13 14 15
+        (v128.const i8x16 0x80 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0)
+        (v128.const i8x16 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0))))
+)
+
+(assert_return (invoke "add" (i32.const 3) (i32.const 4)) (i32.const 7))
+(assert_return (invoke "select") (i64.const 7))
+(assert_return (invoke "load" (i32.const 100)) (i32.const 16))
+(assert_trap (invoke "load" (i32.const 65530)) "out of bounds memory access")
+(assert_return (invoke "square") (i32.const 16))
+(assert_return (invoke "square") (i32.const 256))
+(assert_return (invoke "add_sat") (i32.const 127))
+(assert_return (invoke "call") (i64.const 4))
+(assert_return (invoke "bitmask") (i32.const 2))
@@ -0,0 +1,39 @@
+(module
+  (func (export "i32.trunc_sat_f32_s") (param f32) (result i32) (i32.trunc_sat_f32_s (local.get 0)))
+  (func (export "i32.trunc_sat_f32_u") (param f32) (result i32) (i32.trunc_sat_f32_u (local.get 0)))
+  (func (export "i32.trunc_sat_f64_s") (param f64) (result i32) (i32.trunc_sat_f64_s (local.get 0)))
+  (func (export "i32.trunc_sat_f64_u") (param f64) (result i32) (i32.trunc_sat_f64_u (local.get 0)))
+  (func (export "i64.trunc_sat_f32_s") (param f32) (result i64) (i64.trunc_sat_f32_s (local.get 0)))
+  (func (export "i64.trunc_sat_f32_u") (param f32) (result i64) (i64.trunc_sat_f32_u (local.get 0)))
+  (func (export "i64.trunc_sat_f64_s") (param f64) (result i64) (i64.trunc_sat_f64_s (local.get 0)))
+  (func (export "i64.trunc_sat_f64_u") (param f64) (result i64) (i64.trunc_sat_f64_u (local.get 0)))
+)
+
+(assert_return (invoke "i32.trunc_sat_f32_s" (f32.const -1.9)) (i32.const -1))
+(assert_return (invoke "i32.trunc_sat_f32_s" (f32.const nan)) (i32.const 0))
+(assert_return (invoke "i32.trunc_sat_f32_s" (f32.const 3e9)) (i32.const 0x7fffffff))
+(assert_return (invoke "i32.trunc_sat_f32_s" (f32.const -3e9)) (i32.const 0x80000000))
+
+(assert_return (invoke "i32.trunc_sat_f32_u" (f32.const 3.7)) (i32.const 3))
+(assert_return (invoke "i32.trunc_sat_f32_u" (f32.const -0.9)) (i32.const 0))
+(assert_return (invoke "i32.trunc_sat_f32_u" (f32.const -1)) (i32.const 0))
+(assert_return (invoke "i32.trunc_sat_f32_u" (f32.const 5e9)) (i32.const 0xffffffff))
+
+(assert_return (invoke "i32.trunc_sat_f64_s" (f64.const 2147483647.9)) (i32.const 0x7fffffff))
+(assert_return (invoke "i32.trunc_sat_f64_s" (f64.const -2147483648.9)) (i32.const 0x80000000))
+(assert_return (invoke "i32.trunc_sat_f64_s" (f64.const -inf)) (i32.const 0x80000000))
+
+(assert_return (invoke "i32.trunc_sat_f64_u" (f64.const 4294967295.9)) (i32.const 0xffffffff))
+(assert_return (invoke "i32.trunc_sat_f64_u" (f64.const inf)) (i32.const 0xffffffff))
+
+(assert_return (invoke "i64.trunc_sat_f32_s" (f32.const -2.5)) (i64.const -2))
+(assert_return (invoke "i64.trunc_sat_f32_s" (f32.const 1e19)) (i64.const 0x7fffffffffffffff))
+
+(assert_return (invoke "i64.trunc_sat_f32_u" (f32.const 1e20)) (i64.const 0xffffffffffffffff))
+(assert_return (invoke "i64.trunc_sat_f32_u" (f32.const nan)) (i64.const 0))
+
+(assert_return (invoke "i64.trunc_sat_f64_s" (f64.const -1e19)) (i64.const 0x8000000000000000))
+(assert_return (invoke "i64.trunc_sat_f64_s" (f64.const 9007199254740993)) (i64.const 9007199254740992))
+
+(assert_return (invoke "i64.trunc_sat_f64_u" (f64.const 1.8446744073709552e19)) (i64.const 0xffffffffffffffff))
+(assert_return (invoke "i64.trunc_sat_f64_u" (f64.const -0x1p-1074)) (i64.const 0))
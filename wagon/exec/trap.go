@@ -0,0 +1,119 @@
+// Copyright 2017 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package exec
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tinychain/tiny-wasm/wagon/exec/internal/compile"
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+)
+
+// Frame is a function that was being executed when the VM trapped.
+type Frame struct {
+	Index  int64  // index of the function in the function index space
+	Name   string // name of the function in the name section, if any
+	Offset int    // offset of the instruction in the bytecode of the function body
+}
+
+func (f Frame) String() string {
+	if f.Name == "" {
+		return fmt.Sprintf("func[%d]+0x%x", f.Index, f.Offset)
+	}
+	return fmt.Sprintf("%s (func[%d])+0x%x", f.Name, f.Index, f.Offset)
+}
+
+// Trap is returned by (*VM).ExecCode when the execution panics, for instance
+// on an out of bounds memory access or an unreachable instruction, and
+// RecoverPanic is set. Its message is the message of the cause, which it
+// unwraps to. ErrAborted and ErrCallStackExhausted are returned as they are.
+type Trap struct {
+	Err    error   // the cause of the trap
+	Frames []Frame // the functions being executed, innermost first
+}
+
+func (t *Trap) Error() string {
+	return t.Err.Error()
+}
+
+// Unwrap returns the cause of the trap.
+func (t *Trap) Unwrap() error {
+	return t.Err
+}
+
+// Trace formats the cause of the trap followed by its frames, one per line.
+func (t *Trap) Trace() string {
+	return FormatTrace(t.Err, len(t.Frames), func(i int) string {
+		return t.Frames[i].String()
+	})
+}
+
+// FormatTrace formats the cause of a trap followed by its n frames, one per
+// line, like (*Trap).Trace. The frame i is formatted by frame(i), which lets
+// the traps wrapping a Trap keep its format.
+func FormatTrace(err error, n int, frame func(i int) string) string {
+	var b strings.Builder
+	b.WriteString(err.Error())
+	for i := 0; i < n; i++ {
+		b.WriteString("\n\tat ")
+		b.WriteString(frame(i))
+	}
+	return b.String()
+}
+
+// trace returns the frames of the current execution, innermost first.
+func (vm *VM) trace() []Frame {
+	if vm.ctx.code == nil {
+		return nil // the execution hasn't started
+	}
+	names := vm.functionNames()
+	frames := make([]Frame, 0, len(vm.frames)+1)
+	for i := len(vm.frames); i >= 0; i-- {
+		ctx := vm.ctx
+		if i < len(vm.frames) {
+			ctx = vm.frames[i]
+		}
+		frame := Frame{Index: ctx.curFunc, Name: names[uint32(ctx.curFunc)]}
+		if compiled, ok := vm.compiled(ctx.curFunc); ok {
+			frame.Offset = instrOffset(compiled.offsets, ctx.pc)
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// instrOffset returns the offset in the function body of the instruction
+// being executed at pc, which is past the start of its compiled code.
+func instrOffset(offsets []compile.Offset, pc int64) int {
+	i := sort.Search(len(offsets), func(i int) bool {
+		return offsets[i].Addr >= pc
+	})
+	if i == 0 {
+		return 0
+	}
+	return offsets[i-1].Offset
+}
+
+// functionNames returns the function names of the module's name section.
+func (vm *VM) functionNames() wasm.NameMap {
+	if vm.funcNames != nil {
+		return vm.funcNames
+	}
+	vm.funcNames = wasm.NameMap{}
+	if s := vm.module.Custom(wasm.CustomSectionName); s != nil {
+		var names wasm.NameSection
+		if err := names.UnmarshalWASM(bytes.NewReader(s.Data)); err != nil {
+			return vm.funcNames
+		}
+		sub, _ := names.Decode(wasm.NameFunction)
+		if funcs, ok := sub.(*wasm.FunctionNames); ok && funcs.Names != nil {
+			vm.funcNames = funcs.Names
+		}
+	}
+	return vm.funcNames
+}
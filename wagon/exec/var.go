@@ -7,25 +7,40 @@ package exec
 func (vm *VM) getLocal() {
 	index := vm.fetchUint32()
 	vm.pushUint64(vm.ctx.locals[int(index)])
+	if vm.simd {
+		vm.setHigh(len(vm.stack)-1, vm.localHighs[vm.ctx.localBase+int(index)])
+	}
 }
 
 func (vm *VM) setLocal() {
 	index := vm.fetchUint32()
+	if vm.simd {
+		vm.localHighs[vm.ctx.localBase+int(index)] = vm.high(len(vm.stack) - 1)
+	}
 	vm.ctx.locals[int(index)] = vm.popUint64()
 }
 
 func (vm *VM) teeLocal() {
 	index := vm.fetchUint32()
-	val := vm.ctx.stack[len(vm.ctx.stack)-1]
+	val := vm.stack[len(vm.stack)-1]
 	vm.ctx.locals[int(index)] = val
+	if vm.simd {
+		vm.localHighs[vm.ctx.localBase+int(index)] = vm.high(len(vm.stack) - 1)
+	}
 }
 
 func (vm *VM) getGlobal() {
 	index := vm.fetchUint32()
-	vm.pushUint64(vm.globals[int(index)])
+	vm.pushUint64(*vm.globals[int(index)])
+	if vm.simd && vm.globalHighs[index] != nil {
+		vm.setHigh(len(vm.stack)-1, *vm.globalHighs[index])
+	}
 }
 
 func (vm *VM) setGlobal() {
 	index := vm.fetchUint32()
-	vm.globals[int(index)] = vm.popUint64()
+	if vm.simd && vm.globalHighs[index] != nil {
+		*vm.globalHighs[index] = vm.high(len(vm.stack) - 1)
+	}
+	*vm.globals[int(index)] = vm.popUint64()
 }
@@ -6,15 +6,18 @@
 package exec
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"math"
+	"sync/atomic"
 
 	"github.com/tinychain/tiny-wasm/wagon/disasm"
 	"github.com/tinychain/tiny-wasm/wagon/exec/internal/compile"
 	"github.com/tinychain/tiny-wasm/wagon/wasm"
+	"github.com/tinychain/tiny-wasm/wagon/wasm/leb128"
 	ops "github.com/tinychain/tiny-wasm/wagon/wasm/operators"
 )
 
@@ -25,8 +28,27 @@ var (
 	// ErrInvalidArgumentCount is returned by (*VM).ExecCode when an invalid
 	// number of arguments to the WebAssembly function are passed to it.
 	ErrInvalidArgumentCount = errors.New("exec: invalid number of arguments to function")
+	// ErrV128Signature is returned by (*VM).ExecCode for a function taking
+	// or returning v128 values, which the host can't pass or receive.
+	ErrV128Signature = errors.New("exec: v128 arguments and results are not supported")
+	// ErrAborted is returned by (*VM).ExecCode when the execution is
+	// cancelled through the flag set by (*VM).SetCancelFlag.
+	ErrAborted = errors.New("exec: execution aborted")
+	// ErrPaused is returned by (*VM).ExecCode and (*VM).Resume when the
+	// execution is paused by (*VM).Pause.
+	ErrPaused = errors.New("exec: execution paused")
+	// ErrCallStackExhausted is returned by (*VM).ExecCode when the depth of
+	// the calls exceeds (*VM).MaxCallDepth.
+	ErrCallStackExhausted = errors.New("exec: call stack exhausted")
+	// ErrUnresolvedImports is returned by NewVM when the imports of the
+	// module aren't resolved, see (*wasm.Module).HasUnresolvedImports.
+	ErrUnresolvedImports = errors.New("exec: module has unresolved imports")
 )
 
+// DefaultMaxCallDepth is the maximum depth of calls if (*VM).MaxCallDepth
+// isn't set.
+const DefaultMaxCallDepth = 16384
+
 // InvalidReturnTypeError is returned by (*VM).ExecCode when the module
 // specifies an invalid return type value for the executed function.
 type InvalidReturnTypeError int8
@@ -43,25 +65,50 @@ func (e InvalidFunctionIndexError) Error() string {
 	return fmt.Sprintf("Invalid index to function index space: %d", int64(e))
 }
 
+// context is the frame of a function being executed. Its operand stack is
+// the part of the VM's stack above stackBase, its locals are the part of the
+// VM's local stack starting at localBase.
 type context struct {
-	stack   []uint64
-	locals  []uint64
-	code    []byte
-	pc      int64
-	curFunc int64
+	locals    []uint64
+	code      []byte
+	pc        int64
+	curFunc   int64
+	stackBase int
+	localBase int
 }
 
 // VM is the execution context for executing WebAssembly bytecode.
 type VM struct {
-	ctx context
+	ctx        context
+	stack      []uint64 // operand stack shared by all frames
+	localStack []uint64 // locals of all frames
+
+	// high halves of the v128 values of the stack, locals and globals, see
+	// v128, kept if simd is set
+	highs       []uint64
+	localHighs  []uint64
+	globalHighs []*uint64 // nil for the globals of other types
+	simd        bool
 
 	module  *wasm.Module
-	globals []uint64
-	memory  []byte
+	globals []*uint64     // values of the globals, shared with the instances importing them
+	memory  *linearMemory // never nil, empty if the module has no linear memory
+	tables  []*table      // imported tables first, shared with the instances importing them
 	funcs   []function
 	wasmi   interface{}
 
+	store     *Store   // store the VM is an instance of, if any
+	funcAddrs []uint32 // addresses of the functions in the store, see Store
+	depth     int      // depth of the calls below the frames, when called by another instance
+	invoked   int      // number of calls from other instances in progress
+
+	dataDropped []bool         // data segments dropped by data.drop or instantiation
+	elemDropped []bool         // element segments dropped by elem.drop or instantiation
+	meter       func(n uint64) // charges bulk memory operators, see SetMeter
+
 	funcTable [256]func()
+	miscTable [256]func() // the operators following the 0xfc prefix
+	simdTable [256]func() // the operators following the 0xfd prefix
 
 	// RecoverPanic controls whether the `ExecCode` method
 	// recovers from a panic and returns it as an error
@@ -70,7 +117,38 @@ type VM struct {
 	// or encountering an invalid instruction, e.g. `unreachable`.
 	RecoverPanic bool
 
+	// MaxCallDepth is the maximum depth of calls between the module's
+	// functions, DefaultMaxCallDepth if zero.
+	MaxCallDepth int
+
 	abort bool // Flag for host functions to terminate execution
+
+	cancel *int32 // Flag for other goroutines to abort execution, see SetCancelFlag
+
+	frames []context // frames of the calling functions, the current one is ctx
+	entry  int64     // index of the function the execution started with
+	pause  int32     // Flag for other goroutines to pause execution, see Pause
+	paused bool      // execution is paused, see Resume
+
+	funcNames wasm.NameMap // names of the name section, see functionNames
+}
+
+// linearMemory is the linear memory of a VM, shared with the instances
+// importing it.
+type linearMemory struct {
+	bytes  []byte
+	limits wasm.ResizableLimits
+}
+
+// table is a table of a VM, shared with the instances importing it. The
+// elements of a table of functions are indices to the function index space
+// of the module, or the addresses of the functions in the store once the VM
+// is an instance of a Store, the ones of a table of ExternRef are host
+// references. Uninitialized elements are wasm.NullElement.
+type table struct {
+	elems  []uint32
+	limits wasm.ResizableLimits
+	typ    wasm.ElemType
 }
 
 // As per the WebAssembly spec: https://github.com/WebAssembly/design/blob/27ac254c854994103c24834a994be16f74f54186/Semantics.md#linear-memory
@@ -79,25 +157,96 @@ const wasmPageSize = 65536 // (64 KB)
 var endianess = binary.LittleEndian
 
 // NewVM creates a new VM from a given module. If the module defines a
-// start function, it will be executed.
+// start function, it will be executed. The imports of the module must have
+// been resolved by ReadModule, a module importing WebAssembly functions or
+// read without a resolver is instantiated with (*Store).Instantiate instead.
 func NewVM(module *wasm.Module) (*VM, error) {
-	var vm VM
+	if module.HasUnresolvedImports() {
+		return nil, ErrUnresolvedImports
+	}
+	vm := VM{
+		module: module,
+		memory: &linearMemory{},
+	}
 
 	if module.Memory != nil && len(module.Memory.Entries) != 0 {
 		if len(module.Memory.Entries) > 1 {
 			return nil, ErrMultipleLinearMemories
 		}
-		vm.memory = make([]byte, uint(module.Memory.Entries[0].Limits.Initial)*wasmPageSize)
-		copy(vm.memory, module.LinearMemoryIndexSpace[0])
+		vm.memory.limits = module.Memory.Entries[0].Limits
+		vm.memory.bytes = make([]byte, uint(vm.memory.limits.Initial)*wasmPageSize)
+		copy(vm.memory.bytes, module.LinearMemoryIndexSpace[0])
+	}
+
+	vm.tables = make([]*table, len(module.TableIndexSpace))
+	for i, elems := range module.TableIndexSpace {
+		vm.tables[i] = &table{
+			elems: append([]uint32(nil), elems...),
+			typ:   wasm.ElemTypeAnyFunc,
+		}
+		if t := module.GetTable(i); t != nil {
+			vm.tables[i].limits, vm.tables[i].typ = t.Limits, t.ElementType
+		}
+	}
+
+	if err := vm.compile(nil); err != nil {
+		return nil, err
+	}
+	if err := vm.initGlobals(nil); err != nil {
+		return nil, err
+	}
+
+	if module.Start != nil {
+		_, err := vm.ExecCode(int64(module.Start.Index))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &vm, nil
+}
+
+// compile sets up the functions of the VM, starting with the imported ones,
+// and the state of the segments of its module.
+func (vm *VM) compile(imported []function) error {
+	module := vm.module
+
+	// active segments are dropped once they are copied at instantiation
+	if module.Data != nil {
+		vm.dataDropped = make([]bool, len(module.Data.Entries))
+		for i, entry := range module.Data.Entries {
+			vm.dataDropped[i] = !entry.Passive
+		}
+	}
+	if module.Elements != nil {
+		vm.elemDropped = make([]bool, len(module.Elements.Entries))
+		for i, entry := range module.Elements.Entries {
+			vm.elemDropped[i] = !entry.Passive
+		}
 	}
 
 	vm.funcs = make([]function, len(module.FunctionIndexSpace))
-	vm.globals = make([]uint64, len(module.GlobalIndexSpace))
+	copy(vm.funcs, imported)
+	vm.simd = module.Features.Has(wasm.FeatureSIMD)
+	// the locals are checked as the functions are compiled
+	if !vm.simd {
+		if module.Types != nil {
+			for _, sig := range module.Types.Entries {
+				if wasm.HasV128(sig.ParamTypes) || wasm.HasV128(sig.ReturnTypes) {
+					return wasm.ErrV128
+				}
+			}
+		}
+		for _, global := range module.GlobalIndexSpace {
+			if global.Type.Type == wasm.ValueTypeV128 {
+				return wasm.ErrV128
+			}
+		}
+	}
 	vm.newFuncTable()
-	vm.module = module
 
-	nNatives := 0
-	for i, fn := range module.FunctionIndexSpace {
+	for i, fn := range module.FunctionIndexSpace[len(imported):] {
+		i += len(imported)
 		// Skip native methods as they need not be
 		// disassembled; simply add them at the end
 		// of the `funcs` array as is, as specified
@@ -109,61 +258,199 @@ func NewVM(module *wasm.Module) (*VM, error) {
 				typ: fn.Host.Type(),
 				val: fn.Host,
 			}
-			nNatives++
 			continue
 		}
 
-		disassembly, err := disasm.NewDisassembly(fn, module)
-		if err != nil {
-			return nil, err
+		if !fn.Body.Decoded() {
+			vm.funcs[i] = lazyFunction{}
+			continue
+		}
+		if err := vm.compileFunc(i); err != nil {
+			return err
 		}
+	}
+	return nil
+}
+
+// compileFunc compiles the function at index of the function index space of
+// the module.
+func (vm *VM) compileFunc(index int) error {
+	fn := vm.module.FunctionIndexSpace[index]
+	disassembly, err := disasm.NewDisassembly(fn, vm.module)
+	if err != nil {
+		return err
+	}
+
+	totalLocalVars := 0
+	totalLocalVars += len(fn.Sig.ParamTypes)
+	for _, entry := range fn.Body.Locals {
+		if entry.Type == wasm.ValueTypeV128 && !vm.simd {
+			return wasm.ErrV128
+		}
+		totalLocalVars += int(entry.Count)
+	}
+	code, table, offsets := compile.Compile(disassembly.Code)
+	vm.funcs[index] = compiledFunction{
+		code:           code,
+		branchTables:   table,
+		offsets:        offsets,
+		maxDepth:       disassembly.MaxDepth,
+		totalLocalVars: totalLocalVars,
+		args:           len(fn.Sig.ParamTypes),
+		returns:        len(fn.Sig.ReturnTypes),
+	}
+	return nil
+}
 
-		totalLocalVars := 0
-		totalLocalVars += len(fn.Sig.ParamTypes)
-		for _, entry := range fn.Body.Locals {
-			totalLocalVars += int(entry.Count)
+// compileLazy compiles the function at index if its body was read lazily,
+// see wasm.DecodeConfig.LazyBodies.
+func (vm *VM) compileLazy(index int64) error {
+	if index < 0 || index >= int64(len(vm.funcs)) {
+		return nil
+	}
+	if _, ok := vm.funcs[index].(lazyFunction); !ok {
+		return nil
+	}
+	return vm.compileFunc(int(index))
+}
+
+// initGlobals sets the globals of the VM, starting with the imported ones,
+// and evaluates the initializer expressions of the others.
+func (vm *VM) initGlobals(imported []*uint64) error {
+	vm.globals = make([]*uint64, len(vm.module.GlobalIndexSpace))
+	copy(vm.globals, imported)
+	highs := make([]*uint64, len(vm.globals))
+	copy(highs, vm.globalHighs)
+	vm.globalHighs = highs
+	for i, global := range vm.module.GlobalIndexSpace[len(imported):] {
+		i += len(imported)
+		if global.Type.Type == wasm.ValueTypeV128 {
+			v, err := vm.initV128(global.Init)
+			if err != nil {
+				return err
+			}
+			vm.globals[i], vm.globalHighs[i] = &v[0], &v[1]
+			continue
 		}
-		code, table := compile.Compile(disassembly.Code)
-		vm.funcs[i] = compiledFunction{
-			code:           code,
-			branchTables:   table,
-			maxDepth:       disassembly.MaxDepth,
-			totalLocalVars: totalLocalVars,
-			args:           len(fn.Sig.ParamTypes),
-			returns:        len(fn.Sig.ReturnTypes) != 0,
+		val, err := vm.initExpr(global.Init)
+		if err != nil {
+			return err
 		}
+		vm.globals[i] = &val
 	}
+	return nil
+}
 
-	for i, global := range module.GlobalIndexSpace {
-		val, err := module.ExecInitExpr(global.Init)
+// initV128 evaluates the initializer expression of a v128 global.
+func (vm *VM) initV128(expr []byte) (v128, error) {
+	if len(expr) != 0 && expr[0] == ops.GetGlobal {
+		index, err := leb128.ReadVarUint32(bytes.NewReader(expr[1:]))
 		if err != nil {
-			return nil, err
+			return v128{}, err
 		}
-		switch v := val.(type) {
-		case int32:
-			vm.globals[i] = uint64(v)
-		case int64:
-			vm.globals[i] = uint64(v)
-		case float32:
-			vm.globals[i] = uint64(math.Float32bits(v))
-		case float64:
-			vm.globals[i] = uint64(math.Float64bits(v))
+		if int(index) >= len(vm.globals) || vm.globalHighs[index] == nil {
+			return v128{}, wasm.InvalidGlobalIndexError(index)
 		}
+		return v128{*vm.globals[index], *vm.globalHighs[index]}, nil
 	}
+	val, err := vm.module.ExecInitExpr(expr)
+	if err != nil {
+		return v128{}, err
+	}
+	b, ok := val.([16]byte)
+	if !ok {
+		return v128{}, InvalidReturnTypeError(wasm.ValueTypeV128)
+	}
+	return v128{endianess.Uint64(b[:]), endianess.Uint64(b[8:])}, nil
+}
 
-	if module.Start != nil {
-		_, err := vm.ExecCode(int64(module.Start.Index))
+// initExpr evaluates the initializer expression expr, which may read the
+// globals of the VM that are already set.
+func (vm *VM) initExpr(expr []byte) (uint64, error) {
+	if len(expr) != 0 && expr[0] == ops.RefFunc {
+		index, err := leb128.ReadVarUint32(bytes.NewReader(expr[1:]))
 		if err != nil {
-			return nil, err
+			return 0, err
 		}
+		if int(index) >= len(vm.funcs) {
+			return 0, wasm.InvalidFunctionIndexError(index)
+		}
+		return uint64(vm.funcRef(index)), nil
 	}
-
-	return &vm, nil
+	if len(expr) != 0 && expr[0] == ops.GetGlobal {
+		index, err := leb128.ReadVarUint32(bytes.NewReader(expr[1:]))
+		if err != nil {
+			return 0, err
+		}
+		if int(index) >= len(vm.globals) || vm.globals[index] == nil {
+			return 0, wasm.InvalidGlobalIndexError(index)
+		}
+		return *vm.globals[index], nil
+	}
+	val, err := vm.module.ExecInitExpr(expr)
+	if err != nil {
+		return 0, err
+	}
+	switch v := val.(type) {
+	case int32:
+		return uint64(v), nil
+	case int64:
+		return uint64(v), nil
+	case float32:
+		return uint64(math.Float32bits(v)), nil
+	case float64:
+		return math.Float64bits(v), nil
+	case uint32:
+		return uint64(v), nil
+	}
+	return 0, nil
 }
 
 // Memory returns the linear memory space for the VM.
 func (vm *VM) Memory() []byte {
-	return vm.memory
+	return vm.memory.bytes
+}
+
+// Global returns the raw value of the global at index in the global index
+// space of the module, as it is stored on the stack.
+func (vm *VM) Global(index int) uint64 {
+	return *vm.globals[index]
+}
+
+// SetWasmIntptr sets the interpreter passed to host functions as their
+// second argument, after the *Process.
+func (vm *VM) SetWasmIntptr(wasmi interface{}) {
+	vm.wasmi = wasmi
+}
+
+// SetCancelFlag sets the flag to cancel the execution with. Once another
+// goroutine atomically stores a non-zero value in flag, the execution stops
+// at the next backward branch or function call and ExecCode fails with
+// ErrAborted.
+func (vm *VM) SetCancelFlag(flag *int32) {
+	vm.cancel = flag
+}
+
+// SetMeter sets the function called with the number of bytes, or table
+// elements, a bulk memory operator is about to copy or fill, so that they can
+// be charged for. It is called once the bounds are checked, before any work
+// is done, and may panic to stop the execution, for instance when running out
+// of gas.
+func (vm *VM) SetMeter(meter func(n uint64)) {
+	vm.meter = meter
+}
+
+// poll is called at backward branches and function calls. It panics with
+// ErrAborted if the execution has been cancelled and pauses it if requested.
+// An execution called by another instance isn't paused, the request is kept
+// until it returns.
+func (vm *VM) poll() {
+	if vm.cancel != nil && atomic.LoadInt32(vm.cancel) != 0 {
+		panic(ErrAborted)
+	}
+	if vm.invoked == 0 && atomic.CompareAndSwapInt32(&vm.pause, 1, 0) {
+		vm.paused = true
+	}
 }
 
 func (vm *VM) pushBool(v bool) {
@@ -174,10 +461,6 @@ func (vm *VM) pushBool(v bool) {
 	}
 }
 
-func (vm *VM) fetchBool() bool {
-	return vm.fetchInt8() != 0
-}
-
 func (vm *VM) fetchInt8() int8 {
 	i := int8(vm.ctx.code[vm.ctx.pc])
 	vm.ctx.pc++
@@ -213,8 +496,8 @@ func (vm *VM) fetchFloat64() float64 {
 }
 
 func (vm *VM) popUint64() uint64 {
-	i := vm.ctx.stack[len(vm.ctx.stack)-1]
-	vm.ctx.stack = vm.ctx.stack[:len(vm.ctx.stack)-1]
+	i := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
 	return i
 }
 
@@ -238,8 +521,21 @@ func (vm *VM) popFloat32() float32 {
 	return math.Float32frombits(vm.popUint32())
 }
 
+// discard drops n elements from the stack, except the preserve values on its
+// top, which are moved down to replace them.
+func (vm *VM) discard(n, preserve int64) {
+	base := len(vm.stack) - int(n)
+	if vm.simd {
+		for i := 0; i < int(preserve); i++ {
+			vm.setHigh(base+i, vm.high(len(vm.stack)-int(preserve)+i))
+		}
+	}
+	copy(vm.stack[base:], vm.stack[len(vm.stack)-int(preserve):])
+	vm.stack = vm.stack[:base+int(preserve)]
+}
+
 func (vm *VM) pushUint64(i uint64) {
-	vm.ctx.stack = append(vm.ctx.stack, i)
+	vm.stack = append(vm.stack, i)
 }
 
 func (vm *VM) pushInt64(i int64) {
@@ -264,94 +560,246 @@ func (vm *VM) pushFloat32(f float32) {
 
 // ExecCode calls the function with the given index and arguments.
 // fnIndex should be a valid index into the function index space of
-// the VM's module.
+// the VM's module. Calls between the module's functions don't recurse
+// on the Go stack, which lets the execution be paused and resumed, see
+// Pause and Snapshot.
+// The return value of a function returning several values is an
+// []interface{} holding all of them.
 func (vm *VM) ExecCode(fnIndex int64, args ...uint64) (rtrn interface{}, err error) {
 	// If used as a library, client code should set vm.RecoverPanic to true
 	// in order to have an error returned.
-	if vm.RecoverPanic {
-		defer func() {
-			if r := recover(); r != nil {
-				switch e := r.(type) {
-				case error:
-					err = e
-				default:
-					err = fmt.Errorf("exec: %v", e)
-				}
-			}
-		}()
+	defer vm.recoverPanic(&err)
+	if err := vm.start(fnIndex, args); err != nil {
+		return nil, err
 	}
+	return vm.run()
+}
+
+// ExecCodeValues is like ExecCode, but returns the raw values returned by
+// the function, whatever their number, as they are stored on the stack.
+func (vm *VM) ExecCodeValues(fnIndex int64, args ...uint64) (rtrns []uint64, err error) {
+	defer vm.recoverPanic(&err)
+	if err := vm.start(fnIndex, args); err != nil {
+		return nil, err
+	}
+	return vm.runValues()
+}
+
+// start prepares the VM to execute the function with the given index and
+// arguments.
+func (vm *VM) start(fnIndex int64, args []uint64) error {
+	vm.ctx = context{}
+	vm.frames = vm.frames[:0]
+	vm.depth, vm.invoked = 0, 0
 	if int(fnIndex) > len(vm.funcs) {
-		return nil, InvalidFunctionIndexError(fnIndex)
+		return InvalidFunctionIndexError(fnIndex)
 	}
-	if len(vm.module.GetFunction(int(fnIndex)).Sig.ParamTypes) != len(args) {
-		return nil, ErrInvalidArgumentCount
+	sig := vm.module.GetFunction(int(fnIndex)).Sig
+	if len(sig.ParamTypes) != len(args) {
+		return ErrInvalidArgumentCount
+	}
+	if wasm.HasV128(sig.ParamTypes) || wasm.HasV128(sig.ReturnTypes) {
+		return ErrV128Signature
+	}
+	if err := vm.compileLazy(fnIndex); err != nil {
+		return err
 	}
 	compiled, ok := vm.funcs[fnIndex].(compiledFunction)
 	if !ok {
 		panic(fmt.Sprintf("exec: function at index %d is not a compiled function", fnIndex))
 	}
-	if len(vm.ctx.stack) < compiled.maxDepth {
-		vm.ctx.stack = make([]uint64, 0, compiled.maxDepth)
-	}
-	vm.ctx.locals = make([]uint64, compiled.totalLocalVars)
-	vm.ctx.pc = 0
-	vm.ctx.code = compiled.code
-	vm.ctx.curFunc = fnIndex
-
-	for i, arg := range args {
-		vm.ctx.locals[i] = arg
-	}
-
-	res := vm.execCode(compiled)
-	if compiled.returns {
-		rtrnType := vm.module.GetFunction(int(fnIndex)).Sig.ReturnTypes[0]
-		switch rtrnType {
-		case wasm.ValueTypeI32:
-			rtrn = uint32(res)
-		case wasm.ValueTypeI64:
-			rtrn = uint64(res)
-		case wasm.ValueTypeF32:
-			rtrn = math.Float32frombits(uint32(res))
-		case wasm.ValueTypeF64:
-			rtrn = math.Float64frombits(res)
+	if cap(vm.stack) < compiled.maxDepth {
+		vm.stack = make([]uint64, 0, compiled.maxDepth)
+	}
+	vm.stack = append(vm.stack[:0], args...)
+	vm.localStack = vm.localStack[:0]
+	vm.frames = vm.frames[:0]
+	vm.entry = fnIndex
+	vm.paused = false
+	vm.enter(compiled, fnIndex)
+
+	vm.poll()
+	return nil
+}
+
+// recoverPanic turns a panic of the execution into *err if vm.RecoverPanic
+// is set. It must be deferred. Panics other than ErrAborted are returned as
+// a *Trap.
+func (vm *VM) recoverPanic(err *error) {
+	if !vm.RecoverPanic {
+		return
+	}
+	if r := recover(); r != nil {
+		switch e := r.(type) {
+		case error:
+			*err = e
 		default:
-			return nil, InvalidReturnTypeError(rtrnType)
+			*err = fmt.Errorf("exec: %v", e)
 		}
+		if *err != ErrAborted && *err != ErrCallStackExhausted {
+			*err = &Trap{Err: *err, Frames: vm.trace()}
+		}
+	}
+}
+
+// run executes the VM until the entry function returns and converts its
+// return values.
+func (vm *VM) run() (rtrn interface{}, err error) {
+	res, err := vm.runValues()
+	if err != nil || len(res) == 0 {
+		return nil, err
+	}
+	types := vm.module.GetFunction(int(vm.entry)).Sig.ReturnTypes
+	if len(res) == 1 {
+		return convertValue(types[0], res[0])
+	}
+	rtrns := make([]interface{}, len(res))
+	for i, v := range res {
+		if rtrns[i], err = convertValue(types[i], v); err != nil {
+			return nil, err
+		}
+	}
+	return rtrns, nil
+}
+
+// runValues executes the VM until the entry function returns and returns
+// its raw return values.
+func (vm *VM) runValues() ([]uint64, error) {
+	res := vm.execCode(0)
+	if vm.paused {
+		return nil, ErrPaused
+	}
+	if vm.abort {
+		return nil, nil
+	}
+	return append([]uint64(nil), res...), nil
+}
+
+// convertValue converts the raw value v of type t to its Go type.
+func convertValue(t wasm.ValueType, v uint64) (interface{}, error) {
+	switch t {
+	case wasm.ValueTypeI32:
+		return uint32(v), nil
+	case wasm.ValueTypeI64:
+		return uint64(v), nil
+	case wasm.ValueTypeF32:
+		return math.Float32frombits(uint32(v)), nil
+	case wasm.ValueTypeF64:
+		return math.Float64frombits(v), nil
+	case wasm.ValueTypeFuncref:
+		return uint32(v), nil
+	case wasm.ValueTypeExternref:
+		return ExternRef(v), nil
+	}
+	return nil, InvalidReturnTypeError(t)
+}
+
+// execCode runs the current frame and the frames of the functions it calls,
+// until the function of the frame pushed on top of the base first frames
+// returns. It returns the return values of that function, which remain on
+// the stack.
+func (vm *VM) execCode(base int) []uint64 {
+	for {
+		vm.execFrame()
+		if vm.abort || vm.paused {
+			return nil
+		}
+
+		// drop the frame's operands, moving its return values, on the
+		// top of the stack, down to the base of the frame
+		returns := vm.funcs[vm.ctx.curFunc].(compiledFunction).returns
+		vm.discard(int64(len(vm.stack)-vm.ctx.stackBase), int64(returns))
+		if len(vm.frames) == base {
+			return vm.stack[vm.ctx.stackBase:]
+		}
+		// return to the caller, dropping the frame's locals
+		vm.localStack = vm.localStack[:vm.ctx.localBase]
+		vm.ctx = vm.frames[len(vm.frames)-1]
+		vm.frames = vm.frames[:len(vm.frames)-1]
+		// the local stack may have been reallocated since the call
+		vm.ctx.locals = vm.localStack[vm.ctx.localBase:len(vm.localStack):len(vm.localStack)]
+	}
+}
+
+// enter makes the compiled function at index the current frame. Its
+// arguments are popped from the operand stack.
+func (vm *VM) enter(compiled compiledFunction, index int64) {
+	localBase := len(vm.localStack)
+	n := localBase + compiled.totalLocalVars
+	if n > cap(vm.localStack) {
+		localStack := make([]uint64, localBase, 2*cap(vm.localStack)+compiled.totalLocalVars)
+		copy(localStack, vm.localStack)
+		vm.localStack = localStack
 	}
+	vm.localStack = vm.localStack[:n]
+	locals := vm.localStack[localBase:n:n]
 
-	return rtrn, nil
+	args := len(vm.stack) - compiled.args
+	copy(locals, vm.stack[args:])
+	for i := compiled.args; i < len(locals); i++ {
+		locals[i] = 0
+	}
+	if vm.simd {
+		if n > len(vm.localHighs) {
+			vm.localHighs = append(vm.localHighs, make([]uint64, n-len(vm.localHighs))...)
+		}
+		highs := vm.localHighs[localBase:n]
+		for i := range highs {
+			highs[i] = 0
+			if i < compiled.args {
+				highs[i] = vm.high(args + i)
+			}
+		}
+	}
+	vm.stack = vm.stack[:args]
+
+	vm.ctx = context{
+		locals:    locals,
+		code:      compiled.code,
+		pc:        0,
+		curFunc:   index,
+		stackBase: args,
+		localBase: localBase,
+	}
 }
 
-func (vm *VM) execCode(compiled compiledFunction) uint64 {
-outer:
-	for int(vm.ctx.pc) < len(vm.ctx.code) && !vm.abort {
+// execFrame executes the code of the current frame until it returns, calls
+// switch to the frame of the called function.
+func (vm *VM) execFrame() {
+	for int(vm.ctx.pc) < len(vm.ctx.code) && !vm.abort && !vm.paused {
 		op := vm.ctx.code[vm.ctx.pc]
 		vm.ctx.pc++
 		switch op {
 		case ops.Return:
-			break outer
+			return
 		case compile.OpJmp:
-			vm.ctx.pc = vm.fetchInt64()
+			target := vm.fetchInt64()
+			backward := target < vm.ctx.pc
+			vm.ctx.pc = target
+			if backward {
+				vm.poll()
+			}
 			continue
 		case compile.OpJmpZ:
 			target := vm.fetchInt64()
 			if vm.popUint32() == 0 {
+				backward := target < vm.ctx.pc
 				vm.ctx.pc = target
+				if backward {
+					vm.poll()
+				}
 				continue
 			}
 		case compile.OpJmpNz:
 			target := vm.fetchInt64()
-			preserveTop := vm.fetchBool()
+			preserve := vm.fetchInt64()
 			discard := vm.fetchInt64()
 			if vm.popUint32() != 0 {
+				backward := target < vm.ctx.pc
 				vm.ctx.pc = target
-				var top uint64
-				if preserveTop {
-					top = vm.ctx.stack[len(vm.ctx.stack)-1]
-				}
-				vm.ctx.stack = vm.ctx.stack[:len(vm.ctx.stack)-int(discard)]
-				if preserveTop {
-					vm.pushUint64(top)
+				vm.discard(discard, preserve)
+				if backward {
+					vm.poll()
 				}
 				continue
 			}
@@ -371,35 +819,33 @@ outer:
 			}
 
 			if target.Return {
-				break outer
+				return
 			}
+			backward := target.Addr < vm.ctx.pc
 			vm.ctx.pc = target.Addr
-			var top uint64
-			if target.PreserveTop {
-				top = vm.ctx.stack[len(vm.ctx.stack)-1]
-			}
-			vm.ctx.stack = vm.ctx.stack[:len(vm.ctx.stack)-int(target.Discard)]
-			if target.PreserveTop {
-				vm.pushUint64(top)
+			vm.discard(target.Discard, target.Preserve)
+			if backward {
+				vm.poll()
 			}
 			continue
 		case compile.OpDiscard:
 			place := vm.fetchInt64()
-			vm.ctx.stack = vm.ctx.stack[:len(vm.ctx.stack)-int(place)]
+			vm.stack = vm.stack[:len(vm.stack)-int(place)]
 		case compile.OpDiscardPreserveTop:
-			top := vm.ctx.stack[len(vm.ctx.stack)-1]
+			top := vm.stack[len(vm.stack)-1]
 			place := vm.fetchInt64()
-			vm.ctx.stack = vm.ctx.stack[:len(vm.ctx.stack)-int(place)]
+			if vm.simd {
+				vm.setHigh(len(vm.stack)-int(place), vm.high(len(vm.stack)-1))
+			}
+			vm.stack = vm.stack[:len(vm.stack)-int(place)]
 			vm.pushUint64(top)
+		case compile.OpDiscardPreserve:
+			preserve := vm.fetchInt64()
+			vm.discard(vm.fetchInt64(), preserve)
 		default:
 			vm.funcTable[op]()
 		}
 	}
-
-	if compiled.returns {
-		return vm.ctx.stack[len(vm.ctx.stack)-1]
-	}
-	return 0
 }
 
 // Process is a proxy passed to host functions in order to access
@@ -457,6 +903,11 @@ func (proc *Process) WriteAt(p []byte, off int64) (int, error) {
 	return length, err
 }
 
+// MemSize returns the current size of the memory of the VM, in bytes.
+func (proc *Process) MemSize() int {
+	return len(proc.vm.Memory())
+}
+
 // Terminate stops the execution of the current module.
 func (proc *Process) Terminate() {
 	proc.vm.abort = true
@@ -16,73 +16,79 @@ import (
 )
 
 func ExampleVM_add() {
-	raw, err := compileWast2Wasm("testdata/add-ex-main.wast")
-	if err != nil {
-		log.Fatalf("could not compile wast file: %v", err)
-	}
+	// A store links the instances of several modules together: a module
+	// instantiated in the store imports the exports of the instances
+	// registered in it, by name.
+	store := exec.NewStore()
 
-	m, err := wasm.ReadModule(bytes.NewReader(raw), func(name string) (*wasm.Module, error) {
-		// ReadModule takes as a second argument an optional "importer" function
-		// that is supposed to locate and import other modules when some module is
-		// requested (by name.)
-		// Theoretically, a general "importer" function not unlike the Python's 'import'
-		// mechanism (that tries to locate and import modules from a $PYTHONPATH)
-		// could be devised.
-		switch name {
-		case "add":
-			raw, err := compileWast2Wasm("testdata/add-ex.wast")
-			if err != nil {
-				return nil, fmt.Errorf("could not compile wast file hosting %q: %v", name, err)
-			}
-
-			add, err := wasm.ReadModule(bytes.NewReader(raw), nil)
-			if err != nil {
-				return nil, fmt.Errorf("could not read wasm %q module: %v", name, err)
-			}
-			return add, nil
-		case "go":
-			// create a whole new module, called "go", from scratch.
-			// this module will contain one exported function "print",
-			// implemented itself in pure Go.
-			print := func(proc *exec.Process, v int32) {
-				fmt.Printf("result = %v\n", v)
-			}
+	// create a whole new module, called "go", from scratch.
+	// this module will contain one exported function "print",
+	// implemented itself in pure Go.
+	print := func(proc *exec.Process, v int32) {
+		fmt.Printf("result = %v\n", v)
+	}
 
-			m := wasm.NewModule()
-			m.Types = &wasm.SectionTypes{
-				Entries: []wasm.FunctionSig{
-					{
-						Form:       0, // value for the 'func' type constructor
-						ParamTypes: []wasm.ValueType{wasm.ValueTypeI32},
-					},
-				},
-			}
-			m.FunctionIndexSpace = []wasm.Function{
+	host := &wasm.Module{
+		Types: &wasm.SectionTypes{
+			Entries: []wasm.FunctionSig{
 				{
-					Sig:  &m.Types.Entries[0],
-					Host: reflect.ValueOf(print),
-					Body: &wasm.FunctionBody{}, // create a dummy wasm body (the actual value will be taken from Host.)
+					Form:       0, // value for the 'func' type constructor
+					ParamTypes: []wasm.ValueType{wasm.ValueTypeI32},
 				},
-			}
-			m.Export = &wasm.SectionExports{
-				Entries: map[string]wasm.ExportEntry{
-					"print": {
-						FieldStr: "print",
-						Kind:     wasm.ExternalFunction,
-						Index:    0,
-					},
-				},
-			}
+			},
+		},
+	}
+	host.FunctionIndexSpace = []wasm.Function{
+		{
+			Sig:  &host.Types.Entries[0],
+			Host: reflect.ValueOf(print),
+			Body: &wasm.FunctionBody{}, // create a dummy wasm body (the actual value will be taken from Host.)
+		},
+	}
+	host.Export = &wasm.SectionExports{
+		Entries: map[string]wasm.ExportEntry{
+			"print": {
+				FieldStr: "print",
+				Kind:     wasm.ExternalFunction,
+				Index:    0,
+			},
+		},
+	}
+	hostVM, err := exec.NewVM(host)
+	if err != nil {
+		log.Fatalf("could not create go vm: %v", err)
+	}
+	if err := store.Register("go", hostVM); err != nil {
+		log.Fatalf("could not register go vm: %v", err)
+	}
 
-			return m, nil
-		}
-		return nil, fmt.Errorf("module %q unknown", name)
-	})
+	// the "add" module exports the function "iadd", which will be executed
+	// in the context of its own instance.
+	raw, err := compileWast2Wasm("testdata/add-ex.wast")
+	if err != nil {
+		log.Fatalf("could not compile wast file: %v", err)
+	}
+	add, err := wasm.ReadModule(bytes.NewReader(raw), nil)
+	if err != nil {
+		log.Fatalf("could not read wasm %q module: %v", "add", err)
+	}
+	if _, err := store.Instantiate("add", add); err != nil {
+		log.Fatalf("could not instantiate module %q: %v", "add", err)
+	}
+
+	// ReadModule takes as a second argument an optional "importer" function
+	// resolving the imports of the module when it is read. Without it, the
+	// imports are only declared and are linked by the store on instantiation.
+	raw, err = compileWast2Wasm("testdata/add-ex-main.wast")
+	if err != nil {
+		log.Fatalf("could not compile wast file: %v", err)
+	}
+	m, err := wasm.ReadModule(bytes.NewReader(raw), nil)
 	if err != nil {
 		log.Fatalf("could not read module: %v", err)
 	}
 
-	vm, err := exec.NewVM(m)
+	vm, err := store.Instantiate("main", m)
 	if err != nil {
 		log.Fatalf("could not create wagon vm: %v", err)
 	}
@@ -5,19 +5,28 @@
 package exec
 
 import (
+	"bytes"
+	"errors"
+	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/tinychain/tiny-wasm/wagon/validate"
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+	ops "github.com/tinychain/tiny-wasm/wagon/wasm/operators"
 )
 
 var (
-	smallMemoryVM      = &VM{memory: []byte{1, 2, 3}}
-	emptyMemoryVM      = &VM{memory: []byte{}}
+	smallMemoryVM      = &VM{memory: &linearMemory{bytes: []byte{1, 2, 3}}}
+	emptyMemoryVM      = &VM{memory: &linearMemory{bytes: []byte{}}}
 	smallMemoryProcess = &Process{vm: smallMemoryVM}
 	emptyMemoryProcess = &Process{vm: emptyMemoryVM}
 	tooBigABuffer      = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 0}
 )
 
 func TestNormalWrite(t *testing.T) {
-	vm := &VM{memory: make([]byte, 300)}
+	vm := &VM{memory: &linearMemory{bytes: make([]byte, 300)}}
 	proc := &Process{vm: vm}
 	n, err := proc.WriteAt(tooBigABuffer, 0)
 	if err != nil {
@@ -33,7 +42,7 @@ func TestWriteBoundary(t *testing.T) {
 	if err == nil {
 		t.Fatal("Should have reported an error and didn't")
 	}
-	if n != len(smallMemoryVM.memory) {
+	if n != len(smallMemoryVM.memory.bytes) {
 		t.Fatalf("Number of written bytes was %d, should have been 0", n)
 	}
 }
@@ -44,7 +53,7 @@ func TestReadBoundary(t *testing.T) {
 	if err == nil {
 		t.Fatal("Should have reported an error and didn't")
 	}
-	if n != len(smallMemoryVM.memory) {
+	if n != len(smallMemoryVM.memory.bytes) {
 		t.Fatalf("Number of written bytes was %d, should have been 0", n)
 	}
 }
@@ -95,7 +104,7 @@ func TestWriteEmpty(t *testing.T) {
 }
 
 func TestWriteOffset(t *testing.T) {
-	vm := &VM{memory: make([]byte, 300)}
+	vm := &VM{memory: &linearMemory{bytes: make([]byte, 300)}}
 	proc := &Process{vm: vm}
 
 	n, err := proc.WriteAt(tooBigABuffer, 2)
@@ -106,7 +115,542 @@ func TestWriteOffset(t *testing.T) {
 		t.Fatalf("Number of written bytes was %d, should have been %d", n, len(tooBigABuffer))
 	}
 
-	if vm.memory[0] != 0 || vm.memory[1] != 0 || vm.memory[2] != tooBigABuffer[0] {
+	if vm.memory.bytes[0] != 0 || vm.memory.bytes[1] != 0 || vm.memory.bytes[2] != tooBigABuffer[0] {
 		t.Fatal("Writing at offset didn't work")
 	}
 }
+
+func TestCancelFlag(t *testing.T) {
+	// (func (loop (br 0)))
+	raw := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+		0x03, 0x02, 0x01, 0x00,
+		0x0a, 0x09, 0x01, 0x07, 0x00, 0x03, 0x40, 0x0c, 0x00, 0x0b, 0x0b,
+	}
+	m, err := wasm.ReadModule(bytes.NewReader(raw), nil)
+	if err != nil {
+		t.Fatalf("Could not read module: %v", err)
+	}
+	vm, err := NewVM(m)
+	if err != nil {
+		t.Fatalf("Could not instantiate vm: %v", err)
+	}
+	vm.RecoverPanic = true
+
+	var flag int32
+	vm.SetCancelFlag(&flag)
+	time.AfterFunc(10*time.Millisecond, func() { atomic.StoreInt32(&flag, 1) })
+
+	done := make(chan error)
+	go func() {
+		_, err := vm.ExecCode(0)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != ErrAborted {
+			t.Errorf("have %v, want %v", err, ErrAborted)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("execution not aborted")
+	}
+
+	// A cancelled VM doesn't start executing
+	if _, err := vm.ExecCode(0); err != ErrAborted {
+		t.Errorf("have %v, want %v", err, ErrAborted)
+	}
+}
+
+// sumSquares is a module whose first function returns the sum of the squares
+// of 1..n, computing the squares with a call to the second function which
+// also adds its argument to the i32 at memory offset 0. It also has an unused
+// (table 1 2 funcref) and its (memory 1 2).
+var sumSquares = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	0x01, 0x06, 0x01, 0x60, 0x01, 0x7f, 0x01, 0x7f,
+	0x03, 0x03, 0x02, 0x00, 0x00,
+	0x04, 0x05, 0x01, 0x70, 0x01, 0x01, 0x02,
+	0x05, 0x04, 0x01, 0x01, 0x01, 0x02,
+	0x0a, 0x32, 0x02,
+	// (local $acc i32) (loop $l (set_local $acc (i32.add (get_local $acc)
+	// (call 1 (get_local $n)))) (br_if $l (tee_local $n (i32.sub
+	// (get_local $n) (i32.const 1))))) (get_local $acc)
+	0x1b, 0x01, 0x01, 0x7f,
+	0x03, 0x40, 0x20, 0x01, 0x20, 0x00, 0x10, 0x01, 0x6a, 0x21, 0x01,
+	0x20, 0x00, 0x41, 0x01, 0x6b, 0x22, 0x00, 0x0d, 0x00, 0x0b,
+	0x20, 0x01, 0x0b,
+	// (i32.store (i32.const 0) (i32.add (i32.load (i32.const 0))
+	// (get_local 0))) (i32.mul (get_local 0) (get_local 0))
+	0x14, 0x00,
+	0x41, 0x00, 0x41, 0x00, 0x28, 0x02, 0x00, 0x20, 0x00, 0x6a, 0x36, 0x02, 0x00,
+	0x20, 0x00, 0x20, 0x00, 0x6c, 0x0b,
+}
+
+func newSumSquaresVM(tb testing.TB) *VM {
+	m, err := wasm.ReadModule(bytes.NewReader(sumSquares), nil)
+	if err != nil {
+		tb.Fatalf("Could not read module: %v", err)
+	}
+	vm, err := NewVM(m)
+	if err != nil {
+		tb.Fatalf("Could not instantiate vm: %v", err)
+	}
+	vm.RecoverPanic = true
+	return vm
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	vm := newSumSquaresVM(t)
+	vm.Pause()
+	res, err := vm.ExecCode(0, 10)
+
+	// Move the execution to a new VM at every pause
+	var pauses int
+	for err == ErrPaused {
+		pauses++
+		snapshot, serr := vm.Snapshot()
+		if serr != nil {
+			t.Fatal(serr)
+		}
+		vm = newSumSquaresVM(t)
+		if rerr := vm.Restore(snapshot); rerr != nil {
+			t.Fatal(rerr)
+		}
+		if !vm.Paused() {
+			t.Fatal("restored vm not paused")
+		}
+		vm.Pause()
+		res, err = vm.Resume()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != uint32(385) {
+		t.Errorf("have %v, want 385", res)
+	}
+	if sum := endianess.Uint32(vm.Memory()); sum != 55 {
+		t.Errorf("memory: have %d, want 55", sum)
+	}
+	// one pause at the start, at each of the 10 calls and 9 backward branches
+	if pauses != 20 {
+		t.Errorf("have %d pauses, want 20", pauses)
+	}
+
+	if _, err := vm.Resume(); err != ErrNotPaused {
+		t.Errorf("have %v, want %v", err, ErrNotPaused)
+	}
+	if _, err := vm.Snapshot(); err != ErrNotPaused {
+		t.Errorf("have %v, want %v", err, ErrNotPaused)
+	}
+}
+
+func TestRestoreInvalid(t *testing.T) {
+	vm := newSumSquaresVM(t)
+	vm.Pause()
+	if _, err := vm.ExecCode(0, 3); err != ErrPaused {
+		t.Fatalf("have %v, want %v", err, ErrPaused)
+	}
+	snapshot, err := vm.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version := append([]byte{snapshotVersion + 1}, snapshot[1:]...)
+	if err := vm.Restore(version); err != UnsupportedSnapshotVersionError(snapshotVersion+1) {
+		t.Errorf("have %v, want unsupported version", err)
+	}
+	for i := 0; i < len(snapshot); i++ {
+		if err := vm.Restore(snapshot[:i]); err != ErrInvalidSnapshot {
+			t.Errorf("truncated at %d: have %v, want %v", i, err, ErrInvalidSnapshot)
+		}
+	}
+	if err := vm.Restore(append(snapshot, 0)); err != ErrInvalidSnapshot {
+		t.Errorf("trailing data: have %v, want %v", err, ErrInvalidSnapshot)
+	}
+
+	// Snapshots of paused VMs whose state is tampered with
+	for _, tt := range []struct {
+		name   string
+		tamper func(vm *VM)
+	}{
+		{"pc within an instruction", func(vm *VM) { vm.ctx.pc = 1 }},
+		{"too many operands", func(vm *VM) {
+			vm.stack = append(vm.stack, make([]uint64, vm.funcs[0].(compiledFunction).maxDepth+1)...)
+		}},
+		{"invalid function", func(vm *VM) { vm.tables[0].elems[0] = 2 }},
+		{"table above maximum", func(vm *VM) {
+			vm.tables[0].elems = append(vm.tables[0].elems, wasm.NullElement, wasm.NullElement)
+		}},
+		{"partial memory page", func(vm *VM) { vm.memory.bytes = vm.memory.bytes[:100] }},
+		{"memory above maximum", func(vm *VM) {
+			vm.memory.bytes = make([]byte, 3*wasmPageSize)
+		}},
+	} {
+		tampered := newSumSquaresVM(t)
+		tampered.Pause()
+		if _, err := tampered.ExecCode(0, 3); err != ErrPaused {
+			t.Fatalf("have %v, want %v", err, ErrPaused)
+		}
+		tt.tamper(tampered)
+		snapshot, err := tampered.Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := vm.Restore(snapshot); err != ErrInvalidSnapshot {
+			t.Errorf("%s: have %v, want %v", tt.name, err, ErrInvalidSnapshot)
+		}
+	}
+
+	// A failed restore leaves the VM untouched
+	if res, err := vm.Resume(); err != nil || res != uint32(14) {
+		t.Errorf("have %v, %v, want 14", res, err)
+	}
+}
+
+func TestLazyBodies(t *testing.T) {
+	// the body of the second function doesn't end with end
+	broken := append([]byte(nil), sumSquares...)
+	broken[len(broken)-1] = 0x01
+
+	for _, tt := range []struct {
+		raw []byte
+		err error
+	}{
+		{sumSquares, nil},
+		{broken, wasm.ErrFunctionNoEnd},
+	} {
+		m, err := wasm.ReadModuleConfig(bytes.NewReader(tt.raw), nil, wasm.DecodeConfig{LazyBodies: true})
+		if err != nil {
+			t.Fatalf("Could not read module: %v", err)
+		}
+		vm, err := NewVM(m)
+		if err != nil {
+			t.Fatalf("Could not instantiate vm: %v", err)
+		}
+		vm.RecoverPanic = true
+		if _, ok := vm.funcs[1].(lazyFunction); !ok {
+			t.Fatalf("function compiled at instantiation")
+		}
+		res, err := vm.ExecCode(0, 3)
+		if tt.err != nil {
+			if trap, ok := err.(*Trap); !ok || trap.Err != tt.err {
+				t.Errorf("have %v, want a trap with %v", err, tt.err)
+			}
+			continue
+		}
+		if err != nil || res != uint32(14) {
+			t.Errorf("have %v, %v, want 14", res, err)
+		}
+		if _, ok := vm.funcs[1].(compiledFunction); !ok {
+			t.Errorf("function not compiled on its first call")
+		}
+	}
+}
+
+// fib is a module whose function computes the Fibonacci numbers recursively:
+// (func (param i32) (result i32) (if (result i32) (i32.lt_s (get_local 0)
+// (i32.const 2)) (get_local 0) (i32.add (call 0 (i32.sub (get_local 0)
+// (i32.const 1))) (call 0 (i32.sub (get_local 0) (i32.const 2))))))
+var fib = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	0x01, 0x06, 0x01, 0x60, 0x01, 0x7f, 0x01, 0x7f,
+	0x03, 0x02, 0x01, 0x00,
+	0x0a, 0x1e, 0x01, 0x1c, 0x00,
+	0x20, 0x00, 0x41, 0x02, 0x48, 0x04, 0x7f, 0x20, 0x00, 0x05,
+	0x20, 0x00, 0x41, 0x01, 0x6b, 0x10, 0x00,
+	0x20, 0x00, 0x41, 0x02, 0x6b, 0x10, 0x00, 0x6a, 0x0b, 0x0b,
+}
+
+func newFibVM(tb testing.TB) *VM {
+	m, err := wasm.ReadModule(bytes.NewReader(fib), nil)
+	if err != nil {
+		tb.Fatalf("Could not read module: %v", err)
+	}
+	vm, err := NewVM(m)
+	if err != nil {
+		tb.Fatalf("Could not instantiate vm: %v", err)
+	}
+	vm.RecoverPanic = true
+	return vm
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	vm := newFibVM(t)
+	vm.MaxCallDepth = 10
+	if res, err := vm.ExecCode(0, 10); err != nil || res != uint32(55) {
+		t.Fatalf("have %v, %v, want 55", res, err)
+	}
+	if _, err := vm.ExecCode(0, 11); err != ErrCallStackExhausted {
+		t.Fatalf("have %v, want %v", err, ErrCallStackExhausted)
+	}
+	// The VM is usable after exhausting its call stack
+	if res, err := vm.ExecCode(0, 20); err != ErrCallStackExhausted || res != nil {
+		t.Fatalf("have %v, %v, want %v", res, err, ErrCallStackExhausted)
+	}
+	vm.MaxCallDepth = 0
+	if res, err := vm.ExecCode(0, 20); err != nil || res != uint32(6765) {
+		t.Fatalf("have %v, %v, want 6765", res, err)
+	}
+}
+
+func BenchmarkCallFib(b *testing.B) {
+	vm := newFibVM(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := vm.ExecCode(0, 20); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCallSumSquares(b *testing.B) {
+	vm := newSumSquaresVM(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := vm.ExecCode(0, 1000); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestTrapFrames(t *testing.T) {
+	// (func $outer nop (call $inner)) (func $inner nop unreachable)
+	raw := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+		0x03, 0x03, 0x02, 0x00, 0x00,
+		0x0a, 0x0c, 0x02, 0x05, 0x00, 0x01, 0x10, 0x01, 0x0b, 0x04, 0x00, 0x01, 0x00, 0x0b,
+		0x00, 0x16, 0x04, 'n', 'a', 'm', 'e',
+		0x01, 0x0f, 0x02, 0x00, 0x05, 'o', 'u', 't', 'e', 'r', 0x01, 0x05, 'i', 'n', 'n', 'e', 'r',
+	}
+	m, err := wasm.ReadModule(bytes.NewReader(raw), nil)
+	if err != nil {
+		t.Fatalf("Could not read module: %v", err)
+	}
+	vm, err := NewVM(m)
+	if err != nil {
+		t.Fatalf("Could not instantiate vm: %v", err)
+	}
+	vm.RecoverPanic = true
+
+	_, err = vm.ExecCode(0)
+	trap, ok := err.(*Trap)
+	if !ok {
+		t.Fatalf("expected trap, have %v", err)
+	}
+	if trap.Err != ErrUnreachable || trap.Error() != ErrUnreachable.Error() || !errors.Is(err, ErrUnreachable) {
+		t.Errorf("unexpected cause %v", trap.Err)
+	}
+	want := []Frame{{Index: 1, Name: "inner", Offset: 1}, {Index: 0, Name: "outer", Offset: 1}}
+	if !reflect.DeepEqual(trap.Frames, want) {
+		t.Errorf("have frames %v, want %v", trap.Frames, want)
+	}
+	const trace = "exec: reached unreachable\n\tat inner (func[1])+0x1\n\tat outer (func[0])+0x1"
+	if trap.Trace() != trace {
+		t.Errorf("have trace %q, want %q", trap.Trace(), trace)
+	}
+
+	// Cancellation isn't a trap
+	var flag int32 = 1
+	vm.SetCancelFlag(&flag)
+	if _, err := vm.ExecCode(0); err != ErrAborted {
+		t.Errorf("have %v, want %v", err, ErrAborted)
+	}
+}
+
+// The behavior of the features is tested by the scripts of testdata/spec.
+
+// featureModules are minimal modules using the features, by feature.
+var featureModules = map[wasm.Features][]byte{
+	// (func (param f32) (result i32) (i32.trunc_sat_f32_s (get_local 0)))
+	wasm.FeatureSatFloatToInt: {
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x06, 0x01, 0x60, 0x01, 0x7d, 0x01, 0x7f,
+		0x03, 0x02, 0x01, 0x00,
+		0x0a, 0x08, 0x01, 0x06, 0x00, 0x20, 0x00, 0xfc, 0x00, 0x0b,
+	},
+	wasm.FeatureBulkMemory: bulkMemory,
+	// (func (result i32 i32) (i32.const 1) (i32.const 2))
+	wasm.FeatureMultiValue: {
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x06, 0x01, 0x60, 0x00, 0x02, 0x7f, 0x7f,
+		0x03, 0x02, 0x01, 0x00,
+		0x0a, 0x08, 0x01, 0x06, 0x00, 0x41, 0x01, 0x41, 0x02, 0x0b,
+	},
+	wasm.FeatureReferenceTypes: tableGrow,
+	// (func (param v128) (result v128) (get_local 0))
+	wasm.FeatureSIMD: {
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x06, 0x01, 0x60, 0x01, 0x7b, 0x01, 0x7b,
+		0x03, 0x02, 0x01, 0x00,
+		0x0a, 0x06, 0x01, 0x04, 0x00, 0x20, 0x00, 0x0b,
+	},
+	// (func (param i32) (result i32) (i32.extend8_s (get_local 0)))
+	wasm.FeatureSignExt: {
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x06, 0x01, 0x60, 0x01, 0x7f, 0x01, 0x7f,
+		0x03, 0x02, 0x01, 0x00,
+		0x0a, 0x07, 0x01, 0x05, 0x00, 0x20, 0x00, 0xc0, 0x0b,
+	},
+}
+
+func TestFeatureGates(t *testing.T) {
+	for _, tt := range []struct {
+		feature wasm.Features
+		err     error // of the validation without the feature
+	}{
+		{wasm.FeatureSatFloatToInt, ops.DisabledError("i32.trunc_sat_f32_s")},
+		{wasm.FeatureBulkMemory, ops.DisabledError("memory.init")},
+		{wasm.FeatureMultiValue, wasm.ErrMultipleResults},
+		{wasm.FeatureReferenceTypes, validate.ErrMultipleTables},
+		{wasm.FeatureSIMD, wasm.ErrV128},
+		{wasm.FeatureSignExt, ops.DisabledError("i32.extend8_s")},
+	} {
+		m, err := wasm.ReadModule(bytes.NewReader(featureModules[tt.feature]), nil)
+		if err != nil {
+			t.Fatalf("Could not read module: %v", err)
+		}
+		err = validate.VerifyModule(m)
+		if verr, ok := err.(validate.Error); ok {
+			err = verr.Err
+		}
+		if err != tt.err {
+			t.Errorf("feature %#x: have %v, want %v", tt.feature, err, tt.err)
+		}
+		if _, err := NewVM(m); err == nil {
+			t.Errorf("feature %#x: NewVM should fail without the feature", tt.feature)
+		}
+
+		m.Features = tt.feature
+		if err := validate.VerifyModule(m); err != nil {
+			t.Errorf("feature %#x: Could not validate module: %v", tt.feature, err)
+		}
+		if _, err := NewVM(m); err != nil {
+			t.Errorf("feature %#x: Could not instantiate vm: %v", tt.feature, err)
+		}
+	}
+}
+
+// bulkMemory is a module with a passive data segment [1 2 3 4] and a passive
+// element segment [func 0] for a table of 1 element, whose function runs
+// each of the bulk memory operators charged by length once.
+var bulkMemory = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+	0x03, 0x02, 0x01, 0x00,
+	0x04, 0x04, 0x01, 0x70, 0x00, 0x01,
+	0x05, 0x03, 0x01, 0x00, 0x01,
+	0x09, 0x05, 0x01, 0x01, 0x00, 0x01, 0x00,
+	0x0c, 0x01, 0x01,
+	0x0a, 0x36, 0x01, 0x34, 0x00,
+	// (memory.init 0 (i32.const 8) (i32.const 1) (i32.const 3))
+	0x41, 0x08, 0x41, 0x01, 0x41, 0x03, 0xfc, 0x08, 0x00, 0x00,
+	// (memory.copy (i32.const 16) (i32.const 8) (i32.const 3))
+	0x41, 0x10, 0x41, 0x08, 0x41, 0x03, 0xfc, 0x0a, 0x00, 0x00,
+	// (memory.fill (i32.const 0) (i32.const 0xaa) (i32.const 4))
+	0x41, 0x00, 0x41, 0xaa, 0x01, 0x41, 0x04, 0xfc, 0x0b, 0x00,
+	// (table.init 0 (i32.const 0) (i32.const 0) (i32.const 1))
+	0x41, 0x00, 0x41, 0x00, 0x41, 0x01, 0xfc, 0x0c, 0x00, 0x00,
+	// (table.copy (i32.const 0) (i32.const 0) (i32.const 1))
+	0x41, 0x00, 0x41, 0x00, 0x41, 0x01, 0xfc, 0x0e, 0x00, 0x00, 0x0b,
+	0x0b, 0x07, 0x01, 0x01, 0x04, 0x01, 0x02, 0x03, 0x04,
+}
+
+func TestBulkMemoryMeter(t *testing.T) {
+	m, err := wasm.ReadModule(bytes.NewReader(bulkMemory), nil)
+	if err != nil {
+		t.Fatalf("Could not read module: %v", err)
+	}
+	m.Features = wasm.FeatureBulkMemory
+	vm, err := NewVM(m)
+	if err != nil {
+		t.Fatalf("Could not instantiate vm: %v", err)
+	}
+	var charged []uint64
+	vm.SetMeter(func(n uint64) { charged = append(charged, n) })
+
+	if _, err := vm.ExecCode(0); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(charged, []uint64{3, 3, 4, 1, 1}) {
+		t.Errorf("have charged %v", charged)
+	}
+}
+
+// tableGrow is a module with a table of 2 functions and a table of 1 to 3
+// externrefs, whose function grows the second table by 2 null references and
+// returns its previous size.
+var tableGrow = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	0x01, 0x05, 0x01, 0x60, 0x00, 0x01, 0x7f,
+	0x03, 0x02, 0x01, 0x00,
+	0x04, 0x08, 0x02, 0x70, 0x00, 0x02, 0x6f, 0x01, 0x01, 0x03,
+	// (table.grow 1 (ref.null extern) (i32.const 2))
+	0x0a, 0x0b, 0x01, 0x09, 0x00, 0xd0, 0x6f, 0x41, 0x02, 0xfc, 0x0f, 0x01, 0x0b,
+}
+
+func TestTableGrowMeter(t *testing.T) {
+	m, err := wasm.ReadModule(bytes.NewReader(tableGrow), nil)
+	if err != nil {
+		t.Fatalf("Could not read module: %v", err)
+	}
+	m.Features = wasm.FeatureReferenceTypes
+	vm, err := NewVM(m)
+	if err != nil {
+		t.Fatalf("Could not instantiate vm: %v", err)
+	}
+	var charged []uint64
+	vm.SetMeter(func(n uint64) { charged = append(charged, n) })
+
+	if res, err := vm.ExecCode(0); err != nil || res != uint32(1) {
+		t.Fatalf("have %v, %v, want 1", res, err)
+	}
+	if !reflect.DeepEqual(charged, []uint64{2}) {
+		t.Errorf("have charged %v", charged)
+	}
+}
+
+func TestExecCodeMultiValue(t *testing.T) {
+	m, err := wasm.ReadModule(bytes.NewReader(featureModules[wasm.FeatureMultiValue]), nil)
+	if err != nil {
+		t.Fatalf("Could not read module: %v", err)
+	}
+	m.Features = wasm.FeatureMultiValue
+	vm, err := NewVM(m)
+	if err != nil {
+		t.Fatalf("Could not instantiate vm: %v", err)
+	}
+
+	values, err := vm.ExecCodeValues(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint64{1, 2}; !reflect.DeepEqual(values, want) {
+		t.Errorf("have %v, want %v", values, want)
+	}
+	res, err := vm.ExecCode(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{uint32(1), uint32(2)}; !reflect.DeepEqual(res, want) {
+		t.Errorf("have %v, want %v", res, want)
+	}
+}
+
+func TestExecCodeV128(t *testing.T) {
+	m, err := wasm.ReadModule(bytes.NewReader(featureModules[wasm.FeatureSIMD]), nil)
+	if err != nil {
+		t.Fatalf("Could not read module: %v", err)
+	}
+	m.Features = wasm.FeatureSIMD
+	vm, err := NewVM(m)
+	if err != nil {
+		t.Fatalf("Could not instantiate vm: %v", err)
+	}
+	if _, err := vm.ExecCode(0, 0); err != ErrV128Signature {
+		t.Errorf("have %v, want %v", err, ErrV128Signature)
+	}
+}
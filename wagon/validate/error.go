@@ -24,6 +24,14 @@ func (e Error) Error() string {
 
 var ErrStackUnderflow = errors.New("validate: stack underflow")
 
+// ErrMultipleTables is returned for a module with several tables, imported
+// or not, if the reference types feature isn't enabled.
+var ErrMultipleTables = errors.New("validate: multiple tables require the reference types feature")
+
+// ErrV128 is returned for a module using the v128 type, in its function
+// types, globals or locals, if the SIMD feature isn't enabled.
+var ErrV128 = wasm.ErrV128
+
 type InvalidImmediateError struct {
 	ImmType string
 	OpName  string
@@ -67,6 +75,12 @@ func (e InvalidElementIndexError) Error() string {
 	return fmt.Sprintf("invalid element index %d", uint32(e))
 }
 
+type InvalidDataIndexError uint32
+
+func (e InvalidDataIndexError) Error() string {
+	return fmt.Sprintf("invalid data segment index %d", uint32(e))
+}
+
 type NoSectionError wasm.SectionID
 
 func (e NoSectionError) Error() string {
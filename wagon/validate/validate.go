@@ -52,10 +52,13 @@ func verifyBody(fn *wasm.FunctionSig, body *wasm.FunctionBody, module *wasm.Modu
 			return vm, err
 		}
 
-		opStruct, err := ops.New(op)
+		opStruct, err := ops.Read(op, vm.code)
 		if err != nil {
 			return vm, err
 		}
+		if !opStruct.Enabled(module.Features) {
+			return vm, ops.DisabledError(opStruct.Name)
+		}
 
 		logger.Printf("PC: %d OP: %s polymorphic: %v", vm.pc(), opStruct.Name, vm.isPolymorphic())
 
@@ -72,13 +75,15 @@ func verifyBody(fn *wasm.FunctionSig, body *wasm.FunctionBody, module *wasm.Modu
 				return vm, err
 			}
 
-			switch wasm.ValueType(sig) {
-			case wasm.ValueTypeI32, wasm.ValueTypeI64, wasm.ValueTypeF32, wasm.ValueTypeF64, wasm.ValueType(wasm.BlockTypeEmpty):
-				vm.pushBlock(op, wasm.BlockType(sig))
-			default:
+			blockSig, err := module.BlockSignature(wasm.BlockType(sig))
+			if err != nil {
 				if !vm.isPolymorphic() {
 					return vm, InvalidImmediateError{"block_type", opStruct.Name}
 				}
+				blockSig = &wasm.FunctionSig{}
+			}
+			if err := vm.pushBlock(op, blockSig); err != nil {
+				return vm, err
 			}
 
 		case ops.Else:
@@ -87,14 +92,14 @@ func verifyBody(fn *wasm.FunctionSig, body *wasm.FunctionBody, module *wasm.Modu
 				return vm, UnmatchedOpError(op)
 			}
 
-			if block.blockType != wasm.BlockTypeEmpty {
-				top, under := vm.topOperand()
-				if !vm.isPolymorphic() && (under || top.Type != wasm.ValueType(block.blockType)) {
-					return vm, InvalidTypeError{wasm.ValueType(block.blockType), top.Type}
-				}
-				vm.pushOperand(wasm.ValueType(block.blockType))
+			if err := vm.checkOperands(block.sig.ReturnTypes); !vm.isPolymorphic() && err != nil {
+				return vm, err
 			}
+			// the else branch starts again from the parameters
 			vm.stackTop = block.stackTop
+			for _, t := range block.sig.ParamTypes {
+				vm.pushOperand(t)
+			}
 		case ops.End:
 			isPolymorphic := vm.isPolymorphic()
 
@@ -103,16 +108,14 @@ func verifyBody(fn *wasm.FunctionSig, body *wasm.FunctionBody, module *wasm.Modu
 				return vm, UnmatchedOpError(op)
 			}
 
-			if block.blockType != wasm.BlockTypeEmpty {
-				top, under := vm.topOperand()
-				if !isPolymorphic && (under || top.Type != wasm.ValueType(block.blockType)) {
-					return vm, InvalidTypeError{wasm.ValueType(block.blockType), top.Type}
-				}
-				vm.stackTop = block.stackTop
-				vm.pushOperand(wasm.ValueType(block.blockType))
-				vm.stackTop = block.stackTop + 1 // as we pushed an element
-			} else {
-				vm.stackTop = block.stackTop
+			// checkOperands is called once the block is popped,
+			// hence isPolymorphic.
+			if err := vm.checkOperands(block.sig.ReturnTypes); !isPolymorphic && err != nil {
+				return vm, err
+			}
+			vm.stackTop = block.stackTop
+			for _, t := range block.sig.ReturnTypes {
+				vm.pushOperand(t)
 			}
 
 		case ops.BrIf, ops.Br:
@@ -159,15 +162,8 @@ func verifyBody(fn *wasm.FunctionSig, body *wasm.FunctionBody, module *wasm.Modu
 			vm.setPolymorphic()
 
 		case ops.Return:
-			if len(fn.ReturnTypes) > 1 {
-				panic("not implemented")
-			}
-			if len(fn.ReturnTypes) != 0 {
-				// only single returns supported for now
-				top, under := vm.popOperand()
-				if !vm.isPolymorphic() && (under || top.Type != fn.ReturnTypes[0]) {
-					return vm, InvalidTypeError{fn.ReturnTypes[0], top.Type}
-				}
+			if err := vm.checkOperands(fn.ReturnTypes); !vm.isPolymorphic() && err != nil {
+				return vm, err
 			}
 			vm.setPolymorphic()
 
@@ -254,6 +250,16 @@ func verifyBody(fn *wasm.FunctionSig, body *wasm.FunctionBody, module *wasm.Modu
 				return vm, err
 			}
 
+		case ops.MiscPrefix:
+			if err := verifyMisc(vm, opStruct, module); err != nil {
+				return vm, err
+			}
+
+		case ops.SIMDPrefix:
+			if err := verifySIMD(vm, opStruct); err != nil {
+				return vm, err
+			}
+
 		case ops.Call:
 			index, err := vm.fetchVarUint()
 			if err != nil {
@@ -274,14 +280,11 @@ func verifyBody(fn *wasm.FunctionSig, body *wasm.FunctionBody, module *wasm.Modu
 				}
 			}
 
-			if len(fn.Sig.ReturnTypes) > 0 {
-				vm.pushOperand(fn.Sig.ReturnTypes[0])
+			for _, t := range fn.Sig.ReturnTypes {
+				vm.pushOperand(t)
 			}
 
 		case ops.CallIndirect:
-			if module.Table == nil || len(module.Table.Entries) == 0 {
-				return vm, NoSectionError(wasm.SectionIDTable)
-			}
 			// The call_indirect process consists of getting two i32 values
 			// off (first from the bytecode stream, and the second from
 			//  the stack) and using first as an index into the "Types" section
@@ -301,6 +304,22 @@ func verifyBody(fn *wasm.FunctionSig, body *wasm.FunctionBody, module *wasm.Modu
 
 			fnExpectSig := module.Types.Entries[index]
 
+			// the table index, reserved in the MVP
+			tableIndex, err := vm.fetchVarUint()
+			if err != nil {
+				return vm, err
+			}
+			table := module.GetTable(int(tableIndex))
+			if table == nil {
+				if tableIndex == 0 {
+					return vm, NoSectionError(wasm.SectionIDTable)
+				}
+				return vm, wasm.InvalidTableIndexError(tableIndex)
+			}
+			if table.ElementType != wasm.ElemTypeAnyFunc {
+				return vm, InvalidTypeError{wasm.ValueTypeFuncref, wasm.ValueType(table.ElementType)}
+			}
+
 			if operand, under := vm.popOperand(); !vm.isPolymorphic() && (under || operand.Type != wasm.ValueTypeI32) {
 				return vm, InvalidTypeError{wasm.ValueTypeI32, operand.Type}
 			}
@@ -313,8 +332,8 @@ func verifyBody(fn *wasm.FunctionSig, body *wasm.FunctionBody, module *wasm.Modu
 				}
 			}
 
-			if len(fnExpectSig.ReturnTypes) > 0 {
-				vm.pushOperand(fnExpectSig.ReturnTypes[0])
+			for _, t := range fnExpectSig.ReturnTypes {
+				vm.pushOperand(t)
 			}
 
 		case ops.Drop:
@@ -342,16 +361,289 @@ func verifyBody(fn *wasm.FunctionSig, body *wasm.FunctionBody, module *wasm.Modu
 
 			// last 2 popped values should be of the same type
 			if operands[0].Type != operands[1].Type {
-				return vm, InvalidTypeError{operands[1].Type, operands[2].Type}
+				return vm, InvalidTypeError{operands[1].Type, operands[0].Type}
+			}
+			// references can only be selected by the typed select
+			if operands[1].Type.IsReference() {
+				return vm, InvalidTypeError{wasm.ValueTypeI32, operands[1].Type}
 			}
 
 			vm.pushOperand(operands[1].Type)
+
+		case ops.SelectT, ops.TableGet, ops.TableSet, ops.RefNull, ops.RefIsNull, ops.RefFunc:
+			if err := verifyRef(vm, opStruct, module); err != nil {
+				return vm, err
+			}
 		}
 	}
 
 	return vm, nil
 }
 
+// verifyMisc reads and verifies the immediates of an operator following the
+// 0xfc prefix.
+func verifyMisc(vm *mockVM, op ops.Op, module *wasm.Module) error {
+	switch op.Code {
+	case ops.MemoryInit, ops.DataDrop:
+		index, err := vm.fetchVarUint()
+		if err != nil {
+			return err
+		}
+		if module.DataCount == nil {
+			return NoSectionError(wasm.SectionIDDataCount)
+		}
+		if index >= module.DataCount.Count {
+			return InvalidDataIndexError(index)
+		}
+		if op.Code == ops.MemoryInit {
+			if _, err := vm.fetchVarUint(); err != nil {
+				return err
+			}
+		}
+	case ops.MemoryCopy:
+		for i := 0; i < 2; i++ {
+			if _, err := vm.fetchVarUint(); err != nil {
+				return err
+			}
+		}
+	case ops.MemoryFill:
+		if _, err := vm.fetchVarUint(); err != nil {
+			return err
+		}
+	case ops.TableInit, ops.ElemDrop:
+		index, err := vm.fetchVarUint()
+		if err != nil {
+			return err
+		}
+		if module.Elements == nil || int(index) >= len(module.Elements.Entries) {
+			return InvalidElementIndexError(index)
+		}
+		if op.Code == ops.TableInit {
+			table, err := verifyTableIndex(vm, module)
+			if err != nil {
+				return err
+			}
+			segment := module.Elements.Entries[index]
+			if (segment.Type == wasm.ElemTypeExternRef) != (table.ElementType == wasm.ElemTypeExternRef) {
+				return InvalidTypeError{wasm.ValueType(table.ElementType), wasm.ValueType(segment.Type)}
+			}
+		}
+	case ops.TableCopy:
+		dst, err := verifyTableIndex(vm, module)
+		if err != nil {
+			return err
+		}
+		src, err := verifyTableIndex(vm, module)
+		if err != nil {
+			return err
+		}
+		if dst.ElementType != src.ElementType {
+			return InvalidTypeError{wasm.ValueType(dst.ElementType), wasm.ValueType(src.ElementType)}
+		}
+	case ops.TableGrow, ops.TableFill:
+		table, err := verifyTableIndex(vm, module)
+		if err != nil {
+			return err
+		}
+		t := wasm.ValueType(table.ElementType)
+		// table.grow takes the initial value of the new elements and
+		// their number, table.fill an index, the value and a length
+		args := []wasm.ValueType{t, wasm.ValueTypeI32}
+		if op.Code == ops.TableFill {
+			args = []wasm.ValueType{wasm.ValueTypeI32, t, wasm.ValueTypeI32}
+		}
+		if err := vm.popOperands(args); err != nil {
+			return err
+		}
+		if op.Code == ops.TableGrow {
+			vm.pushOperand(wasm.ValueTypeI32)
+		}
+	case ops.TableSize:
+		if _, err := verifyTableIndex(vm, module); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// simdMemoryAlign maps the SIMD memory operators to the log2 of their natural
+// alignment, the largest alignment their memory_immediate may have.
+var simdMemoryAlign = map[byte]uint32{
+	ops.V128Load: 4, ops.V128Store: 4,
+	ops.V128Load8x8S: 3, ops.V128Load8x8U: 3, ops.V128Load16x4S: 3, ops.V128Load16x4U: 3, ops.V128Load32x2S: 3, ops.V128Load32x2U: 3,
+	ops.V128Load8Splat: 0, ops.V128Load16Splat: 1, ops.V128Load32Splat: 2, ops.V128Load64Splat: 3,
+	ops.V128Load32Zero: 2, ops.V128Load64Zero: 3,
+	ops.V128Load8Lane: 0, ops.V128Load16Lane: 1, ops.V128Load32Lane: 2, ops.V128Load64Lane: 3,
+	ops.V128Store8Lane: 0, ops.V128Store16Lane: 1, ops.V128Store32Lane: 2, ops.V128Store64Lane: 3,
+}
+
+// simdLanes maps the SIMD operators taking a lane index to the number of
+// lanes of their shape.
+var simdLanes = map[byte]byte{
+	ops.I8x16ExtractLaneS: 16, ops.I8x16ExtractLaneU: 16, ops.I8x16ReplaceLane: 16,
+	ops.I16x8ExtractLaneS: 8, ops.I16x8ExtractLaneU: 8, ops.I16x8ReplaceLane: 8,
+	ops.I32x4ExtractLane: 4, ops.I32x4ReplaceLane: 4,
+	ops.I64x2ExtractLane: 2, ops.I64x2ReplaceLane: 2,
+	ops.F32x4ExtractLane: 4, ops.F32x4ReplaceLane: 4,
+	ops.F64x2ExtractLane: 2, ops.F64x2ReplaceLane: 2,
+	ops.V128Load8Lane: 16, ops.V128Load16Lane: 8, ops.V128Load32Lane: 4, ops.V128Load64Lane: 2,
+	ops.V128Store8Lane: 16, ops.V128Store16Lane: 8, ops.V128Store32Lane: 4, ops.V128Store64Lane: 2,
+}
+
+// verifySIMD reads and verifies the immediates of an operator following the
+// 0xfd prefix, its operands are checked like those of other operators.
+func verifySIMD(vm *mockVM, op ops.Op) error {
+	if align, ok := simdMemoryAlign[op.Code]; ok {
+		flags, err := vm.fetchVarUint()
+		if err != nil {
+			return err
+		}
+		if flags > align {
+			return InvalidImmediateError{"alignment", op.Name}
+		}
+		// offset
+		if _, err := vm.fetchVarUint(); err != nil {
+			return err
+		}
+	}
+	if lanes, ok := simdLanes[op.Code]; ok {
+		lane, err := vm.code.ReadByte()
+		if err != nil {
+			return err
+		}
+		if lane >= lanes {
+			return InvalidImmediateError{"laneidx", op.Name}
+		}
+	}
+	switch op.Code {
+	case ops.V128Const, ops.I8x16Shuffle:
+		var b [16]byte
+		if _, err := io.ReadFull(vm.code, b[:]); err != nil {
+			return err
+		}
+		if op.Code == ops.I8x16Shuffle {
+			for _, lane := range b {
+				if lane >= 32 {
+					return InvalidImmediateError{"laneidx", op.Name}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// usesV128 reports whether the types, globals or locals of the module use the
+// v128 type.
+func usesV128(module *wasm.Module) bool {
+	for _, sig := range module.Types.Entries {
+		if wasm.HasV128(sig.ParamTypes) || wasm.HasV128(sig.ReturnTypes) {
+			return true
+		}
+	}
+	for _, global := range module.GlobalIndexSpace {
+		if global.Type.Type == wasm.ValueTypeV128 {
+			return true
+		}
+	}
+	for _, body := range module.Code.Bodies {
+		for _, entry := range body.Locals {
+			if entry.Type == wasm.ValueTypeV128 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// verifyRef reads the immediates of the reference operators, the table
+// operators and the typed select, and verifies their operands.
+func verifyRef(vm *mockVM, op ops.Op, module *wasm.Module) error {
+	switch op.Code {
+	case ops.SelectT:
+		n, err := vm.fetchVarUint()
+		if err != nil {
+			return err
+		}
+		if n != 1 {
+			return InvalidImmediateError{"vec(valtype)", op.Name}
+		}
+		v, err := vm.fetchVarInt()
+		if err != nil {
+			return err
+		}
+		t := wasm.ValueType(v)
+		if int32(t) != v || !isValueType(t) {
+			return InvalidImmediateError{"valtype", op.Name}
+		}
+		if err := vm.popOperands([]wasm.ValueType{t, t, wasm.ValueTypeI32}); err != nil {
+			return err
+		}
+		vm.pushOperand(t)
+	case ops.TableGet, ops.TableSet:
+		table, err := verifyTableIndex(vm, module)
+		if err != nil {
+			return err
+		}
+		t := wasm.ValueType(table.ElementType)
+		if op.Code == ops.TableGet {
+			if err := vm.popOperands([]wasm.ValueType{wasm.ValueTypeI32}); err != nil {
+				return err
+			}
+			vm.pushOperand(t)
+		} else if err := vm.popOperands([]wasm.ValueType{wasm.ValueTypeI32, t}); err != nil {
+			return err
+		}
+	case ops.RefNull:
+		v, err := vm.fetchVarInt()
+		if err != nil {
+			return err
+		}
+		t := wasm.ValueType(v)
+		if !t.IsReference() {
+			return InvalidImmediateError{"reftype", op.Name}
+		}
+		vm.pushOperand(t)
+	case ops.RefIsNull:
+		operand, under := vm.popOperand()
+		if !vm.isPolymorphic() && (under || !operand.Type.IsReference()) {
+			return InvalidTypeError{wasm.ValueTypeFuncref, operand.Type}
+		}
+		vm.pushOperand(wasm.ValueTypeI32)
+	case ops.RefFunc:
+		index, err := vm.fetchVarUint()
+		if err != nil {
+			return err
+		}
+		if module.GetFunction(int(index)) == nil {
+			return wasm.InvalidFunctionIndexError(index)
+		}
+	}
+	return nil
+}
+
+// isValueType reports whether t is a number, vector or reference type.
+func isValueType(t wasm.ValueType) bool {
+	switch t {
+	case wasm.ValueTypeI32, wasm.ValueTypeI64, wasm.ValueTypeF32, wasm.ValueTypeF64, wasm.ValueTypeV128:
+		return true
+	}
+	return t.IsReference()
+}
+
+// verifyTableIndex reads and verifies a table index immediate, and returns
+// the type of the table.
+func verifyTableIndex(vm *mockVM, module *wasm.Module) (*wasm.Table, error) {
+	index, err := vm.fetchVarUint()
+	if err != nil {
+		return nil, err
+	}
+	table := module.GetTable(int(index))
+	if table == nil {
+		return nil, wasm.InvalidTableIndexError(index)
+	}
+	return table, nil
+}
+
 // VerifyModule verifies the given module according to WebAssembly verification
 // specs.
 func VerifyModule(module *wasm.Module) error {
@@ -361,9 +653,33 @@ func VerifyModule(module *wasm.Module) error {
 	if module.Code == nil {
 		return NoSectionError(wasm.SectionIDCode)
 	}
+	for i := range module.Code.Bodies {
+		if err := module.Code.Bodies[i].Decode(); err != nil {
+			return err
+		}
+	}
+
+	if !module.Features.Has(wasm.FeatureMultiValue) {
+		for _, sig := range module.Types.Entries {
+			if len(sig.ReturnTypes) > 1 {
+				return wasm.ErrMultipleResults
+			}
+		}
+	}
+
+	if !module.Features.Has(wasm.FeatureReferenceTypes) && module.GetTable(1) != nil {
+		return ErrMultipleTables
+	}
+
+	if !module.Features.Has(wasm.FeatureSIMD) && usesV128(module) {
+		return ErrV128
+	}
 
 	logger.Printf("There are %d functions", len(module.Function.Types))
 	for i, fn := range module.FunctionIndexSpace {
+		if fn.IsImport() {
+			continue
+		}
 		if vm, err := verifyBody(fn.Sig, fn.Body, module); err != nil {
 			return Error{vm.pc(), i, err}
 		}
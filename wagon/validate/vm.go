@@ -33,12 +33,12 @@ type mockVM struct {
 // it is used to verify that the block signature set by the operator is the correct
 // one when the block ends
 type block struct {
-	pc          int            // the pc where the control flow operator starting the block is located
-	stackTop    int            // stack top when the block started
-	blockType   wasm.BlockType // block_type signature of the control operator
-	op          byte           // opcode for the operator starting the new block
-	polymorphic bool           // whether the block has a polymorphic stack
-	loop        bool           // whether the block is the body of a loop instruction
+	pc          int               // the pc where the control flow operator starting the block is located
+	stackTop    int               // stack top when the block started, below its parameters
+	sig         *wasm.FunctionSig // the parameters and results of the block, from its block_type
+	op          byte              // opcode for the operator starting the new block
+	polymorphic bool              // whether the block has a polymorphic stack
+	loop        bool              // whether the block is the body of a loop instruction
 }
 
 func (vm *mockVM) fetchVarUint() (uint32, error) {
@@ -71,16 +71,26 @@ func (vm *mockVM) fetchUint64() (uint64, error) {
 	return binary.LittleEndian.Uint64(buf[:]), nil
 }
 
-func (vm *mockVM) pushBlock(op byte, blockType wasm.BlockType) {
-	logger.Printf("Pushing block %v", blockType)
+// pushBlock starts a block with the signature sig, whose parameters must be
+// on the top of the stack.
+func (vm *mockVM) pushBlock(op byte, sig *wasm.FunctionSig) error {
+	logger.Printf("Pushing block %v", sig)
+	if err := vm.checkOperands(sig.ParamTypes); !vm.isPolymorphic() && err != nil {
+		return err
+	}
+	stackTop := vm.stackTop - len(sig.ParamTypes)
+	if stackTop < 0 {
+		stackTop = 0
+	}
 	vm.blocks = append(vm.blocks, block{
 		pc:          vm.pc(),
-		stackTop:    vm.stackTop,
-		blockType:   blockType,
+		stackTop:    stackTop,
+		sig:         sig,
 		polymorphic: vm.isPolymorphic(),
 		op:          op,
 		loop:        op == ops.Loop,
 	})
+	return nil
 }
 
 // Get a block from it's relative nesting depth
@@ -95,32 +105,41 @@ func (vm *mockVM) getBlockFromDepth(depth int) *block {
 // Returns nil if depth is a valid nesting depth value that can be
 // branched to.
 func (vm *mockVM) canBranch(depth int) error {
-	blockType := wasm.BlockTypeEmpty
+	var types []wasm.ValueType
 
 	block := vm.getBlockFromDepth(depth)
-	// jumping to the start of a loop block doesn't push a value
-	// on the stack.
+	// jumping to the start of a loop block takes its parameters, other
+	// blocks take their results.
 	if block == nil {
 		if depth == len(vm.blocks) {
 			//equivalent to a `return', as the function
 			//body is an "implicit" block
-			if len(vm.curFunc.ReturnTypes) != 0 {
-				blockType = wasm.BlockType(vm.curFunc.ReturnTypes[0])
-			}
+			types = vm.curFunc.ReturnTypes
 		} else {
 			return InvalidLabelError(uint32(depth))
 		}
-	} else if !block.loop {
-		blockType = block.blockType
+	} else if block.loop {
+		types = block.sig.ParamTypes
+	} else {
+		types = block.sig.ReturnTypes
 	}
 
-	if blockType != wasm.BlockTypeEmpty {
-		top, under := vm.topOperand()
-		if under || top.Type != wasm.ValueType(blockType) {
-			return InvalidTypeError{wasm.ValueType(blockType), top.Type}
+	return vm.checkOperands(types)
+}
+
+// checkOperands returns nil if the operands on the top of the stack are of
+// the given types, without popping them.
+func (vm *mockVM) checkOperands(types []wasm.ValueType) error {
+	for i := range types {
+		want := types[len(types)-1-i]
+		stackTop := vm.stackTop - 1 - i
+		if stackTop < 0 {
+			return InvalidTypeError{want, 0}
+		}
+		if got := vm.stack[stackTop].Type; got != want {
+			return InvalidTypeError{want, got}
 		}
 	}
-
 	return nil
 }
 
@@ -181,6 +200,18 @@ func (vm *mockVM) pushOperand(t wasm.ValueType) {
 	logger.Printf("Stack after push is %v. Pushed %v", vm.stack[:vm.stackTop], o)
 }
 
+// popOperands pops operands of the given types, the last one being on the
+// top of the stack.
+func (vm *mockVM) popOperands(types []wasm.ValueType) error {
+	for i := len(types) - 1; i >= 0; i-- {
+		operand, under := vm.popOperand()
+		if !vm.isPolymorphic() && (under || operand.Type != types[i]) {
+			return InvalidTypeError{types[i], operand.Type}
+		}
+	}
+	return nil
+}
+
 func (vm *mockVM) adjustStack(op ops.Op) error {
 	for _, t := range op.Args {
 		op, under := vm.popOperand()
@@ -0,0 +1,37 @@
+// Copyright 2017 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wasm
+
+// Features is a set of WebAssembly proposals beyond the MVP. The operators
+// of a proposal may only be used by modules enabling its feature.
+type Features uint64
+
+const (
+	// FeatureSatFloatToInt enables the non-trapping float-to-int conversions
+	// (i32.trunc_sat_f32_s, etc.).
+	FeatureSatFloatToInt Features = 1 << iota
+	// FeatureBulkMemory enables the bulk memory operators (memory.copy,
+	// etc.) and passive data and element segments.
+	FeatureBulkMemory
+	// FeatureMultiValue enables functions returning several values and
+	// blocks whose signature is a type of the type section, taking
+	// parameters and returning any number of values.
+	FeatureMultiValue
+	// FeatureReferenceTypes enables the funcref and externref value types,
+	// modules with several tables, the table operators (table.get, etc.),
+	// the reference operators (ref.null, etc.) and the typed select.
+	FeatureReferenceTypes
+	// FeatureSIMD enables the v128 value type and the fixed-width SIMD
+	// operators following the 0xfd prefix (v128.load, i32x4.add, etc.).
+	FeatureSIMD
+	// FeatureSignExt enables the sign-extension operators (i32.extend8_s,
+	// etc.).
+	FeatureSignExt
+)
+
+// Has reports whether all the features of g are enabled in f.
+func (f Features) Has(g Features) bool {
+	return f&g == g
+}
@@ -81,6 +81,9 @@ func (t GlobalVarImport) MarshalWASM(w io.Writer) error {
 }
 
 var (
+	// ErrImportMutGlobal is returned by ReadModule when a mutable global is
+	// imported through its resolver, which can only copy it. Mutable globals
+	// are shared by the instances of an exec.Store.
 	ErrImportMutGlobal           = errors.New("wasm: cannot import global mutable variable")
 	ErrNoExportsInImportedModule = errors.New("wasm: imported module has no exports")
 )
@@ -124,7 +127,6 @@ func (module *Module) resolveImports(resolve ResolveFunc) error {
 
 	modules := make(map[string]*Module)
 
-	var funcs uint32
 	for _, importEntry := range module.Import.Entries {
 		importedModule, ok := modules[importEntry.ModuleName]
 		if !ok {
@@ -163,10 +165,20 @@ func (module *Module) resolveImports(resolve ResolveFunc) error {
 			if fn == nil {
 				return InvalidFunctionIndexError(index)
 			}
-			module.FunctionIndexSpace = append(module.FunctionIndexSpace, *fn)
-			module.Code.Bodies = append(module.Code.Bodies, *fn.Body)
-			module.imports.Funcs = append(module.imports.Funcs, funcs)
-			funcs++
+			typeIndex := importEntry.Type.(FuncImport).Type
+			if fn.IsHost() {
+				module.FunctionIndexSpace = append(module.FunctionIndexSpace, *fn)
+				module.Code.Bodies = append(module.Code.Bodies, *fn.Body)
+			} else {
+				// The body of a WebAssembly function refers to the
+				// index spaces of its own module, it can only be
+				// called from another module through an instance.
+				if err := module.declareFunction(typeIndex); err != nil {
+					return err
+				}
+				module.imports.unresolved = true
+			}
+			module.imports.Funcs = append(module.imports.Funcs, typeIndex)
 		case ExternalGlobal:
 			glb := importedModule.GetGlobal(int(index))
 			if glb == nil {
@@ -184,7 +196,7 @@ func (module *Module) resolveImports(resolve ResolveFunc) error {
 			if int(index) >= len(importedModule.TableIndexSpace) {
 				return InvalidTableIndexError(index)
 			}
-			module.TableIndexSpace[0] = importedModule.TableIndexSpace[0]
+			module.TableIndexSpace = append(module.TableIndexSpace, importedModule.TableIndexSpace[index])
 			module.imports.Tables++
 		case ExternalMemory:
 			if int(index) >= len(importedModule.LinearMemoryIndexSpace) {
@@ -198,3 +210,49 @@ func (module *Module) resolveImports(resolve ResolveFunc) error {
 	}
 	return nil
 }
+
+// declareImports adds the imports of the module to its index spaces without
+// resolving them, for them to be resolved when the module is instantiated in
+// an exec.Store: the functions and globals only have their type, imported
+// tables and memories are left empty. Unlike resolveImports, it leaves the
+// function section as it was decoded.
+func (module *Module) declareImports() error {
+	for _, importEntry := range module.Import.Entries {
+		switch imp := importEntry.Type.(type) {
+		case FuncImport:
+			if err := module.declareFunction(imp.Type); err != nil {
+				return err
+			}
+		case GlobalVarImport:
+			module.GlobalIndexSpace = append(module.GlobalIndexSpace, GlobalEntry{Type: imp.Type})
+			module.imports.Globals++
+		case TableImport:
+			module.TableIndexSpace = append(module.TableIndexSpace, nil)
+			module.imports.Tables++
+		case MemoryImport:
+			module.imports.Memories++
+		default:
+			return InvalidExternalError(importEntry.Type.Kind())
+		}
+		module.imports.unresolved = true
+	}
+	return nil
+}
+
+// declareFunction adds an imported function of type typeIndex, without a
+// body, to the function index space.
+func (module *Module) declareFunction(typeIndex uint32) error {
+	if module.Types == nil || int(typeIndex) >= len(module.Types.Entries) {
+		return InvalidFunctionIndexError(typeIndex)
+	}
+	module.FunctionIndexSpace = append(module.FunctionIndexSpace, Function{Sig: &module.Types.Entries[typeIndex]})
+	return nil
+}
+
+// HasUnresolvedImports reports whether some imports of the module are only
+// declared in its index spaces, as ReadModule does without a resolver, or
+// for the WebAssembly functions it imports. Such a module must be
+// instantiated in an exec.Store.
+func (module *Module) HasUnresolvedImports() bool {
+	return module.imports.unresolved
+}
@@ -88,14 +88,38 @@ func (m *Module) GetGlobal(i int) *GlobalEntry {
 	return &m.GlobalIndexSpace[i]
 }
 
+// NullElement is the value of the table elements which aren't initialized
+// by an element segment.
+const NullElement = ^uint32(0)
+
+// nullElements returns a table of n uninitialized elements.
+func nullElements(n int) []uint32 {
+	table := make([]uint32, n)
+	for i := range table {
+		table[i] = NullElement
+	}
+	return table
+}
+
 func (m *Module) populateTables() error {
-	if m.Table == nil || len(m.Table.Entries) == 0 || m.Elements == nil || len(m.Elements.Entries) == 0 {
+	if m.Table != nil {
+		for i, t := range m.Table.Entries {
+			i += m.imports.Tables
+			if i < len(m.TableIndexSpace) && len(m.TableIndexSpace[i]) < int(t.Limits.Initial) {
+				table := nullElements(int(t.Limits.Initial))
+				copy(table, m.TableIndexSpace[i])
+				m.TableIndexSpace[i] = table
+			}
+		}
+	}
+	if m.Elements == nil || len(m.Elements.Entries) == 0 {
 		return nil
 	}
 
 	for _, elem := range m.Elements.Entries {
-		// the MVP dictates that index should always be zero, we shuold
-		// probably check this
+		if elem.Passive || elem.Declarative {
+			continue
+		}
 		if int(elem.Index) >= len(m.TableIndexSpace) {
 			return InvalidTableIndexError(elem.Index)
 		}
@@ -111,9 +135,9 @@ func (m *Module) populateTables() error {
 
 		table := m.TableIndexSpace[int(elem.Index)]
 		if int(offset)+len(elem.Elems) > len(table) {
-			data := make([]uint32, int(offset)+len(elem.Elems))
-			copy(data[offset:], elem.Elems)
+			data := nullElements(int(offset) + len(elem.Elems))
 			copy(data, table)
+			copy(data[offset:], elem.Elems)
 			m.TableIndexSpace[int(elem.Index)] = data
 		} else {
 			copy(table[int(offset):], elem.Elems)
@@ -125,10 +149,33 @@ func (m *Module) populateTables() error {
 	return nil
 }
 
+// GetTable returns the type of the table at index i of the table index
+// space, starting with the imported tables. Returns nil when the index is
+// invalid.
+func (m *Module) GetTable(i int) *Table {
+	if i < 0 {
+		return nil
+	}
+	if m.Import != nil {
+		for _, entry := range m.Import.Entries {
+			if imp, ok := entry.Type.(TableImport); ok {
+				if i == 0 {
+					return &imp.Type
+				}
+				i--
+			}
+		}
+	}
+	if m.Table == nil || i >= len(m.Table.Entries) {
+		return nil
+	}
+	return &m.Table.Entries[i]
+}
+
 // GetTableElement returns an element from the tableindex  space indexed
 // by the integer index. It returns an error if index is invalid.
 func (m *Module) GetTableElement(index int) (uint32, error) {
-	if index >= len(m.TableIndexSpace[0]) {
+	if index >= len(m.TableIndexSpace[0]) || m.TableIndexSpace[0][index] == NullElement {
 		return 0, InvalidTableIndexError(index)
 	}
 
@@ -142,6 +189,9 @@ func (m *Module) populateLinearMemory() error {
 	// each module can only have a single linear memory in the MVP
 
 	for _, entry := range m.Data.Entries {
+		if entry.Passive {
+			continue
+		}
 		if entry.Index != 0 {
 			return InvalidLinearMemoryIndexError(entry.Index)
 		}
@@ -158,8 +208,8 @@ func (m *Module) populateLinearMemory() error {
 		memory := m.LinearMemoryIndexSpace[int(entry.Index)]
 		if int(offset)+len(entry.Data) > len(memory) {
 			data := make([]byte, int(offset)+len(entry.Data))
-			copy(data[offset:], entry.Data)
 			copy(data, memory)
+			copy(data[offset:], entry.Data)
 			m.LinearMemoryIndexSpace[int(entry.Index)] = data
 		} else {
 			copy(memory[int(offset):], entry.Data)
@@ -20,7 +20,12 @@ const (
 	f32Const  byte = 0x43
 	f64Const  byte = 0x44
 	getGlobal byte = 0x23
+	refNull   byte = 0xd0
+	refFunc   byte = 0xd2
 	end       byte = 0x0b
+
+	simdPrefix byte   = 0xfd
+	v128Const  uint32 = 0x0c // v128.const, following simdPrefix
 )
 
 var ErrEmptyInitExpr = errors.New("wasm: Initializer expression produces no value")
@@ -67,11 +72,19 @@ outer:
 			if _, err := readU64(r); err != nil {
 				return nil, err
 			}
-		case getGlobal:
+		case getGlobal, refFunc:
 			_, err := leb128.ReadVarUint32(r)
 			if err != nil {
 				return nil, err
 			}
+		case refNull:
+			if _, err := leb128.ReadVarint32(r); err != nil {
+				return nil, err
+			}
+		case simdPrefix:
+			if _, err := readV128Const(r); err != nil {
+				return nil, err
+			}
 		case end:
 			break outer
 		default:
@@ -86,13 +99,89 @@ outer:
 	return buf.Bytes(), nil
 }
 
+// readV128Const reads the rest of a v128.const instruction following the
+// 0xfd prefix, and returns its value.
+func readV128Const(r io.Reader) ([16]byte, error) {
+	var v [16]byte
+	code, err := leb128.ReadVarUint32(r)
+	if err != nil {
+		return v, err
+	}
+	if code != v128Const {
+		return v, InvalidInitExprOpError(simdPrefix)
+	}
+	_, err = io.ReadFull(r, v[:])
+	return v, err
+}
+
+// readElemExpr reads an element of a segment encoded as an expression, a
+// ref.func or ref.null expression, and returns the index of the function or
+// NullElement.
+func readElemExpr(r io.Reader) (uint32, error) {
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, err
+	}
+	var elem uint32
+	switch b[0] {
+	case refFunc:
+		index, err := leb128.ReadVarUint32(r)
+		if err != nil {
+			return 0, err
+		}
+		elem = index
+	case refNull:
+		t, err := leb128.ReadVarint32(r)
+		if err != nil {
+			return 0, err
+		}
+		if !ValueType(t).IsReference() {
+			return 0, InvalidInitExprOpError(b[0])
+		}
+		elem = NullElement
+	default:
+		return 0, InvalidInitExprOpError(b[0])
+	}
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, err
+	}
+	if b[0] != end {
+		return 0, InvalidInitExprOpError(b[0])
+	}
+	return elem, nil
+}
+
+// writeElemExpr writes an element of type t of a segment encoded as an
+// expression.
+func writeElemExpr(w io.Writer, t ElemType, elem uint32) error {
+	if elem == NullElement {
+		if _, err := w.Write([]byte{refNull}); err != nil {
+			return err
+		}
+		if err := t.MarshalWASM(w); err != nil {
+			return err
+		}
+	} else {
+		if _, err := w.Write([]byte{refFunc}); err != nil {
+			return err
+		}
+		if _, err := leb128.WriteVarUint32(w, elem); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte{end})
+	return err
+}
+
 // ExecInitExpr executes an initializer expression and returns an interface{} value
-// which can either be int32, int64, float32 or float64.
+// which can either be int32, int64, float32 or float64, uint32 for a
+// reference: the index of a function, or NullElement, or [16]byte for a v128.
 // It returns an error if the expression is invalid, and nil when the expression
 // yields no value.
 func (m *Module) ExecInitExpr(expr []byte) (interface{}, error) {
 	var stack []uint64
 	var lastVal ValueType
+	var vec [16]byte
 	r := bytes.NewReader(expr)
 
 	if r.Len() == 0 {
@@ -145,6 +234,34 @@ func (m *Module) ExecInitExpr(expr []byte) (interface{}, error) {
 				return nil, InvalidGlobalIndexError(index)
 			}
 			lastVal = globalVar.Type.Type
+		case refNull:
+			t, err := leb128.ReadVarint32(r)
+			if err != nil {
+				return nil, err
+			}
+			if !ValueType(t).IsReference() {
+				return nil, InvalidInitExprOpError(b)
+			}
+			stack = append(stack, uint64(NullElement))
+			lastVal = ValueType(t)
+		case refFunc:
+			index, err := leb128.ReadVarUint32(r)
+			if err != nil {
+				return nil, err
+			}
+			if m.GetFunction(int(index)) == nil {
+				return nil, InvalidFunctionIndexError(index)
+			}
+			stack = append(stack, uint64(index))
+			lastVal = ValueTypeFuncref
+		case simdPrefix:
+			v, err := readV128Const(r)
+			if err != nil {
+				return nil, err
+			}
+			vec = v
+			stack = append(stack, 0)
+			lastVal = ValueTypeV128
 		case end:
 			break
 		default:
@@ -166,6 +283,10 @@ func (m *Module) ExecInitExpr(expr []byte) (interface{}, error) {
 		return math.Float32frombits(uint32(v)), nil
 	case ValueTypeF64:
 		return math.Float64frombits(uint64(v)), nil
+	case ValueTypeFuncref, ValueTypeExternref:
+		return uint32(v), nil
+	case ValueTypeV128:
+		return vec, nil
 	default:
 		panic(fmt.Sprintf("Invalid value type produced by initializer expression: %d", int8(lastVal)))
 	}
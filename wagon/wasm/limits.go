@@ -0,0 +1,61 @@
+// Copyright 2017 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wasm
+
+import "fmt"
+
+// DecodeConfig configures the decoding of a module by DecodeModuleConfig
+// and ReadModuleConfig. The limits are enforced as the module is read,
+// before anything is allocated for the entries exceeding them; zero means
+// no limit.
+type DecodeConfig struct {
+	MaxSections        uint32 // number of sections, custom ones included
+	MaxFunctions       uint32 // number of functions defined by the module
+	MaxLocals          uint32 // number of locals of a function, besides its parameters
+	MaxBodySize        uint32 // size in bytes of a function body
+	MaxDataSegmentSize uint32 // size in bytes of the data of a data segment
+	MaxTableSize       uint32 // initial number of elements of a table
+	MaxMemoryPages     uint32 // initial number of pages of a linear memory
+
+	// LazyBodies defers the decoding of the locals and the code of the
+	// function bodies to their first use, see (*FunctionBody).Decode. A VM
+	// compiles such a function on its first call, while validating the
+	// module decodes all of them.
+	LazyBodies bool
+}
+
+// LimitError is returned when a module exceeds a limit of its DecodeConfig.
+type LimitError struct {
+	Limit string // the name of the limit, e.g. "MaxFunctions"
+	Value uint64 // the count or size exceeding it
+	Max   uint32
+}
+
+func (e LimitError) Error() string {
+	return fmt.Sprintf("wasm: %s exceeded: %d > %d", e.Limit, e.Value, e.Max)
+}
+
+// checkLimit returns a LimitError if v exceeds max, unless max is zero.
+func checkLimit(limit string, v uint64, max uint32) error {
+	if max != 0 && v > uint64(max) {
+		return LimitError{Limit: limit, Value: v, Max: max}
+	}
+	return nil
+}
+
+// maxPrealloc is the largest number of entries, or bytes, allocated ahead
+// of reading them when their number is read from the module. More are
+// only allocated as they are read, lest a few bytes claiming a huge
+// number of them make the decoder exhaust the memory.
+const maxPrealloc = 1 << 12
+
+// capacity returns the capacity to allocate for n entries read from the
+// module.
+func capacity(n uint32) int {
+	if n > maxPrealloc {
+		return maxPrealloc
+	}
+	return int(n)
+}
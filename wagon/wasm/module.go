@@ -32,24 +32,34 @@ func (fct *Function) IsHost() bool {
 	return fct.Host != reflect.Value{}
 }
 
+// IsImport indicates whether this function is imported from another module
+// without being resolved, see HasUnresolvedImports. It has no body.
+func (fct *Function) IsImport() bool {
+	return fct.Body == nil && !fct.IsHost()
+}
+
 // Module represents a parsed WebAssembly module:
 // http://webassembly.org/docs/modules/
 type Module struct {
 	Version  uint32
 	Sections []Section
 
-	Types    *SectionTypes
-	Import   *SectionImports
-	Function *SectionFunctions
-	Table    *SectionTables
-	Memory   *SectionMemories
-	Global   *SectionGlobals
-	Export   *SectionExports
-	Start    *SectionStartFunction
-	Elements *SectionElements
-	Code     *SectionCode
-	Data     *SectionData
-	Customs  []*SectionCustom
+	Types     *SectionTypes
+	Import    *SectionImports
+	Function  *SectionFunctions
+	Table     *SectionTables
+	Memory    *SectionMemories
+	Global    *SectionGlobals
+	Export    *SectionExports
+	Start     *SectionStartFunction
+	Elements  *SectionElements
+	DataCount *SectionDataCount
+	Code      *SectionCode
+	Data      *SectionData
+	Customs   []*SectionCustom
+
+	// The features beyond the MVP the module may use, none by default
+	Features Features
 
 	// The function index space of the module
 	FunctionIndexSpace []Function
@@ -61,11 +71,16 @@ type Module struct {
 	LinearMemoryIndexSpace [][]byte
 
 	imports struct {
-		Funcs    []uint32
-		Globals  int
-		Tables   int
-		Memories int
+		Funcs      []uint32
+		Globals    int
+		Tables     int
+		Memories   int
+		unresolved bool
 	}
+
+	// the configuration the module is decoded with, whose limits also
+	// apply to the bodies decoded lazily
+	decodeConfig DecodeConfig
 }
 
 // Custom returns a custom section with a specific name, if it exists.
@@ -100,11 +115,17 @@ type ResolveFunc func(name string) (*Module, error)
 // DecodeModule is the same as ReadModule, but it only decodes the module without
 // initializing the index space or resolving imports.
 func DecodeModule(r io.Reader) (*Module, error) {
+	return DecodeModuleConfig(r, DecodeConfig{})
+}
+
+// DecodeModuleConfig is like DecodeModule, but enforces the limits of cfg
+// and decodes the function bodies lazily if cfg.LazyBodies is set.
+func DecodeModuleConfig(r io.Reader, cfg DecodeConfig) (*Module, error) {
 	reader := &readpos.ReadPos{
 		R:      r,
 		CurPos: 0,
 	}
-	m := &Module{}
+	m := &Module{decodeConfig: cfg}
 	magic, err := readU32(reader)
 	if err != nil {
 		return nil, err
@@ -127,31 +148,49 @@ func DecodeModule(r io.Reader) (*Module, error) {
 }
 
 // ReadModule reads a module from the reader r. resolvePath must take a string
-// and a return a reader to the module pointed to by the string.
+// and a return a reader to the module pointed to by the string. If
+// resolvePath is nil, the imports of the module are only declared, see
+// HasUnresolvedImports.
 func ReadModule(r io.Reader, resolvePath ResolveFunc) (*Module, error) {
-	m, err := DecodeModule(r)
+	return ReadModuleConfig(r, resolvePath, DecodeConfig{})
+}
+
+// ReadModuleConfig is like ReadModule, but decodes the module as
+// DecodeModuleConfig does. The modules returned by resolvePath are decoded
+// by it.
+func ReadModuleConfig(r io.Reader, resolvePath ResolveFunc, cfg DecodeConfig) (*Module, error) {
+	m, err := DecodeModuleConfig(r, cfg)
 	if err != nil {
 		return nil, err
 	}
 
 	m.LinearMemoryIndexSpace = make([][]byte, 1)
-	if m.Table != nil {
-		m.TableIndexSpace = make([][]uint32, int(len(m.Table.Entries)))
-	}
 
-	if m.Import != nil && resolvePath != nil {
-		err := m.resolveImports(resolvePath)
+	if m.Import != nil {
+		if resolvePath != nil {
+			err = m.resolveImports(resolvePath)
+		} else {
+			err = m.declareImports()
+		}
 		if err != nil {
 			return nil, err
 		}
 	}
+	// the tables of the module follow the imported ones
+	if m.Table != nil {
+		m.TableIndexSpace = append(m.TableIndexSpace, make([][]uint32, len(m.Table.Entries))...)
+	}
 
-	for _, fn := range []func() error{
+	populate := []func() error{
 		m.populateGlobals,
 		m.populateFunctions,
-		m.populateTables,
-		m.populateLinearMemory,
-	} {
+	}
+	// The segments of a module with unresolved imports may depend on them,
+	// they are applied when the module is instantiated.
+	if !m.imports.unresolved {
+		populate = append(populate, m.populateTables, m.populateLinearMemory)
+	}
+	for _, fn := range populate {
 		if err := fn(); err != nil {
 			return nil, err
 		}
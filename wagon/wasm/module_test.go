@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"io/ioutil"
 	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/tinychain/tiny-wasm/wagon/wasm"
@@ -45,3 +46,228 @@ func TestReadModule(t *testing.T) {
 		}
 	}
 }
+
+func TestPassiveSegments(t *testing.T) {
+	raw := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+		0x03, 0x02, 0x01, 0x00,
+		0x04, 0x04, 0x01, 0x70, 0x00, 0x02,
+		0x05, 0x03, 0x01, 0x00, 0x01,
+		// (elem (i32.const 1) 0) (elem func 0)
+		0x09, 0x0b, 0x02, 0x00, 0x41, 0x01, 0x0b, 0x01, 0x00, 0x01, 0x00, 0x01, 0x00,
+		0x0c, 0x01, 0x02,
+		0x0a, 0x04, 0x01, 0x02, 0x00, 0x0b,
+		// (data (i32.const 0) "a") (data "bc")
+		0x0b, 0x0b, 0x02, 0x00, 0x41, 0x00, 0x0b, 0x01, 0x61, 0x01, 0x02, 0x62, 0x63,
+	}
+	m, err := wasm.ReadModule(bytes.NewReader(raw), nil)
+	if err != nil {
+		t.Fatalf("error reading module %v", err)
+	}
+	if m.DataCount == nil || m.DataCount.Count != 2 {
+		t.Fatalf("unexpected data count section %v", m.DataCount)
+	}
+	if elems := m.Elements.Entries; elems[0].Passive || !elems[1].Passive || elems[1].Offset != nil {
+		t.Errorf("unexpected element segments %v", elems)
+	}
+	if data := m.Data.Entries; data[0].Passive || !data[1].Passive || string(data[1].Data) != "bc" {
+		t.Errorf("unexpected data segments %v", data)
+	}
+
+	// passive segments aren't copied at instantiation
+	if table := m.TableIndexSpace[0]; len(table) != 2 || table[0] != wasm.NullElement || table[1] != 0 {
+		t.Errorf("unexpected table %v", table)
+	}
+	if memory := m.LinearMemoryIndexSpace[0]; string(memory) != "a" {
+		t.Errorf("unexpected memory %q", memory)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := wasm.EncodeModule(buf, m); err != nil {
+		t.Fatalf("error writing module %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), raw) {
+		t.Errorf("modules are different")
+	}
+
+	// the data count must match the data section
+	raw[44] = 0x03
+	if _, err := wasm.ReadModule(bytes.NewReader(raw), nil); err == nil {
+		t.Error("expected error for mismatched data count")
+	}
+}
+
+func TestReferenceTypeSegments(t *testing.T) {
+	raw := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+		0x03, 0x02, 0x01, 0x00,
+		// (table 1 funcref) (table 2 externref)
+		0x04, 0x07, 0x02, 0x70, 0x00, 0x01, 0x6f, 0x00, 0x02,
+		// (elem declare func 0) (elem externref (ref.null extern))
+		// (elem 1 (i32.const 1) externref (ref.null extern))
+		// (elem (i32.const 0) funcref (ref.func 0))
+		0x09, 0x1d, 0x04,
+		0x03, 0x00, 0x01, 0x00,
+		0x05, 0x6f, 0x01, 0xd0, 0x6f, 0x0b,
+		0x06, 0x01, 0x41, 0x01, 0x0b, 0x6f, 0x01, 0xd0, 0x6f, 0x0b,
+		0x04, 0x41, 0x00, 0x0b, 0x01, 0xd2, 0x00, 0x0b,
+		0x0a, 0x04, 0x01, 0x02, 0x00, 0x0b,
+	}
+	m, err := wasm.ReadModule(bytes.NewReader(raw), nil)
+	if err != nil {
+		t.Fatalf("error reading module %v", err)
+	}
+	elems := m.Elements.Entries
+	if !elems[0].Declarative || elems[0].Offset != nil || elems[0].Exprs {
+		t.Errorf("unexpected declarative segment %v", elems[0])
+	}
+	if !elems[1].Passive || elems[1].Type != wasm.ElemTypeExternRef || elems[1].Elems[0] != wasm.NullElement {
+		t.Errorf("unexpected passive segment %v", elems[1])
+	}
+	if elems[2].Index != 1 || elems[3].Type != wasm.ElemTypeAnyFunc || elems[3].Elems[0] != 0 {
+		t.Errorf("unexpected active segments %v", elems[2:])
+	}
+	if table := m.TableIndexSpace[0]; len(table) != 1 || table[0] != 0 {
+		t.Errorf("unexpected table %v", table)
+	}
+	if table := m.TableIndexSpace[1]; len(table) != 2 || table[0] != wasm.NullElement || table[1] != wasm.NullElement {
+		t.Errorf("unexpected table %v", table)
+	}
+	if table := m.GetTable(1); table == nil || table.ElementType != wasm.ElemTypeExternRef {
+		t.Errorf("unexpected table type %v", table)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := wasm.EncodeModule(buf, m); err != nil {
+		t.Fatalf("error writing module %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), raw) {
+		t.Errorf("modules are different")
+	}
+}
+
+// limitsModule is a module with two functions with two locals each and a
+// body of 6 bytes, a table of 2 elements, a memory of 2 pages and a data
+// segment of 3 bytes, in 6 sections.
+var limitsModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	0x01, 0x05, 0x01, 0x60, 0x00, 0x01, 0x7f,
+	0x03, 0x03, 0x02, 0x00, 0x00,
+	0x04, 0x04, 0x01, 0x70, 0x00, 0x02,
+	0x05, 0x03, 0x01, 0x00, 0x02,
+	// (func (local i32 i32) (i32.const 42)) twice
+	0x0a, 0x0f, 0x02,
+	0x06, 0x01, 0x02, 0x7f, 0x41, 0x2a, 0x0b,
+	0x06, 0x01, 0x02, 0x7f, 0x41, 0x2a, 0x0b,
+	0x0b, 0x09, 0x01, 0x00, 0x41, 0x00, 0x0b, 0x03, 0x61, 0x62, 0x63,
+}
+
+func TestDecodeLimits(t *testing.T) {
+	for _, tc := range []struct {
+		cfg   wasm.DecodeConfig
+		limit string
+		value uint64
+	}{
+		{cfg: wasm.DecodeConfig{}},
+		{cfg: wasm.DecodeConfig{MaxSections: 6, MaxFunctions: 2, MaxLocals: 2, MaxBodySize: 6, MaxDataSegmentSize: 3, MaxTableSize: 2, MaxMemoryPages: 2}},
+		{cfg: wasm.DecodeConfig{MaxSections: 5}, limit: "MaxSections", value: 6},
+		{cfg: wasm.DecodeConfig{MaxFunctions: 1}, limit: "MaxFunctions", value: 2},
+		{cfg: wasm.DecodeConfig{MaxLocals: 1}, limit: "MaxLocals", value: 2},
+		{cfg: wasm.DecodeConfig{MaxBodySize: 5}, limit: "MaxBodySize", value: 6},
+		{cfg: wasm.DecodeConfig{MaxDataSegmentSize: 2}, limit: "MaxDataSegmentSize", value: 3},
+		{cfg: wasm.DecodeConfig{MaxTableSize: 1}, limit: "MaxTableSize", value: 2},
+		{cfg: wasm.DecodeConfig{MaxMemoryPages: 1}, limit: "MaxMemoryPages", value: 2},
+	} {
+		_, err := wasm.DecodeModuleConfig(bytes.NewReader(limitsModule), tc.cfg)
+		if tc.limit == "" {
+			if err != nil {
+				t.Errorf("%+v: unexpected error %v", tc.cfg, err)
+			}
+			continue
+		}
+		lerr, ok := err.(wasm.LimitError)
+		if !ok || lerr.Limit != tc.limit || lerr.Value != tc.value {
+			t.Errorf("%+v: got error %v, want %s exceeded by %d", tc.cfg, err, tc.limit, tc.value)
+		}
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	header := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	for _, tc := range []struct {
+		name string
+		raw  []byte
+	}{
+		// a custom section claiming a name of 0xffffffff bytes
+		{"custom name", []byte{0x00, 0x06, 0xff, 0xff, 0xff, 0xff, 0x0f, 0x61}},
+		// a type section claiming 0xffffffff types
+		{"type count", []byte{0x01, 0x06, 0xff, 0xff, 0xff, 0xff, 0x0f, 0x60}},
+		// a code section claiming a body of 0xffffffff bytes
+		{"body size", []byte{
+			0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+			0x03, 0x02, 0x01, 0x00,
+			0x0a, 0x07, 0x01, 0xff, 0xff, 0xff, 0xff, 0x0f, 0x00,
+		}},
+		// a data segment claiming 0xffffffff bytes
+		{"data size", []byte{
+			0x05, 0x03, 0x01, 0x00, 0x01,
+			0x0b, 0x0a, 0x01, 0x00, 0x41, 0x00, 0x0b, 0xff, 0xff, 0xff, 0xff, 0x0f,
+		}},
+	} {
+		raw := append(append([]byte{}, header...), tc.raw...)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := wasm.DecodeModule(bytes.NewReader(raw))
+		runtime.ReadMemStats(&after)
+		if err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+		if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+			t.Errorf("%s: %d bytes allocated", tc.name, n)
+		}
+	}
+}
+
+func TestLazyBodies(t *testing.T) {
+	m, err := wasm.DecodeModuleConfig(bytes.NewReader(limitsModule), wasm.DecodeConfig{
+		MaxLocals:  1,
+		LazyBodies: true,
+	})
+	if err != nil {
+		t.Fatalf("error reading module %v", err)
+	}
+	body := &m.Code.Bodies[0]
+	if body.Code != nil || body.Locals != nil {
+		t.Fatalf("body decoded eagerly: %v", body)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := wasm.EncodeModule(buf, m); err != nil {
+		t.Fatalf("error writing module %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), limitsModule) {
+		t.Errorf("modules are different")
+	}
+
+	err = body.Decode()
+	if lerr, ok := err.(wasm.LimitError); !ok || lerr.Limit != "MaxLocals" {
+		t.Errorf("got error %v, want MaxLocals exceeded", err)
+	}
+
+	m, err = wasm.DecodeModuleConfig(bytes.NewReader(limitsModule), wasm.DecodeConfig{LazyBodies: true})
+	if err != nil {
+		t.Fatalf("error reading module %v", err)
+	}
+	body = &m.Code.Bodies[1]
+	if err := body.Decode(); err != nil {
+		t.Fatalf("error decoding body %v", err)
+	}
+	if err := body.Decode(); err != nil {
+		t.Fatalf("error decoding body twice %v", err)
+	}
+	if len(body.Locals) != 1 || body.Locals[0].Count != 2 || !bytes.Equal(body.Code, []byte{0x41, 0x2a}) {
+		t.Errorf("unexpected body %v", body)
+	}
+}
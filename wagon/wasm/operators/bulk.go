@@ -0,0 +1,22 @@
+// Copyright 2017 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package operators
+
+import (
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+)
+
+var bulkArgs = []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32, wasm.ValueTypeI32}
+
+// Bulk memory operators
+var (
+	MemoryInit = newPrefixedOp(MiscPrefix, 0x08, "memory.init", bulkArgs, noReturn, wasm.FeatureBulkMemory)
+	DataDrop   = newPrefixedOp(MiscPrefix, 0x09, "data.drop", nil, noReturn, wasm.FeatureBulkMemory)
+	MemoryCopy = newPrefixedOp(MiscPrefix, 0x0a, "memory.copy", bulkArgs, noReturn, wasm.FeatureBulkMemory)
+	MemoryFill = newPrefixedOp(MiscPrefix, 0x0b, "memory.fill", bulkArgs, noReturn, wasm.FeatureBulkMemory)
+	TableInit  = newPrefixedOp(MiscPrefix, 0x0c, "table.init", bulkArgs, noReturn, wasm.FeatureBulkMemory)
+	ElemDrop   = newPrefixedOp(MiscPrefix, 0x0d, "elem.drop", nil, noReturn, wasm.FeatureBulkMemory)
+	TableCopy  = newPrefixedOp(MiscPrefix, 0x0e, "table.copy", bulkArgs, noReturn, wasm.FeatureBulkMemory)
+)
@@ -62,3 +62,15 @@ var (
 	F64ConvertUI64 = newConversionOp(0xba, "f64.convert_u/i64")
 	F64PromoteF32  = newConversionOp(0xbb, "f64.promote/f32")
 )
+
+// Non-trapping float-to-int conversions
+var (
+	I32TruncSatSF32 = newPrefixedOp(MiscPrefix, 0x00, "i32.trunc_sat_f32_s", []wasm.ValueType{wasm.ValueTypeF32}, wasm.ValueTypeI32, wasm.FeatureSatFloatToInt)
+	I32TruncSatUF32 = newPrefixedOp(MiscPrefix, 0x01, "i32.trunc_sat_f32_u", []wasm.ValueType{wasm.ValueTypeF32}, wasm.ValueTypeI32, wasm.FeatureSatFloatToInt)
+	I32TruncSatSF64 = newPrefixedOp(MiscPrefix, 0x02, "i32.trunc_sat_f64_s", []wasm.ValueType{wasm.ValueTypeF64}, wasm.ValueTypeI32, wasm.FeatureSatFloatToInt)
+	I32TruncSatUF64 = newPrefixedOp(MiscPrefix, 0x03, "i32.trunc_sat_f64_u", []wasm.ValueType{wasm.ValueTypeF64}, wasm.ValueTypeI32, wasm.FeatureSatFloatToInt)
+	I64TruncSatSF32 = newPrefixedOp(MiscPrefix, 0x04, "i64.trunc_sat_f32_s", []wasm.ValueType{wasm.ValueTypeF32}, wasm.ValueTypeI64, wasm.FeatureSatFloatToInt)
+	I64TruncSatUF32 = newPrefixedOp(MiscPrefix, 0x05, "i64.trunc_sat_f32_u", []wasm.ValueType{wasm.ValueTypeF32}, wasm.ValueTypeI64, wasm.FeatureSatFloatToInt)
+	I64TruncSatSF64 = newPrefixedOp(MiscPrefix, 0x06, "i64.trunc_sat_f64_s", []wasm.ValueType{wasm.ValueTypeF64}, wasm.ValueTypeI64, wasm.FeatureSatFloatToInt)
+	I64TruncSatUF64 = newPrefixedOp(MiscPrefix, 0x07, "i64.trunc_sat_f64_u", []wasm.ValueType{wasm.ValueTypeF64}, wasm.ValueTypeI64, wasm.FeatureSatFloatToInt)
+)
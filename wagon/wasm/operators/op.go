@@ -8,15 +8,27 @@ package operators
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/tinychain/tiny-wasm/wagon/wasm"
+	"github.com/tinychain/tiny-wasm/wagon/wasm/leb128"
 )
 
 var (
 	ops      [256]Op // an array of Op values mapped by wasm opcodes, used by New().
 	noReturn = wasm.ValueType(wasm.BlockTypeEmpty)
+
+	// arrays of Op values mapped by the opcodes following a prefix byte
+	prefixed = map[byte]*[256]Op{}
+
+	// the operators mapped by their names, used by Lookup()
+	byName = map[string]*Op{}
 )
 
+// MiscPrefix is the prefix byte of the miscellaneous operators, like the
+// non-trapping float-to-int conversions.
+const MiscPrefix byte = 0xfc
+
 // Op describes a WASM operator.
 type Op struct {
 	Code byte   // The single-byte opcode
@@ -28,12 +40,30 @@ type Op struct {
 	Polymorphic bool
 	Args        []wasm.ValueType // an array of value types used by the operator as arguments, is nil for polymorphic operators
 	Returns     wasm.ValueType   // the value returned (pushed) by the operator, is 0 for polymorphic operators
+
+	Prefix  byte          // The prefix byte preceding the opcode, 0 for single-byte opcodes
+	Feature wasm.Features // The feature the operator belongs to, 0 for the MVP
 }
 
 func (o Op) IsValid() bool {
 	return o.Name != ""
 }
 
+// FirstByte returns the first byte of the operator's encoding, its prefix
+// for a prefixed operator. Unlike Code, it never matches a single-byte
+// opcode for a prefixed operator.
+func (o Op) FirstByte() byte {
+	if o.Prefix != 0 {
+		return o.Prefix
+	}
+	return o.Code
+}
+
+// Enabled reports whether the operator may be used with the given features.
+func (o Op) Enabled(features wasm.Features) bool {
+	return features.Has(o.Feature)
+}
+
 func newOp(code byte, name string, args []wasm.ValueType, returns wasm.ValueType) byte {
 	if ops[code].IsValid() {
 		panic(fmt.Errorf("Opcode %#x is already assigned to %s", code, ops[code].Name))
@@ -47,6 +77,7 @@ func newOp(code byte, name string, args []wasm.ValueType, returns wasm.ValueType
 		Returns:     returns,
 	}
 	ops[code] = op
+	register(&ops[code])
 	return code
 }
 
@@ -61,9 +92,59 @@ func newPolymorphicOp(code byte, name string) byte {
 		Polymorphic: true,
 	}
 	ops[code] = op
+	register(&ops[code])
+	return code
+}
+
+// withFeature sets the feature of the single-byte operator code.
+func withFeature(code byte, feature wasm.Features) byte {
+	ops[code].Feature = feature
 	return code
 }
 
+func newPrefixedOp(prefix, code byte, name string, args []wasm.ValueType, returns wasm.ValueType, feature wasm.Features) byte {
+	return setPrefixedOp(prefix, Op{
+		Code:    code,
+		Name:    name,
+		Args:    args,
+		Returns: returns,
+		Feature: feature,
+	})
+}
+
+func newPolymorphicPrefixedOp(prefix, code byte, name string, feature wasm.Features) byte {
+	return setPrefixedOp(prefix, Op{
+		Code:        code,
+		Name:        name,
+		Polymorphic: true,
+		Feature:     feature,
+	})
+}
+
+func setPrefixedOp(prefix byte, op Op) byte {
+	table, ok := prefixed[prefix]
+	if !ok {
+		table = new([256]Op)
+		prefixed[prefix] = table
+	}
+	if table[op.Code].IsValid() {
+		panic(fmt.Errorf("Opcode %#x %#x is already assigned to %s", prefix, op.Code, table[op.Code].Name))
+	}
+
+	op.Prefix = prefix
+	table[op.Code] = op
+	register(&table[op.Code])
+	return op.Code
+}
+
+// register maps the name of op to it, unless the name is already taken:
+// the typed select shares the name of select.
+func register(op *Op) {
+	if _, ok := byName[op.Name]; !ok {
+		byName[op.Name] = op
+	}
+}
+
 type InvalidOpcodeError byte
 
 func (e InvalidOpcodeError) Error() string {
@@ -84,3 +165,60 @@ func New(code byte) (Op, error) {
 	}
 	return op, nil
 }
+
+// InvalidPrefixedOpcodeError is returned for an unknown opcode following a
+// prefix byte.
+type InvalidPrefixedOpcodeError struct {
+	Prefix byte
+	Code   uint32
+}
+
+func (e InvalidPrefixedOpcodeError) Error() string {
+	return fmt.Sprintf("Invalid opcode: %#x %#x", e.Prefix, e.Code)
+}
+
+// Lookup returns the Op object of the operator with the given name, the
+// untyped one for select.
+func Lookup(name string) (Op, bool) {
+	op, ok := byName[name]
+	if !ok {
+		return Op{}, false
+	}
+	return *op, true
+}
+
+// IsPrefix reports whether b is the prefix byte of multi-byte opcodes.
+func IsPrefix(b byte) bool {
+	_, ok := prefixed[b]
+	return ok
+}
+
+// NewPrefixed returns the Op object for the given opcode following prefix.
+func NewPrefixed(prefix byte, code uint32) (Op, error) {
+	table, ok := prefixed[prefix]
+	if !ok || code >= uint32(len(table)) || !table[code].IsValid() {
+		return Op{}, InvalidPrefixedOpcodeError{prefix, code}
+	}
+	return table[code], nil
+}
+
+// Read returns the Op object for the opcode starting with the byte b. The
+// opcode of a prefixed operator is read from r.
+func Read(b byte, r io.Reader) (Op, error) {
+	if !IsPrefix(b) {
+		return New(b)
+	}
+	code, err := leb128.ReadVarUint32(r)
+	if err != nil {
+		return Op{}, err
+	}
+	return NewPrefixed(b, code)
+}
+
+// DisabledError is returned for an operator of a feature the module doesn't
+// enable.
+type DisabledError string
+
+func (e DisabledError) Error() string {
+	return fmt.Sprintf("operator %s is not enabled", string(e))
+}
@@ -5,7 +5,10 @@
 package operators
 
 import (
+	"bytes"
 	"testing"
+
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
 )
 
 func TestNew(t *testing.T) {
@@ -28,3 +31,55 @@ func TestNew(t *testing.T) {
 		t.Fatalf("0xff: operator %v is valid (should be invalid)", op2)
 	}
 }
+
+func TestRead(t *testing.T) {
+	op1, err := Read(MiscPrefix, bytes.NewReader([]byte{I64TruncSatUF64}))
+	if err != nil {
+		t.Fatalf("unexpected error from Read: %v", err)
+	}
+	if op1.Name != "i64.trunc_sat_f64_u" || op1.FirstByte() != MiscPrefix {
+		t.Fatalf("0xfc 0x07: unexpected Op %v", op1)
+	}
+	if op1.Enabled(0) || !op1.Enabled(wasm.FeatureSatFloatToInt) {
+		t.Fatalf("0xfc 0x07: operator should only be enabled by its feature")
+	}
+
+	// the opcode following the prefix is an unsigned LEB128
+	if _, err := Read(MiscPrefix, bytes.NewReader([]byte{0x80, 0x01})); err != (InvalidPrefixedOpcodeError{MiscPrefix, 0x80}) {
+		t.Fatalf("0xfc 0x80: unexpected error %v", err)
+	}
+
+	op2, err := Read(Nop, bytes.NewReader(nil))
+	if err != nil {
+		t.Fatalf("unexpected error from Read: %v", err)
+	}
+	if op2.Name != "nop" || !op2.Enabled(0) {
+		t.Fatalf("0x01: unexpected Op %v", op2)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		prefix byte
+		code   byte
+	}{
+		{"nop", 0, Nop},
+		{"select", 0, Select},
+		{"ref.null", 0, RefNull},
+		{"i64.trunc_sat_f64_u", MiscPrefix, I64TruncSatUF64},
+		{"i8x16.shuffle", SIMDPrefix, I8x16Shuffle},
+	} {
+		op, ok := Lookup(tt.name)
+		if !ok || op.Prefix != tt.prefix || op.Code != tt.code {
+			t.Errorf("%s: unexpected Op %v", tt.name, op)
+		}
+	}
+	// the feature is set after the operator is created
+	if op, _ := Lookup("ref.null"); op.Feature != wasm.FeatureReferenceTypes {
+		t.Errorf("ref.null: unexpected feature %v", op.Feature)
+	}
+	if _, ok := Lookup("i32.nop"); ok {
+		t.Error("i32.nop: unexpected Op")
+	}
+}
@@ -4,7 +4,15 @@
 
 package operators
 
+import (
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+)
+
 var (
 	Drop   = newPolymorphicOp(0x1a, "drop")
 	Select = newPolymorphicOp(0x1b, "select")
+
+	// SelectT is the typed select, taking the types of its operands as
+	// immediates.
+	SelectT = withFeature(newPolymorphicOp(0x1c, "select"), wasm.FeatureReferenceTypes)
 )
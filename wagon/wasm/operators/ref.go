@@ -0,0 +1,25 @@
+// Copyright 2017 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package operators
+
+import (
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+)
+
+// Reference operators
+var (
+	RefNull   = withFeature(newPolymorphicOp(0xd0, "ref.null"), wasm.FeatureReferenceTypes)
+	RefIsNull = withFeature(newPolymorphicOp(0xd1, "ref.is_null"), wasm.FeatureReferenceTypes)
+	RefFunc   = withFeature(newOp(0xd2, "ref.func", nil, wasm.ValueTypeFuncref), wasm.FeatureReferenceTypes)
+)
+
+// Table operators
+var (
+	TableGet  = withFeature(newPolymorphicOp(0x25, "table.get"), wasm.FeatureReferenceTypes)
+	TableSet  = withFeature(newPolymorphicOp(0x26, "table.set"), wasm.FeatureReferenceTypes)
+	TableGrow = newPolymorphicPrefixedOp(MiscPrefix, 0x0f, "table.grow", wasm.FeatureReferenceTypes)
+	TableSize = newPrefixedOp(MiscPrefix, 0x10, "table.size", nil, wasm.ValueTypeI32, wasm.FeatureReferenceTypes)
+	TableFill = newPolymorphicPrefixedOp(MiscPrefix, 0x11, "table.fill", wasm.FeatureReferenceTypes)
+)
@@ -0,0 +1,281 @@
+// Copyright 2017 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package operators
+
+import (
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+)
+
+// SIMDPrefix is the prefix byte of the fixed-width SIMD operators, which
+// operate on v128 values. All their opcodes fit in a byte, although they are
+// encoded as LEB128 following the prefix.
+const SIMDPrefix byte = 0xfd
+
+// The arguments are listed from the top of the stack, as for i32.store.
+var (
+	vec     = []wasm.ValueType{wasm.ValueTypeV128}
+	vec2    = []wasm.ValueType{wasm.ValueTypeV128, wasm.ValueTypeV128}
+	vec3    = []wasm.ValueType{wasm.ValueTypeV128, wasm.ValueTypeV128, wasm.ValueTypeV128}
+	vecI32  = []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeV128}
+	vecI64  = []wasm.ValueType{wasm.ValueTypeI64, wasm.ValueTypeV128}
+	vecF32  = []wasm.ValueType{wasm.ValueTypeF32, wasm.ValueTypeV128}
+	vecF64  = []wasm.ValueType{wasm.ValueTypeF64, wasm.ValueTypeV128}
+	i32     = []wasm.ValueType{wasm.ValueTypeI32}
+	i64     = []wasm.ValueType{wasm.ValueTypeI64}
+	f32     = []wasm.ValueType{wasm.ValueTypeF32}
+	f64     = []wasm.ValueType{wasm.ValueTypeF64}
+	addrVec = []wasm.ValueType{wasm.ValueTypeV128, wasm.ValueTypeI32}
+)
+
+// SIMD operators
+var (
+	// memory operators
+	V128Load        = newPrefixedOp(SIMDPrefix, 0x00, "v128.load", i32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Load8x8S    = newPrefixedOp(SIMDPrefix, 0x01, "v128.load8x8_s", i32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Load8x8U    = newPrefixedOp(SIMDPrefix, 0x02, "v128.load8x8_u", i32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Load16x4S   = newPrefixedOp(SIMDPrefix, 0x03, "v128.load16x4_s", i32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Load16x4U   = newPrefixedOp(SIMDPrefix, 0x04, "v128.load16x4_u", i32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Load32x2S   = newPrefixedOp(SIMDPrefix, 0x05, "v128.load32x2_s", i32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Load32x2U   = newPrefixedOp(SIMDPrefix, 0x06, "v128.load32x2_u", i32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Load8Splat  = newPrefixedOp(SIMDPrefix, 0x07, "v128.load8_splat", i32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Load16Splat = newPrefixedOp(SIMDPrefix, 0x08, "v128.load16_splat", i32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Load32Splat = newPrefixedOp(SIMDPrefix, 0x09, "v128.load32_splat", i32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Load64Splat = newPrefixedOp(SIMDPrefix, 0x0a, "v128.load64_splat", i32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Store       = newPrefixedOp(SIMDPrefix, 0x0b, "v128.store", addrVec, noReturn, wasm.FeatureSIMD)
+
+	// constant, shuffle and lane operators
+	V128Const         = newPrefixedOp(SIMDPrefix, 0x0c, "v128.const", nil, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16Shuffle      = newPrefixedOp(SIMDPrefix, 0x0d, "i8x16.shuffle", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16Swizzle      = newPrefixedOp(SIMDPrefix, 0x0e, "i8x16.swizzle", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16Splat        = newPrefixedOp(SIMDPrefix, 0x0f, "i8x16.splat", i32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8Splat        = newPrefixedOp(SIMDPrefix, 0x10, "i16x8.splat", i32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4Splat        = newPrefixedOp(SIMDPrefix, 0x11, "i32x4.splat", i32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2Splat        = newPrefixedOp(SIMDPrefix, 0x12, "i64x2.splat", i64, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Splat        = newPrefixedOp(SIMDPrefix, 0x13, "f32x4.splat", f32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Splat        = newPrefixedOp(SIMDPrefix, 0x14, "f64x2.splat", f64, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16ExtractLaneS = newPrefixedOp(SIMDPrefix, 0x15, "i8x16.extract_lane_s", vec, wasm.ValueTypeI32, wasm.FeatureSIMD)
+	I8x16ExtractLaneU = newPrefixedOp(SIMDPrefix, 0x16, "i8x16.extract_lane_u", vec, wasm.ValueTypeI32, wasm.FeatureSIMD)
+	I8x16ReplaceLane  = newPrefixedOp(SIMDPrefix, 0x17, "i8x16.replace_lane", vecI32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8ExtractLaneS = newPrefixedOp(SIMDPrefix, 0x18, "i16x8.extract_lane_s", vec, wasm.ValueTypeI32, wasm.FeatureSIMD)
+	I16x8ExtractLaneU = newPrefixedOp(SIMDPrefix, 0x19, "i16x8.extract_lane_u", vec, wasm.ValueTypeI32, wasm.FeatureSIMD)
+	I16x8ReplaceLane  = newPrefixedOp(SIMDPrefix, 0x1a, "i16x8.replace_lane", vecI32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4ExtractLane  = newPrefixedOp(SIMDPrefix, 0x1b, "i32x4.extract_lane", vec, wasm.ValueTypeI32, wasm.FeatureSIMD)
+	I32x4ReplaceLane  = newPrefixedOp(SIMDPrefix, 0x1c, "i32x4.replace_lane", vecI32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2ExtractLane  = newPrefixedOp(SIMDPrefix, 0x1d, "i64x2.extract_lane", vec, wasm.ValueTypeI64, wasm.FeatureSIMD)
+	I64x2ReplaceLane  = newPrefixedOp(SIMDPrefix, 0x1e, "i64x2.replace_lane", vecI64, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4ExtractLane  = newPrefixedOp(SIMDPrefix, 0x1f, "f32x4.extract_lane", vec, wasm.ValueTypeF32, wasm.FeatureSIMD)
+	F32x4ReplaceLane  = newPrefixedOp(SIMDPrefix, 0x20, "f32x4.replace_lane", vecF32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2ExtractLane  = newPrefixedOp(SIMDPrefix, 0x21, "f64x2.extract_lane", vec, wasm.ValueTypeF64, wasm.FeatureSIMD)
+	F64x2ReplaceLane  = newPrefixedOp(SIMDPrefix, 0x22, "f64x2.replace_lane", vecF64, wasm.ValueTypeV128, wasm.FeatureSIMD)
+
+	// comparison operators
+	I8x16Eq  = newPrefixedOp(SIMDPrefix, 0x23, "i8x16.eq", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16Ne  = newPrefixedOp(SIMDPrefix, 0x24, "i8x16.ne", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16LtS = newPrefixedOp(SIMDPrefix, 0x25, "i8x16.lt_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16LtU = newPrefixedOp(SIMDPrefix, 0x26, "i8x16.lt_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16GtS = newPrefixedOp(SIMDPrefix, 0x27, "i8x16.gt_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16GtU = newPrefixedOp(SIMDPrefix, 0x28, "i8x16.gt_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16LeS = newPrefixedOp(SIMDPrefix, 0x29, "i8x16.le_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16LeU = newPrefixedOp(SIMDPrefix, 0x2a, "i8x16.le_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16GeS = newPrefixedOp(SIMDPrefix, 0x2b, "i8x16.ge_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16GeU = newPrefixedOp(SIMDPrefix, 0x2c, "i8x16.ge_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8Eq  = newPrefixedOp(SIMDPrefix, 0x2d, "i16x8.eq", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8Ne  = newPrefixedOp(SIMDPrefix, 0x2e, "i16x8.ne", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8LtS = newPrefixedOp(SIMDPrefix, 0x2f, "i16x8.lt_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8LtU = newPrefixedOp(SIMDPrefix, 0x30, "i16x8.lt_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8GtS = newPrefixedOp(SIMDPrefix, 0x31, "i16x8.gt_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8GtU = newPrefixedOp(SIMDPrefix, 0x32, "i16x8.gt_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8LeS = newPrefixedOp(SIMDPrefix, 0x33, "i16x8.le_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8LeU = newPrefixedOp(SIMDPrefix, 0x34, "i16x8.le_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8GeS = newPrefixedOp(SIMDPrefix, 0x35, "i16x8.ge_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8GeU = newPrefixedOp(SIMDPrefix, 0x36, "i16x8.ge_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4Eq  = newPrefixedOp(SIMDPrefix, 0x37, "i32x4.eq", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4Ne  = newPrefixedOp(SIMDPrefix, 0x38, "i32x4.ne", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4LtS = newPrefixedOp(SIMDPrefix, 0x39, "i32x4.lt_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4LtU = newPrefixedOp(SIMDPrefix, 0x3a, "i32x4.lt_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4GtS = newPrefixedOp(SIMDPrefix, 0x3b, "i32x4.gt_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4GtU = newPrefixedOp(SIMDPrefix, 0x3c, "i32x4.gt_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4LeS = newPrefixedOp(SIMDPrefix, 0x3d, "i32x4.le_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4LeU = newPrefixedOp(SIMDPrefix, 0x3e, "i32x4.le_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4GeS = newPrefixedOp(SIMDPrefix, 0x3f, "i32x4.ge_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4GeU = newPrefixedOp(SIMDPrefix, 0x40, "i32x4.ge_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Eq  = newPrefixedOp(SIMDPrefix, 0x41, "f32x4.eq", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Ne  = newPrefixedOp(SIMDPrefix, 0x42, "f32x4.ne", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Lt  = newPrefixedOp(SIMDPrefix, 0x43, "f32x4.lt", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Gt  = newPrefixedOp(SIMDPrefix, 0x44, "f32x4.gt", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Le  = newPrefixedOp(SIMDPrefix, 0x45, "f32x4.le", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Ge  = newPrefixedOp(SIMDPrefix, 0x46, "f32x4.ge", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Eq  = newPrefixedOp(SIMDPrefix, 0x47, "f64x2.eq", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Ne  = newPrefixedOp(SIMDPrefix, 0x48, "f64x2.ne", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Lt  = newPrefixedOp(SIMDPrefix, 0x49, "f64x2.lt", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Gt  = newPrefixedOp(SIMDPrefix, 0x4a, "f64x2.gt", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Le  = newPrefixedOp(SIMDPrefix, 0x4b, "f64x2.le", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Ge  = newPrefixedOp(SIMDPrefix, 0x4c, "f64x2.ge", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+
+	// bitwise operators
+	V128Not       = newPrefixedOp(SIMDPrefix, 0x4d, "v128.not", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128And       = newPrefixedOp(SIMDPrefix, 0x4e, "v128.and", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128AndNot    = newPrefixedOp(SIMDPrefix, 0x4f, "v128.andnot", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Or        = newPrefixedOp(SIMDPrefix, 0x50, "v128.or", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Xor       = newPrefixedOp(SIMDPrefix, 0x51, "v128.xor", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Bitselect = newPrefixedOp(SIMDPrefix, 0x52, "v128.bitselect", vec3, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128AnyTrue   = newPrefixedOp(SIMDPrefix, 0x53, "v128.any_true", vec, wasm.ValueTypeI32, wasm.FeatureSIMD)
+
+	// lane memory operators
+	V128Load8Lane   = newPrefixedOp(SIMDPrefix, 0x54, "v128.load8_lane", addrVec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Load16Lane  = newPrefixedOp(SIMDPrefix, 0x55, "v128.load16_lane", addrVec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Load32Lane  = newPrefixedOp(SIMDPrefix, 0x56, "v128.load32_lane", addrVec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Load64Lane  = newPrefixedOp(SIMDPrefix, 0x57, "v128.load64_lane", addrVec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Store8Lane  = newPrefixedOp(SIMDPrefix, 0x58, "v128.store8_lane", addrVec, noReturn, wasm.FeatureSIMD)
+	V128Store16Lane = newPrefixedOp(SIMDPrefix, 0x59, "v128.store16_lane", addrVec, noReturn, wasm.FeatureSIMD)
+	V128Store32Lane = newPrefixedOp(SIMDPrefix, 0x5a, "v128.store32_lane", addrVec, noReturn, wasm.FeatureSIMD)
+	V128Store64Lane = newPrefixedOp(SIMDPrefix, 0x5b, "v128.store64_lane", addrVec, noReturn, wasm.FeatureSIMD)
+	V128Load32Zero  = newPrefixedOp(SIMDPrefix, 0x5c, "v128.load32_zero", i32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	V128Load64Zero  = newPrefixedOp(SIMDPrefix, 0x5d, "v128.load64_zero", i32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+
+	// arithmetic and conversion operators
+	F32x4DemoteF64x2Zero      = newPrefixedOp(SIMDPrefix, 0x5e, "f32x4.demote_f64x2_zero", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2PromoteLowF32x4      = newPrefixedOp(SIMDPrefix, 0x5f, "f64x2.promote_low_f32x4", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16Abs                  = newPrefixedOp(SIMDPrefix, 0x60, "i8x16.abs", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16Neg                  = newPrefixedOp(SIMDPrefix, 0x61, "i8x16.neg", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16Popcnt               = newPrefixedOp(SIMDPrefix, 0x62, "i8x16.popcnt", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16AllTrue              = newPrefixedOp(SIMDPrefix, 0x63, "i8x16.all_true", vec, wasm.ValueTypeI32, wasm.FeatureSIMD)
+	I8x16Bitmask              = newPrefixedOp(SIMDPrefix, 0x64, "i8x16.bitmask", vec, wasm.ValueTypeI32, wasm.FeatureSIMD)
+	I8x16NarrowI16x8S         = newPrefixedOp(SIMDPrefix, 0x65, "i8x16.narrow_i16x8_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16NarrowI16x8U         = newPrefixedOp(SIMDPrefix, 0x66, "i8x16.narrow_i16x8_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Ceil                 = newPrefixedOp(SIMDPrefix, 0x67, "f32x4.ceil", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Floor                = newPrefixedOp(SIMDPrefix, 0x68, "f32x4.floor", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Trunc                = newPrefixedOp(SIMDPrefix, 0x69, "f32x4.trunc", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Nearest              = newPrefixedOp(SIMDPrefix, 0x6a, "f32x4.nearest", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16Shl                  = newPrefixedOp(SIMDPrefix, 0x6b, "i8x16.shl", vecI32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16ShrS                 = newPrefixedOp(SIMDPrefix, 0x6c, "i8x16.shr_s", vecI32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16ShrU                 = newPrefixedOp(SIMDPrefix, 0x6d, "i8x16.shr_u", vecI32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16Add                  = newPrefixedOp(SIMDPrefix, 0x6e, "i8x16.add", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16AddSatS              = newPrefixedOp(SIMDPrefix, 0x6f, "i8x16.add_sat_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16AddSatU              = newPrefixedOp(SIMDPrefix, 0x70, "i8x16.add_sat_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16Sub                  = newPrefixedOp(SIMDPrefix, 0x71, "i8x16.sub", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16SubSatS              = newPrefixedOp(SIMDPrefix, 0x72, "i8x16.sub_sat_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16SubSatU              = newPrefixedOp(SIMDPrefix, 0x73, "i8x16.sub_sat_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Ceil                 = newPrefixedOp(SIMDPrefix, 0x74, "f64x2.ceil", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Floor                = newPrefixedOp(SIMDPrefix, 0x75, "f64x2.floor", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16MinS                 = newPrefixedOp(SIMDPrefix, 0x76, "i8x16.min_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16MinU                 = newPrefixedOp(SIMDPrefix, 0x77, "i8x16.min_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16MaxS                 = newPrefixedOp(SIMDPrefix, 0x78, "i8x16.max_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16MaxU                 = newPrefixedOp(SIMDPrefix, 0x79, "i8x16.max_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Trunc                = newPrefixedOp(SIMDPrefix, 0x7a, "f64x2.trunc", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I8x16AvgrU                = newPrefixedOp(SIMDPrefix, 0x7b, "i8x16.avgr_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8ExtaddPairwiseI8x16S = newPrefixedOp(SIMDPrefix, 0x7c, "i16x8.extadd_pairwise_i8x16_s", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8ExtaddPairwiseI8x16U = newPrefixedOp(SIMDPrefix, 0x7d, "i16x8.extadd_pairwise_i8x16_u", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4ExtaddPairwiseI16x8S = newPrefixedOp(SIMDPrefix, 0x7e, "i32x4.extadd_pairwise_i16x8_s", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4ExtaddPairwiseI16x8U = newPrefixedOp(SIMDPrefix, 0x7f, "i32x4.extadd_pairwise_i16x8_u", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8Abs                  = newPrefixedOp(SIMDPrefix, 0x80, "i16x8.abs", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8Neg                  = newPrefixedOp(SIMDPrefix, 0x81, "i16x8.neg", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8Q15mulrSatS          = newPrefixedOp(SIMDPrefix, 0x82, "i16x8.q15mulr_sat_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8AllTrue              = newPrefixedOp(SIMDPrefix, 0x83, "i16x8.all_true", vec, wasm.ValueTypeI32, wasm.FeatureSIMD)
+	I16x8Bitmask              = newPrefixedOp(SIMDPrefix, 0x84, "i16x8.bitmask", vec, wasm.ValueTypeI32, wasm.FeatureSIMD)
+	I16x8NarrowI32x4S         = newPrefixedOp(SIMDPrefix, 0x85, "i16x8.narrow_i32x4_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8NarrowI32x4U         = newPrefixedOp(SIMDPrefix, 0x86, "i16x8.narrow_i32x4_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8ExtendLowI8x16S      = newPrefixedOp(SIMDPrefix, 0x87, "i16x8.extend_low_i8x16_s", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8ExtendHighI8x16S     = newPrefixedOp(SIMDPrefix, 0x88, "i16x8.extend_high_i8x16_s", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8ExtendLowI8x16U      = newPrefixedOp(SIMDPrefix, 0x89, "i16x8.extend_low_i8x16_u", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8ExtendHighI8x16U     = newPrefixedOp(SIMDPrefix, 0x8a, "i16x8.extend_high_i8x16_u", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8Shl                  = newPrefixedOp(SIMDPrefix, 0x8b, "i16x8.shl", vecI32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8ShrS                 = newPrefixedOp(SIMDPrefix, 0x8c, "i16x8.shr_s", vecI32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8ShrU                 = newPrefixedOp(SIMDPrefix, 0x8d, "i16x8.shr_u", vecI32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8Add                  = newPrefixedOp(SIMDPrefix, 0x8e, "i16x8.add", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8AddSatS              = newPrefixedOp(SIMDPrefix, 0x8f, "i16x8.add_sat_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8AddSatU              = newPrefixedOp(SIMDPrefix, 0x90, "i16x8.add_sat_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8Sub                  = newPrefixedOp(SIMDPrefix, 0x91, "i16x8.sub", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8SubSatS              = newPrefixedOp(SIMDPrefix, 0x92, "i16x8.sub_sat_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8SubSatU              = newPrefixedOp(SIMDPrefix, 0x93, "i16x8.sub_sat_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Nearest              = newPrefixedOp(SIMDPrefix, 0x94, "f64x2.nearest", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8Mul                  = newPrefixedOp(SIMDPrefix, 0x95, "i16x8.mul", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8MinS                 = newPrefixedOp(SIMDPrefix, 0x96, "i16x8.min_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8MinU                 = newPrefixedOp(SIMDPrefix, 0x97, "i16x8.min_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8MaxS                 = newPrefixedOp(SIMDPrefix, 0x98, "i16x8.max_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8MaxU                 = newPrefixedOp(SIMDPrefix, 0x99, "i16x8.max_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8AvgrU                = newPrefixedOp(SIMDPrefix, 0x9b, "i16x8.avgr_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8ExtmulLowI8x16S      = newPrefixedOp(SIMDPrefix, 0x9c, "i16x8.extmul_low_i8x16_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8ExtmulHighI8x16S     = newPrefixedOp(SIMDPrefix, 0x9d, "i16x8.extmul_high_i8x16_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8ExtmulLowI8x16U      = newPrefixedOp(SIMDPrefix, 0x9e, "i16x8.extmul_low_i8x16_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I16x8ExtmulHighI8x16U     = newPrefixedOp(SIMDPrefix, 0x9f, "i16x8.extmul_high_i8x16_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4Abs                  = newPrefixedOp(SIMDPrefix, 0xa0, "i32x4.abs", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4Neg                  = newPrefixedOp(SIMDPrefix, 0xa1, "i32x4.neg", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4AllTrue              = newPrefixedOp(SIMDPrefix, 0xa3, "i32x4.all_true", vec, wasm.ValueTypeI32, wasm.FeatureSIMD)
+	I32x4Bitmask              = newPrefixedOp(SIMDPrefix, 0xa4, "i32x4.bitmask", vec, wasm.ValueTypeI32, wasm.FeatureSIMD)
+	I32x4ExtendLowI16x8S      = newPrefixedOp(SIMDPrefix, 0xa7, "i32x4.extend_low_i16x8_s", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4ExtendHighI16x8S     = newPrefixedOp(SIMDPrefix, 0xa8, "i32x4.extend_high_i16x8_s", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4ExtendLowI16x8U      = newPrefixedOp(SIMDPrefix, 0xa9, "i32x4.extend_low_i16x8_u", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4ExtendHighI16x8U     = newPrefixedOp(SIMDPrefix, 0xaa, "i32x4.extend_high_i16x8_u", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4Shl                  = newPrefixedOp(SIMDPrefix, 0xab, "i32x4.shl", vecI32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4ShrS                 = newPrefixedOp(SIMDPrefix, 0xac, "i32x4.shr_s", vecI32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4ShrU                 = newPrefixedOp(SIMDPrefix, 0xad, "i32x4.shr_u", vecI32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4Add                  = newPrefixedOp(SIMDPrefix, 0xae, "i32x4.add", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4Sub                  = newPrefixedOp(SIMDPrefix, 0xb1, "i32x4.sub", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4Mul                  = newPrefixedOp(SIMDPrefix, 0xb5, "i32x4.mul", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4MinS                 = newPrefixedOp(SIMDPrefix, 0xb6, "i32x4.min_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4MinU                 = newPrefixedOp(SIMDPrefix, 0xb7, "i32x4.min_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4MaxS                 = newPrefixedOp(SIMDPrefix, 0xb8, "i32x4.max_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4MaxU                 = newPrefixedOp(SIMDPrefix, 0xb9, "i32x4.max_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4DotI16x8S            = newPrefixedOp(SIMDPrefix, 0xba, "i32x4.dot_i16x8_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4ExtmulLowI16x8S      = newPrefixedOp(SIMDPrefix, 0xbc, "i32x4.extmul_low_i16x8_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4ExtmulHighI16x8S     = newPrefixedOp(SIMDPrefix, 0xbd, "i32x4.extmul_high_i16x8_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4ExtmulLowI16x8U      = newPrefixedOp(SIMDPrefix, 0xbe, "i32x4.extmul_low_i16x8_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4ExtmulHighI16x8U     = newPrefixedOp(SIMDPrefix, 0xbf, "i32x4.extmul_high_i16x8_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2Abs                  = newPrefixedOp(SIMDPrefix, 0xc0, "i64x2.abs", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2Neg                  = newPrefixedOp(SIMDPrefix, 0xc1, "i64x2.neg", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2AllTrue              = newPrefixedOp(SIMDPrefix, 0xc3, "i64x2.all_true", vec, wasm.ValueTypeI32, wasm.FeatureSIMD)
+	I64x2Bitmask              = newPrefixedOp(SIMDPrefix, 0xc4, "i64x2.bitmask", vec, wasm.ValueTypeI32, wasm.FeatureSIMD)
+	I64x2ExtendLowI32x4S      = newPrefixedOp(SIMDPrefix, 0xc7, "i64x2.extend_low_i32x4_s", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2ExtendHighI32x4S     = newPrefixedOp(SIMDPrefix, 0xc8, "i64x2.extend_high_i32x4_s", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2ExtendLowI32x4U      = newPrefixedOp(SIMDPrefix, 0xc9, "i64x2.extend_low_i32x4_u", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2ExtendHighI32x4U     = newPrefixedOp(SIMDPrefix, 0xca, "i64x2.extend_high_i32x4_u", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2Shl                  = newPrefixedOp(SIMDPrefix, 0xcb, "i64x2.shl", vecI32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2ShrS                 = newPrefixedOp(SIMDPrefix, 0xcc, "i64x2.shr_s", vecI32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2ShrU                 = newPrefixedOp(SIMDPrefix, 0xcd, "i64x2.shr_u", vecI32, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2Add                  = newPrefixedOp(SIMDPrefix, 0xce, "i64x2.add", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2Sub                  = newPrefixedOp(SIMDPrefix, 0xd1, "i64x2.sub", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2Mul                  = newPrefixedOp(SIMDPrefix, 0xd5, "i64x2.mul", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2Eq                   = newPrefixedOp(SIMDPrefix, 0xd6, "i64x2.eq", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2Ne                   = newPrefixedOp(SIMDPrefix, 0xd7, "i64x2.ne", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2LtS                  = newPrefixedOp(SIMDPrefix, 0xd8, "i64x2.lt_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2GtS                  = newPrefixedOp(SIMDPrefix, 0xd9, "i64x2.gt_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2LeS                  = newPrefixedOp(SIMDPrefix, 0xda, "i64x2.le_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2GeS                  = newPrefixedOp(SIMDPrefix, 0xdb, "i64x2.ge_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2ExtmulLowI32x4S      = newPrefixedOp(SIMDPrefix, 0xdc, "i64x2.extmul_low_i32x4_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2ExtmulHighI32x4S     = newPrefixedOp(SIMDPrefix, 0xdd, "i64x2.extmul_high_i32x4_s", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2ExtmulLowI32x4U      = newPrefixedOp(SIMDPrefix, 0xde, "i64x2.extmul_low_i32x4_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I64x2ExtmulHighI32x4U     = newPrefixedOp(SIMDPrefix, 0xdf, "i64x2.extmul_high_i32x4_u", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Abs                  = newPrefixedOp(SIMDPrefix, 0xe0, "f32x4.abs", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Neg                  = newPrefixedOp(SIMDPrefix, 0xe1, "f32x4.neg", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Sqrt                 = newPrefixedOp(SIMDPrefix, 0xe3, "f32x4.sqrt", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Add                  = newPrefixedOp(SIMDPrefix, 0xe4, "f32x4.add", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Sub                  = newPrefixedOp(SIMDPrefix, 0xe5, "f32x4.sub", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Mul                  = newPrefixedOp(SIMDPrefix, 0xe6, "f32x4.mul", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Div                  = newPrefixedOp(SIMDPrefix, 0xe7, "f32x4.div", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Min                  = newPrefixedOp(SIMDPrefix, 0xe8, "f32x4.min", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Max                  = newPrefixedOp(SIMDPrefix, 0xe9, "f32x4.max", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Pmin                 = newPrefixedOp(SIMDPrefix, 0xea, "f32x4.pmin", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4Pmax                 = newPrefixedOp(SIMDPrefix, 0xeb, "f32x4.pmax", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Abs                  = newPrefixedOp(SIMDPrefix, 0xec, "f64x2.abs", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Neg                  = newPrefixedOp(SIMDPrefix, 0xed, "f64x2.neg", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Sqrt                 = newPrefixedOp(SIMDPrefix, 0xef, "f64x2.sqrt", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Add                  = newPrefixedOp(SIMDPrefix, 0xf0, "f64x2.add", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Sub                  = newPrefixedOp(SIMDPrefix, 0xf1, "f64x2.sub", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Mul                  = newPrefixedOp(SIMDPrefix, 0xf2, "f64x2.mul", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Div                  = newPrefixedOp(SIMDPrefix, 0xf3, "f64x2.div", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Min                  = newPrefixedOp(SIMDPrefix, 0xf4, "f64x2.min", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Max                  = newPrefixedOp(SIMDPrefix, 0xf5, "f64x2.max", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Pmin                 = newPrefixedOp(SIMDPrefix, 0xf6, "f64x2.pmin", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2Pmax                 = newPrefixedOp(SIMDPrefix, 0xf7, "f64x2.pmax", vec2, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4TruncSatF32x4S       = newPrefixedOp(SIMDPrefix, 0xf8, "i32x4.trunc_sat_f32x4_s", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4TruncSatF32x4U       = newPrefixedOp(SIMDPrefix, 0xf9, "i32x4.trunc_sat_f32x4_u", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4ConvertI32x4S        = newPrefixedOp(SIMDPrefix, 0xfa, "f32x4.convert_i32x4_s", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F32x4ConvertI32x4U        = newPrefixedOp(SIMDPrefix, 0xfb, "f32x4.convert_i32x4_u", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4TruncSatF64x2SZero   = newPrefixedOp(SIMDPrefix, 0xfc, "i32x4.trunc_sat_f64x2_s_zero", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	I32x4TruncSatF64x2UZero   = newPrefixedOp(SIMDPrefix, 0xfd, "i32x4.trunc_sat_f64x2_u_zero", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2ConvertLowI32x4S     = newPrefixedOp(SIMDPrefix, 0xfe, "f64x2.convert_low_i32x4_s", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+	F64x2ConvertLowI32x4U     = newPrefixedOp(SIMDPrefix, 0xff, "f64x2.convert_low_i32x4_u", vec, wasm.ValueTypeV128, wasm.FeatureSIMD)
+)
@@ -5,20 +5,28 @@
 package wasm
 
 import (
+	"bytes"
 	"encoding/binary"
 	"io"
 
 	"github.com/tinychain/tiny-wasm/wagon/wasm/leb128"
 )
 
+// readBytes reads n bytes. Past maxPrealloc, they are allocated as they
+// are read, so that a truncated module can't make it allocate more than
+// it holds.
 func readBytes(r io.Reader, n int) ([]byte, error) {
-	bytes := make([]byte, n)
-	_, err := io.ReadFull(r, bytes)
-	if err != nil {
-		return bytes, err
+	if n <= maxPrealloc {
+		p := make([]byte, n)
+		_, err := io.ReadFull(r, p)
+		return p, err
 	}
-
-	return bytes, nil
+	buf := bytes.NewBuffer(make([]byte, 0, maxPrealloc))
+	read, err := io.CopyN(buf, r, int64(n))
+	if err == io.EOF && read > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return buf.Bytes(), err
 }
 
 func readBytesUint(r io.Reader) ([]byte, error) {
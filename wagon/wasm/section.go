@@ -45,6 +45,10 @@ const (
 	SectionIDElement  SectionID = 9
 	SectionIDCode     SectionID = 10
 	SectionIDData     SectionID = 11
+
+	// SectionIDDataCount is the section of the bulk memory proposal
+	// declaring the number of data segments ahead of the code section.
+	SectionIDDataCount SectionID = 12
 )
 
 func (s SectionID) String() string {
@@ -61,6 +65,8 @@ func (s SectionID) String() string {
 		SectionIDElement:  "element",
 		SectionIDCode:     "code",
 		SectionIDData:     "data",
+
+		SectionIDDataCount: "datacount",
 	}[s]
 	if !ok {
 		return "unknown"
@@ -91,6 +97,14 @@ func (e InvalidSectionIDError) Error() string {
 	return fmt.Sprintf("wasm: invalid section ID %d", e)
 }
 
+// InvalidSegmentFlagsError is returned when the flags of a data or element
+// segment encode an unsupported kind of segment.
+type InvalidSegmentFlagsError uint32
+
+func (e InvalidSegmentFlagsError) Error() string {
+	return fmt.Sprintf("wasm: invalid segment flags %d", uint32(e))
+}
+
 type InvalidCodeIndexError int
 
 func (e InvalidCodeIndexError) Error() string {
@@ -119,6 +133,9 @@ func (m *Module) readSection(r *readpos.ReadPos) (bool, error) {
 		return false, err
 	}
 	s := RawSection{ID: SectionID(id)}
+	if err := checkLimit("MaxSections", uint64(len(m.Sections)+1), m.decodeConfig.MaxSections); err != nil {
+		return false, err
+	}
 
 	logger.Println("Reading payload length")
 
@@ -132,7 +149,7 @@ func (m *Module) readSection(r *readpos.ReadPos) (bool, error) {
 	s.Start = r.CurPos
 
 	sectionBytes := new(bytes.Buffer)
-	sectionBytes.Grow(int(payloadDataLen))
+	sectionBytes.Grow(capacity(payloadDataLen))
 	sectionReader := io.LimitReader(io.TeeReader(r, sectionBytes), int64(payloadDataLen))
 
 	var sec Section
@@ -186,10 +203,18 @@ func (m *Module) readSection(r *readpos.ReadPos) (bool, error) {
 		logger.Println("section data")
 		m.Data = &SectionData{}
 		sec = m.Data
+	case SectionIDDataCount:
+		logger.Println("section data count")
+		m.DataCount = &SectionDataCount{}
+		sec = m.DataCount
 	default:
 		return false, InvalidSectionIDError(s.ID)
 	}
-	err = sec.ReadPayload(sectionReader)
+	if ls, ok := sec.(limitedSection); ok {
+		err = ls.readPayload(sectionReader, &m.decodeConfig)
+	} else {
+		err = sec.ReadPayload(sectionReader)
+	}
 	if err != nil {
 		logger.Println(err)
 		return false, err
@@ -212,11 +237,39 @@ func (m *Module) readSection(r *readpos.ReadPos) (bool, error) {
 		for i := range s.Bodies {
 			s.Bodies[i].Module = m
 		}
+	case SectionIDData:
+		if m.DataCount != nil && int(m.DataCount.Count) != len(m.Data.Entries) {
+			return false, errors.New("The data count and the number of entries in the data section are unequal")
+		}
+	case SectionIDTable:
+		for _, t := range m.Table.Entries {
+			if err := checkLimit("MaxTableSize", uint64(t.Limits.Initial), m.decodeConfig.MaxTableSize); err != nil {
+				return false, err
+			}
+		}
+	case SectionIDMemory:
+		for _, mem := range m.Memory.Entries {
+			if err := checkLimit("MaxMemoryPages", uint64(mem.Limits.Initial), m.decodeConfig.MaxMemoryPages); err != nil {
+				return false, err
+			}
+		}
 	}
 	m.Sections = append(m.Sections, sec)
 	return false, nil
 }
 
+// limitedSection is a section whose decoding enforces the limits of a
+// DecodeConfig.
+type limitedSection interface {
+	readPayload(r io.Reader, cfg *DecodeConfig) error
+}
+
+var (
+	_ limitedSection = (*SectionFunctions)(nil)
+	_ limitedSection = (*SectionCode)(nil)
+	_ limitedSection = (*SectionData)(nil)
+)
+
 var _ Section = (*SectionCustom)(nil)
 
 type SectionCustom struct {
@@ -268,11 +321,13 @@ func (s *SectionTypes) ReadPayload(r io.Reader) error {
 	if err != nil {
 		return err
 	}
-	s.Entries = make([]FunctionSig, int(count))
-	for i := range s.Entries {
-		if err = s.Entries[i].UnmarshalWASM(r); err != nil {
+	s.Entries = make([]FunctionSig, 0, capacity(count))
+	for i := uint32(0); i < count; i++ {
+		var entry FunctionSig
+		if err = entry.UnmarshalWASM(r); err != nil {
 			return err
 		}
+		s.Entries = append(s.Entries, entry)
 	}
 	return nil
 }
@@ -307,12 +362,14 @@ func (s *SectionImports) ReadPayload(r io.Reader) error {
 	if err != nil {
 		return err
 	}
-	s.Entries = make([]ImportEntry, count)
-	for i := range s.Entries {
-		err = s.Entries[i].UnmarshalWASM(r)
+	s.Entries = make([]ImportEntry, 0, capacity(count))
+	for i := uint32(0); i < count; i++ {
+		var entry ImportEntry
+		err = entry.UnmarshalWASM(r)
 		if err != nil {
 			return err
 		}
+		s.Entries = append(s.Entries, entry)
 	}
 	return nil
 }
@@ -409,17 +466,24 @@ func (*SectionFunctions) SectionID() SectionID {
 }
 
 func (s *SectionFunctions) ReadPayload(r io.Reader) error {
+	return s.readPayload(r, &DecodeConfig{})
+}
+
+func (s *SectionFunctions) readPayload(r io.Reader, cfg *DecodeConfig) error {
 	count, err := leb128.ReadVarUint32(r)
 	if err != nil {
 		return err
 	}
-	s.Types = make([]uint32, count)
-	for i := range s.Types {
+	if err := checkLimit("MaxFunctions", uint64(count), cfg.MaxFunctions); err != nil {
+		return err
+	}
+	s.Types = make([]uint32, 0, capacity(count))
+	for i := uint32(0); i < count; i++ {
 		t, err := leb128.ReadVarUint32(r)
 		if err != nil {
 			return err
 		}
-		s.Types[i] = t
+		s.Types = append(s.Types, t)
 	}
 	return nil
 }
@@ -451,12 +515,14 @@ func (s *SectionTables) ReadPayload(r io.Reader) error {
 	if err != nil {
 		return err
 	}
-	s.Entries = make([]Table, count)
-	for i := range s.Entries {
-		err = s.Entries[i].UnmarshalWASM(r)
+	s.Entries = make([]Table, 0, capacity(count))
+	for i := uint32(0); i < count; i++ {
+		var entry Table
+		err = entry.UnmarshalWASM(r)
 		if err != nil {
 			return err
 		}
+		s.Entries = append(s.Entries, entry)
 	}
 	return nil
 }
@@ -488,12 +554,14 @@ func (s *SectionMemories) ReadPayload(r io.Reader) error {
 	if err != nil {
 		return err
 	}
-	s.Entries = make([]Memory, count)
-	for i := range s.Entries {
-		err = s.Entries[i].UnmarshalWASM(r)
+	s.Entries = make([]Memory, 0, capacity(count))
+	for i := uint32(0); i < count; i++ {
+		var entry Memory
+		err = entry.UnmarshalWASM(r)
 		if err != nil {
 			return err
 		}
+		s.Entries = append(s.Entries, entry)
 	}
 	return nil
 }
@@ -525,13 +593,15 @@ func (s *SectionGlobals) ReadPayload(r io.Reader) error {
 	if err != nil {
 		return err
 	}
-	s.Globals = make([]GlobalEntry, count)
+	s.Globals = make([]GlobalEntry, 0, capacity(count))
 	logger.Printf("%d global entries\n", count)
-	for i := range s.Globals {
-		err = s.Globals[i].UnmarshalWASM(r)
+	for i := uint32(0); i < count; i++ {
+		var entry GlobalEntry
+		err = entry.UnmarshalWASM(r)
 		if err != nil {
 			return err
 		}
+		s.Globals = append(s.Globals, entry)
 	}
 	return nil
 }
@@ -589,7 +659,7 @@ func (s *SectionExports) ReadPayload(r io.Reader) error {
 	if err != nil {
 		return err
 	}
-	s.Entries = make(map[string]ExportEntry, count)
+	s.Entries = make(map[string]ExportEntry, capacity(count))
 	for i := uint32(0); i < count; i++ {
 		var entry ExportEntry
 		err = entry.UnmarshalWASM(r)
@@ -628,7 +698,7 @@ func (s *SectionExports) WritePayload(w io.Writer) error {
 type DuplicateExportError string
 
 func (e DuplicateExportError) Error() string {
-	return fmt.Sprintf("Duplicate export entry: %s", e)
+	return fmt.Sprintf("Duplicate export entry: %s", string(e))
 }
 
 // ExportEntry represents an exported entry by the module
@@ -703,12 +773,14 @@ func (s *SectionElements) ReadPayload(r io.Reader) error {
 	if err != nil {
 		return err
 	}
-	s.Entries = make([]ElementSegment, count)
-	for i := range s.Entries {
-		err = s.Entries[i].UnmarshalWASM(r)
+	s.Entries = make([]ElementSegment, 0, capacity(count))
+	for i := uint32(0); i < count; i++ {
+		var entry ElementSegment
+		err = entry.UnmarshalWASM(r)
 		if err != nil {
 			return err
 		}
+		s.Entries = append(s.Entries, entry)
 	}
 	return nil
 }
@@ -727,50 +799,156 @@ func (s *SectionElements) WritePayload(w io.Writer) error {
 
 // ElementSegment describes a group of repeated elements that begin at a specified offset
 type ElementSegment struct {
-	Index  uint32 // The index into the global table space, should always be 0 in the MVP.
-	Offset []byte // initializer expression for computing the offset for placing elements, should return an i32 value
-	Elems  []uint32
-}
+	Index   uint32 // The index into the global table space, should always be 0 in the MVP.
+	Offset  []byte // initializer expression for computing the offset for placing elements, should return an i32 value
+	Elems   []uint32
+	Passive bool // passive segments have no offset and are only copied to a table by table.init
+
+	// Declarative segments have no offset and can't be copied to a table,
+	// they only declare the functions ref.func refers to.
+	Declarative bool
+	// Exprs is true if the elements are encoded as ref.func and ref.null
+	// expressions, the latter being decoded as NullElement.
+	Exprs bool
+	// Type is the type of the elements, ElemTypeAnyFunc unless the segment
+	// is encoded with expressions of another type.
+	Type ElemType
+}
+
+// Flags of the segment encodings of the bulk memory proposal
+const (
+	segmentActive      = 0x00 // active segment of the first memory or table
+	segmentPassive     = 0x01 // passive segment
+	segmentActiveIndex = 0x02 // active segment with an explicit memory or table index
+)
 
-func (s *ElementSegment) UnmarshalWASM(r io.Reader) error {
-	var err error
+// Flags of the element segment encodings of the reference types proposal,
+// combined with the ones above.
+const (
+	segmentDeclarative = 0x03 // declarative element segment
+	segmentExprs       = 0x04 // elements encoded as expressions
+)
 
-	if s.Index, err = leb128.ReadVarUint32(r); err != nil {
+// elemKindFunc is the only element kind of passive element segments and
+// element segments with an explicit table index.
+const elemKindFunc = 0x00
+
+func (s *ElementSegment) UnmarshalWASM(r io.Reader) error {
+	flags, err := leb128.ReadVarUint32(r)
+	if err != nil {
 		return err
 	}
-	if s.Offset, err = readInitExpr(r); err != nil {
-		return err
+	if flags > segmentExprs|segmentDeclarative {
+		return InvalidSegmentFlagsError(flags)
+	}
+	s.Exprs = flags&segmentExprs != 0
+	s.Type = ElemTypeAnyFunc
+	switch flags &^ segmentExprs {
+	case segmentActive:
+		s.Index = 0
+	case segmentPassive:
+		s.Passive = true
+	case segmentActiveIndex:
+		if s.Index, err = leb128.ReadVarUint32(r); err != nil {
+			return err
+		}
+	case segmentDeclarative:
+		s.Declarative = true
+	}
+	if !s.Passive && !s.Declarative {
+		if s.Offset, err = readInitExpr(r); err != nil {
+			return err
+		}
+	}
+	// the MVP encoding has neither an element kind nor a type
+	if flags&^segmentExprs != segmentActive {
+		if s.Exprs {
+			if err := s.Type.UnmarshalWASM(r); err != nil {
+				return err
+			}
+			if s.Type != ElemTypeAnyFunc && s.Type != ElemTypeExternRef {
+				return InvalidSegmentFlagsError(flags)
+			}
+		} else {
+			kind, err := readBytes(r, 1)
+			if err != nil {
+				return err
+			}
+			if kind[0] != elemKindFunc {
+				return InvalidSegmentFlagsError(flags)
+			}
+		}
 	}
 
 	numElems, err := leb128.ReadVarUint32(r)
 	if err != nil {
 		return err
 	}
-	s.Elems = make([]uint32, numElems)
+	s.Elems = make([]uint32, 0, capacity(numElems))
 
-	for i := range s.Elems {
-		e, err := leb128.ReadVarUint32(r)
+	for i := uint32(0); i < numElems; i++ {
+		var e uint32
+		if s.Exprs {
+			e, err = readElemExpr(r)
+		} else {
+			e, err = leb128.ReadVarUint32(r)
+		}
 		if err != nil {
 			return err
 		}
-		s.Elems[i] = e
+		s.Elems = append(s.Elems, e)
 	}
 
 	return nil
 }
 
 func (s *ElementSegment) MarshalWASM(w io.Writer) error {
-	if _, err := leb128.WriteVarUint32(w, s.Index); err != nil {
+	var flags uint32
+	switch {
+	case s.Passive:
+		flags = segmentPassive
+	case s.Declarative:
+		flags = segmentDeclarative
+	case s.Index != 0 || s.Type == ElemTypeExternRef:
+		// the encoding of the first table has no type
+		flags = segmentActiveIndex
+	}
+	if s.Exprs {
+		flags |= segmentExprs
+	}
+	if _, err := leb128.WriteVarUint32(w, flags); err != nil {
 		return err
 	}
-	if _, err := w.Write(s.Offset); err != nil {
-		return err
+	if flags&^segmentExprs == segmentActiveIndex {
+		if _, err := leb128.WriteVarUint32(w, s.Index); err != nil {
+			return err
+		}
+	}
+	if !s.Passive && !s.Declarative {
+		if _, err := w.Write(s.Offset); err != nil {
+			return err
+		}
+	}
+	if flags&^segmentExprs != segmentActive {
+		if s.Exprs {
+			if err := s.Type.MarshalWASM(w); err != nil {
+				return err
+			}
+		} else if _, err := w.Write([]byte{elemKindFunc}); err != nil {
+			return err
+		}
 	}
 
 	if _, err := leb128.WriteVarUint32(w, uint32(len(s.Elems))); err != nil {
 		return err
 	}
 	for _, e := range s.Elems {
+		if s.Exprs {
+			if err := writeElemExpr(w, s.Type, e); err != nil {
+				return err
+			}
+			continue
+		}
 		if _, err := leb128.WriteVarUint32(w, e); err != nil {
 			return err
 		}
@@ -789,18 +967,27 @@ func (*SectionCode) SectionID() SectionID {
 }
 
 func (s *SectionCode) ReadPayload(r io.Reader) error {
+	return s.readPayload(r, &DecodeConfig{})
+}
+
+func (s *SectionCode) readPayload(r io.Reader, cfg *DecodeConfig) error {
 	count, err := leb128.ReadVarUint32(r)
 	if err != nil {
 		return err
 	}
-	s.Bodies = make([]FunctionBody, count)
+	if err := checkLimit("MaxFunctions", uint64(count), cfg.MaxFunctions); err != nil {
+		return err
+	}
+	s.Bodies = make([]FunctionBody, 0, capacity(count))
 	logger.Printf("%d function bodies\n", count)
 
-	for i := range s.Bodies {
+	for i := uint32(0); i < count; i++ {
 		logger.Printf("Reading function %d\n", i)
-		if err = s.Bodies[i].UnmarshalWASM(r); err != nil {
+		var body FunctionBody
+		if err = body.read(r, cfg); err != nil {
 			return err
 		}
+		s.Bodies = append(s.Bodies, body)
 	}
 	return nil
 }
@@ -823,41 +1010,89 @@ type FunctionBody struct {
 	Module *Module // The parent module containing this function body, for execution purposes
 	Locals []LocalEntry
 	Code   []byte
+
+	raw []byte // the encoded body of a body read lazily, until it is decoded
 }
 
 func (f *FunctionBody) UnmarshalWASM(r io.Reader) error {
+	return f.read(r, &DecodeConfig{})
+}
 
+// read reads a function body, which is only decoded on first use if
+// cfg.LazyBodies is set.
+func (f *FunctionBody) read(r io.Reader, cfg *DecodeConfig) error {
 	bodySize, err := leb128.ReadVarUint32(r)
 	if err != nil {
 		return err
 	}
+	if err := checkLimit("MaxBodySize", uint64(bodySize), cfg.MaxBodySize); err != nil {
+		return err
+	}
 
-	body := make([]byte, bodySize)
+	body, err := readBytes(r, int(bodySize))
+	if err != nil {
+		return err
+	}
+	if cfg.LazyBodies {
+		f.raw = body
+		return nil
+	}
+	return f.decode(body, cfg)
+}
 
-	if _, err = io.ReadFull(r, body); err != nil {
+// Decode decodes the locals and the code of a body read lazily, see
+// DecodeConfig.LazyBodies, enforcing the limits the module was read with.
+// It does nothing if the body is already decoded. The functions of this
+// module and of the packages using them decode the bodies they use.
+func (f *FunctionBody) Decode() error {
+	if f.raw == nil {
+		return nil
+	}
+	cfg := &DecodeConfig{}
+	if f.Module != nil {
+		cfg = &f.Module.decodeConfig
+	}
+	if err := f.decode(f.raw, cfg); err != nil {
 		return err
 	}
+	f.raw = nil
+	return nil
+}
 
+// Decoded reports whether the locals and the code of the body are decoded,
+// which they are unless it was read lazily and Decode wasn't called.
+func (f *FunctionBody) Decoded() bool {
+	return f.raw == nil
+}
+
+func (f *FunctionBody) decode(body []byte, cfg *DecodeConfig) error {
 	bytesReader := bytes.NewBuffer(body)
 
 	localCount, err := leb128.ReadVarUint32(bytesReader)
 	if err != nil {
 		return err
 	}
-	f.Locals = make([]LocalEntry, localCount)
+	f.Locals = make([]LocalEntry, 0, capacity(localCount))
 
-	for i := range f.Locals {
-		if err = f.Locals[i].UnmarshalWASM(bytesReader); err != nil {
+	var locals uint64
+	for i := uint32(0); i < localCount; i++ {
+		var entry LocalEntry
+		if err = entry.UnmarshalWASM(bytesReader); err != nil {
+			return err
+		}
+		locals += uint64(entry.Count)
+		if err := checkLimit("MaxLocals", locals, cfg.MaxLocals); err != nil {
 			return err
 		}
+		f.Locals = append(f.Locals, entry)
 	}
 
-	logger.Printf("bodySize: %d, localCount: %d\n", bodySize, localCount)
+	logger.Printf("bodySize: %d, localCount: %d\n", len(body), localCount)
 
 	code := bytesReader.Bytes()
 	logger.Printf("Read %d bytes for function body", len(code))
 
-	if code[len(code)-1] != end {
+	if len(code) == 0 || code[len(code)-1] != end {
 		return ErrFunctionNoEnd
 	}
 
@@ -867,6 +1102,9 @@ func (f *FunctionBody) UnmarshalWASM(r io.Reader) error {
 }
 
 func (f *FunctionBody) MarshalWASM(w io.Writer) error {
+	if f.raw != nil {
+		return writeBytesUint(w, f.raw)
+	}
 	body := new(bytes.Buffer)
 	if _, err := leb128.WriteVarUint32(body, uint32(len(f.Locals))); err != nil {
 		return err
@@ -925,15 +1163,21 @@ func (*SectionData) SectionID() SectionID {
 }
 
 func (s *SectionData) ReadPayload(r io.Reader) error {
+	return s.readPayload(r, &DecodeConfig{})
+}
+
+func (s *SectionData) readPayload(r io.Reader, cfg *DecodeConfig) error {
 	count, err := leb128.ReadVarUint32(r)
 	if err != nil {
 		return err
 	}
-	s.Entries = make([]DataSegment, count)
-	for i := range s.Entries {
-		if err = s.Entries[i].UnmarshalWASM(r); err != nil {
+	s.Entries = make([]DataSegment, 0, capacity(count))
+	for i := uint32(0); i < count; i++ {
+		var entry DataSegment
+		if err = entry.read(r, cfg); err != nil {
 			return err
 		}
+		s.Entries = append(s.Entries, entry)
 	}
 	return nil
 }
@@ -952,34 +1196,98 @@ func (s *SectionData) WritePayload(w io.Writer) error {
 
 // DataSegment describes a group of repeated elements that begin at a specified offset in the linear memory
 type DataSegment struct {
-	Index  uint32 // The index into the global linear memory space, should always be 0 in the MVP.
-	Offset []byte // initializer expression for computing the offset for placing elements, should return an i32 value
-	Data   []byte
+	Index   uint32 // The index into the global linear memory space, should always be 0 in the MVP.
+	Offset  []byte // initializer expression for computing the offset for placing elements, should return an i32 value
+	Data    []byte
+	Passive bool // passive segments have no offset and are only copied to the linear memory by memory.init
 }
 
 func (s *DataSegment) UnmarshalWASM(r io.Reader) error {
-	var err error
+	return s.read(r, &DecodeConfig{})
+}
 
-	if s.Index, err = leb128.ReadVarUint32(r); err != nil {
+func (s *DataSegment) read(r io.Reader, cfg *DecodeConfig) error {
+	flags, err := leb128.ReadVarUint32(r)
+	if err != nil {
 		return err
 	}
-	if s.Offset, err = readInitExpr(r); err != nil {
+	switch flags {
+	case segmentActive:
+		s.Index = 0
+	case segmentPassive:
+		s.Passive = true
+	case segmentActiveIndex:
+		if s.Index, err = leb128.ReadVarUint32(r); err != nil {
+			return err
+		}
+	default:
+		return InvalidSegmentFlagsError(flags)
+	}
+	if !s.Passive {
+		if s.Offset, err = readInitExpr(r); err != nil {
+			return err
+		}
+	}
+	n, err := leb128.ReadVarUint32(r)
+	if err != nil {
+		return err
+	}
+	if err := checkLimit("MaxDataSegmentSize", uint64(n), cfg.MaxDataSegmentSize); err != nil {
 		return err
 	}
-	s.Data, err = readBytesUint(r)
+	s.Data, err = readBytes(r, int(n))
 	return err
 }
 
 func (s *DataSegment) MarshalWASM(w io.Writer) error {
-	if _, err := leb128.WriteVarUint32(w, s.Index); err != nil {
-		return err
-	}
-	if _, err := w.Write(s.Offset); err != nil {
-		return err
+	switch {
+	case s.Passive:
+		if _, err := leb128.WriteVarUint32(w, segmentPassive); err != nil {
+			return err
+		}
+	case s.Index != 0:
+		if _, err := leb128.WriteVarUint32(w, segmentActiveIndex); err != nil {
+			return err
+		}
+		if _, err := leb128.WriteVarUint32(w, s.Index); err != nil {
+			return err
+		}
+		if _, err := w.Write(s.Offset); err != nil {
+			return err
+		}
+	default:
+		if _, err := leb128.WriteVarUint32(w, segmentActive); err != nil {
+			return err
+		}
+		if _, err := w.Write(s.Offset); err != nil {
+			return err
+		}
 	}
 	return writeBytesUint(w, s.Data)
 }
 
+// SectionDataCount declares the number of data segments, so that memory.init
+// and data.drop can be validated before the data section is read.
+type SectionDataCount struct {
+	RawSection
+	Count uint32
+}
+
+func (*SectionDataCount) SectionID() SectionID {
+	return SectionIDDataCount
+}
+
+func (s *SectionDataCount) ReadPayload(r io.Reader) error {
+	var err error
+	s.Count, err = leb128.ReadVarUint32(r)
+	return err
+}
+
+func (s *SectionDataCount) WritePayload(w io.Writer) error {
+	_, err := leb128.WriteVarUint32(w, s.Count)
+	return err
+}
+
 // A list of well-known custom sections
 const (
 	CustomSectionName = "name"
@@ -5,6 +5,7 @@
 package wasm
 
 import (
+	"errors"
 	"fmt"
 	"io"
 
@@ -29,6 +30,14 @@ const (
 	ValueTypeI64 ValueType = -0x02
 	ValueTypeF32 ValueType = -0x03
 	ValueTypeF64 ValueType = -0x04
+
+	// ValueTypeV128 is the 128-bit vector type of the SIMD operators.
+	ValueTypeV128 ValueType = -0x05
+
+	// Reference types, the values of which are function indices or opaque
+	// host references, NullElement for the null reference.
+	ValueTypeFuncref   ValueType = -0x10
+	ValueTypeExternref ValueType = -0x11
 )
 
 var valueTypeStrMap = map[ValueType]string{
@@ -36,6 +45,11 @@ var valueTypeStrMap = map[ValueType]string{
 	ValueTypeI64: "i64",
 	ValueTypeF32: "f32",
 	ValueTypeF64: "f64",
+
+	ValueTypeV128: "v128",
+
+	ValueTypeFuncref:   "funcref",
+	ValueTypeExternref: "externref",
 }
 
 func (t ValueType) String() string {
@@ -46,6 +60,11 @@ func (t ValueType) String() string {
 	return str
 }
 
+// IsReference reports whether t is a reference type.
+func (t ValueType) IsReference() bool {
+	return t == ValueTypeFuncref || t == ValueTypeExternref
+}
+
 // TypeFunc represents the value type of a function
 const TypeFunc int = -0x20
 
@@ -63,21 +82,88 @@ func (t ValueType) MarshalWASM(w io.Writer) error {
 	return err
 }
 
-// BlockType represents the signature of a structured block
-type BlockType ValueType // varint7
+// BlockType represents the signature of a structured block: empty, a single
+// value type, or an index into the type section.
+type BlockType int32 // varint33
 const BlockTypeEmpty BlockType = -0x40
 
+// IsTypeIndex reports whether the signature of the block is a function type
+// of the type section.
+func (b BlockType) IsTypeIndex() bool {
+	return b >= 0
+}
+
 func (b BlockType) String() string {
 	if b == BlockTypeEmpty {
 		return "<empty block>"
 	}
+	if b.IsTypeIndex() {
+		return fmt.Sprintf("<type %d>", int32(b))
+	}
 	return ValueType(b).String()
 }
 
+// ErrMultipleResults is returned for a function type with several results
+// if the multi-value feature isn't enabled.
+var ErrMultipleResults = errors.New("wasm: multiple results require the multi-value feature")
+
+// ErrV128 is returned for a module using the v128 type, in its function
+// types, globals or locals, if the SIMD feature isn't enabled.
+var ErrV128 = errors.New("wasm: the v128 type requires the SIMD feature")
+
+// HasV128 reports whether any of the types is v128.
+func HasV128(types []ValueType) bool {
+	for _, t := range types {
+		if t == ValueTypeV128 {
+			return true
+		}
+	}
+	return false
+}
+
+type InvalidBlockTypeError BlockType
+
+func (e InvalidBlockTypeError) Error() string {
+	return fmt.Sprintf("wasm: invalid block type: %d", int32(e))
+}
+
+// BlockSignature returns the parameters and the results of a block whose
+// signature is b. Type indices are only valid if the multi-value feature is
+// enabled.
+func (m *Module) BlockSignature(b BlockType) (*FunctionSig, error) {
+	switch {
+	case b == BlockTypeEmpty:
+		return &FunctionSig{Form: int8(TypeFunc)}, nil
+	case b.IsTypeIndex():
+		if !m.Features.Has(FeatureMultiValue) || m.Types == nil || int(b) >= len(m.Types.Entries) {
+			return nil, InvalidBlockTypeError(b)
+		}
+		return &m.Types.Entries[b], nil
+	}
+	switch ValueType(b) {
+	case ValueTypeI32, ValueTypeI64, ValueTypeF32, ValueTypeF64:
+		return &FunctionSig{Form: int8(TypeFunc), ReturnTypes: []ValueType{ValueType(b)}}, nil
+	case ValueTypeFuncref, ValueTypeExternref:
+		if m.Features.Has(FeatureReferenceTypes) {
+			return &FunctionSig{Form: int8(TypeFunc), ReturnTypes: []ValueType{ValueType(b)}}, nil
+		}
+	case ValueTypeV128:
+		if m.Features.Has(FeatureSIMD) {
+			return &FunctionSig{Form: int8(TypeFunc), ReturnTypes: []ValueType{ValueType(b)}}, nil
+		}
+	}
+	return nil, InvalidBlockTypeError(b)
+}
+
 // ElemType describes the type of a table's elements
 type ElemType int // varint7
-// ElemTypeAnyFunc descibres an any_func value
-const ElemTypeAnyFunc ElemType = -0x10
+const (
+	// ElemTypeAnyFunc descibres an any_func value
+	ElemTypeAnyFunc ElemType = -0x10
+	// ElemTypeExternRef describes an opaque host reference, tables of this
+	// type require the reference types feature.
+	ElemTypeExternRef ElemType = -0x11
+)
 
 func (t *ElemType) UnmarshalWASM(r io.Reader) error {
 	b, err := leb128.ReadVarint32(r)
@@ -94,8 +180,11 @@ func (t ElemType) MarshalWASM(w io.Writer) error {
 }
 
 func (t ElemType) String() string {
-	if t == ElemTypeAnyFunc {
+	switch t {
+	case ElemTypeAnyFunc:
 		return "anyfunc"
+	case ElemTypeExternRef:
+		return "externref"
 	}
 
 	return "<unknown elem_type>"
@@ -134,13 +223,15 @@ func (f *FunctionSig) UnmarshalWASM(r io.Reader) error {
 	if err != nil {
 		return err
 	}
-	f.ParamTypes = make([]ValueType, paramCount)
+	f.ParamTypes = make([]ValueType, 0, capacity(paramCount))
 
-	for i := range f.ParamTypes {
-		err = f.ParamTypes[i].UnmarshalWASM(r)
+	for i := uint32(0); i < paramCount; i++ {
+		var t ValueType
+		err = t.UnmarshalWASM(r)
 		if err != nil {
 			return err
 		}
+		f.ParamTypes = append(f.ParamTypes, t)
 	}
 
 	returnCount, err := leb128.ReadVarUint32(r)
@@ -148,12 +239,14 @@ func (f *FunctionSig) UnmarshalWASM(r io.Reader) error {
 		return err
 	}
 
-	f.ReturnTypes = make([]ValueType, returnCount)
-	for i := range f.ReturnTypes {
-		err = f.ReturnTypes[i].UnmarshalWASM(r)
+	f.ReturnTypes = make([]ValueType, 0, capacity(returnCount))
+	for i := uint32(0); i < returnCount; i++ {
+		var t ValueType
+		err = t.UnmarshalWASM(r)
 		if err != nil {
 			return err
 		}
+		f.ReturnTypes = append(f.ReturnTypes, t)
 	}
 
 	return nil
@@ -0,0 +1,472 @@
+// Copyright 2018 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wast
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+
+	"github.com/tinychain/tiny-wasm/wagon/disasm"
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+	ops "github.com/tinychain/tiny-wasm/wagon/wasm/operators"
+)
+
+// opNames maps the names of the operators in the current text format to
+// the older ones of package operators.
+var opNames = map[string]string{
+	"local.get":           "get_local",
+	"local.set":           "set_local",
+	"local.tee":           "tee_local",
+	"global.get":          "get_global",
+	"global.set":          "set_global",
+	"current_memory":      "memory.size",
+	"grow_memory":         "memory.grow",
+	"i32.wrap_i64":        "i32.wrap/i64",
+	"i32.trunc_f32_s":     "i32.trunc_s/f32",
+	"i32.trunc_f32_u":     "i32.trunc_u/f32",
+	"i32.trunc_f64_s":     "i32.trunc_s/f64",
+	"i32.trunc_f64_u":     "i32.trunc_u/f64",
+	"i64.extend_i32_s":    "i64.extend_s/i32",
+	"i64.extend_i32_u":    "i64.extend_u/i32",
+	"i64.trunc_f32_s":     "i64.trunc_s/f32",
+	"i64.trunc_f32_u":     "i64.trunc_u/f32",
+	"i64.trunc_f64_s":     "i64.trunc_s/f64",
+	"i64.trunc_f64_u":     "i64.trunc_u/f64",
+	"f32.convert_i32_s":   "f32.convert_s/i32",
+	"f32.convert_i32_u":   "f32.convert_u/i32",
+	"f32.convert_i64_s":   "f32.convert_s/i64",
+	"f32.convert_i64_u":   "f32.convert_u/i64",
+	"f32.demote_f64":      "f32.demote/f64",
+	"f64.convert_i32_s":   "f64.convert_s/i32",
+	"f64.convert_i32_u":   "f64.convert_u/i32",
+	"f64.convert_i64_s":   "f64.convert_s/i64",
+	"f64.convert_i64_u":   "f64.convert_u/i64",
+	"f64.promote_f32":     "f64.promote/f32",
+	"i32.reinterpret_f32": "i32.reinterpret/f32",
+	"i64.reinterpret_f64": "i64.reinterpret/f64",
+	"f32.reinterpret_i32": "f32.reinterpret/i32",
+	"f64.reinterpret_i64": "f64.reinterpret/i64",
+}
+
+// code parses the instructions of a function body or of a constant
+// expression.
+type code struct {
+	p      *moduleParser
+	locals *space
+	labels []string // the labels of the enclosing blocks, "" if unnamed
+	instrs []disasm.Instr
+}
+
+func (b *code) emit(op ops.Op, imms ...interface{}) {
+	b.instrs = append(b.instrs, disasm.Instr{Op: op, Immediates: imms})
+}
+
+func (b *code) emitCode(code byte) {
+	op, _ := ops.New(code)
+	b.emit(op)
+}
+
+// assemble returns the encoding of the parsed instructions.
+func (b *code) assemble() []byte {
+	p, err := disasm.Assemble(b.instrs)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// all parses the instructions up to the end of c.
+func (b *code) all(c *cursor) {
+	if e := b.seq(c); e != nil {
+		errorf(e, "unexpected %s", e.atom)
+	}
+}
+
+// seq parses the instructions up to the end of c or up to the keywords end
+// or else, which it returns.
+func (b *code) seq(c *cursor) *sexpr {
+	for !c.done() {
+		e := c.next()
+		switch {
+		case e.isList:
+			b.folded(e)
+		case e.str:
+			errorf(e, "unexpected %v", e)
+		case e.atom == "end" || e.atom == "else":
+			return e
+		default:
+			b.plain(e, c)
+		}
+	}
+	return nil
+}
+
+// plain parses the instruction whose keyword is e, whose immediates and
+// nested instructions are read from c.
+func (b *code) plain(e *sexpr, c *cursor) {
+	switch e.atom {
+	case "block", "loop", "if":
+		op := b.op(e)
+		label := c.id()
+		b.emit(op, b.p.blockType(c))
+		b.labels = append(b.labels, label)
+		end := b.seq(c)
+		if end != nil && end.atom == "else" && op.Code == ops.If {
+			b.endLabel(c, label)
+			b.emitCode(ops.Else)
+			end = b.seq(c)
+			b.dropEmptyElse()
+		}
+		if end == nil || end.atom != "end" {
+			errorf(e, "missing end of %s", e.atom)
+		}
+		b.endLabel(c, label)
+		b.labels = b.labels[:len(b.labels)-1]
+		b.emitCode(ops.End)
+	default:
+		op, imms := b.instr(e, c)
+		b.emit(op, imms...)
+	}
+}
+
+// dropEmptyElse removes the last instruction if it is an else, as an empty
+// else branch isn't encoded.
+func (b *code) dropEmptyElse() {
+	if n := len(b.instrs); b.instrs[n-1].Op.Code == ops.Else && b.instrs[n-1].Op.Prefix == 0 {
+		b.instrs = b.instrs[:n-1]
+	}
+}
+
+// endLabel checks the optional label following the end or the else of the
+// block labeled label.
+func (b *code) endLabel(c *cursor, label string) {
+	if e := c.peek(); e != nil && !e.isList && !e.str && strings.HasPrefix(e.atom, "$") {
+		if e.atom != label {
+			errorf(e, "mismatching label %s", e.atom)
+		}
+		c.next()
+	}
+}
+
+// folded parses the folded instruction e.
+func (b *code) folded(e *sexpr) {
+	c := e.cursor(0)
+	kw := c.atom()
+	switch kw.atom {
+	case "block", "loop":
+		op := b.op(kw)
+		label := c.id()
+		b.emit(op, b.p.blockType(c))
+		b.labels = append(b.labels, label)
+		b.all(c)
+	case "if":
+		op := b.op(kw)
+		label := c.id()
+		bt := b.p.blockType(c)
+		for !c.done() && !c.peekList("then") {
+			b.foldedOperand(c)
+		}
+		b.emit(op, bt)
+		b.labels = append(b.labels, label)
+		if !c.peekList("then") {
+			errorf(e, "missing then")
+		}
+		b.all(c.next().cursor(1))
+		if c.peekList("else") {
+			b.emitCode(ops.Else)
+			b.all(c.next().cursor(1))
+			b.dropEmptyElse()
+		}
+		c.end()
+	default:
+		op, imms := b.instr(kw, c)
+		for !c.done() {
+			b.foldedOperand(c)
+		}
+		b.emit(op, imms...)
+		return
+	}
+	b.labels = b.labels[:len(b.labels)-1]
+	b.emitCode(ops.End)
+}
+
+// foldedOperand parses the next folded instruction of c.
+func (b *code) foldedOperand(c *cursor) {
+	e := c.next()
+	if !e.isList {
+		errorf(e, "unexpected %v", e)
+	}
+	b.folded(e)
+}
+
+// op returns the operator named by the keyword e.
+func (b *code) op(e *sexpr) ops.Op {
+	name := e.atom
+	if n, ok := opNames[name]; ok {
+		name = n
+	}
+	op, ok := ops.Lookup(name)
+	if !ok {
+		errorf(e, "unknown operator %s", e.atom)
+	}
+	return op
+}
+
+// instr returns the operator of the non-structured instruction whose
+// keyword is e and its immediates, read from c.
+func (b *code) instr(e *sexpr, c *cursor) (ops.Op, []interface{}) {
+	op := b.op(e)
+	p := b.p
+	switch op.FirstByte() {
+	case ops.Block, ops.Loop, ops.If, ops.Else, ops.End:
+		errorf(e, "unexpected %s", e.atom)
+	case ops.Br, ops.BrIf:
+		return op, []interface{}{b.label(c.next())}
+	case ops.BrTable:
+		imms := []interface{}{uint32(0)}
+		for c.peekIndex() {
+			imms = append(imms, b.label(c.next()))
+		}
+		if len(imms) == 1 {
+			errorf(e, "missing label of br_table")
+		}
+		imms[0] = uint32(len(imms) - 2)
+		return op, imms
+	case ops.Call, ops.RefFunc:
+		return op, []interface{}{p.funcs.resolve(c.next())}
+	case ops.CallIndirect:
+		table := uint32(0)
+		if c.peekIndex() {
+			x := c.next()
+			if !c.peekList("type") && !c.peekList("param") && !c.peekList("result") {
+				// the type index of the MVP text format
+				return op, []interface{}{p.types.resolve(x), table}
+			}
+			table = p.tables.resolve(x)
+		}
+		typ, _ := p.typeUse(c)
+		return op, []interface{}{typ, table}
+	case ops.Select:
+		if !c.peekList("result") {
+			return op, nil
+		}
+		op, _ = ops.New(ops.SelectT)
+		types := p.valueTypes(c, "result")
+		imms := []interface{}{uint32(len(types))}
+		for _, t := range types {
+			imms = append(imms, t)
+		}
+		return op, imms
+	case ops.GetLocal, ops.SetLocal, ops.TeeLocal:
+		return op, []interface{}{b.locals.resolve(c.next())}
+	case ops.GetGlobal, ops.SetGlobal:
+		return op, []interface{}{p.globals.resolve(c.next())}
+	case ops.TableGet, ops.TableSet:
+		return op, []interface{}{b.tableUse(c)}
+	case ops.I32Load, ops.I64Load, ops.F32Load, ops.F64Load,
+		ops.I32Load8s, ops.I32Load8u, ops.I32Load16s, ops.I32Load16u,
+		ops.I64Load8s, ops.I64Load8u, ops.I64Load16s, ops.I64Load16u, ops.I64Load32s, ops.I64Load32u,
+		ops.I32Store, ops.I64Store, ops.F32Store, ops.F64Store,
+		ops.I32Store8, ops.I32Store16, ops.I64Store8, ops.I64Store16, ops.I64Store32:
+		align, offset := b.memarg(c, memoryAlign(op.Code))
+		return op, []interface{}{align, offset}
+	case ops.CurrentMemory, ops.GrowMemory:
+		return op, []interface{}{uint8(b.memoryUse(c))}
+	case ops.I32Const:
+		return op, []interface{}{int32(b.int(c.atom(), 32))}
+	case ops.I64Const:
+		return op, []interface{}{int64(b.int(c.atom(), 64))}
+	case ops.F32Const:
+		return op, []interface{}{math.Float32frombits(uint32(b.float(c.atom(), 32)))}
+	case ops.F64Const:
+		return op, []interface{}{math.Float64frombits(b.float(c.atom(), 64))}
+	case ops.RefNull:
+		return op, []interface{}{heapType(c.atom())}
+	case ops.MiscPrefix:
+		return op, b.miscImmediates(op, c)
+	case ops.SIMDPrefix:
+		return op, b.simdImmediates(op, c)
+	}
+	return op, nil
+}
+
+func (b *code) miscImmediates(op ops.Op, c *cursor) []interface{} {
+	p := b.p
+	switch op.Code {
+	case ops.MemoryInit:
+		p.dataCount = true
+		return []interface{}{p.datas.resolve(c.next()), uint8(0)}
+	case ops.DataDrop:
+		p.dataCount = true
+		return []interface{}{p.datas.resolve(c.next())}
+	case ops.MemoryCopy:
+		return []interface{}{uint8(0), uint8(0)}
+	case ops.MemoryFill:
+		return []interface{}{uint8(0)}
+	case ops.TableInit:
+		x := c.next()
+		if !c.peekIndex() {
+			return []interface{}{p.elems.resolve(x), uint32(0)}
+		}
+		return []interface{}{p.elems.resolve(c.next()), p.tables.resolve(x)}
+	case ops.ElemDrop:
+		return []interface{}{p.elems.resolve(c.next())}
+	case ops.TableCopy:
+		if !c.peekIndex() {
+			return []interface{}{uint32(0), uint32(0)}
+		}
+		dst := p.tables.resolve(c.next())
+		return []interface{}{dst, p.tables.resolve(c.next())}
+	case ops.TableGrow, ops.TableSize, ops.TableFill:
+		return []interface{}{b.tableUse(c)}
+	}
+	return nil
+}
+
+func (b *code) simdImmediates(op ops.Op, c *cursor) []interface{} {
+	switch {
+	case op.Code == ops.V128Const:
+		return []interface{}{b.v128(c)}
+	case op.Code == ops.I8x16Shuffle:
+		var lanes [16]byte
+		for i := range lanes {
+			lanes[i] = b.lane(c.atom())
+		}
+		return []interface{}{lanes}
+	case strings.HasPrefix(op.Name, "v128.load") || strings.HasPrefix(op.Name, "v128.store"):
+		align, offset := b.memarg(c, simdAlign(op.Code))
+		if strings.HasSuffix(op.Name, "_lane") {
+			return []interface{}{align, offset, b.lane(c.atom())}
+		}
+		return []interface{}{align, offset}
+	case strings.Contains(op.Name, "_lane"):
+		return []interface{}{b.lane(c.atom())}
+	}
+	return nil
+}
+
+// shapes are the numbers and the sizes of the lanes of the shapes of
+// v128.const.
+var shapes = map[string]struct{ n, size uint }{
+	"i8x16": {16, 8},
+	"i16x8": {8, 16},
+	"i32x4": {4, 32},
+	"i64x2": {2, 64},
+	"f32x4": {4, 32},
+	"f64x2": {2, 64},
+}
+
+// v128 parses the shape and the lanes of a v128.const.
+func (b *code) v128(c *cursor) [16]byte {
+	var v [16]byte
+	shape := c.atom()
+	s, ok := shapes[shape.atom]
+	if !ok {
+		errorf(shape, "unknown shape %s", shape.atom)
+	}
+	for i := uint(0); i < s.n; i++ {
+		var bits uint64
+		if shape.atom[0] == 'f' {
+			bits = b.float(c.atom(), s.size)
+		} else {
+			bits = b.int(c.atom(), s.size)
+		}
+		var lane [8]byte
+		binary.LittleEndian.PutUint64(lane[:], bits)
+		copy(v[i*s.size/8:], lane[:s.size/8])
+	}
+	return v
+}
+
+func (b *code) lane(e *sexpr) uint8 {
+	n, ok := parseNat(e.atom, 8)
+	if !ok {
+		errorf(e, "invalid lane index %s", e.atom)
+	}
+	return uint8(n)
+}
+
+func (b *code) int(e *sexpr, size uint) uint64 {
+	n, ok := parseInt(e.atom, size)
+	if !ok {
+		errorf(e, "invalid i%d %s", size, e.atom)
+	}
+	return n
+}
+
+func (b *code) float(e *sexpr, size uint) uint64 {
+	n, ok := parseFloat(e.atom, size)
+	if !ok {
+		errorf(e, "invalid f%d %s", size, e.atom)
+	}
+	return n
+}
+
+// memarg parses the optional offset and alignment of a memory operator
+// and returns the log2 of the alignment, natural by default.
+func (b *code) memarg(c *cursor, natural uint32) (uint32, uint32) {
+	var offset uint64
+	align := natural
+	if e := c.peek(); e != nil && !e.isList && strings.HasPrefix(e.atom, "offset=") {
+		c.next()
+		var ok bool
+		if offset, ok = parseNat(e.atom[len("offset="):], 32); !ok {
+			errorf(e, "invalid offset %s", e.atom)
+		}
+	}
+	if e := c.peek(); e != nil && !e.isList && strings.HasPrefix(e.atom, "align=") {
+		c.next()
+		n, ok := parseNat(e.atom[len("align="):], 32)
+		if !ok || n == 0 || n&(n-1) != 0 {
+			errorf(e, "invalid alignment %s", e.atom)
+		}
+		for align = 0; n > 1; n >>= 1 {
+			align++
+		}
+	}
+	return align, uint32(offset)
+}
+
+// tableUse parses the optional table index of a table operator.
+func (b *code) tableUse(c *cursor) uint32 {
+	if c.peekIndex() {
+		return b.p.tables.resolve(c.next())
+	}
+	return 0
+}
+
+// memoryUse parses the optional memory index of a memory operator.
+func (b *code) memoryUse(c *cursor) uint32 {
+	if c.peekIndex() {
+		return b.p.mems.resolve(c.next())
+	}
+	return 0
+}
+
+// label returns the depth of the label e, an identifier or a depth.
+func (b *code) label(e *sexpr) uint32 {
+	if e.isIndex() && e.atom[0] == '$' {
+		for i := len(b.labels) - 1; i >= 0; i-- {
+			if b.labels[i] == e.atom {
+				return uint32(len(b.labels) - 1 - i)
+			}
+		}
+		errorf(e, "unknown label %s", e.atom)
+	}
+	return index(e)
+}
+
+// heapType returns the type of the null reference of ref.null.
+func heapType(e *sexpr) wasm.ValueType {
+	switch e.atom {
+	case "func":
+		return wasm.ValueTypeFuncref
+	case "extern":
+		return wasm.ValueTypeExternref
+	}
+	errorf(e, "unknown heap type %s", e.atom)
+	return 0
+}
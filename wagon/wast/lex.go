@@ -0,0 +1,390 @@
+// Copyright 2018 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wast
+
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// SyntaxError is returned for a malformed text, at the given line and
+// column.
+type SyntaxError struct {
+	Line, Col int
+	Msg       string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("wast: %d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+type pos struct {
+	line, col int
+}
+
+// sexpr is an S-expression of the text format: a list, a string or an
+// atom, i.e. a keyword, a number or an identifier.
+type sexpr struct {
+	list   []*sexpr
+	isList bool
+	atom   string // the text of an atom or the bytes of a string
+	str    bool
+	pos    pos
+}
+
+// is reports whether e is a list starting with the keyword kw.
+func (e *sexpr) is(kw string) bool {
+	return e.isList && len(e.list) != 0 && e.list[0].isKeyword(kw)
+}
+
+// isKeyword reports whether e is the atom kw.
+func (e *sexpr) isKeyword(kw string) bool {
+	return !e.isList && !e.str && e.atom == kw
+}
+
+// isIndex reports whether e is a numeric index or an identifier.
+func (e *sexpr) isIndex() bool {
+	if e.isList || e.str || e.atom == "" {
+		return false
+	}
+	c := e.atom[0]
+	return c == '$' || '0' <= c && c <= '9'
+}
+
+func (e *sexpr) String() string {
+	switch {
+	case e.isList && len(e.list) != 0 && !e.list[0].isList:
+		return "(" + e.list[0].String()
+	case e.isList:
+		return "("
+	case e.str:
+		return strconv.Quote(e.atom)
+	}
+	return e.atom
+}
+
+// cursor returns a cursor over the elements of the list e, starting at the
+// i-th one.
+func (e *sexpr) cursor(i int) *cursor {
+	return &cursor{list: e.list, i: i, parent: e}
+}
+
+// errorf aborts the parsing with a syntax error at the position of e. The
+// error is recovered by the exported functions.
+func errorf(e *sexpr, format string, args ...interface{}) {
+	panic(&SyntaxError{Line: e.pos.line, Col: e.pos.col, Msg: fmt.Sprintf(format, args...)})
+}
+
+// recoverSyntaxError sets *err to the syntax error the parsing was aborted
+// with.
+func recoverSyntaxError(err *error) {
+	if r := recover(); r != nil {
+		e, ok := r.(*SyntaxError)
+		if !ok {
+			panic(r)
+		}
+		*err = e
+	}
+}
+
+// cursor iterates over the elements of a list.
+type cursor struct {
+	list   []*sexpr
+	i      int
+	parent *sexpr
+}
+
+func (c *cursor) done() bool {
+	return c.i >= len(c.list)
+}
+
+// peek returns the next element, nil at the end of the list.
+func (c *cursor) peek() *sexpr {
+	if c.done() {
+		return nil
+	}
+	return c.list[c.i]
+}
+
+func (c *cursor) next() *sexpr {
+	if c.done() {
+		errorf(c.parent, "unexpected end of %v)", c.parent)
+	}
+	c.i++
+	return c.list[c.i-1]
+}
+
+// end checks that the list has no more elements.
+func (c *cursor) end() {
+	if e := c.peek(); e != nil {
+		errorf(e, "unexpected %v", e)
+	}
+}
+
+// peekList reports whether the next element is a list starting with kw.
+func (c *cursor) peekList(kw string) bool {
+	e := c.peek()
+	return e != nil && e.is(kw)
+}
+
+// peekKeyword reports whether the next element is the atom kw.
+func (c *cursor) peekKeyword(kw string) bool {
+	e := c.peek()
+	return e != nil && e.isKeyword(kw)
+}
+
+// peekIndex reports whether the next element is an index.
+func (c *cursor) peekIndex() bool {
+	e := c.peek()
+	return e != nil && e.isIndex()
+}
+
+// id returns the next element if it is an identifier, "" otherwise.
+func (c *cursor) id() string {
+	if e := c.peek(); e != nil && !e.isList && !e.str && len(e.atom) > 1 && e.atom[0] == '$' {
+		c.i++
+		return e.atom
+	}
+	return ""
+}
+
+func (c *cursor) atom() *sexpr {
+	e := c.next()
+	if e.isList || e.str {
+		errorf(e, "unexpected %v", e)
+	}
+	return e
+}
+
+func (c *cursor) string() string {
+	e := c.next()
+	if !e.str {
+		errorf(e, "expected a string, got %v", e)
+	}
+	return e.atom
+}
+
+// strings returns the concatenation of the next strings.
+func (c *cursor) strings() []byte {
+	var p []byte
+	for e := c.peek(); e != nil && e.str; e = c.peek() {
+		p = append(p, e.atom...)
+		c.i++
+	}
+	return p
+}
+
+// scanner splits a text into S-expressions.
+type scanner struct {
+	src []byte
+	off int
+	pos pos
+}
+
+// parseSexprs parses the S-expressions of src.
+func parseSexprs(src []byte) (exprs []*sexpr, err error) {
+	defer recoverSyntaxError(&err)
+	s := &scanner{src: src, pos: pos{1, 1}}
+	return s.list(nil), nil
+}
+
+func (s *scanner) errorf(p pos, format string, args ...interface{}) {
+	panic(&SyntaxError{Line: p.line, Col: p.col, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (s *scanner) advance() {
+	if s.src[s.off] == '\n' {
+		s.pos.line++
+		s.pos.col = 0
+	}
+	s.off++
+	s.pos.col++
+}
+
+func (s *scanner) peek(i int) byte {
+	if s.off+i >= len(s.src) {
+		return 0
+	}
+	return s.src[s.off+i]
+}
+
+// list returns the elements up to the closing parenthesis of the list
+// opened by open, or up to the end of the text if open is nil.
+func (s *scanner) list(open *sexpr) []*sexpr {
+	var list []*sexpr
+	for {
+		s.skipSpace()
+		p := s.pos
+		if s.off == len(s.src) {
+			if open != nil {
+				s.errorf(open.pos, "unclosed parenthesis")
+			}
+			return list
+		}
+		switch s.src[s.off] {
+		case '(':
+			s.advance()
+			e := &sexpr{isList: true, pos: p}
+			e.list = s.list(e)
+			list = append(list, e)
+		case ')':
+			if open == nil {
+				s.errorf(p, "unexpected )")
+			}
+			s.advance()
+			return list
+		case '"':
+			list = append(list, &sexpr{atom: s.string(), str: true, pos: p})
+		default:
+			start := s.off
+			for s.off < len(s.src) && isAtomChar(s.src[s.off]) {
+				s.advance()
+			}
+			if s.off == start {
+				s.errorf(p, "unexpected character %q", s.src[s.off])
+			}
+			list = append(list, &sexpr{atom: string(s.src[start:s.off]), pos: p})
+		}
+	}
+}
+
+// skipSpace skips the white space and the comments.
+func (s *scanner) skipSpace() {
+	for s.off < len(s.src) {
+		switch c := s.src[s.off]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			s.advance()
+		case c == ';' && s.peek(1) == ';':
+			for s.off < len(s.src) && s.src[s.off] != '\n' {
+				s.advance()
+			}
+		case c == '(' && s.peek(1) == ';':
+			s.blockComment()
+		default:
+			return
+		}
+	}
+}
+
+// blockComment skips a block comment, which may be nested.
+func (s *scanner) blockComment() {
+	p := s.pos
+	depth := 0
+	for s.off < len(s.src) {
+		switch {
+		case s.src[s.off] == '(' && s.peek(1) == ';':
+			depth++
+			s.advance()
+		case s.src[s.off] == ';' && s.peek(1) == ')':
+			depth--
+			s.advance()
+		}
+		s.advance()
+		if depth == 0 {
+			return
+		}
+	}
+	s.errorf(p, "unclosed comment")
+}
+
+// string returns the bytes of the string starting at the current offset.
+func (s *scanner) string() string {
+	p := s.pos
+	s.advance()
+	var buf []byte
+	for {
+		if s.off == len(s.src) || s.src[s.off] == '\n' {
+			s.errorf(p, "unclosed string")
+		}
+		esc := s.pos
+		c := s.src[s.off]
+		s.advance()
+		switch {
+		case c == '"':
+			return string(buf)
+		case c < 0x20 || c == 0x7f:
+			s.errorf(esc, "control character in string")
+		case c != '\\':
+			buf = append(buf, c)
+			continue
+		}
+		if s.off == len(s.src) {
+			s.errorf(p, "unclosed string")
+		}
+		c = s.src[s.off]
+		s.advance()
+		switch c {
+		case 'n':
+			buf = append(buf, '\n')
+		case 't':
+			buf = append(buf, '\t')
+		case 'r':
+			buf = append(buf, '\r')
+		case '"', '\'', '\\':
+			buf = append(buf, c)
+		case 'u':
+			buf = s.unicodeEscape(esc, buf)
+		default:
+			h, l := unhex(c), unhex(s.peek(0))
+			if h < 0 || l < 0 {
+				s.errorf(esc, "invalid escape in string")
+			}
+			s.advance()
+			buf = append(buf, byte(h<<4|l))
+		}
+	}
+}
+
+// unicodeEscape appends the UTF-8 encoding of the escape \u{...}, starting
+// at esc, to buf.
+func (s *scanner) unicodeEscape(esc pos, buf []byte) []byte {
+	if s.peek(0) != '{' {
+		s.errorf(esc, "invalid escape in string")
+	}
+	s.advance()
+	var r rune
+	n := 0
+	for ; s.peek(0) != '}'; n++ {
+		d := unhex(s.peek(0))
+		if d < 0 || r > utf8.MaxRune {
+			s.errorf(esc, "invalid escape in string")
+		}
+		r = r<<4 | rune(d)
+		s.advance()
+	}
+	s.advance()
+	if n == 0 || r > utf8.MaxRune || 0xd800 <= r && r < 0xe000 {
+		s.errorf(esc, "invalid escape in string")
+	}
+	var p [utf8.UTFMax]byte
+	return append(buf, p[:utf8.EncodeRune(p[:], r)]...)
+}
+
+func unhex(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c - 'a' + 10)
+	case 'A' <= c && c <= 'F':
+		return int(c - 'A' + 10)
+	}
+	return -1
+}
+
+// isAtomChar reports whether c may appear in a keyword, a number or an
+// identifier.
+func isAtomChar(c byte) bool {
+	switch {
+	case '0' <= c && c <= '9', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		return true
+	}
+	switch c {
+	case '!', '#', '$', '%', '&', '\'', '*', '+', '-', '.', '/', ':', '<', '=', '>', '?', '@', '\\', '^', '_', '`', '|', '~':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+// Copyright 2018 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wast
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// See https://webassembly.github.io/spec/core/text/values.html
+
+// splitSign returns whether s starts with a minus sign and s without its
+// sign.
+func splitSign(s string) (bool, string) {
+	switch {
+	case strings.HasPrefix(s, "-"):
+		return true, s[1:]
+	case strings.HasPrefix(s, "+"):
+		return false, s[1:]
+	}
+	return false, s
+}
+
+// removeUnderscores returns s without the underscores separating its
+// digits, and false if an underscore doesn't separate two digits.
+func removeUnderscores(s string) (string, bool) {
+	if !strings.Contains(s, "_") {
+		return s, true
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i == 0 || i == len(s)-1 || unhex(s[i-1]) < 0 || unhex(s[i+1]) < 0 {
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// parseNat parses an unsigned integer of at most size bits, without sign.
+func parseNat(s string, size uint) (uint64, bool) {
+	base := 10
+	if strings.HasPrefix(s, "0x") {
+		base = 16
+		s = s[2:]
+	}
+	s, ok := removeUnderscores(s)
+	if !ok || s == "" || s[0] == '+' || s[0] == '-' {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(s, base, int(size))
+	return n, err == nil
+}
+
+// parseInt parses a signed or unsigned integer of size bits and returns
+// its bits.
+func parseInt(s string, size uint) (uint64, bool) {
+	neg, s := splitSign(s)
+	n, ok := parseNat(s, 64)
+	if !ok {
+		return 0, false
+	}
+	mask := uint64(math.MaxUint64) >> (64 - size)
+	switch {
+	case neg && n > 1<<(size-1):
+		return 0, false
+	case neg:
+		return -n & mask, true
+	case n > mask:
+		return 0, false
+	}
+	return n, true
+}
+
+// parseFloat parses a floating-point number of size bits, 32 or 64, and
+// returns its bits. NaNs may have a payload, as in nan:0x200000.
+func parseFloat(s string, size uint) (uint64, bool) {
+	neg, s := splitSign(s)
+	mant := uint(52)
+	if size == 32 {
+		mant = 23
+	}
+	inf := uint64(math.MaxUint64) >> (64 - size) &^ (1 << (size - 1)) &^ (1<<mant - 1)
+
+	var bits uint64
+	switch {
+	case s == "inf":
+		bits = inf
+	case s == "nan":
+		bits = inf | 1<<(mant-1)
+	case strings.HasPrefix(s, "nan:0x"):
+		payload, ok := parseNat(s[4:], 64)
+		if !ok || payload == 0 || payload >= 1<<mant {
+			return 0, false
+		}
+		bits = inf | payload
+	default:
+		// the text format, unlike ParseFloat, has no inf or nan in other
+		// cases and allows hexadecimal numbers without exponent
+		if s == "" || s[0] < '0' || s[0] > '9' {
+			return 0, false
+		}
+		s, ok := removeUnderscores(s)
+		if !ok {
+			return 0, false
+		}
+		if strings.HasPrefix(s, "0x") && !strings.ContainsAny(s, "pP") {
+			s += "p0"
+		}
+		f, err := strconv.ParseFloat(s, int(size))
+		if err != nil {
+			return 0, false
+		}
+		if size == 32 {
+			bits = uint64(math.Float32bits(float32(f)))
+		} else {
+			bits = math.Float64bits(f)
+		}
+	}
+	if neg {
+		bits |= 1 << (size - 1)
+	}
+	return bits, true
+}
@@ -0,0 +1,789 @@
+// Copyright 2018 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wast
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+	ops "github.com/tinychain/tiny-wasm/wagon/wasm/operators"
+)
+
+// ParseModule parses a module in the WebAssembly text format, with or
+// without its enclosing (module ...). Like wasm.DecodeModule, it neither
+// initializes the index spaces of the module nor resolves its imports; its
+// sections can be encoded with wasm.EncodeModule.
+func ParseModule(r io.Reader) (*wasm.Module, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return parseText(src)
+}
+
+// parseText parses the module of src, with or without its enclosing
+// (module ...).
+func parseText(src []byte) (*wasm.Module, error) {
+	exprs, err := parseSexprs(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(exprs) != 1 || !exprs[0].is("module") {
+		exprs = append([]*sexpr{{atom: "module", pos: pos{1, 1}}}, exprs...)
+		return parseModule(&sexpr{list: exprs, isList: true, pos: pos{1, 1}})
+	}
+	return parseModule(exprs[0])
+}
+
+// space is an index space of a module.
+type space struct {
+	kind    string
+	names   map[string]uint32
+	n       uint32
+	defined bool // whether definitions, which follow the imports, were seen
+}
+
+func newSpace(kind string) *space {
+	return &space{kind: kind, names: make(map[string]uint32)}
+}
+
+// add adds an entry with the identifier id, if not "", to the space and
+// returns its index.
+func (s *space) add(e *sexpr, id string) uint32 {
+	if id != "" {
+		if _, ok := s.names[id]; ok {
+			errorf(e, "duplicate %s %s", s.kind, id)
+		}
+		s.names[id] = s.n
+	}
+	s.n++
+	return s.n - 1
+}
+
+// resolve returns the index e, an identifier or a number.
+func (s *space) resolve(e *sexpr) uint32 {
+	if !e.isIndex() {
+		errorf(e, "expected a %s index, got %v", s.kind, e)
+	}
+	if e.atom[0] != '$' {
+		return index(e)
+	}
+	i, ok := s.names[e.atom]
+	if !ok {
+		errorf(e, "unknown %s %s", s.kind, e.atom)
+	}
+	return i
+}
+
+// index returns the numeric index e.
+func index(e *sexpr) uint32 {
+	n, ok := parseNat(e.atom, 32)
+	if !ok {
+		errorf(e, "invalid index %v", e)
+	}
+	return uint32(n)
+}
+
+// moduleParser parses the fields of a module in two passes: the first one
+// declares their identifiers and the second one, once all identifiers are
+// known, builds the sections of the module.
+type moduleParser struct {
+	types, funcs, tables, mems, globals, elems, datas *space
+
+	// the index of the functions, tables, memories, globals and segments
+	// declared by the fields
+	indices map[*sexpr]uint32
+
+	sigs      []wasm.FunctionSig
+	imports   []wasm.ImportEntry
+	funcTypes []uint32
+	bodies    []wasm.FunctionBody
+	tabs      []wasm.Table
+	memories  []wasm.Memory
+	globalVar []wasm.GlobalEntry
+	exports   *wasm.SectionExports
+	start     *wasm.SectionStartFunction
+	elemSegs  []wasm.ElementSegment
+	dataSegs  []wasm.DataSegment
+	dataCount bool // whether memory.init or data.drop is used
+}
+
+func parseModule(e *sexpr) (m *wasm.Module, err error) {
+	defer recoverSyntaxError(&err)
+	p := &moduleParser{
+		types:   newSpace("type"),
+		funcs:   newSpace("function"),
+		tables:  newSpace("table"),
+		mems:    newSpace("memory"),
+		globals: newSpace("global"),
+		elems:   newSpace("element segment"),
+		datas:   newSpace("data segment"),
+		indices: make(map[*sexpr]uint32),
+		exports: &wasm.SectionExports{Entries: make(map[string]wasm.ExportEntry)},
+	}
+	c := e.cursor(1)
+	c.id()
+	fields := c.list[c.i:]
+	for _, f := range fields {
+		p.declare(f)
+	}
+	for _, f := range fields {
+		p.define(f)
+	}
+	return p.module(), nil
+}
+
+// module returns the module holding the parsed sections.
+func (p *moduleParser) module() *wasm.Module {
+	m := &wasm.Module{Version: wasm.Version}
+	add := func(s wasm.Section) {
+		m.Sections = append(m.Sections, s)
+	}
+	if len(p.sigs) != 0 {
+		m.Types = &wasm.SectionTypes{Entries: p.sigs}
+		add(m.Types)
+	}
+	if len(p.imports) != 0 {
+		m.Import = &wasm.SectionImports{Entries: p.imports}
+		add(m.Import)
+	}
+	if len(p.funcTypes) != 0 {
+		m.Function = &wasm.SectionFunctions{Types: p.funcTypes}
+		add(m.Function)
+	}
+	if len(p.tabs) != 0 {
+		m.Table = &wasm.SectionTables{Entries: p.tabs}
+		add(m.Table)
+	}
+	if len(p.memories) != 0 {
+		m.Memory = &wasm.SectionMemories{Entries: p.memories}
+		add(m.Memory)
+	}
+	if len(p.globalVar) != 0 {
+		m.Global = &wasm.SectionGlobals{Globals: p.globalVar}
+		add(m.Global)
+	}
+	if len(p.exports.Names) != 0 {
+		m.Export = p.exports
+		add(m.Export)
+	}
+	if p.start != nil {
+		m.Start = p.start
+		add(m.Start)
+	}
+	if len(p.elemSegs) != 0 {
+		m.Elements = &wasm.SectionElements{Entries: p.elemSegs}
+		add(m.Elements)
+	}
+	if p.dataCount {
+		m.DataCount = &wasm.SectionDataCount{Count: uint32(len(p.dataSegs))}
+		add(m.DataCount)
+	}
+	if len(p.bodies) != 0 {
+		m.Code = &wasm.SectionCode{Bodies: p.bodies}
+		add(m.Code)
+	}
+	if len(p.dataSegs) != 0 {
+		m.Data = &wasm.SectionData{Entries: p.dataSegs}
+		add(m.Data)
+	}
+	return m
+}
+
+// declare adds the identifiers of the field e to the index spaces.
+func (p *moduleParser) declare(e *sexpr) {
+	if !e.isList || len(e.list) == 0 {
+		errorf(e, "expected a module field, got %v", e)
+	}
+	c := e.cursor(1)
+	switch kw := e.list[0]; {
+	case kw.isKeyword("type"):
+		p.typeField(e)
+	case kw.isKeyword("import"):
+		c.string()
+		c.string()
+		desc := c.next()
+		if !desc.isList || len(desc.list) == 0 {
+			errorf(desc, "expected an import description, got %v", desc)
+		}
+		s := p.space(desc.list[0])
+		if s.defined {
+			errorf(e, "import after the definition of a %s", s.kind)
+		}
+		p.indices[e] = s.add(desc, desc.cursor(1).id())
+	case kw.isKeyword("func"), kw.isKeyword("table"), kw.isKeyword("memory"), kw.isKeyword("global"):
+		s := p.space(kw)
+		id := c.id()
+		for c.peekList("export") {
+			c.next()
+		}
+		if c.peekList("import") {
+			if s.defined {
+				errorf(e, "import after the definition of a %s", s.kind)
+			}
+		} else {
+			s.defined = true
+		}
+		p.indices[e] = s.add(e, id)
+		// the inline segments of a table or a memory
+		if kw.isKeyword("table") && hasInlineSegment(c, "elem") {
+			p.elems.add(e, "")
+		} else if kw.isKeyword("memory") && hasInlineSegment(c, "data") {
+			p.datas.add(e, "")
+		}
+	case kw.isKeyword("elem"):
+		p.indices[e] = p.elems.add(e, c.id())
+	case kw.isKeyword("data"):
+		p.indices[e] = p.datas.add(e, c.id())
+	case kw.isKeyword("export"), kw.isKeyword("start"):
+	default:
+		errorf(e, "unknown module field %v", kw)
+	}
+}
+
+// hasInlineSegment reports whether the table or memory whose remaining
+// elements are c is followed by an inline segment with keyword kw.
+func hasInlineSegment(c *cursor, kw string) bool {
+	for _, e := range c.list[c.i:] {
+		if e.is(kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// space returns the index space of the entries of kind kw.
+func (p *moduleParser) space(kw *sexpr) *space {
+	switch {
+	case kw.isKeyword("func"):
+		return p.funcs
+	case kw.isKeyword("table"):
+		return p.tables
+	case kw.isKeyword("memory"):
+		return p.mems
+	case kw.isKeyword("global"):
+		return p.globals
+	}
+	errorf(kw, "unknown kind %v", kw)
+	return nil
+}
+
+// define builds the entries of the field e.
+func (p *moduleParser) define(e *sexpr) {
+	c := e.cursor(1)
+	switch kw := e.list[0]; kw.atom {
+	case "import":
+		module, name := c.string(), c.string()
+		desc := c.next()
+		c.end()
+		dc := desc.cursor(1)
+		dc.id()
+		p.importDesc(desc, dc, module, name)
+	case "func":
+		p.function(e, c)
+	case "table":
+		p.table(e, c)
+	case "memory":
+		p.memory(e, c)
+	case "global":
+		p.global(e, c)
+	case "export":
+		name := c.string()
+		desc := c.next()
+		c.end()
+		if !desc.isList || len(desc.list) != 2 {
+			errorf(desc, "expected an export description, got %v", desc)
+		}
+		p.export(desc, name, p.space(desc.list[0]).resolve(desc.list[1]))
+	case "start":
+		if p.start != nil {
+			errorf(e, "multiple start functions")
+		}
+		p.start = &wasm.SectionStartFunction{Index: p.funcs.resolve(c.next())}
+		c.end()
+	case "elem":
+		p.elem(e, c)
+	case "data":
+		p.data(e, c)
+	}
+}
+
+// export adds an export of the entry i of the kind of desc.
+func (p *moduleParser) export(desc *sexpr, name string, i uint32) {
+	if _, ok := p.exports.Entries[name]; ok {
+		errorf(desc, "duplicate export %q", name)
+	}
+	var kind wasm.External
+	switch kw := desc.list[0]; kw.atom {
+	case "func":
+		kind = wasm.ExternalFunction
+	case "table":
+		kind = wasm.ExternalTable
+	case "memory":
+		kind = wasm.ExternalMemory
+	case "global":
+		kind = wasm.ExternalGlobal
+	}
+	p.exports.Entries[name] = wasm.ExportEntry{FieldStr: name, Kind: kind, Index: i}
+	p.exports.Names = append(p.exports.Names, name)
+}
+
+// inlineExports parses the inline exports of the field e, the entry i of
+// its kind, and its inline import, returning whether it is imported.
+func (p *moduleParser) inlineExports(e *sexpr, c *cursor) (module, name string, imported bool) {
+	for c.peekList("export") {
+		ec := c.next().cursor(1)
+		p.export(e, ec.string(), p.indices[e])
+		ec.end()
+	}
+	if !c.peekList("import") {
+		return "", "", false
+	}
+	ic := c.next().cursor(1)
+	module, name = ic.string(), ic.string()
+	ic.end()
+	return module, name, true
+}
+
+// importDesc parses the description desc of an import, from c.
+func (p *moduleParser) importDesc(desc *sexpr, c *cursor, module, name string) {
+	entry := wasm.ImportEntry{ModuleName: module, FieldName: name}
+	switch desc.list[0].atom {
+	case "func":
+		typ, _ := p.typeUse(c)
+		entry.Type = wasm.FuncImport{Type: typ}
+	case "table":
+		entry.Type = wasm.TableImport{Type: p.tableType(c)}
+	case "memory":
+		entry.Type = wasm.MemoryImport{Type: wasm.Memory{Limits: p.limits(c)}}
+	case "global":
+		entry.Type = wasm.GlobalVarImport{Type: p.globalType(c)}
+	}
+	c.end()
+	p.imports = append(p.imports, entry)
+}
+
+// typeField parses a type definition.
+func (p *moduleParser) typeField(e *sexpr) {
+	c := e.cursor(1)
+	p.types.add(e, c.id())
+	f := c.next()
+	if !f.is("func") {
+		errorf(f, "expected a function type, got %v", f)
+	}
+	fc := f.cursor(1)
+	fc.id()
+	sig, _ := p.signature(fc)
+	fc.end()
+	c.end()
+	p.sigs = append(p.sigs, sig)
+}
+
+// signature parses the parameters and the results of a function type, and
+// returns it with the identifiers of the parameters.
+func (p *moduleParser) signature(c *cursor) (wasm.FunctionSig, []string) {
+	sig := wasm.FunctionSig{Form: int8(wasm.TypeFunc)}
+	var ids []string
+	for c.peekList("param") {
+		pc := c.next().cursor(1)
+		if id := pc.id(); id != "" {
+			sig.ParamTypes = append(sig.ParamTypes, p.valueType(pc.next()))
+			ids = append(ids, id)
+			pc.end()
+			continue
+		}
+		for !pc.done() {
+			sig.ParamTypes = append(sig.ParamTypes, p.valueType(pc.next()))
+			ids = append(ids, "")
+		}
+	}
+	sig.ReturnTypes = p.valueTypes(c, "result")
+	return sig, ids
+}
+
+// valueTypes parses the types of the lists starting with kw.
+func (p *moduleParser) valueTypes(c *cursor, kw string) []wasm.ValueType {
+	var types []wasm.ValueType
+	for c.peekList(kw) {
+		rc := c.next().cursor(1)
+		for !rc.done() {
+			types = append(types, p.valueType(rc.next()))
+		}
+	}
+	return types
+}
+
+func (p *moduleParser) valueType(e *sexpr) wasm.ValueType {
+	switch {
+	case e.isKeyword("i32"):
+		return wasm.ValueTypeI32
+	case e.isKeyword("i64"):
+		return wasm.ValueTypeI64
+	case e.isKeyword("f32"):
+		return wasm.ValueTypeF32
+	case e.isKeyword("f64"):
+		return wasm.ValueTypeF64
+	case e.isKeyword("v128"):
+		return wasm.ValueTypeV128
+	case e.isKeyword("funcref"), e.isKeyword("anyfunc"):
+		return wasm.ValueTypeFuncref
+	case e.isKeyword("externref"):
+		return wasm.ValueTypeExternref
+	}
+	errorf(e, "unknown value type %v", e)
+	return 0
+}
+
+// typeUse parses a reference to a function type, returning its index and
+// the identifiers of its parameters. A function type is added to the type
+// section if none matches the parameters and results.
+func (p *moduleParser) typeUse(c *cursor) (uint32, []string) {
+	if !c.peekList("type") {
+		sig, ids := p.signature(c)
+		return p.typeIndex(sig), ids
+	}
+	e := c.next()
+	tc := e.cursor(1)
+	typ := p.types.resolve(tc.next())
+	tc.end()
+	if int(typ) >= len(p.sigs) {
+		errorf(e, "unknown type %d", typ)
+	}
+	if !c.peekList("param") && !c.peekList("result") {
+		return typ, make([]string, len(p.sigs[typ].ParamTypes))
+	}
+	sig, ids := p.signature(c)
+	if !sameSignature(sig, p.sigs[typ]) {
+		errorf(e, "inconsistent type use")
+	}
+	return typ, ids
+}
+
+// typeIndex returns the index of the first function type equal to sig,
+// which is added to the types if there isn't any.
+func (p *moduleParser) typeIndex(sig wasm.FunctionSig) uint32 {
+	for i, s := range p.sigs {
+		if sameSignature(s, sig) {
+			return uint32(i)
+		}
+	}
+	p.sigs = append(p.sigs, sig)
+	return uint32(len(p.sigs) - 1)
+}
+
+func sameSignature(a, b wasm.FunctionSig) bool {
+	return sameTypes(a.ParamTypes, b.ParamTypes) && sameTypes(a.ReturnTypes, b.ReturnTypes)
+}
+
+func sameTypes(a, b []wasm.ValueType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// blockType parses the signature of a block: a single result is encoded
+// as a value type, other signatures as type indices.
+func (p *moduleParser) blockType(c *cursor) wasm.BlockType {
+	if c.peekList("type") {
+		typ, _ := p.typeUse(c)
+		return wasm.BlockType(typ)
+	}
+	sig, _ := p.signature(c)
+	switch {
+	case len(sig.ParamTypes) == 0 && len(sig.ReturnTypes) == 0:
+		return wasm.BlockTypeEmpty
+	case len(sig.ParamTypes) == 0 && len(sig.ReturnTypes) == 1:
+		return wasm.BlockType(sig.ReturnTypes[0])
+	}
+	return wasm.BlockType(p.typeIndex(sig))
+}
+
+// function parses a function definition or an inline function import.
+func (p *moduleParser) function(e *sexpr, c *cursor) {
+	c.id()
+	if module, name, ok := p.inlineExports(e, c); ok {
+		p.importDesc(e, c, module, name)
+		return
+	}
+	typ, ids := p.typeUse(c)
+	locals := newSpace("local")
+	for _, id := range ids {
+		locals.add(e, id)
+	}
+	var body wasm.FunctionBody
+	for c.peekList("local") {
+		lc := c.next().cursor(1)
+		if id := lc.id(); id != "" {
+			body.Locals = addLocal(body.Locals, p.valueType(lc.next()))
+			locals.add(e, id)
+			lc.end()
+			continue
+		}
+		for !lc.done() {
+			body.Locals = addLocal(body.Locals, p.valueType(lc.next()))
+			locals.add(e, "")
+		}
+	}
+	b := &code{p: p, locals: locals}
+	b.all(c)
+	body.Code = b.assemble()
+	p.funcTypes = append(p.funcTypes, typ)
+	p.bodies = append(p.bodies, body)
+}
+
+// addLocal adds a local of type t to the local entries.
+func addLocal(locals []wasm.LocalEntry, t wasm.ValueType) []wasm.LocalEntry {
+	if n := len(locals); n != 0 && locals[n-1].Type == t {
+		locals[n-1].Count++
+		return locals
+	}
+	return append(locals, wasm.LocalEntry{Count: 1, Type: t})
+}
+
+// constExpr parses the instructions of a constant expression and returns
+// their encoding, terminated by end.
+func (p *moduleParser) constExpr(c *cursor) []byte {
+	b := &code{p: p, locals: newSpace("local")}
+	b.all(c)
+	return append(b.assemble(), ops.End)
+}
+
+// limits parses the initial size and the optional maximum of a table or a
+// memory.
+func (p *moduleParser) limits(c *cursor) wasm.ResizableLimits {
+	var lim wasm.ResizableLimits
+	lim.Initial = p.u32(c.atom())
+	if c.peekIndex() {
+		lim.Flags = 1
+		lim.Maximum = p.u32(c.atom())
+	}
+	return lim
+}
+
+func (p *moduleParser) u32(e *sexpr) uint32 {
+	n, ok := parseNat(e.atom, 32)
+	if !ok {
+		errorf(e, "invalid u32 %s", e.atom)
+	}
+	return uint32(n)
+}
+
+func (p *moduleParser) tableType(c *cursor) wasm.Table {
+	lim := p.limits(c)
+	return wasm.Table{ElementType: p.elemType(c.atom()), Limits: lim}
+}
+
+func (p *moduleParser) elemType(e *sexpr) wasm.ElemType {
+	switch p.valueType(e) {
+	case wasm.ValueTypeFuncref:
+		return wasm.ElemTypeAnyFunc
+	case wasm.ValueTypeExternref:
+		return wasm.ElemTypeExternRef
+	}
+	errorf(e, "unknown reference type %v", e)
+	return 0
+}
+
+func (p *moduleParser) globalType(c *cursor) wasm.GlobalVar {
+	if !c.peekList("mut") {
+		return wasm.GlobalVar{Type: p.valueType(c.next())}
+	}
+	mc := c.next().cursor(1)
+	g := wasm.GlobalVar{Type: p.valueType(mc.next()), Mutable: true}
+	mc.end()
+	return g
+}
+
+// table parses a table definition, with an optional inline element
+// segment, or an inline table import.
+func (p *moduleParser) table(e *sexpr, c *cursor) {
+	c.id()
+	if module, name, ok := p.inlineExports(e, c); ok {
+		p.importDesc(e, c, module, name)
+		return
+	}
+	if c.peekIndex() {
+		p.tabs = append(p.tabs, p.tableType(c))
+		c.end()
+		return
+	}
+	seg := wasm.ElementSegment{
+		Index:  p.indices[e],
+		Offset: []byte{ops.I32Const, 0, ops.End},
+		Type:   p.elemType(c.atom()),
+	}
+	ec := c.next()
+	if !ec.is("elem") {
+		errorf(ec, "expected an element segment, got %v", ec)
+	}
+	c.end()
+	p.elemList(&seg, ec.cursor(1), true)
+	n := uint32(len(seg.Elems))
+	p.tabs = append(p.tabs, wasm.Table{
+		ElementType: seg.Type,
+		Limits:      wasm.ResizableLimits{Flags: 1, Initial: n, Maximum: n},
+	})
+	p.elemSegs = append(p.elemSegs, seg)
+}
+
+// memory parses a memory definition, with an optional inline data
+// segment, or an inline memory import.
+func (p *moduleParser) memory(e *sexpr, c *cursor) {
+	c.id()
+	if module, name, ok := p.inlineExports(e, c); ok {
+		p.importDesc(e, c, module, name)
+		return
+	}
+	if !c.peekList("data") {
+		p.memories = append(p.memories, wasm.Memory{Limits: p.limits(c)})
+		c.end()
+		return
+	}
+	dc := c.next().cursor(1)
+	c.end()
+	data := dc.strings()
+	dc.end()
+	const pageSize = 65536
+	n := uint32((len(data) + pageSize - 1) / pageSize)
+	p.memories = append(p.memories, wasm.Memory{Limits: wasm.ResizableLimits{Flags: 1, Initial: n, Maximum: n}})
+	p.dataSegs = append(p.dataSegs, wasm.DataSegment{
+		Index:  p.indices[e],
+		Offset: []byte{ops.I32Const, 0, ops.End},
+		Data:   data,
+	})
+}
+
+// global parses a global definition or an inline global import.
+func (p *moduleParser) global(e *sexpr, c *cursor) {
+	c.id()
+	if module, name, ok := p.inlineExports(e, c); ok {
+		p.importDesc(e, c, module, name)
+		return
+	}
+	typ := p.globalType(c)
+	p.globalVar = append(p.globalVar, wasm.GlobalEntry{Type: typ, Init: p.constExpr(c)})
+}
+
+// offset parses the offset of an active segment, either (offset instr*) or
+// a folded instruction.
+func (p *moduleParser) offset(c *cursor) []byte {
+	e := c.next()
+	if e.is("offset") {
+		return p.constExpr(e.cursor(1))
+	}
+	if !e.isList {
+		errorf(e, "expected an offset, got %v", e)
+	}
+	return p.constExpr(&cursor{list: []*sexpr{e}, parent: e})
+}
+
+// elem parses an element segment.
+func (p *moduleParser) elem(e *sexpr, c *cursor) {
+	c.id()
+	seg := wasm.ElementSegment{Type: wasm.ElemTypeAnyFunc}
+	active := false
+	switch {
+	case c.peekKeyword("declare"):
+		c.next()
+		seg.Declarative = true
+	case c.peekList("table"):
+		tc := c.next().cursor(1)
+		seg.Index = p.tables.resolve(tc.next())
+		tc.end()
+		active = true
+	case c.peekIndex():
+		// the table index of the MVP text format
+		seg.Index = p.tables.resolve(c.next())
+		active = true
+	}
+	if e := c.peek(); active || e != nil && e.isList && !e.is("item") {
+		seg.Offset = p.offset(c)
+		active = true
+	}
+	seg.Passive = !active && !seg.Declarative
+	switch {
+	case c.peekKeyword("func"):
+		c.next()
+		p.elemList(&seg, c, false)
+	case c.peekKeyword("funcref"), c.peekKeyword("anyfunc"), c.peekKeyword("externref"):
+		seg.Type = p.elemType(c.next())
+		p.elemList(&seg, c, true)
+	case active:
+		// the function indices of the MVP text format
+		p.elemList(&seg, c, false)
+	default:
+		errorf(e, "missing element type")
+	}
+	p.elemSegs = append(p.elemSegs, seg)
+}
+
+// elemList parses the elements of a segment, as expressions if exprs is
+// true or if the elements of an inline segment are expressions.
+func (p *moduleParser) elemList(seg *wasm.ElementSegment, c *cursor, exprs bool) {
+	if e := c.peek(); e != nil && !e.isList {
+		exprs = false
+	}
+	seg.Exprs = exprs
+	for !c.done() {
+		e := c.next()
+		if !exprs {
+			seg.Elems = append(seg.Elems, p.funcs.resolve(e))
+			continue
+		}
+		if !e.isList {
+			errorf(e, "expected an element expression, got %v", e)
+		}
+		ec := &cursor{list: []*sexpr{e}, parent: e}
+		if e.is("item") {
+			ec = e.cursor(1)
+		}
+		b := &code{p: p, locals: newSpace("local")}
+		b.all(ec)
+		if len(b.instrs) != 1 {
+			errorf(e, "unsupported element expression")
+		}
+		switch ins := b.instrs[0]; ins.Op.Code {
+		case ops.RefFunc:
+			seg.Elems = append(seg.Elems, ins.Immediates[0].(uint32))
+		case ops.RefNull:
+			seg.Elems = append(seg.Elems, wasm.NullElement)
+		default:
+			errorf(e, "unsupported element expression")
+		}
+	}
+}
+
+// data parses a data segment.
+func (p *moduleParser) data(e *sexpr, c *cursor) {
+	c.id()
+	var seg wasm.DataSegment
+	active := false
+	switch {
+	case c.peekList("memory"):
+		mc := c.next().cursor(1)
+		seg.Index = p.mems.resolve(mc.next())
+		mc.end()
+		active = true
+	case c.peekIndex():
+		// the memory index of the MVP text format
+		seg.Index = p.mems.resolve(c.next())
+		active = true
+	}
+	if e := c.peek(); active || e != nil && e.isList {
+		seg.Offset = p.offset(c)
+		active = true
+	}
+	seg.Passive = !active
+	seg.Data = c.strings()
+	c.end()
+	p.dataSegs = append(p.dataSegs, seg)
+}
@@ -0,0 +1,205 @@
+// Copyright 2018 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wast
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tinychain/tiny-wasm/wagon/exec"
+	"github.com/tinychain/tiny-wasm/wagon/validate"
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+	"github.com/tinychain/tiny-wasm/wagon/wast"
+)
+
+// writeModule returns the text representation of m, as written by WriteTo.
+func writeModule(t *testing.T, m *wasm.Module) string {
+	buf := new(bytes.Buffer)
+	if err := wast.WriteTo(buf, m); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestParseModule(t *testing.T) {
+	for _, dir := range []string{"../wasm/testdata", "../exec/testdata", "../exec/testdata/spec"} {
+		fnames, err := filepath.Glob(filepath.Join(dir, "*.wast"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, fname := range fnames {
+			name := fname
+			t.Run(filepath.Base(name), func(t *testing.T) {
+				src, err := ioutil.ReadFile(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				raw, err := ioutil.ReadFile(strings.TrimSuffix(name, ".wast") + ".wasm")
+				if os.IsNotExist(err) || bytes.Contains(src, []byte("(assert_")) {
+					t.Skip("not a module")
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				want, err := wasm.DecodeModule(bytes.NewReader(raw))
+				if err != nil {
+					t.Fatal(err)
+				}
+				want.Customs = nil
+
+				m, err := wast.ParseModule(bytes.NewReader(src))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if have, want := writeModule(t, m), writeModule(t, want); have != want {
+					t.Fatalf("have:\n%s\nwant:\n%s", have, want)
+				}
+
+				// the module can be encoded and decoded again
+				buf := new(bytes.Buffer)
+				if err := wasm.EncodeModule(buf, m); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := wasm.DecodeModule(buf); err != nil {
+					t.Fatal(err)
+				}
+			})
+		}
+	}
+}
+
+func encode(t *testing.T, text string) []byte {
+	m, err := wast.ParseModule(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if err := wasm.EncodeModule(buf, m); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseAbbreviations(t *testing.T) {
+	have := encode(t, `(module
+  (func $log (import "env" "log") (param i32))
+  (func $main (export "main") (param $x i32) (result i32)
+    (local $y i64)
+    block $out
+      local.get $x
+      br_if $out
+      (call $log (local.get $x))
+    end $out
+    (if (result i32) (global.get $g)
+      (then (i32.const 1))
+      (else (i32.const 0x10))))
+  (global $g (import "env" "g") i32)
+  (table funcref (elem $main $log))
+  (memory (data "hi"))
+  (data (i32.const 8) "ab" "c\64"))`)
+	want := encode(t, `
+  (type (func (param i32)))
+  (type (func (param i32) (result i32)))
+  (import "env" "log" (func (type 0)))
+  (import "env" "g" (global i32))
+  (func (type 1) (local i64)
+    block
+      get_local 0
+      br_if 0
+      get_local 0
+      call 0
+    end
+    get_global 0
+    if (result i32)
+      i32.const 1
+    else
+      i32.const 16
+    end)
+  (table 2 2 anyfunc)
+  (memory 1 1)
+  (export "main" (func 1))
+  (elem (i32.const 0) 1 0)
+  (data (i32.const 0) "hi")
+  (data (i32.const 8) "abcd")`)
+	if !bytes.Equal(have, want) {
+		t.Errorf("have %x, want %x", have, want)
+	}
+}
+
+func TestParseAndExec(t *testing.T) {
+	raw := encode(t, `(module
+  (func $fac (export "fac") (param $n i64) (result i64)
+    (if (result i64) (i64.eqz (local.get $n))
+      (then (i64.const 1))
+      (else (i64.mul (local.get $n) (call $fac (i64.sub (local.get $n) (i64.const 1)))))))
+  (func (export "switch") (param i32) (result i32)
+    (block $c (block $b (block $a
+      (br_table $a $b $c (local.get 0)))
+      (return (i32.const 10)))
+      (return (i32.const 20)))
+    i32.const 30)
+  (memory 1)
+  (data (i32.const 16) "\2a\00\00\00")
+  (func (export "load") (result i32) (i32.load offset=16 align=4 (i32.const 0)))
+  (table funcref (elem $seven))
+  (type $ret (func (result i32)))
+  (func $seven (type $ret) i32.const 7)
+  (func (export "indirect") (result i32) (call_indirect (type $ret) (i32.const 0))))`)
+	m, err := wasm.ReadModule(bytes.NewReader(raw), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validate.VerifyModule(m); err != nil {
+		t.Fatal(err)
+	}
+	vm, err := exec.NewVM(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		args []uint64
+		want interface{}
+	}{
+		{"fac", []uint64{5}, uint64(120)},
+		{"switch", []uint64{0}, uint32(10)},
+		{"switch", []uint64{1}, uint32(20)},
+		{"switch", []uint64{9}, uint32(30)},
+		{"load", nil, uint32(42)},
+		{"indirect", nil, uint32(7)},
+	} {
+		have, err := vm.ExecCode(int64(m.Export.Entries[tt.name].Index), tt.args...)
+		if err != nil {
+			t.Fatalf("%s%v: %v", tt.name, tt.args, err)
+		}
+		if have != tt.want {
+			t.Errorf("%s%v: have %v, want %v", tt.name, tt.args, have, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tt := range []struct {
+		text, err string
+	}{
+		{`(module (func (i32.const 0x1_0000_0000)))`, "1:26: invalid i32 0x1_0000_0000"},
+		{`(module (func call $nope))`, "1:20: unknown function $nope"},
+		{"(module\n  (func block $a end $b))", "2:22: mismatching label $b"},
+		{`(module (memory 1) (import "a" "b" (memory 1)))`, "1:20: import after the definition of a memory"},
+		{`(module (func i32.nop))`, "1:15: unknown operator i32.nop"},
+		{`(module (data "\x"))`, "1:16: invalid escape in string"},
+		{`(module (func (nop))`, "1:1: unclosed parenthesis"},
+		{`(module (func (i32.load align=3 (i32.const 0))))`, "1:25: invalid alignment align=3"},
+	} {
+		_, err := wast.ParseModule(strings.NewReader(tt.text))
+		if err == nil || !strings.HasSuffix(err.Error(), tt.err) {
+			t.Errorf("%s: have %v, want %s", tt.text, err, tt.err)
+		}
+	}
+}
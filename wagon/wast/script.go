@@ -0,0 +1,577 @@
+// Copyright 2018 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wast
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+
+	"github.com/tinychain/tiny-wasm/wagon/exec"
+	"github.com/tinychain/tiny-wasm/wagon/validate"
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+)
+
+// See https://github.com/WebAssembly/spec/tree/master/interpreter#scripts
+
+// ScriptConfig configures RunScript.
+type ScriptConfig struct {
+	// Features are the features enabled in the modules of the script.
+	Features wasm.Features
+	// Skip lists the lines of the commands not to run, for instance the
+	// assertions on behaviors the VM doesn't conform to yet.
+	Skip []int
+}
+
+// Result is the outcome of a command of a script.
+type Result struct {
+	Line    int    // line of the command in the script
+	Command string // keyword of the command, e.g. "assert_return"
+	Skipped bool   // whether the command is in the skip list
+	Err     error  // why the command failed, nil if it succeeded or was skipped
+}
+
+func (r Result) String() string {
+	switch {
+	case r.Skipped:
+		return fmt.Sprintf("%d: %s: skipped", r.Line, r.Command)
+	case r.Err != nil:
+		return fmt.Sprintf("%d: %s: %v", r.Line, r.Command, r.Err)
+	}
+	return fmt.Sprintf("%d: %s: ok", r.Line, r.Command)
+}
+
+// RunScript runs the commands of a WebAssembly spec test script and returns
+// their results, in order. The modules of the script are read by
+// wasm.ReadModule, verified by validate.VerifyModule and instantiated in an
+// exec.Store, where the "spectest" instance exports the functions, globals,
+// table and memory the spec tests import; its functions print nothing.
+//
+// The messages of assert_trap and assert_exhaustion are matched against the
+// errors of the VM, the ones of assert_invalid, assert_malformed,
+// assert_unlinkable and assert_uninstantiable are not. A failing command
+// doesn't stop the script, a malformed one does: RunScript returns a
+// *SyntaxError and the results of the commands run before it.
+func RunScript(r io.Reader, cfg ScriptConfig) ([]Result, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	exprs, err := parseSexprs(src)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &script{
+		cfg:       cfg,
+		store:     exec.NewStore(),
+		instances: make(map[string]*instance),
+	}
+	m, err := parseText([]byte(spectest))
+	if err != nil {
+		return nil, err
+	}
+	if m, err = s.encode(m); err != nil {
+		return nil, err
+	}
+	if _, err := s.store.Instantiate("spectest", m); err != nil {
+		return nil, err
+	}
+
+	skip := make(map[int]bool, len(cfg.Skip))
+	for _, line := range cfg.Skip {
+		skip[line] = true
+	}
+	results := make([]Result, 0, len(exprs))
+	err = s.runAll(exprs, skip, &results)
+	return results, err
+}
+
+// spectest is the module the spec tests import from.
+const spectest = `
+(func (export "print"))
+(func (export "print_i32") (param i32))
+(func (export "print_i64") (param i64))
+(func (export "print_f32") (param f32))
+(func (export "print_f64") (param f64))
+(func (export "print_i32_f32") (param i32 f32))
+(func (export "print_f64_f64") (param f64 f64))
+(global (export "global_i32") i32 (i32.const 666))
+(global (export "global_i64") i64 (i64.const 666))
+(global (export "global_f32") f32 (f32.const 666.6))
+(global (export "global_f64") f64 (f64.const 666.6))
+(table (export "table") 10 20 funcref)
+(memory (export "memory") 1 2)
+`
+
+// script is the state of a running script.
+type script struct {
+	cfg       ScriptConfig
+	store     *exec.Store
+	current   *instance            // the last instantiated module
+	instances map[string]*instance // the modules by identifier
+}
+
+// instance is an instance of a module of the script.
+type instance struct {
+	vm     *exec.VM
+	module *wasm.Module
+}
+
+// runAll runs the commands exprs, appending their results to *results.
+func (s *script) runAll(exprs []*sexpr, skip map[int]bool, results *[]Result) (err error) {
+	defer recoverSyntaxError(&err)
+	for _, e := range exprs {
+		if !e.isList || len(e.list) == 0 || e.list[0].isList || e.list[0].str {
+			errorf(e, "expected a command, got %v", e)
+		}
+		r := Result{Line: e.pos.line, Command: e.list[0].atom}
+		if skip[r.Line] {
+			r.Skipped = true
+		} else {
+			r.Err = s.run(e)
+		}
+		*results = append(*results, r)
+	}
+	return nil
+}
+
+// run runs the command e.
+func (s *script) run(e *sexpr) error {
+	c := e.cursor(1)
+	switch kw := e.list[0].atom; kw {
+	case "module":
+		s.current = nil
+		id := c.id()
+		m, err := s.verify(e)
+		if err != nil {
+			return err
+		}
+		inst, err := s.instantiate(m)
+		if err != nil {
+			return err
+		}
+		s.current = inst
+		if id != "" {
+			s.instances[id] = inst
+		}
+		return nil
+
+	case "register":
+		name := c.string()
+		inst, err := s.instance(c)
+		c.end()
+		if err != nil {
+			return err
+		}
+		return s.store.Register(name, inst.vm)
+
+	case "invoke", "get":
+		_, err := s.action(e)
+		return err
+
+	case "assert_return", "assert_return_canonical_nan", "assert_return_arithmetic_nan":
+		action := c.next()
+		var want []value
+		switch kw {
+		case "assert_return_canonical_nan":
+			want = []value{{pattern: "nan:canonical"}}
+		case "assert_return_arithmetic_nan":
+			want = []value{{pattern: "nan:arithmetic"}}
+		default:
+			for !c.done() {
+				want = append(want, parseValue(c.next(), true))
+			}
+		}
+		c.end()
+		got, err := s.action(action)
+		if err != nil {
+			return err
+		}
+		if len(got) != len(want) {
+			return fmt.Errorf("got %d results, want %d", len(got), len(want))
+		}
+		for i := range got {
+			if !want[i].match(got[i]) {
+				return fmt.Errorf("result %d is %v, want %v", i, got[i], want[i])
+			}
+		}
+		return nil
+
+	case "assert_trap", "assert_exhaustion":
+		arg := c.next()
+		msg := c.string()
+		c.end()
+		var err error
+		if arg.is("module") {
+			var m *wasm.Module
+			if m, err = s.verify(arg); err != nil {
+				return err
+			}
+			if _, err = s.instantiate(m); err == nil {
+				return errors.New("the module is instantiated")
+			}
+		} else if _, err = s.action(arg); err == nil {
+			return errors.New("the action didn't trap")
+		}
+		if err == exec.ErrCallStackExhausted {
+			// the VM doesn't report it as a trap
+			err = &exec.Trap{Err: err}
+		}
+		trap, ok := err.(*exec.Trap)
+		if !ok {
+			return err
+		}
+		if !matchTrap(trap, msg) {
+			return fmt.Errorf("trapped with %q, want %q", err, msg)
+		}
+		return nil
+
+	case "assert_malformed":
+		arg := c.next()
+		c.string()
+		c.end()
+		if _, err := s.decode(arg); err == nil {
+			return errors.New("the module is well-formed")
+		}
+		return nil
+
+	case "assert_invalid":
+		arg := c.next()
+		c.string()
+		c.end()
+		m, err := s.decode(arg)
+		if _, ok := err.(*SyntaxError); ok {
+			return err
+		}
+		if err == nil && validate.VerifyModule(m) == nil {
+			return errors.New("the module is valid")
+		}
+		return nil
+
+	case "assert_unlinkable", "assert_uninstantiable":
+		arg := c.next()
+		c.string()
+		c.end()
+		m, err := s.verify(arg)
+		if err != nil {
+			return err
+		}
+		_, err = s.instantiate(m)
+		switch _, ok := err.(exec.ImportError); {
+		case err == nil:
+			return errors.New("the module is instantiated")
+		case ok != (kw == "assert_unlinkable"):
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown command %s", e.list[0].atom)
+}
+
+// decode reads the module defined by the module command e, either in the
+// text format, quoted in the text format or in the binary format. The
+// syntax errors of the text are returned as a *SyntaxError.
+func (s *script) decode(e *sexpr) (*wasm.Module, error) {
+	if !e.is("module") {
+		errorf(e, "expected a module, got %v", e)
+	}
+	c := e.cursor(1)
+	c.id()
+	var (
+		m   *wasm.Module
+		err error
+	)
+	switch {
+	case c.peekKeyword("binary"):
+		c.next()
+		bin := c.strings()
+		c.end()
+		return s.read(bin)
+	case c.peekKeyword("quote"):
+		c.next()
+		src := c.strings()
+		c.end()
+		m, err = parseText(src)
+	default:
+		m, err = parseModule(e)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.encode(m)
+}
+
+// encode reads the module m, parsed from the text format, as if it was
+// decoded from the binary format.
+func (s *script) encode(m *wasm.Module) (*wasm.Module, error) {
+	buf := new(bytes.Buffer)
+	if err := wasm.EncodeModule(buf, m); err != nil {
+		return nil, err
+	}
+	return s.read(buf.Bytes())
+}
+
+// read reads the module bin in the binary format.
+func (s *script) read(bin []byte) (*wasm.Module, error) {
+	m, err := wasm.ReadModule(bytes.NewReader(bin), nil)
+	if err != nil {
+		return nil, err
+	}
+	m.Features = s.cfg.Features
+	return m, nil
+}
+
+// verify decodes and verifies the module e.
+func (s *script) verify(e *sexpr) (*wasm.Module, error) {
+	m, err := s.decode(e)
+	if err != nil {
+		return nil, err
+	}
+	if err := validate.VerifyModule(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// instantiate instantiates m in the store of the script. A start function
+// trapping is returned as an *exec.Trap.
+func (s *script) instantiate(m *wasm.Module) (inst *instance, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(error)
+			if !ok {
+				e = fmt.Errorf("exec: %v", r)
+			}
+			err = &exec.Trap{Err: e}
+		}
+	}()
+	vm, err := s.store.Instantiate("", m)
+	if err != nil {
+		return nil, err
+	}
+	vm.RecoverPanic = true
+	return &instance{vm: vm, module: m}, nil
+}
+
+// trapErrors are the errors of the VM for the spec trap messages they don't
+// contain.
+var trapErrors = map[string]error{
+	"indirect call type mismatch": exec.ErrSignatureMismatch,
+	"uninitialized element":       exec.ErrUndefinedElementIndex,
+}
+
+// matchTrap reports whether trap matches the spec message msg.
+func matchTrap(trap *exec.Trap, msg string) bool {
+	cause := trap.Err
+	for prefix, e := range trapErrors {
+		if strings.HasPrefix(msg, prefix) && cause == e {
+			return true
+		}
+	}
+	return strings.Contains(cause.Error(), msg)
+}
+
+// instance returns the instance named by the optional identifier of c, the
+// current one by default.
+func (s *script) instance(c *cursor) (*instance, error) {
+	if e := c.peek(); e != nil && e.isIndex() && e.atom[0] == '$' {
+		c.next()
+		inst, ok := s.instances[e.atom]
+		if !ok {
+			return nil, fmt.Errorf("unknown module %s", e.atom)
+		}
+		return inst, nil
+	}
+	if s.current == nil {
+		return nil, errors.New("no module is instantiated")
+	}
+	return s.current, nil
+}
+
+// action performs the invoke or get action e and returns its results.
+func (s *script) action(e *sexpr) ([]value, error) {
+	c := e.cursor(1)
+	if !e.is("invoke") && !e.is("get") {
+		errorf(e, "expected an action, got %v", e)
+	}
+	inst, err := s.instance(c)
+	if err != nil {
+		return nil, err
+	}
+	name := c.string()
+	var args []value
+	for !c.done() {
+		args = append(args, parseValue(c.next(), false))
+	}
+	var (
+		export wasm.ExportEntry
+		ok     bool
+	)
+	if inst.module.Export != nil {
+		export, ok = inst.module.Export.Entries[name]
+	}
+
+	if e.is("get") {
+		if !ok || export.Kind != wasm.ExternalGlobal {
+			return nil, fmt.Errorf("unknown global %q", name)
+		}
+		t := inst.module.GetGlobal(int(export.Index)).Type.Type
+		if t == wasm.ValueTypeV128 {
+			return nil, exec.ErrV128Signature
+		}
+		return []value{newValue(t, inst.vm.Global(int(export.Index)))}, nil
+	}
+
+	if !ok || export.Kind != wasm.ExternalFunction {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+	sig := inst.module.GetFunction(int(export.Index)).Sig
+	if len(args) != len(sig.ParamTypes) {
+		return nil, exec.ErrInvalidArgumentCount
+	}
+	raw := make([]uint64, len(args))
+	for i, arg := range args {
+		if arg.typ != sig.ParamTypes[i] {
+			return nil, fmt.Errorf("argument %d is %v, want %v", i, arg, sig.ParamTypes[i])
+		}
+		raw[i] = arg.bits
+	}
+	res, err := inst.vm.ExecCodeValues(int64(export.Index), raw...)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]value, len(res))
+	for i, v := range res {
+		results[i] = newValue(sig.ReturnTypes[i], v)
+	}
+	return results, nil
+}
+
+// value is an argument or a result of an action, or an expected result,
+// which may be a pattern instead.
+type value struct {
+	typ     wasm.ValueType
+	bits    uint64
+	pattern string // nan:canonical, nan:arithmetic or ref.func, "" for a value
+}
+
+// newValue returns the value of type t stored on the stack as v.
+func newValue(t wasm.ValueType, v uint64) value {
+	if t != wasm.ValueTypeI64 && t != wasm.ValueTypeF64 {
+		v = uint64(uint32(v))
+	}
+	return value{typ: t, bits: v}
+}
+
+// parseValue parses the constant e, a pattern if result is set.
+func parseValue(e *sexpr, result bool) value {
+	if !e.isList || len(e.list) == 0 {
+		errorf(e, "expected a constant, got %v", e)
+	}
+	c := e.cursor(1)
+	var v value
+	switch kw := e.list[0]; kw.atom {
+	case "i32.const", "i64.const":
+		v.typ, v.bits = wasm.ValueTypeI32, 32
+		if kw.atom == "i64.const" {
+			v.typ, v.bits = wasm.ValueTypeI64, 64
+		}
+		a := c.atom()
+		n, ok := parseInt(a.atom, uint(v.bits))
+		if !ok {
+			errorf(a, "invalid i%d %s", v.bits, a.atom)
+		}
+		v.bits = n
+	case "f32.const", "f64.const":
+		v.typ, v.bits = wasm.ValueTypeF32, 32
+		if kw.atom == "f64.const" {
+			v.typ, v.bits = wasm.ValueTypeF64, 64
+		}
+		a := c.atom()
+		if result && (a.atom == "nan:canonical" || a.atom == "nan:arithmetic") {
+			v.pattern = a.atom
+			break
+		}
+		n, ok := parseFloat(a.atom, uint(v.bits))
+		if !ok {
+			errorf(a, "invalid f%d %s", v.bits, a.atom)
+		}
+		v.bits = n
+	case "ref.null":
+		v.typ, v.bits = heapType(c.atom()), uint64(wasm.NullElement)
+	case "ref.extern":
+		v.typ = wasm.ValueTypeExternref
+		a := c.atom()
+		n, ok := parseNat(a.atom, 32)
+		if !ok {
+			errorf(a, "invalid reference %s", a.atom)
+		}
+		v.bits = n
+	case "ref.func":
+		if !result {
+			errorf(e, "unexpected %v", e)
+		}
+		v.typ, v.pattern = wasm.ValueTypeFuncref, kw.atom
+	case "v128.const":
+		errorf(e, "v128 values are not supported")
+	default:
+		errorf(e, "expected a constant, got %v", e)
+	}
+	c.end()
+	return v
+}
+
+// match reports whether the result got matches the expected result v.
+func (v value) match(got value) bool {
+	// the legacy assert_return_*_nan commands don't give the type
+	if v.typ != 0 && got.typ != v.typ {
+		return false
+	}
+	switch v.pattern {
+	case "ref.func":
+		return uint32(got.bits) != wasm.NullElement
+	case "nan:canonical", "nan:arithmetic":
+		var quiet, mask uint64 = 0x7fc00000, 0x7fffffff
+		if got.typ == wasm.ValueTypeF64 {
+			quiet, mask = 0x7ff8000000000000, 0x7fffffffffffffff
+		} else if got.typ != wasm.ValueTypeF32 {
+			return false
+		}
+		if v.pattern == "nan:arithmetic" {
+			mask = quiet
+		}
+		return got.bits&mask == quiet
+	}
+	return got.bits == v.bits
+}
+
+func (v value) String() string {
+	switch v.pattern {
+	case "ref.func":
+		return "(ref.func)"
+	case "":
+	default:
+		return fmt.Sprintf("(%v.const %s)", v.typ, v.pattern)
+	}
+	switch v.typ {
+	case wasm.ValueTypeI32:
+		return fmt.Sprintf("(i32.const %d)", int32(v.bits))
+	case wasm.ValueTypeI64:
+		return fmt.Sprintf("(i64.const %d)", int64(v.bits))
+	case wasm.ValueTypeF32:
+		return fmt.Sprintf("(f32.const %v)", math.Float32frombits(uint32(v.bits)))
+	case wasm.ValueTypeF64:
+		return fmt.Sprintf("(f64.const %v)", math.Float64frombits(v.bits))
+	}
+	if uint32(v.bits) == wasm.NullElement {
+		return fmt.Sprintf("(ref.null %v)", v.typ)
+	}
+	return fmt.Sprintf("(%v %d)", v.typ, v.bits)
+}
@@ -0,0 +1,120 @@
+// Copyright 2018 The go-interpreter Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wast
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+	"github.com/tinychain/tiny-wasm/wagon/wast"
+)
+
+// scriptSkips are the commands of the scripts the VM doesn't conform to, by
+// file name.
+var scriptSkips = map[string][]int{
+	"script.wast": {
+		59, // the type of the result of a function body isn't verified
+		68, // the version of a module isn't verified
+	},
+}
+
+// scriptFeatures are the features the scripts need besides multi-value, by
+// file name.
+var scriptFeatures = map[string]wasm.Features{
+	"bulk.wast":            wasm.FeatureBulkMemory,
+	"reference_types.wast": wasm.FeatureReferenceTypes,
+	"sign_extension.wast":  wasm.FeatureSignExt,
+	"simd.wast":            wasm.FeatureSIMD,
+	"trunc_sat.wast":       wasm.FeatureSatFloatToInt,
+}
+
+func TestRunScript(t *testing.T) {
+	files, err := filepath.Glob("../exec/testdata/spec/*.wast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files = append(files, "testdata/script.wast")
+	for _, fname := range files {
+		src, err := ioutil.ReadFile(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(src, []byte("(assert_")) {
+			continue
+		}
+		t.Run(filepath.Base(fname), func(t *testing.T) {
+			results, err := wast.RunScript(bytes.NewReader(src), wast.ScriptConfig{
+				Features: wasm.FeatureMultiValue | scriptFeatures[filepath.Base(fname)],
+				Skip:     scriptSkips[filepath.Base(fname)],
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) == 0 {
+				t.Fatal("no results")
+			}
+			for _, r := range results {
+				if r.Err != nil {
+					t.Error(r)
+				}
+			}
+		})
+	}
+}
+
+func TestRunScriptResults(t *testing.T) {
+	const script = `(module
+  (func (export "id") (param i32) (result i32) (local.get 0))
+  (func (export "f") (result f32) (f32.const 0.5)))
+(assert_return (invoke "id" (i32.const 1)) (i32.const 2))
+(assert_return (invoke "id" (i32.const 1)) (i32.const 2))
+(assert_trap (invoke "id" (i32.const 1)) "unreachable")
+(assert_return (invoke "f") (f32.const nan:canonical))
+(assert_return (invoke "f") (f64.const 0.5))
+(assert_return (invoke "g"))
+(assert_invalid (module (func)) "type mismatch")
+(assert_malformed (module quote "(func)") "unexpected token")
+(assert_return (invoke $M "id" (i32.const 1)) (i32.const 1))
+(module (func $f (export "f") (call $g)))
+(assert_return (invoke "f"))
+(fail)
+`
+	results, err := wast.RunScript(strings.NewReader(script), wast.ScriptConfig{Skip: []int{5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"1: module: ok",
+		"4: assert_return: result 0 is (i32.const 1), want (i32.const 2)",
+		"5: assert_return: skipped",
+		"6: assert_trap: the action didn't trap",
+		"7: assert_return: result 0 is (f32.const 0.5), want (f32.const nan:canonical)",
+		"8: assert_return: result 0 is (f32.const 0.5), want (f64.const 0.5)",
+		`9: assert_return: unknown function "g"`,
+		"10: assert_invalid: the module is valid",
+		"11: assert_malformed: the module is well-formed",
+		"12: assert_return: unknown module $M",
+		"13: module: wast: 13:37: unknown function $g",
+		"14: assert_return: no module is instantiated",
+		"15: fail: unknown command fail",
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %v", len(results), len(want), results)
+	}
+	for i, r := range results {
+		if r.String() != want[i] {
+			t.Errorf("got %q, want %q", r, want[i])
+		}
+	}
+
+	_, err = wast.RunScript(strings.NewReader(`(module) (assert_return (invoke "f" (i32.const x)))`), wast.ScriptConfig{})
+	if _, ok := err.(*wast.SyntaxError); !ok {
+		t.Errorf("got %v, want a syntax error", err)
+	}
+}
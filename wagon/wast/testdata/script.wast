@@ -0,0 +1,85 @@
+;; A script exercising the commands of the spec test scripts.
+
+(module $Mem
+  (import "spectest" "memory" (memory 1))
+  (import "spectest" "global_i32" (global $g i32))
+  (global (export "g") i32 (global.get $g))
+  (func (export "load") (param i32) (result i32)
+    (i32.load8_u (local.get 0)))
+  (func (export "store") (param i32 i32)
+    (i32.store8 (local.get 0) (local.get 1)))
+)
+(register "mem" $Mem)
+
+(assert_return (get "g") (i32.const 666))
+(invoke "store" (i32.const 7) (i32.const 42))
+(assert_return (invoke "load" (i32.const 7)) (i32.const 42))
+(assert_trap (invoke "load" (i32.const 65536)) "out of bounds memory access")
+
+(module
+  (import "mem" "load" (func $load (param i32) (result i32)))
+  (import "spectest" "print_i32" (func $print (param i32)))
+  (func (export "load") (param i32) (result i32)
+    (call $print (local.get 0))
+    (call $load (local.get 0)))
+  (func (export "div") (param i32 i32) (result i32)
+    (i32.div_u (local.get 0) (local.get 1)))
+  (func (export "neg") (param i64) (result i64)
+    (i64.sub (i64.const 0) (local.get 0)))
+  (func (export "nan") (param f32) (result f32)
+    (f32.div (local.get 0) (local.get 0)))
+  (func (export "sqrt") (param f64) (result f64)
+    (f64.sqrt (local.get 0)))
+  (func (export "two") (result i32 i64)
+    (i32.const 1) (i64.const -1))
+  (func $loop (export "loop")
+    (call $loop))
+  (func (export "unreachable")
+    unreachable)
+)
+
+(assert_return (invoke "load" (i32.const 7)) (i32.const 42))
+(assert_return (invoke "div" (i32.const 7) (i32.const 2)) (i32.const 3))
+(assert_trap (invoke "div" (i32.const 1) (i32.const 0)) "integer divide by zero")
+(assert_return (invoke "neg" (i64.const 0x7fffffffffffffff)) (i64.const -0x7fff_ffff_ffff_ffff))
+(assert_return (invoke "nan" (f32.const 0)) (f32.const nan:arithmetic))
+(assert_return (invoke "sqrt" (f64.const 0x1p+2)) (f64.const 2.0))
+(assert_return (invoke "sqrt" (f64.const -1)) (f64.const nan:canonical))
+(assert_return (invoke "two") (i32.const 1) (i64.const -1))
+(assert_exhaustion (invoke "loop") "call stack exhausted")
+(assert_trap (invoke "unreachable") "unreachable")
+
+(assert_trap
+  (module (func $start unreachable) (start $start))
+  "unreachable")
+
+(assert_invalid
+  (module (func (result i32) (i32.add (i32.const 0) (i64.const 0))))
+  "type mismatch")
+(assert_invalid
+  (module (func (result i32) (i64.const 0)))
+  "type mismatch")
+(assert_malformed
+  (module quote "(func (result i32) (i32.const 0x))")
+  "unknown operator")
+(assert_malformed
+  (module binary "\00asn" "\01\00\00\00")
+  "magic header not detected")
+(assert_malformed
+  (module binary "\00asm" "\02\00\00\00")
+  "unknown binary version")
+(assert_unlinkable
+  (module (import "spectest" "unknown" (func)))
+  "unknown import")
+(assert_uninstantiable
+  (module (memory 1) (data (i32.const 65536) "a"))
+  "out of bounds memory access")
+
+(module binary
+  "\00asm" "\01\00\00\00"
+  "\01\05\01\60\00\01\7f"                 ;; type section: () -> i32
+  "\03\02\01\00"                          ;; function section
+  "\07\07\01\03\6f\6e\65\00\00"           ;; export section: "one"
+  "\0a\06\01\04\00\41\01\0b"              ;; code section: i32.const 1
+)
+(assert_return (invoke "one") (i32.const 1))
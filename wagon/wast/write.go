@@ -10,6 +10,7 @@ package wast
 import (
 	"bufio"
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"math"
@@ -51,6 +52,13 @@ func newWriter(w io.Writer, m *wasm.Module) (*writer, error) {
 			wr.fnames = funcs.Names
 		}
 	}
+	if m.Code != nil {
+		for i := range m.Code.Bodies {
+			if err := m.Code.Bodies[i].Decode(); err != nil {
+				return nil, err
+			}
+		}
+	}
 	return wr, nil
 }
 
@@ -219,6 +227,8 @@ func (w *writer) writeTables() {
 		switch t.ElementType {
 		case wasm.ElemTypeAnyFunc:
 			w.WriteString("anyfunc")
+		case wasm.ElemTypeExternRef:
+			w.WriteString("externref")
 		}
 		w.WriteString(")")
 	}
@@ -272,14 +282,42 @@ func (w *writer) writeElements() {
 	for _, d := range w.m.Elements.Entries {
 		w.WriteString("\n")
 		w.WriteString(tab + "(elem")
-		if d.Index != 0 {
-			w.Print(" %d", d.Index)
+		switch {
+		case d.Passive:
+		case d.Declarative:
+			w.WriteString(" declare")
+		default:
+			if d.Index != 0 {
+				w.Print(" %d", d.Index)
+			}
+			w.WriteString(" (")
+			w.writeCode(d.Offset, true)
+			w.WriteString(")")
+		}
+		if !d.Exprs {
+			if d.Passive || d.Declarative {
+				w.WriteString(" func")
+			}
+			for _, v := range d.Elems {
+				w.Print(" %d", v)
+			}
+			w.WriteString(")")
+			continue
+		}
+		if d.Type == wasm.ElemTypeExternRef {
+			w.WriteString(" externref")
+		} else {
+			w.WriteString(" funcref")
 		}
-		w.WriteString(" (")
-		w.writeCode(d.Offset, true)
-		w.WriteString(")")
 		for _, v := range d.Elems {
-			w.Print(" %d", v)
+			switch {
+			case v != wasm.NullElement:
+				w.Print(" (ref.func %d)", v)
+			case d.Type == wasm.ElemTypeExternRef:
+				w.WriteString(" (ref.null extern)")
+			default:
+				w.WriteString(" (ref.null func)")
+			}
 		}
 		w.WriteString(")")
 	}
@@ -292,12 +330,15 @@ func (w *writer) writeData() {
 	for _, d := range w.m.Data.Entries {
 		w.WriteString("\n")
 		w.WriteString(tab + "(data")
-		if d.Index != 0 {
-			w.Print(" %d", d.Index)
+		if !d.Passive {
+			if d.Index != 0 {
+				w.Print(" %d", d.Index)
+			}
+			w.WriteString(" (")
+			w.writeCode(d.Offset, true)
+			w.WriteString(")")
 		}
-		w.WriteString(" (")
-		w.writeCode(d.Offset, true)
-		w.Print(") %s)", quoteData(d.Data))
+		w.Print(" %s)", quoteData(d.Data))
 	}
 }
 
@@ -352,12 +393,12 @@ func (w *writer) writeCode(code []byte, isInit bool) {
 		if !isInit {
 			w.WriteString("\n")
 		}
-		switch ins.Op.Code {
+		switch ins.Op.FirstByte() {
 		case operators.End, operators.Else:
 			tabs--
 			block--
 		}
-		if isInit && !hadEnd && ins.Op.Code == operators.End {
+		if isInit && !hadEnd && ins.Op.FirstByte() == operators.End {
 			hadEnd = true
 			continue
 		}
@@ -371,7 +412,7 @@ func (w *writer) writeCode(code []byte, isInit bool) {
 			}
 		}
 		w.WriteString(ins.Op.Name)
-		switch ins.Op.Code {
+		switch ins.Op.FirstByte() {
 		case operators.Else:
 			tabs++
 			block++
@@ -379,7 +420,9 @@ func (w *writer) writeCode(code []byte, isInit bool) {
 			tabs++
 			block++
 			b := ins.Immediates[0].(wasm.BlockType)
-			if b != wasm.BlockTypeEmpty {
+			if b.IsTypeIndex() {
+				w.Print(" (type %d)", int32(b))
+			} else if b != wasm.BlockTypeEmpty {
 				w.WriteString(" (result ")
 				w.WriteString(b.String())
 				w.WriteString(")")
@@ -418,13 +461,82 @@ func (w *writer) writeCode(code []byte, isInit bool) {
 			continue
 		case operators.CallIndirect:
 			i1 := ins.Immediates[0].(uint32)
+			if table := ins.Immediates[1].(uint32); table != 0 {
+				w.Print(" %d", table)
+			}
 			w.Print(" (type %d)", i1)
 			continue
+		case operators.SelectT:
+			w.WriteString(" (result")
+			for _, t := range ins.Immediates[1:] {
+				w.Print(" %v", t)
+			}
+			w.WriteString(")")
+			continue
+		case operators.RefNull:
+			if ins.Immediates[0].(wasm.ValueType) == wasm.ValueTypeExternref {
+				w.WriteString(" extern")
+			} else {
+				w.WriteString(" func")
+			}
+			continue
 		case operators.CurrentMemory, operators.GrowMemory:
 			r := ins.Immediates[0].(uint8)
 			if r == 0 {
 				continue
 			}
+		case operators.MiscPrefix:
+			switch ins.Op.Code {
+			case operators.MemoryInit:
+				w.Print(" %d", ins.Immediates[0].(uint32))
+				continue
+			case operators.MemoryCopy, operators.MemoryFill:
+				continue
+			case operators.TableInit:
+				elem, table := ins.Immediates[0].(uint32), ins.Immediates[1].(uint32)
+				if table != 0 {
+					w.Print(" %d", table)
+				}
+				w.Print(" %d", elem)
+				continue
+			case operators.TableCopy:
+				dst, src := ins.Immediates[0].(uint32), ins.Immediates[1].(uint32)
+				if dst != 0 || src != 0 {
+					w.Print(" %d %d", dst, src)
+				}
+				continue
+			}
+		case operators.SIMDPrefix:
+			switch ins.Op.Code {
+			case operators.V128Const:
+				v := ins.Immediates[0].([16]byte)
+				w.WriteString(" i32x4")
+				for i := 0; i < 16; i += 4 {
+					w.Print(" 0x%08x", binary.LittleEndian.Uint32(v[i:]))
+				}
+				continue
+			case operators.I8x16Shuffle:
+				for _, lane := range ins.Immediates[0].([16]byte) {
+					w.Print(" %d", lane)
+				}
+				continue
+			}
+			if len(ins.Immediates) < 2 {
+				break
+			}
+			// memory_immediate, followed by the lane index of the lane
+			// memory operators
+			align, offset := ins.Immediates[0].(uint32), ins.Immediates[1].(uint32)
+			if offset != 0 {
+				w.Print(" offset=%d", offset)
+			}
+			if align != simdAlign(ins.Op.Code) {
+				w.Print(" align=%d", 1<<align)
+			}
+			for _, a := range ins.Immediates[2:] {
+				w.Print(" %v", a)
+			}
+			continue
 		case operators.I32Store, operators.I64Store,
 			operators.I32Store8, operators.I64Store8,
 			operators.I32Store16, operators.I64Store16,
@@ -440,26 +552,10 @@ func (w *writer) writeCode(code []byte, isInit bool) {
 
 			i1 := ins.Immediates[0].(uint32)
 			i2 := ins.Immediates[1].(uint32)
-			dst := 0 // in log 2 (i8)
-			switch ins.Op.Code {
-			case operators.I64Load, operators.I64Store,
-				operators.F64Load, operators.F64Store:
-				dst = 3
-			case operators.I32Load, operators.I64Load32s, operators.I64Load32u,
-				operators.I32Store, operators.I64Store32,
-				operators.F32Load, operators.F32Store:
-				dst = 2
-			case operators.I32Load16u, operators.I32Load16s, operators.I64Load16u, operators.I64Load16s,
-				operators.I32Store16, operators.I64Store16:
-				dst = 1
-			case operators.I32Load8u, operators.I32Load8s, operators.I64Load8u, operators.I64Load8s,
-				operators.I32Store8, operators.I64Store8:
-				dst = 0
-			}
 			if i2 != 0 {
 				w.Print(" offset=%d", i2)
 			}
-			if int(i1) != dst {
+			if i1 != memoryAlign(ins.Op.Code) {
 				w.Print(" align=%d", 1<<i1)
 			}
 			continue
@@ -471,6 +567,42 @@ func (w *writer) writeCode(code []byte, isInit bool) {
 	}
 }
 
+// memoryAlign returns the log2 of the natural alignment of a memory
+// operator.
+func memoryAlign(code byte) uint32 {
+	switch code {
+	case operators.I64Load, operators.I64Store,
+		operators.F64Load, operators.F64Store:
+		return 3
+	case operators.I32Load, operators.I64Load32s, operators.I64Load32u,
+		operators.I32Store, operators.I64Store32,
+		operators.F32Load, operators.F32Store:
+		return 2
+	case operators.I32Load16u, operators.I32Load16s, operators.I64Load16u, operators.I64Load16s,
+		operators.I32Store16, operators.I64Store16:
+		return 1
+	}
+	return 0
+}
+
+// simdAlign returns the log2 of the natural alignment of a SIMD memory
+// operator.
+func simdAlign(code byte) uint32 {
+	switch code {
+	case operators.V128Load, operators.V128Store:
+		return 4
+	case operators.V128Load8x8S, operators.V128Load8x8U, operators.V128Load16x4S, operators.V128Load16x4U,
+		operators.V128Load32x2S, operators.V128Load32x2U, operators.V128Load64Splat, operators.V128Load64Zero,
+		operators.V128Load64Lane, operators.V128Store64Lane:
+		return 3
+	case operators.V128Load32Splat, operators.V128Load32Zero, operators.V128Load32Lane, operators.V128Store32Lane:
+		return 2
+	case operators.V128Load16Splat, operators.V128Load16Lane, operators.V128Store16Lane:
+		return 1
+	}
+	return 0
+}
+
 func formatFloat32(v float32) string {
 	s := ""
 	if v == float32(int32(v)) {
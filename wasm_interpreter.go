@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"github.com/tinychain/tinychain/core/vm"
 	"reflect"
+	"sort"
+	"sync"
 
 	"github.com/tinychain/tiny-wasm/wagon/exec"
 	"github.com/tinychain/tiny-wasm/wagon/wasm"
@@ -21,147 +23,110 @@ const (
 	TerminateInvalid
 )
 
-// funcSet wraps the necessary fields of an importing wasm module
+// funcSet wraps the necessary fields of an importing wasm module. Function
+// sets are immutable once built and shared by all interpreters.
 type funcSet struct {
-	entries []wasm.FunctionSig
-	funcs   []wasm.Function
-	exports map[string]wasm.ExportEntry
+	handlers map[string]reflect.Value // host function handlers
+	entries  []wasm.FunctionSig
+	funcs    []wasm.Function
+	exports  map[string]wasm.ExportEntry
 }
 
-type WasmIntptr struct {
-	// execution fields
-	vm            *exec.VM
-	contract      *Contract
-	readonly      bool          // static mode
-	evm           *EVM          // evm instance
-	terminateType TerminateType // termination type of the execution
-	returnData    []byte        // returning output data for the execution
-
-	// module resolver components
-	handlers      map[string]reflect.Value // eei function handlers
-	debugHandlers map[string]reflect.Value // debug function handlers
-	eeiFuncSet    *funcSet                 // eei function set
-	debugFuncSet  *funcSet                 // debug function set
-
-	// meter
-	metering bool
-}
-
-func NewWasmIntptr(evm *EVM) *WasmIntptr {
-	w := &WasmIntptr{
-		evm:        evm,
-		handlers:   make(map[string]reflect.Value),
-		eeiFuncSet: &funcSet{exports: make(map[string]wasm.ExportEntry)},
-	}
-
-	w.initEEIModule()
-	if w.debug() {
-		w.initDebugModule()
+// newFuncSet builds the function set exporting the given host functions under
+// their map keys. A host function takes the *exec.Process and the
+// *WasmIntptr, followed by its wasm parameters.
+func newFuncSet(fns map[string]interface{}) *funcSet {
+	names := make([]string, 0, len(fns))
+	for name := range fns {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	return w
-}
-
-func (w *WasmIntptr) initEEIModule() {
-	// eei function register
-	api := &eeiApi{}
-	rapi := reflect.TypeOf(api)
-	fnCount := rapi.NumMethod()
-	for i := 0; i < fnCount; i++ {
-		fn := rapi.Method(i)
-		w.handlers[fn.Name] = fn.Func
+	set := &funcSet{
+		handlers: make(map[string]reflect.Value, len(names)),
+		entries:  make([]wasm.FunctionSig, len(names)),
+		funcs:    make([]wasm.Function, len(names)),
+		exports:  make(map[string]wasm.ExportEntry, len(names)),
 	}
-
-	i := 0
-	for k, f := range w.handlers {
-		rType := reflect.TypeOf(f)
+	for i, name := range names {
+		f := reflect.ValueOf(fns[name])
+		rType := f.Type()
 		numIn := rType.NumIn() - 2
 		args := make([]wasm.ValueType, numIn)
 		for j := 0; j < numIn; j++ {
-			args[j] = goType2WasmType(rType.In(j + 2).Kind())
+			args[j] = goType2WasmType(rType.In(j + 2))
 		}
 
 		numOut := rType.NumOut()
 		returns := make([]wasm.ValueType, numOut)
-		for j := 0; j < numIn; j++ {
-			returns[j] = goType2WasmType(rType.Out(j).Kind())
+		for j := 0; j < numOut; j++ {
+			returns[j] = goType2WasmType(rType.Out(j))
 		}
 
-		w.eeiFuncSet.entries[i] = wasm.FunctionSig{
+		set.handlers[name] = f
+		set.entries[i] = wasm.FunctionSig{
 			ParamTypes:  args,
 			ReturnTypes: returns,
 		}
-
-		w.eeiFuncSet.funcs[i] = wasm.Function{
-			Sig:  &w.eeiFuncSet.entries[i],
+		set.funcs[i] = wasm.Function{
+			Sig:  &set.entries[i],
 			Body: &wasm.FunctionBody{},
 			Host: f,
 		}
-
-		w.eeiFuncSet.exports[k] = wasm.ExportEntry{
-			FieldStr: k,
+		set.exports[name] = wasm.ExportEntry{
+			FieldStr: name,
 			Kind:     wasm.ExternalFunction,
 			Index:    uint32(i),
 		}
-
-		i++
 	}
+	return set
 }
 
-func (w *WasmIntptr) initDebugModule() {
-	w.debugHandlers = make(map[string]reflect.Value)
-	w.debugFuncSet = &funcSet{exports: make(map[string]wasm.ExportEntry)}
-
-	dapi := &eeiDebugApi{}
-	rdapi := reflect.TypeOf(dapi)
-	fnCount := rdapi.NumMethod()
-	for i := 0; i < fnCount; i++ {
-		fn := rdapi.Method(i)
-		w.debugHandlers[fn.Name] = fn.Func
-	}
-
-	i := 0
-	for k, v := range w.debugHandlers {
-		rType := reflect.TypeOf(v)
-		numIn := rType.NumIn() - 2
-		args := make([]wasm.ValueType, numIn)
-		for j := 0; j < numIn; j++ {
-			args[i] = goType2WasmType(rType.In(j + 2).Kind())
-		}
-		numOut := rType.NumOut()
-		returns := make([]wasm.ValueType, numOut)
-		for j := 0; j < numOut; j++ {
-			returns[j] = goType2WasmType(rType.Out(j).Kind())
-		}
+var (
+	funcSetsOnce sync.Once
+	eeiFuncSet   *funcSet // `ethereum` module
+	debugFuncSet *funcSet // `debug` module
+)
 
-		w.debugFuncSet.entries[i] = wasm.FunctionSig{
-			ParamTypes:  args,
-			ReturnTypes: returns,
-		}
+// hostFuncSets returns the function sets of the host modules, building them
+// on first use.
+func hostFuncSets() (eei *funcSet, debug *funcSet) {
+	funcSetsOnce.Do(func() {
+		eeiFuncSet = newFuncSet(eeiFuncs())
+		debugFuncSet = newFuncSet(eeiDebugFuncs())
+	})
+	return eeiFuncSet, debugFuncSet
+}
 
-		w.debugFuncSet.funcs[i] = wasm.Function{
-			Sig:  &w.debugFuncSet.entries[i],
-			Body: &wasm.FunctionBody{},
-			Host: v,
-		}
+type WasmIntptr struct {
+	// execution fields
+	vm            *exec.VM
+	contract      *Contract
+	readonly      bool          // static mode
+	evm           *EVM          // evm instance
+	terminateType TerminateType // termination type of the execution
+	returnData    []byte        // returning output data for the execution
 
-		w.debugFuncSet.exports[k] = wasm.ExportEntry{
-			FieldStr: k,
-			Kind:     wasm.ExternalFunction,
-			Index:    uint32(i),
-		}
+	// module resolver components
+	eeiFuncSet   *funcSet // eei function set
+	debugFuncSet *funcSet // debug function set
 
-		i++
-	}
+	// meter
+	metering bool
+}
 
+func NewWasmIntptr(evm *EVM) *WasmIntptr {
+	w := &WasmIntptr{evm: evm}
+	w.eeiFuncSet, w.debugFuncSet = hostFuncSets()
+	return w
 }
 
 func (w *WasmIntptr) GetHandlers() map[string]reflect.Value {
-	return w.handlers
+	return w.eeiFuncSet.handlers
 }
 
 func (w *WasmIntptr) GetHandler(name string) (reflect.Value, bool) {
-	val, ok := w.handlers[name]
+	val, ok := w.eeiFuncSet.handlers[name]
 	return val, ok
 }
 
@@ -175,12 +140,18 @@ func (w *WasmIntptr) useGas(amount uint64) {
 	}
 
 	if amount > w.contract.Gas {
-		panic("out of gas")
+		panic(vm.ErrOutOfGas)
 	}
 
 	w.contract.Gas -= amount
 }
 
+// useBulkGas charges the bytes, or table elements, copied or filled by a
+// bulk memory operator like the host functions copying memory.
+func (w *WasmIntptr) useBulkGas(n uint64) {
+	w.useGas(GasCostCopy * n)
+}
+
 func (w *WasmIntptr) StateDB() vm.StateDB {
 	return w.evm.StateDB
 }
@@ -189,15 +160,17 @@ func (w *WasmIntptr) Run(contract *Contract, input []byte) ([]byte, error) {
 	w.evm.depth++
 	w.contract = contract
 	w.contract.Input = input
+	w.terminateType = TerminateFinish
 
 	defer func() {
 		w.evm.depth--
 	}()
 
-	module, err := wasm.ReadModule(bytes.NewReader(contract.Code), ModuleResolver(w))
+	module, err := wasm.ReadModuleConfig(bytes.NewReader(contract.Code), ModuleResolver(w), w.evm.vmConfig.WasmDecodeConfig)
 	if err != nil {
 		return nil, err
 	}
+	module.Features = w.evm.vmConfig.WasmFeatures
 
 	mainIndex, err := w.verifyModule(module)
 	if err != nil {
@@ -209,13 +182,25 @@ func (w *WasmIntptr) Run(contract *Contract, input []byte) ([]byte, error) {
 		return nil, fmt.Errorf("failed to create vm: %v", err)
 	}
 	vm.RecoverPanic = true
+	vm.SetWasmIntptr(w)
+	vm.SetCancelFlag(&w.evm.abort)
+	vm.SetMeter(w.useBulkGas)
 	w.vm = vm
 
 	sig := module.FunctionIndexSpace[mainIndex].Sig
 	if len(sig.ParamTypes) == 0 && len(sig.ReturnTypes) == 0 {
 		_, err := vm.ExecCode(int64(mainIndex))
+		if err == exec.ErrAborted {
+			w.terminateType = TerminateInvalid
+			return nil, ErrExecutionAborted
+		}
+		if trap, ok := err.(*exec.Trap); ok {
+			err = trapError(contract.Address(), trap)
+		}
 		if err != nil {
 			w.terminateType = TerminateInvalid
+		} else if w.terminateType == TerminateRevert {
+			err = errExecutionReverted
 		}
 
 		if w.StateDB().HasSuicided(contract.Address()) {
@@ -0,0 +1,151 @@
+package tinywasm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tinychain/tiny-wasm/wagon/wasm"
+)
+
+func TestWasmFeatures(t *testing.T) {
+	// main: (drop (i64.trunc_sat_f64_s (f64.const 1e300)))
+	code := importModule(nil, []byte{0x44, 0x9c, 0x75, 0x00, 0x88, 0x3c, 0xe4, 0x37, 0x7e, 0xfc, 0x06, 0x1a}, nil)
+
+	statedb, msg := estimateState(code)
+	msg.GasLimit = 100000
+	res, err := DoCall(testContext(), statedb, Config{}, msg, nil, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Err == nil || !strings.Contains(res.Err.Error(), "i64.trunc_sat_f64_s is not enabled") {
+		t.Errorf("have %v, want the operator to be disabled", res.Err)
+	}
+
+	statedb, msg = estimateState(code)
+	msg.GasLimit = 100000
+	res, err = DoCall(testContext(), statedb, Config{WasmFeatures: wasm.FeatureSatFloatToInt}, msg, nil, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Err != nil {
+		t.Errorf("unexpected error %v", res.Err)
+	}
+}
+
+func TestSIMDFeature(t *testing.T) {
+	// main: (drop (i32x4.extract_lane 0 (i32x4.splat (i32.const 1))))
+	code := importModule(nil, []byte{0x41, 0x01, 0xfd, 0x11, 0xfd, 0x1b, 0x00, 0x1a}, nil)
+
+	for _, tt := range []struct {
+		features wasm.Features
+		err      string
+	}{
+		{0, "i32x4.splat is not enabled"},
+		{wasm.FeatureSIMD, ""},
+	} {
+		statedb, msg := estimateState(code)
+		msg.GasLimit = 100000
+		res, err := DoCall(testContext(), statedb, Config{WasmFeatures: tt.features}, msg, nil, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.err == "" && res.Err != nil {
+			t.Errorf("unexpected error %v", res.Err)
+		}
+		if tt.err != "" && (res.Err == nil || !strings.Contains(res.Err.Error(), tt.err)) {
+			t.Errorf("have %v, want %q", res.Err, tt.err)
+		}
+	}
+}
+
+// v128Module assembles a contract whose empty `main` function declares the
+// locals, and which defines the globals, without any SIMD instruction.
+func v128Module(locals, globals []byte) []byte {
+	code := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	code = append(code, wasmSection(0x01, []byte{0x01, 0x60, 0x00, 0x00})...)
+	code = append(code, wasmSection(0x03, []byte{0x01, 0x00})...)
+	code = append(code, wasmSection(0x05, []byte{0x01, 0x00, 0x01})...)
+	if globals != nil {
+		code = append(code, wasmSection(0x06, globals)...)
+	}
+	exports := append([]byte{0x02}, wasmName("main")...)
+	exports = append(exports, 0x00, 0x00)
+	exports = append(exports, wasmName("memory")...)
+	exports = append(exports, 0x02, 0x00)
+	code = append(code, wasmSection(0x07, exports)...)
+	fn := append(append([]byte{}, locals...), 0x0b)
+	return append(code, wasmSection(0x0a, append([]byte{0x01, byte(len(fn))}, fn...))...)
+}
+
+func TestV128Disabled(t *testing.T) {
+	// (global v128 (v128.const i64x2 0 0))
+	global := append([]byte{0x01, 0x7b, 0x00, 0xfd, 0x0c}, make([]byte, 16)...)
+	global = append(global, 0x0b)
+
+	for name, code := range map[string][]byte{
+		"local":  v128Module([]byte{0x01, 0x01, 0x7b}, nil),
+		"global": v128Module([]byte{0x00}, global),
+	} {
+		for _, features := range []wasm.Features{0, wasm.FeatureSIMD} {
+			statedb, msg := estimateState(code)
+			msg.GasLimit = 100000
+			res, err := DoCall(testContext(), statedb, Config{WasmFeatures: features}, msg, nil, nil, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if features == 0 && (res.Err == nil || !strings.Contains(res.Err.Error(), wasm.ErrV128.Error())) {
+				t.Errorf("%s: have %v, want %v", name, res.Err, wasm.ErrV128)
+			}
+			if features != 0 && res.Err != nil {
+				t.Errorf("%s: unexpected error %v", name, res.Err)
+			}
+		}
+	}
+}
+
+func TestWasmDecodeConfig(t *testing.T) {
+	// main: (drop (i32.const 1))
+	code := importModule(nil, []byte{0x41, 0x01, 0x1a}, nil)
+
+	for _, tt := range []struct {
+		cfg wasm.DecodeConfig
+		err string
+	}{
+		{wasm.DecodeConfig{LazyBodies: true}, ""},
+		{wasm.DecodeConfig{MaxBodySize: 4}, "MaxBodySize exceeded"},
+		{wasm.DecodeConfig{MaxSections: 2}, "MaxSections exceeded"},
+	} {
+		statedb, msg := estimateState(code)
+		msg.GasLimit = 100000
+		res, err := DoCall(testContext(), statedb, Config{WasmDecodeConfig: tt.cfg}, msg, nil, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.err == "" && res.Err != nil {
+			t.Errorf("unexpected error %v", res.Err)
+		}
+		if tt.err != "" && (res.Err == nil || !strings.Contains(res.Err.Error(), tt.err)) {
+			t.Errorf("have %v, want %q", res.Err, tt.err)
+		}
+	}
+}
+
+func TestBulkMemoryGas(t *testing.T) {
+	usedGas := func(n int32) uint64 {
+		// main: (memory.fill (i32.const 0) (i32.const 1) (i32.const n))
+		body := append(append(append(i32Const(0), i32Const(1)...), i32Const(n)...), 0xfc, 0x0b, 0x00)
+		statedb, msg := estimateState(importModule(nil, body, nil))
+		msg.GasLimit = 100000
+		res, err := DoCall(testContext(), statedb, Config{WasmFeatures: wasm.FeatureBulkMemory}, msg, nil, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Err != nil {
+			t.Fatalf("unexpected error %v", res.Err)
+		}
+		return res.UsedGas
+	}
+	if diff := usedGas(1000) - usedGas(0); diff != 1000*GasCostCopy {
+		t.Errorf("have %d gas for 1000 bytes, want %d", diff, 1000*GasCostCopy)
+	}
+}